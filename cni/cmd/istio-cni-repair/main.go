@@ -75,6 +75,11 @@ func parseFlags() (filters *repair.Filters, options *ControllerOptions) {
 	// Repair Options
 	pflag.Bool("delete-pods", false, "Controller will delete pods")
 	pflag.Bool("label-pods", false, "Controller will label pods")
+	pflag.Bool("repair-pods", false,
+		"Controller will re-apply the traffic redirection in the network namespace of pods, "+
+			"requires the host PID namespace and a privileged container")
+	pflag.String("iptables-path", repair.DefaultIptablesPath,
+		"The path of istio-iptables, run in the network namespace of pods if --repair-pods is true")
 	pflag.Bool("run-as-daemon", false, "Controller will run in a loop")
 	pflag.String(
 		"broken-pod-label-key",
@@ -113,6 +118,8 @@ func parseFlags() (filters *repair.Filters, options *ControllerOptions) {
 		RepairOptions: &repair.Options{
 			DeletePods:    viper.GetBool("delete-pods"),
 			LabelPods:     viper.GetBool("label-pods"),
+			RepairPods:    viper.GetBool("repair-pods"),
+			IptablesPath:  viper.GetString("iptables-path"),
 			PodLabelKey:   viper.GetString("broken-pod-label-key"),
 			PodLabelValue: viper.GetString("broken-pod-label-value"),
 		},
@@ -140,10 +147,14 @@ func logCurrentOptions(bpr *repair.BrokenPodReconciler, options *ControllerOptio
 	if options.RunAsDaemon {
 		log.Infof("Controller Option: Running as a Daemon.")
 	}
-	if bpr.Options.DeletePods {
+	if bpr.Options.RepairPods {
+		log.Infof("Controller Option: Repairing the redirection of broken pods with %s. Pod Deletion and Labeling deactivated.",
+			bpr.Options.IptablesPath)
+	}
+	if bpr.Options.DeletePods && !bpr.Options.RepairPods {
 		log.Info("Controller Option: Deleting broken pods. Pod Labeling deactivated.")
 	}
-	if bpr.Options.LabelPods && !bpr.Options.DeletePods {
+	if bpr.Options.LabelPods && !bpr.Options.DeletePods && !bpr.Options.RepairPods {
 		log.Infof(
 			"Controller Option: Labeling broken pods with label %s=%s",
 			bpr.Options.PodLabelKey,
@@ -202,11 +213,15 @@ func main() {
 
 	} else {
 		err = nil
-		if podFixer.Options.LabelPods {
-			err = multierr.Append(err, podFixer.LabelBrokenPods())
-		}
-		if podFixer.Options.DeletePods {
-			err = multierr.Append(err, podFixer.DeleteBrokenPods())
+		if podFixer.Options.RepairPods {
+			err = multierr.Append(err, podFixer.RepairBrokenPods())
+		} else {
+			if podFixer.Options.LabelPods {
+				err = multierr.Append(err, podFixer.LabelBrokenPods())
+			}
+			if podFixer.Options.DeletePods {
+				err = multierr.Append(err, podFixer.DeleteBrokenPods())
+			}
 		}
 		if err != nil {
 			log.Fatalf(err.Error())
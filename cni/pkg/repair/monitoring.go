@@ -20,6 +20,7 @@ var (
 	typeLabel  = monitoring.MustCreateLabel("type")
 	deleteType = "delete"
 	labelType  = "label"
+	repairType = "repair"
 
 	resultLabel   = monitoring.MustCreateLabel("result")
 	resultSuccess = "success"
@@ -0,0 +1,147 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repair
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"istio.io/api/annotation"
+	"istio.io/istio/pilot/cmd/pilot-agent/options"
+)
+
+const (
+	// DefaultIptablesPath is the path of istio-iptables in the install-cni image.
+	DefaultIptablesPath = "/opt/cni/bin/istio-iptables"
+
+	defaultProcPath = "/proc"
+	proxyContainer  = "istio-proxy"
+)
+
+// redirectRunner runs istio-iptables in the network namespace of a pod.
+type redirectRunner interface {
+	// NetNS returns the path of the network namespace of the pod.
+	NetNS(pod v1.Pod) (string, error)
+	// Run runs istio-iptables in the network namespace, returning its output and exit code. An error is only
+	// returned if the command could not be run.
+	Run(netns string, args []string) (out string, code int, err error)
+}
+
+// nsenterRunner enters the network namespace of pods running on the node with nsenter. It must run in the host
+// PID namespace, with enough privileges to enter other network namespaces.
+type nsenterRunner struct {
+	procPath     string
+	iptablesPath string
+}
+
+// NetNS finds a process of the pod by its cgroup, which contains the pod UID. All containers of the pod,
+// including the sandbox, share the network namespace.
+func (r nsenterRunner) NetNS(pod v1.Pod) (string, error) {
+	uid := string(pod.UID)
+	if uid == "" {
+		return "", fmt.Errorf("pod %s/%s has no UID", pod.Namespace, pod.Name)
+	}
+	// The systemd cgroup driver replaces the dashes of the UID with underscores.
+	systemdUID := strings.ReplaceAll(uid, "-", "_")
+	cgroups, err := filepath.Glob(filepath.Join(r.procPath, "[0-9]*", "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	for _, cgroup := range cgroups {
+		content, err := ioutil.ReadFile(cgroup)
+		if err != nil {
+			// The process exited since the directory was listed.
+			continue
+		}
+		if bytes.Contains(content, []byte(uid)) || bytes.Contains(content, []byte(systemdUID)) {
+			return filepath.Join(filepath.Dir(cgroup), "ns", "net"), nil
+		}
+	}
+	return "", fmt.Errorf("no process found for pod %s/%s (uid %s)", pod.Namespace, pod.Name, uid)
+}
+
+func (r nsenterRunner) Run(netns string, args []string) (string, int, error) {
+	cmdArgs := append([]string{"--net=" + netns, "--", r.iptablesPath}, args...)
+	out, err := exec.Command("nsenter", cmdArgs...).CombinedOutput()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return string(out), exitErr.ExitCode(), nil
+		}
+		return string(out), 0, err
+	}
+	return string(out), 0, nil
+}
+
+// redirectArgs returns the istio-iptables arguments applying the redirection of the pod, as the istio-cni plugin
+// does from the pod annotations when the pod is created.
+func redirectArgs(pod v1.Pod) []string {
+	get := func(key, defaultVal string) string {
+		if v, f := pod.Annotations[key]; f {
+			return v
+		}
+		return defaultVal
+	}
+	excludeInboundPorts := strings.TrimSuffix(strings.TrimSpace(get(annotation.SidecarTrafficExcludeInboundPorts.Name, "15020")), ",")
+	if excludeInboundPorts != "" {
+		excludeInboundPorts += ","
+	}
+	excludeInboundPorts = strings.Join(dedupPorts(strings.Split(excludeInboundPorts+"15020,15021,15090", ",")), ",")
+
+	args := []string{
+		"-p", "15001",
+		"-u", "1337",
+		"-m", get(annotation.SidecarInterceptionMode.Name, "REDIRECT"),
+		"-i", get(annotation.SidecarTrafficIncludeOutboundIPRanges.Name, "*"),
+		"-b", get(annotation.SidecarTrafficIncludeInboundPorts.Name, "*"),
+		"-d", excludeInboundPorts,
+		"-o", get(annotation.SidecarTrafficExcludeOutboundPorts.Name, "15020"),
+		"-x", get(annotation.SidecarTrafficExcludeOutboundIPRanges.Name, ""),
+		"-k", get(annotation.SidecarTrafficKubevirtInterfaces.Name, ""),
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name != proxyContainer {
+			continue
+		}
+		for _, e := range c.Env {
+			if e.Name == options.DNSCaptureByAgent.Name {
+				if dns, _ := strconv.ParseBool(e.Value); dns {
+					args = append(args, "--redirect-dns", "--capture-all-dns")
+				}
+			}
+		}
+	}
+	return args
+}
+
+func dedupPorts(ports []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, port := range ports {
+		if !seen[port] {
+			seen[port] = true
+			out = append(out, port)
+		}
+	}
+	return out
+}
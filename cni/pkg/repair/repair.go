@@ -24,6 +24,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	client "k8s.io/client-go/kubernetes"
 
+	"istio.io/istio/tools/istio-iptables/pkg/constants"
 	"istio.io/pkg/log"
 )
 
@@ -32,6 +33,9 @@ type Options struct {
 	PodLabelValue string `json:"pod_label_value"`
 	LabelPods     bool   `json:"label_pods"`
 	DeletePods    bool   `json:"delete_broken_pods"`
+	RepairPods    bool   `json:"repair_pods"`
+	// IptablesPath is the path of the istio-iptables binary run in the network namespace of pods to repair.
+	IptablesPath string `json:"iptables_path"`
 }
 
 type Filters struct {
@@ -49,6 +53,8 @@ type BrokenPodReconciler struct {
 	client  client.Interface
 	Filters *Filters
 	Options *Options
+	// runner re-applies the redirection of pods, using nsenter if nil.
+	runner redirectRunner
 }
 
 // Constructs a new BrokenPodReconciler struct.
@@ -63,7 +69,9 @@ func NewBrokenPodReconciler(client client.Interface, filters *Filters, options *
 func (bpr BrokenPodReconciler) ReconcilePod(pod v1.Pod) (err error) {
 	log.Debugf("Reconciling pod %s", pod.Name)
 
-	if bpr.Options.DeletePods {
+	if bpr.Options.RepairPods {
+		err = multierr.Append(err, bpr.repairBrokenPod(pod))
+	} else if bpr.Options.DeletePods {
 		err = multierr.Append(err, bpr.deleteBrokenPod(pod))
 	} else if bpr.Options.LabelPods {
 		err = multierr.Append(err, bpr.labelBrokenPod(pod))
@@ -160,6 +168,109 @@ func (bpr BrokenPodReconciler) deleteBrokenPod(pod v1.Pod) error {
 	return nil
 }
 
+// Re-apply the redirection of all pods detected as broken by ListPods
+func (bpr BrokenPodReconciler) RepairBrokenPods() (err error) {
+	// Get a list of all broken pods
+	podList, err := bpr.ListBrokenPods()
+	if err != nil {
+		return err
+	}
+
+	for _, pod := range podList.Items {
+		err = multierr.Append(err, bpr.repairBrokenPod(pod))
+	}
+	return err
+}
+
+// repairBrokenPod re-applies the istio-iptables rules in the network namespace of the pod, so the validation init
+// container succeeds on its next restart without deleting the pod. Pods whose rules are already in place are skipped.
+func (bpr BrokenPodReconciler) repairBrokenPod(pod v1.Pod) error {
+	m := podsRepaired.With(typeLabel.Value(repairType))
+	// Added for safety, to make sure no healthy pods get repaired.
+	if !bpr.detectPod(pod) || pod.Spec.HostNetwork {
+		m.With(resultLabel.Value(resultSkip)).Increment()
+		return nil
+	}
+	log.Infof("Pod detected as broken, repairing redirection: %s/%s", pod.Namespace, pod.Name)
+
+	runner := bpr.runner
+	if runner == nil {
+		iptablesPath := bpr.Options.IptablesPath
+		if iptablesPath == "" {
+			iptablesPath = DefaultIptablesPath
+		}
+		runner = nsenterRunner{procPath: defaultProcPath, iptablesPath: iptablesPath}
+	}
+	fail := func(err error) error {
+		log.Errorf("Failed to repair pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		m.With(resultLabel.Value(resultFail)).Increment()
+		bpr.recordEvent(pod, v1.EventTypeWarning, "RedirectionRepairFailed",
+			fmt.Sprintf("Failed to re-apply the traffic redirection: %v", err))
+		return err
+	}
+
+	netns, err := runner.NetNS(pod)
+	if err != nil {
+		return fail(err)
+	}
+	args := redirectArgs(pod)
+	// The pod stays detected as broken until the validation init container restarts, skip it if the rules were
+	// already repaired.
+	out, code, err := runner.Run(netns, append(args, "--diff"))
+	if err != nil {
+		return fail(err)
+	}
+	if code == 0 {
+		log.Infof("Pod %s/%s already has the redirection rules, skipping", pod.Namespace, pod.Name)
+		m.With(resultLabel.Value(resultSkip)).Increment()
+		return nil
+	}
+	if code != constants.DiffErrorCode {
+		return fail(fmt.Errorf("failed to compare the redirection rules (exit code %d): %s", code, strings.TrimSpace(out)))
+	}
+	out, code, err = runner.Run(netns, args)
+	if err != nil {
+		return fail(err)
+	}
+	if code != 0 {
+		return fail(fmt.Errorf("istio-iptables exited with code %d: %s", code, strings.TrimSpace(out)))
+	}
+	m.With(resultLabel.Value(resultSuccess)).Increment()
+	bpr.recordEvent(pod, v1.EventTypeNormal, "RedirectionRepaired",
+		"Re-applied the traffic redirection in the pod network namespace")
+	return nil
+}
+
+// recordEvent reports the outcome of a repair as an event of the pod. Failing to create the event does not fail
+// the repair.
+func (bpr BrokenPodReconciler) recordEvent(pod v1.Pod, eventType, reason, message string) {
+	now := metav1.Now()
+	event := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", pod.Name, now.UnixNano()),
+			Namespace: pod.Namespace,
+		},
+		InvolvedObject: v1.ObjectReference{
+			Kind:            "Pod",
+			APIVersion:      "v1",
+			Namespace:       pod.Namespace,
+			Name:            pod.Name,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         v1.EventSource{Component: "istio-cni-repair", Host: pod.Spec.NodeName},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	if _, err := bpr.client.CoreV1().Events(pod.Namespace).Create(context.TODO(), event, metav1.CreateOptions{}); err != nil {
+		log.Warnf("Failed to record event %s for pod %s/%s: %v", reason, pod.Namespace, pod.Name, err)
+	}
+}
+
 // Lists all pods identified as broken by our Filter criteria
 func (bpr BrokenPodReconciler) ListBrokenPods() (list v1.PodList, err error) {
 	var rawList *v1.PodList
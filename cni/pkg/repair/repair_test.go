@@ -17,6 +17,9 @@ package repair
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"sync"
@@ -592,6 +595,181 @@ func TestBrokenPodReconciler_deleteBrokenPods(t *testing.T) {
 	}
 }
 
+// fakeRunner records the istio-iptables invocations, returning the exit code of --diff.
+type fakeRunner struct {
+	diffCode int
+	runCode  int
+	netnsErr error
+	calls    [][]string
+}
+
+func (f *fakeRunner) NetNS(pod v1.Pod) (string, error) {
+	if f.netnsErr != nil {
+		return "", f.netnsErr
+	}
+	return "/proc/1/ns/net", nil
+}
+
+func (f *fakeRunner) Run(netns string, args []string) (string, int, error) {
+	f.calls = append(f.calls, args)
+	if args[len(args)-1] == "--diff" {
+		return "", f.diffCode, nil
+	}
+	return "", f.runCode, nil
+}
+
+func TestBrokenPodReconciler_repairBrokenPods(t *testing.T) {
+	filters := &Filters{
+		InitContainerName:               constants.ValidationContainerName,
+		InitContainerExitCode:           126,
+		InitContainerTerminationMessage: "Died for some reason",
+	}
+	tests := []struct {
+		name       string
+		pods       []v1.Pod
+		runner     *fakeRunner
+		wantErr    bool
+		wantRuns   int
+		wantEvents []string
+		wantCount  float64
+		wantTags   []tag.Tag
+	}{
+		{
+			name:      "No broken pods",
+			pods:      []v1.Pod{workingPod, workingPodDiedPreviously},
+			runner:    &fakeRunner{},
+			wantCount: 0,
+		},
+		{
+			name:       "With broken pods",
+			pods:       []v1.Pod{workingPod, brokenPodWaiting},
+			runner:     &fakeRunner{diffCode: constants.DiffErrorCode},
+			wantRuns:   2,
+			wantEvents: []string{"RedirectionRepaired"},
+			wantCount:  1,
+			wantTags:   []tag.Tag{{Key: tag.Key(resultLabel), Value: resultSuccess}, {Key: tag.Key(typeLabel), Value: repairType}},
+		},
+		{
+			name:      "With already repaired pods",
+			pods:      []v1.Pod{brokenPodWaiting},
+			runner:    &fakeRunner{},
+			wantRuns:  1,
+			wantCount: 1,
+			wantTags:  []tag.Tag{{Key: tag.Key(resultLabel), Value: resultSkip}, {Key: tag.Key(typeLabel), Value: repairType}},
+		},
+		{
+			name:       "With failing istio-iptables",
+			pods:       []v1.Pod{brokenPodWaiting},
+			runner:     &fakeRunner{diffCode: constants.DiffErrorCode, runCode: 1},
+			wantErr:    true,
+			wantRuns:   2,
+			wantEvents: []string{"RedirectionRepairFailed"},
+			wantCount:  1,
+			wantTags:   []tag.Tag{{Key: tag.Key(resultLabel), Value: resultFail}, {Key: tag.Key(typeLabel), Value: repairType}},
+		},
+		{
+			name:       "Without network namespace",
+			pods:       []v1.Pod{brokenPodWaiting},
+			runner:     &fakeRunner{netnsErr: fmt.Errorf("no process found")},
+			wantErr:    true,
+			wantEvents: []string{"RedirectionRepairFailed"},
+			wantCount:  1,
+			wantTags:   []tag.Tag{{Key: tag.Key(resultLabel), Value: resultFail}, {Key: tag.Key(typeLabel), Value: repairType}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := initStats(tt.name)
+			bpr := BrokenPodReconciler{
+				client:  labelBrokenPodsClientset(tt.pods...),
+				Filters: filters,
+				Options: &Options{RepairPods: true},
+				runner:  tt.runner,
+			}
+			if err := bpr.RepairBrokenPods(); (err != nil) != tt.wantErr {
+				t.Errorf("RepairBrokenPods() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(tt.runner.calls) != tt.wantRuns {
+				t.Errorf("RepairBrokenPods() ran istio-iptables %d times, want %d: %v", len(tt.runner.calls), tt.wantRuns, tt.runner.calls)
+			}
+			events, err := bpr.client.CoreV1().Events("").List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("RepairBrokenPods() error listing events: %v", err)
+			}
+			var haveEvents []string
+			for _, e := range events.Items {
+				if e.InvolvedObject.Name != brokenPodWaiting.Name {
+					t.Errorf("RepairBrokenPods() recorded event for pod %s", e.InvolvedObject.Name)
+				}
+				haveEvents = append(haveEvents, e.Reason)
+			}
+			if !reflect.DeepEqual(haveEvents, tt.wantEvents) {
+				t.Errorf("RepairBrokenPods() haveEvents = %v, wantEvents = %v", haveEvents, tt.wantEvents)
+			}
+			if err := checkStats(tt.wantCount, tt.wantTags, exp); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRedirectArgs(t *testing.T) {
+	pod := *makePod(makePodArgs{
+		PodName: "Pod",
+		Annotations: map[string]string{
+			"sidecar.istio.io/interceptionMode":           "TPROXY",
+			"traffic.sidecar.istio.io/excludeInboundPorts": "8080,15020",
+			"traffic.sidecar.istio.io/includeInboundPorts": "",
+		},
+		InitContainerStatus: &workingInitContainer,
+	})
+	pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{
+		Name: "istio-proxy",
+		Env:  []v1.EnvVar{{Name: "ISTIO_META_DNS_CAPTURE", Value: "true"}},
+	})
+	want := []string{
+		"-p", "15001", "-u", "1337", "-m", "TPROXY", "-i", "*", "-b", "", "-d", "8080,15020,15021,15090",
+		"-o", "15020", "-x", "", "-k", "", "--redirect-dns", "--capture-all-dns",
+	}
+	if got := redirectArgs(pod); !reflect.DeepEqual(got, want) {
+		t.Errorf("redirectArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestNsenterRunner_NetNS(t *testing.T) {
+	proc, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(proc)
+	cgroups := map[string]string{
+		"1":   "0::/init.scope\n",
+		"42":  "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1234_5678.slice/cri-containerd-abc.scope\n",
+		"self": "0::/kubepods/besteffort/pod1234-5678/abc\n",
+	}
+	for pid, cgroup := range cgroups {
+		if err := os.MkdirAll(filepath.Join(proc, pid), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(proc, pid, "cgroup"), []byte(cgroup), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := nsenterRunner{procPath: proc}
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", UID: "1234-5678"}}
+	netns, err := r.NetNS(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(proc, "42", "ns", "net"); netns != want {
+		t.Errorf("NetNS() = %s, want %s", netns, want)
+	}
+	pod.UID = "8765-4321"
+	if netns, err := r.NetNS(pod); err == nil {
+		t.Errorf("NetNS() = %s, want error", netns)
+	}
+}
+
 type testExporter struct {
 	sync.Mutex
 
@@ -284,6 +284,14 @@ var testGrid = []testCase{
 			{msg.VirtualServiceHostNotFoundInGateway, "VirtualService httpbin"},
 		},
 	},
+	{
+		name:       "virtualServiceRateLimit",
+		inputFiles: []string{"testdata/virtualservice_ratelimit.yaml"},
+		analyzer:   &virtualservice.RateLimitAnalyzer{},
+		expected: []message{
+			{msg.RateLimitRouteNotFound, "VirtualService unknown-route.default"},
+		},
+	},
 	{
 		name:       "serviceMultipleDeployments",
 		inputFiles: []string{"testdata/deployment-multi-service.yaml"},
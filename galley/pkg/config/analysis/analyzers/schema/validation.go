@@ -69,8 +69,10 @@ func (a *ValidationAnalyzer) Analyze(ctx analysis.Context) {
 
 		warnings, err := a.s.Resource().ValidateConfig(config.Config{
 			Meta: config.Meta{
-				Name:      string(name),
-				Namespace: string(ns),
+				Name:        string(name),
+				Namespace:   string(ns),
+				Labels:      r.Metadata.Labels,
+				Annotations: r.Metadata.Annotations,
 			},
 			Spec: r.Message,
 		})
@@ -39,6 +39,7 @@ func TestCorrectArgs(t *testing.T) {
 	testSchema := schemaWithValidateFn(func(cfg config.Config) (warnings validation.Warning, errs error) {
 		g.Expect(cfg.Name).To(Equal("name"))
 		g.Expect(cfg.Namespace).To(Equal("ns"))
+		g.Expect(cfg.Annotations).To(Equal(map[string]string{"foo": "bar"}))
 		g.Expect(cfg.Spec).To(Equal(m1))
 		return nil, nil
 	})
@@ -47,7 +48,8 @@ func TestCorrectArgs(t *testing.T) {
 			{
 				Message: &v1alpha3.VirtualService{},
 				Metadata: resource.Metadata{
-					FullName: resource.NewFullName("ns", "name"),
+					FullName:    resource.NewFullName("ns", "name"),
+					Annotations: map[string]string{"foo": "bar"},
 				},
 				Origin: fakeOrigin{},
 			},
@@ -0,0 +1,74 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package virtualservice
+
+import (
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/ratelimit"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// RateLimitAnalyzer checks the routes referenced by the rate limit policies of virtual services. The rate limit
+// services are set with extension providers of the mesh config, and are checked by istiod.
+type RateLimitAnalyzer struct{}
+
+var _ analysis.Analyzer = &RateLimitAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *RateLimitAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "virtualservice.RateLimitAnalyzer",
+		Description: "Checks the routes referenced by the rate limit policies of virtual services",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *RateLimitAnalyzer) Analyze(ctx analysis.Context) {
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), func(r *resource.Instance) bool {
+		// Invalid policies are reported by the schema validation.
+		policies, err := ratelimit.Parse(r.Metadata.Annotations)
+		if err != nil || len(policies) == 0 {
+			return true
+		}
+		a.analyzeVirtualService(r, ctx, policies)
+		return true
+	})
+}
+
+func (a *RateLimitAnalyzer) analyzeVirtualService(r *resource.Instance, ctx analysis.Context, policies []*ratelimit.Policy) {
+	vs := r.Message.(*v1alpha3.VirtualService)
+	routes := map[string]struct{}{}
+	for _, http := range vs.Http {
+		routes[http.Name] = struct{}{}
+	}
+
+	for i, p := range policies {
+		if p == nil {
+			continue
+		}
+		for _, name := range p.Routes {
+			if _, f := routes[name]; !f {
+				ctx.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), msg.NewRateLimitRouteNotFound(r, i, name))
+			}
+		}
+	}
+}
@@ -254,6 +254,25 @@ func TestDefaultResourcesRespectsMeshConfig(t *testing.T) {
 	g.Expect(sa.sources).To(HaveLen(1))
 }
 
+func TestAddFileKubeMeshConfigValidatesExtensionProviders(t *testing.T) {
+	g := NewWithT(t)
+
+	sa := NewSourceAnalyzer(basicmeta.MustGet(), blankCombinedAnalyzer, "", "", nil, false, timeout)
+
+	// The rate limit provider is missing its domain
+	tmpMeshFile := tempFileFromString(t, `
+extensionProviders:
+- name: ratelimit
+  envoyRateLimit:
+    service: ratelimit.foo.svc.cluster.local
+    port: 8081
+`)
+	defer func() { _ = os.Remove(tmpMeshFile.Name()) }()
+
+	err := sa.AddFileKubeMeshConfig(tmpMeshFile.Name())
+	g.Expect(err).To(MatchError(ContainSubstring("domain must be set")))
+}
+
 func TestResourceFiltering(t *testing.T) {
 	g := NewWithT(t)
 
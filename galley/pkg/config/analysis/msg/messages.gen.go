@@ -176,6 +176,10 @@ var (
 	// ConflictingGateways defines a diag.MessageType for message "ConflictingGateways".
 	// Description: Gateway should not have the same selector, port and matched hosts of server
 	ConflictingGateways = diag.NewMessageType(diag.Error, "IST0145", "Conflict with gateways %s (workload selector %s, port %s, hosts %v).")
+
+	// RateLimitRouteNotFound defines a diag.MessageType for message "RateLimitRouteNotFound".
+	// Description: A rate limit policy references an HTTP route which is not defined in the virtual service
+	RateLimitRouteNotFound = diag.NewMessageType(diag.Warning, "IST0146", "Rate limit policy %d references HTTP route %q, which is not defined in the virtual service.")
 )
 
 // All returns a list of all known message types.
@@ -223,6 +227,7 @@ func All() []*diag.MessageType {
 		LocalhostListener,
 		InvalidApplicationUID,
 		ConflictingGateways,
+		RateLimitRouteNotFound,
 	}
 }
 
@@ -647,3 +652,13 @@ func NewConflictingGateways(r *resource.Instance, gateway string, selector strin
 		hosts,
 	)
 }
+
+// NewRateLimitRouteNotFound returns a new diag.Message based on RateLimitRouteNotFound.
+func NewRateLimitRouteNotFound(r *resource.Instance, policy int, route string) diag.Message {
+	return diag.NewMessage(
+		RateLimitRouteNotFound,
+		r,
+		policy,
+		route,
+	)
+}
@@ -71,6 +71,7 @@ require (
 	github.com/xeipuuv/gojsonpointer v0.0.0-20190905194746-02993c407bfb // indirect
 	github.com/yl2chen/cidranger v1.0.2
 	go.opencensus.io v0.23.0
+	go.opentelemetry.io/proto/otlp v0.7.0
 	go.uber.org/atomic v1.7.0
 	go.uber.org/multierr v1.7.0
 	golang.org/x/net v0.0.0-20210525063256-abc453219eb5
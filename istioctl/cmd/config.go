@@ -43,12 +43,16 @@ var settableFlags = map[string]interface{}{
 func configCmd() *cobra.Command {
 	configCmd := &cobra.Command{
 		Use:   "config SUBCOMMAND",
-		Short: "Configure istioctl defaults",
+		Short: "Configure istioctl defaults and show the config history of Istiod",
 		Args:  cobra.NoArgs,
 		Example: `  # list configuration parameters
-  istioctl config list`,
+  istioctl config list
+
+  # show the recent config changes
+  istioctl x config history`,
 	}
 	configCmd.AddCommand(listCommand())
+	configCmd.AddCommand(historyCommand())
 	return configCmd
 }
 
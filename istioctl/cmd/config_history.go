@@ -0,0 +1,206 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	envoy_corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/multixds"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func historyCommand() *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	var centralOpts clioptions.CentralControlPlaneOptions
+	var since, outputFormat string
+	var limit int
+	var showDiff bool
+
+	historyCmd := &cobra.Command{
+		Use:   "history [<kind>[/<name>[.<namespace>]]]",
+		Short: "Show the recent config changes seen by Istiod",
+		Long: `
+Show the recent config changes seen by Istiod, optionally restricted to a kind or a single config.
+Istiod keeps a bounded in memory history of changes, see PILOT_CONFIG_HISTORY_SIZE.
+`,
+		Example: `  # Show the config changes of the last 30 minutes
+  istioctl x config history --since 30m
+
+  # Show the changes of a VirtualService, including the diff of its spec
+  istioctl x config history VirtualService/reviews.bookinfo --diff
+
+  # Show the last 10 changes to Gateways in the istio-system namespace
+  istioctl x config history Gateway -n istio-system --limit 10`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			if outputFormat != "" && outputFormat != "short" && outputFormat != "json" {
+				return fmt.Errorf("output format %q not supported", outputFormat)
+			}
+			query, err := historyQuery(args, handlers.HandleNamespace(namespace, defaultNamespace), namespace, since, limit)
+			if err != nil {
+				return err
+			}
+			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return err
+			}
+			xdsRequest := xdsapi.DiscoveryRequest{
+				ResourceNames: []string{"config_history?" + query.Encode()},
+				Node: &envoy_corev3.Node{
+					Id: "debug~0.0.0.0~istioctl~cluster.local",
+				},
+				TypeUrl: v3.DebugType,
+			}
+			xdsResponses, err := multixds.AllRequestAndProcessXds(&xdsRequest, centralOpts, istioNamespace, "", "", kubeClient)
+			if err != nil {
+				return err
+			}
+			entries, err := mergeConfigHistory(xdsResponses)
+			if err != nil {
+				return err
+			}
+			if outputFormat == "json" {
+				out, err := json.MarshalIndent(entries, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintln(c.OutOrStdout(), string(out))
+				return err
+			}
+			return printConfigHistory(c.OutOrStdout(), entries, showDiff)
+		},
+	}
+
+	opts.AttachControlPlaneFlags(historyCmd)
+	centralOpts.AttachControlPlaneFlags(historyCmd)
+	historyCmd.Flags().StringVar(&since, "since", "",
+		"Only show changes newer than a relative duration like 5s, 2m, or 3h, or a RFC3339 timestamp")
+	historyCmd.Flags().IntVar(&limit, "limit", 0, "Only show the most recent changes, if positive")
+	historyCmd.Flags().BoolVar(&showDiff, "diff", false, "Show the diff of the spec of each change")
+	historyCmd.Flags().StringVarP(&outputFormat, "output", "o", "short", "Output format: one of json|short")
+	return historyCmd
+}
+
+// historyQuery builds the query of the config_history debug endpoint. The namespace of a named config defaults to
+// defaultNs, while listing a kind uses the namespace flag as is, so all namespaces are listed if it is not set.
+func historyQuery(args []string, defaultNs, flagNs, since string, limit int) (url.Values, error) {
+	query := url.Values{}
+	if len(args) > 0 {
+		kind, name := args[0], ""
+		if i := strings.Index(kind, "/"); i >= 0 {
+			kind, name = kind[:i], kind[i+1:]
+		}
+		if kind == "" {
+			return nil, fmt.Errorf("invalid config %q, expected <kind>[/<name>[.<namespace>]]", args[0])
+		}
+		query.Set("kind", kind)
+		if name != "" {
+			ns := defaultNs
+			if i := strings.LastIndex(name, "."); i >= 0 {
+				name, ns = name[:i], name[i+1:]
+			}
+			query.Set("name", name)
+			query.Set("namespace", ns)
+		} else if flagNs != "" {
+			query.Set("namespace", flagNs)
+		}
+	} else if flagNs != "" {
+		query.Set("namespace", flagNs)
+	}
+	if since != "" {
+		query.Set("since", since)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	return query, nil
+}
+
+// mergeConfigHistory merges the histories reported by each Istiod. Changes seen by several instances are
+// reported once, and the result is sorted oldest first.
+func mergeConfigHistory(responses map[string]*xdsapi.DiscoveryResponse) ([]model.ConfigHistoryEntry, error) {
+	type changeKey struct {
+		kind, name, namespace, event, resourceVersion string
+	}
+	merged := map[changeKey]model.ConfigHistoryEntry{}
+	for istiod, response := range responses {
+		for _, resource := range response.Resources {
+			entries := []model.ConfigHistoryEntry{}
+			if err := json.Unmarshal(resource.Value, &entries); err != nil {
+				return nil, fmt.Errorf("failed to read config history from %s: %v: %s", istiod, err,
+					strings.TrimSpace(string(resource.Value)))
+			}
+			for _, e := range entries {
+				key := changeKey{e.Kind, e.Name, e.Namespace, e.Event, e.ResourceVersion}
+				if prev, f := merged[key]; !f || e.Time.Before(prev.Time) {
+					merged[key] = e
+				}
+			}
+		}
+	}
+	out := make([]model.ConfigHistoryEntry, 0, len(merged))
+	for _, e := range merged {
+		out = append(out, e)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Time.Before(out[j].Time)
+	})
+	return out, nil
+}
+
+func printConfigHistory(writer io.Writer, entries []model.ConfigHistoryEntry, showDiff bool) error {
+	if len(entries) == 0 {
+		_, err := fmt.Fprintln(writer, "No config changes found.")
+		return err
+	}
+	// The table is aligned in a buffer first, so the diffs can be interleaved without breaking the alignment.
+	table := &bytes.Buffer{}
+	w := new(tabwriter.Writer).Init(table, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(w, "TIME\tEVENT\tKIND\tNAME\tNAMESPACE\tRESOURCE VERSION")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.UTC().Format(time.RFC3339), e.Event, e.Kind, e.Name,
+			e.Namespace, e.ResourceVersion)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	lines := strings.SplitAfter(table.String(), "\n")
+	out := &strings.Builder{}
+	out.WriteString(lines[0])
+	for i, e := range entries {
+		out.WriteString(lines[i+1])
+		if showDiff && e.Diff != "" {
+			out.WriteString(strings.TrimRight(e.Diff, "\n") + "\n")
+		}
+	}
+	_, err := io.WriteString(writer, out.String())
+	return err
+}
@@ -15,10 +15,18 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/any"
+
+	"istio.io/istio/pilot/pkg/model"
 )
 
 func TestConfigList(t *testing.T) {
@@ -50,3 +58,75 @@ xds-port                15012            default
 		})
 	}
 }
+
+func TestConfigHistoryQuery(t *testing.T) {
+	cases := []struct {
+		args   []string
+		flagNs string
+		since  string
+		limit  int
+		want   string
+	}{
+		{want: ""},
+		{flagNs: "bookinfo", want: "namespace=bookinfo"},
+		{args: []string{"VirtualService"}, want: "kind=VirtualService"},
+		{args: []string{"VirtualService"}, flagNs: "bookinfo", want: "kind=VirtualService&namespace=bookinfo"},
+		{args: []string{"VirtualService/reviews"}, want: "kind=VirtualService&name=reviews&namespace=default"},
+		{args: []string{"VirtualService/reviews.bookinfo"}, want: "kind=VirtualService&name=reviews&namespace=bookinfo"},
+		{args: []string{"Gateway"}, since: "5m", limit: 3, want: "kind=Gateway&limit=3&since=5m"},
+	}
+	for _, c := range cases {
+		t.Run(strings.Join(c.args, " "), func(t *testing.T) {
+			got, err := historyQuery(c.args, "default", c.flagNs, c.since, c.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Encode() != c.want {
+				t.Errorf("got %q, want %q", got.Encode(), c.want)
+			}
+		})
+	}
+	if _, err := historyQuery([]string{"/reviews"}, "default", "", "", 0); err == nil {
+		t.Errorf("expected an error for a missing kind")
+	}
+}
+
+func TestConfigHistoryOutput(t *testing.T) {
+	at := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	entry := func(name, rv string, offset time.Duration) model.ConfigHistoryEntry {
+		return model.ConfigHistoryEntry{
+			Time: at.Add(offset), Event: "update", Kind: "VirtualService", Name: name, Namespace: "default",
+			ResourceVersion: rv, Diff: "--- old\n+++ new\n",
+		}
+	}
+	history := func(entries ...model.ConfigHistoryEntry) *discovery.DiscoveryResponse {
+		by, err := json.Marshal(entries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &discovery.DiscoveryResponse{Resources: []*any.Any{{Value: by}}}
+	}
+	// Both instances saw the change of "a", it must be reported once.
+	entries, err := mergeConfigHistory(map[string]*discovery.DiscoveryResponse{
+		"istiod-1": history(entry("a", "1", 0), entry("b", "2", time.Minute)),
+		"istiod-2": history(entry("a", "1", time.Second)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := printConfigHistory(out, entries, true); err != nil {
+		t.Fatal(err)
+	}
+	want := `TIME                   EVENT    KIND             NAME   NAMESPACE   RESOURCE VERSION
+2021-03-01T10:00:00Z   update   VirtualService   a      default     1
+--- old
++++ new
+2021-03-01T10:01:00Z   update   VirtualService   b      default     2
+--- old
++++ new
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -91,6 +91,9 @@ By default it will use the default serviceAccount from (istio-system) namespace
   # Retrieve sync diff for a single Envoy and Istiod
   istioctl x internal-debug syncz istio-egressgateway-59585c5b9c-ndc59.istio-system
 
+  # Retrieve the status of the JWT public keys fetched by Istiod
+  istioctl x internal-debug jwksz
+
   # SECURITY OPTIONS
 
   # Retrieve syncz debug information directly from the control plane, using token security
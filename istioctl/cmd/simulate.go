@@ -0,0 +1,503 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
+	"istio.io/istio/pilot/pkg/simulation"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/test"
+)
+
+// simulateOptions holds the flags of the simulate command.
+type simulateOptions struct {
+	// request
+	url      string
+	method   string
+	headers  []string
+	address  string
+	protocol string
+	tlsMode  string
+	sni      string
+	callMode string
+
+	// source of configuration
+	configDumpFile string
+	offlineConfig  []string
+
+	// offline proxy
+	proxyType      string
+	proxyNamespace string
+	proxyIP        string
+	proxyLabels    map[string]string
+
+	output string
+}
+
+// simulateResult is the user facing result of a simulated request.
+type simulateResult struct {
+	Listener    string `json:"listener,omitempty"`
+	FilterChain string `json:"filterChain,omitempty"`
+	RouteConfig string `json:"routeConfig,omitempty"`
+	VirtualHost string `json:"virtualHost,omitempty"`
+	Route       string `json:"route,omitempty"`
+	Cluster     string `json:"cluster,omitempty"`
+	// DownstreamTLS describes the TLS required by the matched filter chain.
+	DownstreamTLS string `json:"downstreamTLS,omitempty"`
+	// UpstreamTLS describes the TLS used when connecting to the matched cluster.
+	UpstreamTLS string `json:"upstreamTLS,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+const (
+	tlsDisabled = "DISABLE"
+	tlsSimple   = "SIMPLE"
+	tlsMutual   = "MUTUAL"
+	tlsIstio    = "ISTIO_MUTUAL"
+	tlsAuto     = "AUTO (ISTIO_MUTUAL for Istio endpoints, DISABLE otherwise)"
+)
+
+func simulateCmd() *cobra.Command {
+	opts := &simulateOptions{}
+	cmd := &cobra.Command{
+		Use:   "simulate [<type>/]<name>[.<namespace>]",
+		Short: "Simulate how a request would be handled by a proxy",
+		Long: `Simulate how a request would be handled by the Envoy instance in the specified pod, without sending it.
+
+The listener, filter chain, virtual host, route and cluster the request would match are reported, along with
+the TLS settings applied to the connection. The configuration is read from the live proxy, from an Envoy
+config dump file, or generated offline from a set of Istio configuration files.`,
+		Example: `  # Simulate an HTTP request sent by a pod
+  istioctl x simulate productpage-v1-6b746f74dc-9stvs.default --url http://reviews:9080/v1 -H "end-user: jason"
+
+  # Simulate a request against a config dump, without using Kubernetes API
+  kubectl exec productpage-v1-6b746f74dc-9stvs -c istio-proxy -- pilot-agent request GET config_dump > envoy-config.json
+  istioctl x simulate --file envoy-config.json --url http://reviews:9080/v1 -o json
+
+  # Simulate a request against configuration generated from local files
+  istioctl x simulate --offline-config services.yaml,virtual-service.yaml --url http://reviews:9080/v1
+
+  # Simulate an inbound mTLS request to a pod
+  istioctl x simulate productpage-v1-6b746f74dc-9stvs.default --url http://10.1.1.1:9080/ --call-mode inbound --tls mtls
+`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			sources := len(args)
+			if opts.configDumpFile != "" {
+				sources++
+			}
+			if len(opts.offlineConfig) > 0 {
+				sources++
+			}
+			if sources != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("simulate requires exactly one of pod name, --file or --offline-config")
+			}
+			if opts.url == "" {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("simulate requires --url")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			call, err := opts.buildCall()
+			if err != nil {
+				return err
+			}
+			var res simulateResult
+			switch {
+			case len(args) == 1:
+				podName, podNamespace, err := getPodName(args[0])
+				if err != nil {
+					return err
+				}
+				dump, err := extractConfigDump(podName, podNamespace)
+				if err != nil {
+					return err
+				}
+				res, err = simulateConfigDump(dump, call)
+				if err != nil {
+					return err
+				}
+			case opts.configDumpFile != "":
+				dump, err := readFile(opts.configDumpFile)
+				if err != nil {
+					return err
+				}
+				res, err = simulateConfigDump(dump, call)
+				if err != nil {
+					return err
+				}
+			default:
+				res, err = opts.simulateOffline(call)
+				if err != nil {
+					return err
+				}
+			}
+			return printSimulateResult(c.OutOrStdout(), res, opts.output)
+		},
+		ValidArgsFunction: validArgsFunction,
+	}
+
+	cmd.PersistentFlags().StringVar(&opts.url, "url", "",
+		"URL of the request, for example http://reviews:9080/v1. Supported schemes are http, https and tcp")
+	cmd.PersistentFlags().StringVarP(&opts.method, "method", "X", http.MethodGet, "HTTP method of the request")
+	cmd.PersistentFlags().StringArrayVarP(&opts.headers, "header", "H", nil,
+		"Header to add to the request, in the form 'name: value'. May be repeated")
+	cmd.PersistentFlags().StringVar(&opts.address, "address", "",
+		"Destination IP address of the request. Defaults to the URL host if it is an IP address")
+	cmd.PersistentFlags().StringVar(&opts.protocol, "protocol", "",
+		"Protocol of the request: one of http|http2|tcp. Defaults to the protocol implied by the URL scheme")
+	cmd.PersistentFlags().StringVar(&opts.tlsMode, "tls", "",
+		"TLS mode of the request: one of plaintext|tls|mtls. Defaults to the mode implied by the URL scheme")
+	cmd.PersistentFlags().StringVar(&opts.sni, "sni", "", "SNI of the request. Defaults to the URL host for TLS requests")
+	cmd.PersistentFlags().StringVar(&opts.callMode, "call-mode", string(simulation.CallModeOutbound),
+		"How the request reaches the proxy: one of outbound|inbound|gateway")
+	cmd.PersistentFlags().StringVarP(&opts.configDumpFile, "file", "f", "", "Envoy config dump JSON file")
+	cmd.PersistentFlags().StringSliceVar(&opts.offlineConfig, "offline-config", nil,
+		"Istio configuration files used to generate the proxy configuration instead of reading it from a proxy")
+	cmd.PersistentFlags().StringVar(&opts.proxyType, "proxy-type", string(model.SidecarProxy),
+		"Type of the proxy to generate configuration for with --offline-config: one of sidecar|router")
+	cmd.PersistentFlags().StringVar(&opts.proxyNamespace, "proxy-namespace", "default",
+		"Namespace of the proxy to generate configuration for with --offline-config")
+	cmd.PersistentFlags().StringVar(&opts.proxyIP, "proxy-ip", "1.1.1.1",
+		"IP address of the proxy to generate configuration for with --offline-config")
+	cmd.PersistentFlags().StringToStringVar(&opts.proxyLabels, "proxy-labels", nil,
+		"Labels of the proxy to generate configuration for with --offline-config")
+	cmd.PersistentFlags().StringVarP(&opts.output, "output", "o", summaryOutput, "Output format: one of json|short")
+
+	return cmd
+}
+
+// buildCall translates the request flags into a simulated call.
+func (o *simulateOptions) buildCall() (simulation.Call, error) {
+	u, err := url.Parse(o.url)
+	if err != nil {
+		return simulation.Call{}, fmt.Errorf("invalid url %q: %v", o.url, err)
+	}
+	call := simulation.Call{
+		Method:   strings.ToUpper(o.method),
+		Path:     u.Path,
+		Sni:      o.sni,
+		CallMode: simulation.CallMode(o.callMode),
+		Headers:  http.Header{},
+	}
+	var defaultPort int
+	switch u.Scheme {
+	case "http":
+		call.Protocol, call.TLS, defaultPort = simulation.HTTP, simulation.Plaintext, 80
+	case "https":
+		call.Protocol, call.TLS, defaultPort = simulation.HTTP, simulation.TLS, 443
+	case "tcp":
+		call.Protocol, call.TLS = simulation.TCP, simulation.Plaintext
+	default:
+		return simulation.Call{}, fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+	if o.protocol != "" {
+		call.Protocol = simulation.Protocol(o.protocol)
+	}
+	if o.tlsMode != "" {
+		call.TLS = simulation.TLSMode(o.tlsMode)
+	}
+	switch call.Protocol {
+	case simulation.HTTP, simulation.HTTP2, simulation.TCP:
+	default:
+		return simulation.Call{}, fmt.Errorf("unsupported protocol %q", call.Protocol)
+	}
+	switch call.TLS {
+	case simulation.Plaintext, simulation.TLS, simulation.MTLS:
+	default:
+		return simulation.Call{}, fmt.Errorf("unsupported tls mode %q", call.TLS)
+	}
+	switch call.CallMode {
+	case simulation.CallModeOutbound, simulation.CallModeInbound, simulation.CallModeGateway:
+	default:
+		return simulation.Call{}, fmt.Errorf("unsupported call mode %q", call.CallMode)
+	}
+
+	call.Port = defaultPort
+	if p := u.Port(); p != "" {
+		if call.Port, err = strconv.Atoi(p); err != nil {
+			return simulation.Call{}, fmt.Errorf("invalid port %q: %v", p, err)
+		}
+	}
+	if call.Port == 0 {
+		return simulation.Call{}, fmt.Errorf("url %q must specify a port", o.url)
+	}
+	call.Address = o.address
+	if call.Address == "" && net.ParseIP(u.Hostname()) != nil {
+		call.Address = u.Hostname()
+	}
+	if call.Protocol != simulation.TCP {
+		call.HostHeader = u.Host
+	}
+	if call.Sni == "" && call.TLS == simulation.TLS {
+		call.Sni = u.Hostname()
+	}
+	for _, h := range o.headers {
+		kv := strings.SplitN(h, ":", 2)
+		if len(kv) != 2 {
+			return simulation.Call{}, fmt.Errorf("invalid header %q, expected 'name: value'", h)
+		}
+		call.Headers.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	}
+	return call, nil
+}
+
+// simulateConfigDump runs the call against the dynamic resources of an Envoy config dump.
+func simulateConfigDump(dump []byte, call simulation.Call) (simulateResult, error) {
+	cd := &configdump.Wrapper{}
+	if err := cd.UnmarshalJSON(dump); err != nil {
+		return simulateResult{}, fmt.Errorf("failed to parse config dump: %v", err)
+	}
+	listeners, err := extractListeners(cd)
+	if err != nil {
+		return simulateResult{}, err
+	}
+	clusters, err := extractClusters(cd)
+	if err != nil {
+		return simulateResult{}, err
+	}
+	routes, err := extractRoutes(cd)
+	if err != nil {
+		return simulateResult{}, err
+	}
+	return runSimulation(listeners, clusters, routes, call)
+}
+
+func extractListeners(cd *configdump.Wrapper) ([]*listener.Listener, error) {
+	dump, err := cd.GetDynamicListenerDump(true)
+	if err != nil {
+		return nil, err
+	}
+	listeners := make([]*listener.Listener, 0, len(dump.DynamicListeners))
+	for _, dl := range dump.DynamicListeners {
+		l := &listener.Listener{}
+		if err := dl.ActiveState.Listener.UnmarshalTo(l); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal listener: %v", err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+func extractClusters(cd *configdump.Wrapper) ([]*cluster.Cluster, error) {
+	dump, err := cd.GetDynamicClusterDump(true)
+	if err != nil {
+		return nil, err
+	}
+	clusters := make([]*cluster.Cluster, 0, len(dump.DynamicActiveClusters))
+	for _, dc := range dump.DynamicActiveClusters {
+		c := &cluster.Cluster{}
+		if err := dc.Cluster.UnmarshalTo(c); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal cluster: %v", err)
+		}
+		clusters = append(clusters, c)
+	}
+	return clusters, nil
+}
+
+func extractRoutes(cd *configdump.Wrapper) ([]*route.RouteConfiguration, error) {
+	dump, err := cd.GetDynamicRouteDump(true)
+	if err != nil {
+		return nil, err
+	}
+	routes := make([]*route.RouteConfiguration, 0, len(dump.DynamicRouteConfigs))
+	for _, dr := range dump.DynamicRouteConfigs {
+		r := &route.RouteConfiguration{}
+		if err := dr.RouteConfig.UnmarshalTo(r); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal route: %v", err)
+		}
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
+
+// simulateOffline generates the proxy configuration from local Istio configuration files and runs the call against it.
+func (o *simulateOptions) simulateOffline(call simulation.Call) (simulateResult, error) {
+	var configs []string
+	for _, f := range o.offlineConfig {
+		b, err := readFile(f)
+		if err != nil {
+			return simulateResult{}, err
+		}
+		configs = append(configs, string(b))
+	}
+	var listeners []*listener.Listener
+	var clusters []*cluster.Cluster
+	var routes []*route.RouteConfiguration
+	err := test.Wrap(func(t test.Failer) {
+		cg := v1alpha3.NewConfigGenTest(t, v1alpha3.TestOptions{ConfigString: strings.Join(configs, "\n---\n")})
+		proxy := cg.SetupProxy(&model.Proxy{
+			Type:            model.NodeType(o.proxyType),
+			ConfigNamespace: o.proxyNamespace,
+			IPAddresses:     []string{o.proxyIP},
+			Metadata:        &model.NodeMetadata{Labels: o.proxyLabels},
+		})
+		listeners = cg.Listeners(proxy)
+		clusters = cg.Clusters(proxy)
+		routes = cg.Routes(proxy)
+	})
+	if err != nil {
+		return simulateResult{}, fmt.Errorf("failed to generate configuration: %v", err)
+	}
+	return runSimulation(listeners, clusters, routes, call)
+}
+
+func runSimulation(listeners []*listener.Listener, clusters []*cluster.Cluster,
+	routes []*route.RouteConfiguration, call simulation.Call) (simulateResult, error) {
+	var res simulation.Result
+	var sim *simulation.Simulation
+	err := test.Wrap(func(t test.Failer) {
+		sim = simulation.NewSimulationFromResources(t, listeners, clusters, routes)
+		res = sim.Run(call)
+	})
+	if err != nil {
+		return simulateResult{}, fmt.Errorf("failed to simulate request: %v", err)
+	}
+	out := simulateResult{
+		Listener:    res.ListenerMatched,
+		FilterChain: res.FilterChainMatched,
+		RouteConfig: res.RouteConfigMatched,
+		VirtualHost: res.VirtualHostMatched,
+		Route:       res.RouteMatched,
+		Cluster:     res.ClusterMatched,
+	}
+	if res.Error != nil {
+		out.Error = res.Error.Error()
+	}
+	// Filter chains are only identifiable by name, so unnamed chains are not reported
+	if l := xdstest.ExtractListener(res.ListenerMatched, listeners); l != nil && res.FilterChainMatched != "" {
+		out.DownstreamTLS = filterChainTLSMode(l, res.FilterChainMatched)
+	}
+	if c := xdstest.ExtractCluster(res.ClusterMatched, clusters); c != nil {
+		out.UpstreamTLS = clusterTLSMode(c)
+	}
+	return out, nil
+}
+
+// filterChainTLSMode reports the TLS mode a filter chain requires from downstream connections.
+func filterChainTLSMode(l *listener.Listener, name string) string {
+	fcs := append([]*listener.FilterChain{l.DefaultFilterChain}, l.FilterChains...)
+	for _, fc := range fcs {
+		if fc == nil || fc.Name != name {
+			continue
+		}
+		if fc.TransportSocket == nil {
+			return tlsDisabled
+		}
+		ctx := &tls.DownstreamTlsContext{}
+		if err := fc.TransportSocket.GetTypedConfig().UnmarshalTo(ctx); err != nil {
+			return ""
+		}
+		if ctx.GetRequireClientCertificate().GetValue() {
+			if isIstioCertificate(ctx.GetCommonTlsContext()) {
+				return tlsIstio
+			}
+			return tlsMutual
+		}
+		return tlsSimple
+	}
+	return ""
+}
+
+// clusterTLSMode reports the TLS mode used for upstream connections of a cluster.
+func clusterTLSMode(c *cluster.Cluster) string {
+	if c.TransportSocket != nil {
+		return upstreamTLSMode(c.TransportSocket.GetTypedConfig())
+	}
+	for _, m := range c.TransportSocketMatches {
+		if m.GetMatch().GetFields()[model.TLSModeLabelShortname].GetStringValue() == model.IstioMutualTLSModeLabel {
+			return tlsAuto
+		}
+	}
+	return tlsDisabled
+}
+
+func upstreamTLSMode(cfg *any.Any) string {
+	ctx := &tls.UpstreamTlsContext{}
+	if err := cfg.UnmarshalTo(ctx); err != nil {
+		return ""
+	}
+	if isIstioCertificate(ctx.GetCommonTlsContext()) {
+		return tlsIstio
+	}
+	if len(ctx.GetCommonTlsContext().GetTlsCertificates()) > 0 ||
+		len(ctx.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs()) > 0 {
+		return tlsMutual
+	}
+	return tlsSimple
+}
+
+// isIstioCertificate checks if the TLS context uses the workload certificate provisioned by Istio.
+func isIstioCertificate(ctx *tls.CommonTlsContext) bool {
+	sds := ctx.GetTlsCertificateSdsSecretConfigs()
+	return len(sds) > 0 && sds[0].GetName() == "default"
+}
+
+func printSimulateResult(w io.Writer, res simulateResult, format string) error {
+	switch format {
+	case jsonOutput:
+		out, err := json.MarshalIndent(res, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	case summaryOutput:
+		tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+		rows := []struct {
+			name, value string
+		}{
+			{"Listener", res.Listener},
+			{"Filter Chain", res.FilterChain},
+			{"Route Config", res.RouteConfig},
+			{"Virtual Host", res.VirtualHost},
+			{"Route", res.Route},
+			{"Cluster", res.Cluster},
+			{"Downstream TLS", res.DownstreamTLS},
+			{"Upstream TLS", res.UpstreamTLS},
+		}
+		for _, r := range rows {
+			if r.value != "" {
+				fmt.Fprintf(tw, "%s:\t%s\n", r.name, r.value)
+			}
+		}
+		if res.Error != "" {
+			fmt.Fprintf(tw, "Error:\t%s\n", res.Error)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("output format %q not supported", format)
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	"github.com/golang/protobuf/jsonpb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
+	"istio.io/istio/pilot/pkg/simulation"
+)
+
+const simulateConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: reviews
+spec:
+  hosts:
+  - reviews.default.svc.cluster.local
+  addresses:
+  - 10.0.0.1
+  ports:
+  - number: 9080
+    name: http
+    protocol: HTTP
+  - number: 9090
+    name: tcp
+    protocol: TCP
+  resolution: STATIC
+  endpoints:
+  - address: 10.1.0.1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: reviews-v2
+spec:
+  hosts:
+  - reviews-v2.default.svc.cluster.local
+  ports:
+  - number: 9080
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 10.1.0.2
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+spec:
+  hosts:
+  - reviews.default.svc.cluster.local
+  http:
+  - name: jason
+    match:
+    - headers:
+        end-user:
+          exact: jason
+    route:
+    - destination:
+        host: reviews-v2.default.svc.cluster.local
+  - name: default
+    route:
+    - destination:
+        host: reviews.default.svc.cluster.local
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: reviews-v2
+spec:
+  host: reviews-v2.default.svc.cluster.local
+  trafficPolicy:
+    tls:
+      mode: ISTIO_MUTUAL
+`
+
+func TestSimulateBuildCall(t *testing.T) {
+	cases := []struct {
+		name    string
+		opts    simulateOptions
+		want    simulation.Call
+		wantErr bool
+	}{
+		{
+			name: "http",
+			opts: simulateOptions{url: "http://reviews:9080/v1", method: "get", headers: []string{"end-user: jason"}, callMode: "outbound"},
+			want: simulation.Call{
+				Port:       9080,
+				Path:       "/v1",
+				Method:     "GET",
+				Protocol:   simulation.HTTP,
+				TLS:        simulation.Plaintext,
+				HostHeader: "reviews:9080",
+				Headers:    map[string][]string{"End-User": {"jason"}},
+				CallMode:   simulation.CallModeOutbound,
+			},
+		},
+		{
+			name: "https default port",
+			opts: simulateOptions{url: "https://example.com", method: "GET", callMode: "gateway"},
+			want: simulation.Call{
+				Port:       443,
+				Method:     "GET",
+				Protocol:   simulation.HTTP,
+				TLS:        simulation.TLS,
+				HostHeader: "example.com",
+				Sni:        "example.com",
+				Headers:    map[string][]string{},
+				CallMode:   simulation.CallModeGateway,
+			},
+		},
+		{
+			name: "tcp with ip",
+			opts: simulateOptions{url: "tcp://10.0.0.1:9090", method: "GET", tlsMode: "mtls", callMode: "inbound"},
+			want: simulation.Call{
+				Address:  "10.0.0.1",
+				Port:     9090,
+				Method:   "GET",
+				Protocol: simulation.TCP,
+				TLS:      simulation.MTLS,
+				Headers:  map[string][]string{},
+				CallMode: simulation.CallModeInbound,
+			},
+		},
+		{
+			name:    "tcp without port",
+			opts:    simulateOptions{url: "tcp://10.0.0.1", callMode: "outbound"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown scheme",
+			opts:    simulateOptions{url: "ftp://10.0.0.1:21", callMode: "outbound"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid header",
+			opts:    simulateOptions{url: "http://reviews:9080", headers: []string{"foo"}, callMode: "outbound"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.opts.buildCall()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wantErr %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(configFile, []byte(simulateConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Generate a config dump equivalent to the offline configuration, to verify both paths agree
+	cg := v1alpha3.NewConfigGenTest(t, v1alpha3.TestOptions{ConfigString: simulateConfig})
+	proxy := cg.SetupProxy(&model.Proxy{})
+	dump := &adminapi.ConfigDump{}
+	listeners := &adminapi.ListenersConfigDump{}
+	for _, l := range cg.Listeners(proxy) {
+		listeners.DynamicListeners = append(listeners.DynamicListeners, &adminapi.ListenersConfigDump_DynamicListener{
+			Name:        l.Name,
+			ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{Listener: mustAny(t, l)},
+		})
+	}
+	clusters := &adminapi.ClustersConfigDump{}
+	for _, c := range cg.Clusters(proxy) {
+		clusters.DynamicActiveClusters = append(clusters.DynamicActiveClusters, &adminapi.ClustersConfigDump_DynamicCluster{
+			Cluster: mustAny(t, c),
+		})
+	}
+	routes := &adminapi.RoutesConfigDump{}
+	for _, r := range cg.Routes(proxy) {
+		routes.DynamicRouteConfigs = append(routes.DynamicRouteConfigs, &adminapi.RoutesConfigDump_DynamicRouteConfig{
+			RouteConfig: mustAny(t, r),
+		})
+	}
+	dump.Configs = append(dump.Configs, mustAny(t, listeners), mustAny(t, clusters), mustAny(t, routes))
+	dumpJSON, err := (&jsonpb.Marshaler{}).MarshalToString(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dumpFile := filepath.Join(dir, "config_dump.json")
+	if err := ioutil.WriteFile(dumpFile, []byte(dumpJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		args string
+		want simulateResult
+	}{
+		{
+			name: "default route",
+			args: "--url http://reviews.default.svc.cluster.local:9080/v1",
+			want: simulateResult{
+				Listener:    "0.0.0.0_9080",
+				RouteConfig: "9080",
+				VirtualHost: "reviews.default.svc.cluster.local:9080",
+				Route:       "default",
+				Cluster:     "outbound|9080||reviews.default.svc.cluster.local",
+				UpstreamTLS: tlsDisabled,
+			},
+		},
+		{
+			name: "header route",
+			args: "--url http://reviews.default.svc.cluster.local:9080/v1 -H end-user:jason",
+			want: simulateResult{
+				Listener:    "0.0.0.0_9080",
+				RouteConfig: "9080",
+				VirtualHost: "reviews.default.svc.cluster.local:9080",
+				Route:       "jason",
+				Cluster:     "outbound|9080||reviews-v2.default.svc.cluster.local",
+				UpstreamTLS: tlsIstio,
+			},
+		},
+		{
+			name: "tcp",
+			args: "--url tcp://10.0.0.1:9090",
+			want: simulateResult{
+				Listener:    "10.0.0.1_9090",
+				Cluster:     "outbound|9090||reviews.default.svc.cluster.local",
+				UpstreamTLS: tlsDisabled,
+			},
+		},
+		{
+			name: "passthrough",
+			args: "--url http://unknown:9080/",
+			want: simulateResult{
+				Listener:    "0.0.0.0_9080",
+				RouteConfig: "9080",
+				VirtualHost: "allow_any",
+				Route:       "allow_any",
+				Cluster:     "PassthroughCluster",
+				UpstreamTLS: tlsDisabled,
+			},
+		},
+	}
+	for _, tt := range cases {
+		for _, source := range []string{"--offline-config " + configFile, "--file " + dumpFile} {
+			t.Run(tt.name+" "+strings.Fields(source)[0], func(t *testing.T) {
+				args := append([]string{"x", "simulate", "-o", "json"}, strings.Fields(source)...)
+				args = append(args, strings.Fields(tt.args)...)
+				var out bytes.Buffer
+				rootCmd := GetRootCmd(args)
+				rootCmd.SetOut(&out)
+				rootCmd.SetErr(&out)
+				if err := rootCmd.Execute(); err != nil {
+					t.Fatalf("unexpected error: %v\n%s", err, out.String())
+				}
+				got := simulateResult{}
+				if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+					t.Fatalf("failed to parse output %q: %v", out.String(), err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("got %+v, want %+v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestPrintSimulateResult(t *testing.T) {
+	res := simulateResult{
+		Listener:    "0.0.0.0_9080",
+		RouteConfig: "9080",
+		Error:       simulation.ErrNoVirtualHost.Error(),
+	}
+	var out bytes.Buffer
+	if err := printSimulateResult(&out, res, summaryOutput); err != nil {
+		t.Fatal(err)
+	}
+	want := `Listener:     0.0.0.0_9080
+Route Config: 9080
+Error:        no virtual host matched
+`
+	if out.String() != want {
+		t.Fatalf("got %q, want %q", out.String(), want)
+	}
+	if err := printSimulateResult(&out, res, "yaml"); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+}
+
+func mustAny(t *testing.T, m proto.Message) *anypb.Any {
+	t.Helper()
+	a, err := anypb.New(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
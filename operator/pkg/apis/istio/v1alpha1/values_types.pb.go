@@ -419,6 +419,10 @@ type CNIRepairConfig struct {
 	BrokenPodLabelKey    string   `protobuf:"bytes,8,opt,name=brokenPodLabelKey,proto3" json:"brokenPodLabelKey,omitempty"`
 	BrokenPodLabelValue  string   `protobuf:"bytes,9,opt,name=brokenPodLabelValue,proto3" json:"brokenPodLabelValue,omitempty"`
 	InitContainerName    string   `protobuf:"bytes,10,opt,name=initContainerName,proto3" json:"initContainerName,omitempty"`
+	// Re-apply the traffic redirection in the network namespace of broken pods rather than labeling or deleting them.
+	RepairPods           bool     `protobuf:"varint,11,opt,name=repairPods,proto3" json:"repairPods,omitempty"`
+	// Path of istio-iptables in the repair container, run in the network namespace of broken pods.
+	IptablesPath         string   `protobuf:"bytes,12,opt,name=iptablesPath,proto3" json:"iptablesPath,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -519,6 +523,20 @@ func (m *CNIRepairConfig) GetInitContainerName() string {
 	return ""
 }
 
+func (m *CNIRepairConfig) GetRepairPods() bool {
+	if m != nil {
+		return m.RepairPods
+	}
+	return false
+}
+
+func (m *CNIRepairConfig) GetIptablesPath() string {
+	if m != nil {
+		return m.IptablesPath
+	}
+	return ""
+}
+
 // Configuration for CPU target utilization for HorizontalPodAutoscaler target.
 type CPUTargetUtilizationConfig struct {
 	// K8s utilization setting for HorizontalPodAutoscaler target.
@@ -173,8 +173,14 @@ func validateMeshConfig(path util.Path, root interface{}) util.Errors {
 		return util.Errors{err}
 	}
 	defaultMesh := mesh.DefaultMeshConfig()
+	// The MeshConfig API has no fields for the providers of the extensionprovider package, so cut them before
+	// decoding strictly. They are validated by ApplyMeshConfigDefaults.
+	meshYaml, err := mesh.CutExtensionProviders(string(vs))
+	if err != nil {
+		return util.Errors{fmt.Errorf("failed to unmarshall mesh config: %v", err)}
+	}
 	// ApplyMeshConfigDefaults allows unknown fields, so we first check for unknown fields
-	if err := gogoprotomarshal.ApplyYAMLStrict(string(vs), &defaultMesh); err != nil {
+	if err := gogoprotomarshal.ApplyYAMLStrict(meshYaml, &defaultMesh); err != nil {
 		return util.Errors{fmt.Errorf("failed to unmarshall mesh config: %v", err)}
 	}
 	// This method will also perform validation automatically
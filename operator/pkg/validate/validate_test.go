@@ -163,6 +163,30 @@ meshConfig:
     discoveryAddress: istiod:15012
 `,
 		},
+		{
+			desc: "Good mesh extension providers",
+			yamlStr: `
+meshConfig:
+  extensionProviders:
+  - name: ratelimit
+    envoyRateLimit:
+      service: ratelimit.foo.svc.cluster.local
+      port: 8081
+      domain: reviews
+`,
+		},
+		{
+			desc: "Bad mesh extension providers",
+			yamlStr: `
+meshConfig:
+  extensionProviders:
+  - name: ratelimit
+    envoyRateLimit:
+      service: ratelimit.foo.svc.cluster.local
+      port: 8081
+`,
+			wantErrs: makeErrors([]string{"extension provider ratelimit: domain must be set"}),
+		},
 	}
 
 	for _, tt := range tests {
@@ -51,6 +51,8 @@ func NewAgentOptions(proxy *model.Proxy, cfg *meshconfig.ProxyConfig) *istioagen
 		o.DNSCapture = DNSCaptureByAgent.Get()
 		o.ProxyNamespace = PodNamespaceVar.Get()
 		o.ProxyDomain = proxy.DNSDomain
+		o.XDSSnapshotDir = xdsSnapshotDirEnv
+		o.XDSSnapshotGracePeriod = xdsSnapshotGracePeriodEnv
 	}
 
 	return o
@@ -63,6 +63,13 @@ var (
 
 	trustDomainEnv = env.RegisterStringVar("TRUST_DOMAIN", "cluster.local",
 		"The trust domain for spiffe certificates").Get()
+	trustDomainAliasesEnv = env.RegisterStringVar("TRUST_DOMAIN_ALIASES", "",
+		"The aliases of the trust domain, separated by commas. The peers of these trust domains are validated "+
+			"against the root certificates of the trust domain.").Get()
+	federatedBundleEndpointsEnv = env.RegisterStringVar("FEDERATED_SPIFFE_BUNDLE_ENDPOINTS", "",
+		"The SPIFFE bundle endpoints of the federated trust domains, as <trustdomain>|<url> tuples separated by ||. "+
+			"The agent fetches the bundle of each trust domain and configures Envoy to validate the peers against the "+
+			"bundle of their trust domain.").Get()
 
 	secretTTLEnv = env.RegisterDurationVar("SECRET_TTL", 24*time.Hour,
 		"The cert lifetime requested by istio agent").Get()
@@ -93,6 +100,13 @@ var (
 	enableBootstrapXdsEnv = env.RegisterBoolVar("BOOTSTRAP_XDS_AGENT", false,
 		"If set to true, agent retrieves the bootstrap configuration prior to starting Envoy").Get()
 
+	// Directory used by istio-agent to persist the last ACKed XDS configuration
+	xdsSnapshotDirEnv = env.RegisterStringVar("XDS_SNAPSHOT_DIR", "",
+		"If set, the agent persists the last XDS configuration ACKed by Envoy in this directory, and serves it to "+
+			"Envoy on startup if the XDS server is unavailable for longer than XDS_SNAPSHOT_GRACE_PERIOD.").Get()
+	xdsSnapshotGracePeriodEnv = env.RegisterDurationVar("XDS_SNAPSHOT_GRACE_PERIOD", 10*time.Second,
+		"The time to wait for the XDS server before serving the persisted XDS snapshot to Envoy.").Get()
+
 	envoyStatusPortEnv = env.RegisterIntVar("ENVOY_STATUS_PORT", 15021,
 		"Envoy health status port value").Get()
 	envoyPrometheusPortEnv = env.RegisterIntVar("ENVOY_PROMETHEUS_PORT", 15090,
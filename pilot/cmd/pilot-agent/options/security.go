@@ -45,6 +45,8 @@ func NewSecurityOptions(proxyConfig *meshconfig.ProxyConfig, stsPort int, tokenM
 		ServiceAccount:                 serviceAccountVar.Get(),
 		XdsAuthProvider:                xdsAuthProvider.Get(),
 		TrustDomain:                    trustDomainEnv,
+		TrustDomainAliases:             trustDomainAliases(trustDomainAliasesEnv),
+		FederatedBundleEndpoints:       federatedBundleEndpointsEnv,
 		Pkcs8Keys:                      pkcs8KeysEnv,
 		ECCSigAlg:                      eccSigAlgEnv,
 		SecretTTL:                      secretTTLEnv,
@@ -115,3 +117,14 @@ func SetupSecurityOptions(proxyConfig *meshconfig.ProxyConfig, secOpt *security.
 	}
 	return o, nil
 }
+
+// trustDomainAliases returns the trust domain aliases of the comma separated list.
+func trustDomainAliases(aliases string) []string {
+	var out []string
+	for _, alias := range strings.Split(aliases, ",") {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			out = append(out, alias)
+		}
+	}
+	return out
+}
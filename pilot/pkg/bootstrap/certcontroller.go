@@ -27,6 +27,7 @@ import (
 
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/keycertbundle"
+	"istio.io/istio/pilot/pkg/leaderelection"
 	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/security"
@@ -53,6 +54,34 @@ func (s *Server) initCertController(args *PilotArgs) error {
 	var err error
 	var secretNames, dnsNames, namespaces []string
 
+	if features.EnableCertificateRequests && s.kubeClient != nil {
+		newRequestController := func() (*chiron.RequestController, error) {
+			return chiron.NewRequestController(defaultCertGracePeriodRatio, defaultMinCertGracePeriod,
+				s.kubeClient.CoreV1(), s.kubeClient.CertificatesV1beta1(), defaultCACertPath,
+				args.RegistryOptions.KubeOptions.DomainSuffix)
+		}
+		// Check the configuration on startup, the controller itself is only created by the leader.
+		if _, err := newRequestController(); err != nil {
+			return fmt.Errorf("failed to create certificate request controller: %v", err)
+		}
+		s.addStartFunc(func(stop <-chan struct{}) error {
+			// A single replica creates and approves the CSRs of the requests.
+			go leaderelection.
+				NewLeaderElection(args.Namespace, args.PodName, leaderelection.CertificateRequestController, s.kubeClient).
+				AddRunFunction(func(leaderStop <-chan struct{}) {
+					// The controller is created for every term, as its queue is shut down when it stops.
+					requests, err := newRequestController()
+					if err != nil {
+						log.Errorf("failed to create certificate request controller: %v", err)
+						return
+					}
+					requests.Run(leaderStop)
+				}).
+				Run(stop)
+			return nil
+		})
+	}
+
 	meshConfig := s.environment.Mesh()
 	if meshConfig.GetCertificates() == nil || len(meshConfig.GetCertificates()) == 0 {
 		// TODO: if the provider is set to Citadel, use that instead of k8s so the API is still preserved.
@@ -30,6 +30,7 @@ import (
 	ingressv1 "istio.io/istio/pilot/pkg/config/kube/ingressv1"
 	"istio.io/istio/pilot/pkg/config/memory"
 	configmonitor "istio.io/istio/pilot/pkg/config/monitor"
+	"istio.io/istio/pilot/pkg/controller/jwks"
 	"istio.io/istio/pilot/pkg/controller/workloadentry"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/leaderelection"
@@ -100,11 +101,12 @@ func (s *Server) initConfigController(args *PilotArgs) error {
 		}
 
 		s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
-			leaderelection.
-				NewLeaderElection(args.Namespace, args.PodName, leaderelection.IngressController, s.kubeClient.Kube()).
+			le := leaderelection.NewLeaderElection(args.Namespace, args.PodName, leaderelection.IngressController, s.kubeClient.Kube())
+			le.
 				AddRunFunction(func(leaderStop <-chan struct{}) {
 					if ingressV1 {
 						ingressSyncer := ingressv1.NewStatusSyncer(s.environment.Watcher, s.kubeClient)
+						ingressSyncer.Fence = le.Fence()
 						// Start informers again. This fixes the case where informers for namespace do not start,
 						// as we create them only after acquiring the leader lock
 						// Note: stop here should be the overall pilot stop, NOT the leader election stop. We are
@@ -115,6 +117,7 @@ func (s *Server) initConfigController(args *PilotArgs) error {
 						ingressSyncer.Run(leaderStop)
 					} else {
 						ingressSyncer := ingress.NewStatusSyncer(s.environment.Watcher, s.kubeClient)
+						ingressSyncer.Fence = le.Fence()
 						// Start informers again. This fixes the case where informers for namespace do not start,
 						// as we create them only after acquiring the leader lock
 						// Note: stop here should be the overall pilot stop, NOT the leader election stop. We are
@@ -303,14 +306,20 @@ func (s *Server) initStatusController(args *PilotArgs, writeStatus bool) {
 	s.XDSServer.StatusReporter = s.statusReporter
 	if writeStatus {
 		s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
-			leaderelection.
-				NewLeaderElection(args.Namespace, args.PodName, leaderelection.StatusController, s.kubeClient).
+			le := leaderelection.NewLeaderElection(args.Namespace, args.PodName, leaderelection.StatusController, s.kubeClient)
+			le.
 				AddRunFunction(func(stop <-chan struct{}) {
 					// Controller should be created for calling the run function every time, so it can
 					// avoid concurrently calling of informer Run() for controller in controller.Start
 					controller := status.NewController(s.kubeClient.RESTConfig(), args.Namespace, s.RWConfigStore)
+					controller.Fence = le.Fence()
 					s.statusReporter.SetController(controller)
 					controller.Start(stop)
+				}).
+				AddRunFunction(func(stop <-chan struct{}) {
+					jwks.NewStatusController(s.RWConfigStore, func() *model.JwksResolver {
+						return s.XDSServer.JwtKeyResolver
+					}).Run(stop)
 				}).Run(stop)
 			return nil
 		})
@@ -47,7 +47,7 @@ import (
 )
 
 type caOptions struct {
-	// Either extCAK8s or extCAGrpc
+	// One of extCAK8s, extCARest or extCAGrpc
 	ExternalCAType   ra.CaExternalType
 	ExternalCASigner string
 	// domain to use in SPIFFE identity URLs
@@ -131,8 +131,20 @@ var (
 
 	// TODO: Likely to be removed and added to mesh config
 	externalCaType = env.RegisterStringVar("EXTERNAL_CA", "",
-		"External CA Integration Type. Permitted Values are ISTIOD_RA_KUBERNETES_API or "+
-			"ISTIOD_RA_ISTIO_API").Get()
+		"External CA Integration Type. Permitted Values are ISTIOD_RA_KUBERNETES_API, "+
+			"ISTIOD_RA_REST_API or ISTIOD_RA_ISTIO_API").Get()
+
+	// TODO: Likely to be removed and added to mesh config
+	externalCaAddress = env.RegisterStringVar("EXTERNAL_CA_ADDRESS", "",
+		"Base URL of the external CA signing API. Used with ISTIOD_RA_REST_API").Get()
+
+	externalCaClientCert = env.RegisterStringVar("EXTERNAL_CA_CLIENT_CERT",
+		path.Join(ra.DefaultExtCACertDir, "client-cert.pem"),
+		"Client certificate used to authenticate to the external CA signing API. Used with ISTIOD_RA_REST_API").Get()
+
+	externalCaClientKey = env.RegisterStringVar("EXTERNAL_CA_CLIENT_KEY",
+		path.Join(ra.DefaultExtCACertDir, "client-key.pem"),
+		"Client private key used to authenticate to the external CA signing API. Used with ISTIOD_RA_REST_API").Get()
 
 	// TODO: Likely to be removed and added to mesh config
 	k8sSigner = env.RegisterStringVar("K8S_SIGNER", "",
@@ -353,6 +365,11 @@ func (s *Server) createIstioRA(client kubelib.Client,
 		K8sClient:      client.CertificatesV1beta1(),
 		TrustDomain:    opts.TrustDomain,
 	}
+	if opts.ExternalCAType == ra.ExtCARest {
+		raOpts.ExternalCAAddress = externalCaAddress
+		raOpts.ClientCertFile = externalCaClientCert
+		raOpts.ClientKeyFile = externalCaClientKey
+	}
 	return ra.NewIstioRA(raOpts)
 }
 
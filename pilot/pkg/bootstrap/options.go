@@ -42,6 +42,9 @@ type RegistryOptions struct {
 	ClusterRegistriesNamespace string
 	KubeConfig                 string
 
+	// ConsulServerAddr is the address of the catalog HTTP API used by the Consul registry.
+	ConsulServerAddr string
+
 	// DistributionTracking control
 	DistributionCacheRetention time.Duration
 
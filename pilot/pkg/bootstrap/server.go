@@ -83,6 +83,7 @@ var DefaultPlugins = []string{
 	plugin.AuthzCustom,
 	plugin.Authn,
 	plugin.Authz,
+	plugin.RateLimit,
 }
 
 const (
@@ -909,6 +910,16 @@ func (s *Server) initRegistryEventHandlers() {
 
 			s.configController.RegisterEventHandler(schema.Resource().GroupVersionKind(), configHandler)
 		}
+		// Record the history of all config kinds, including those handled by the service registries.
+		// Events from the initial sync are skipped, they do not reflect changes.
+		historyHandler := func(old config.Config, curr config.Config, event model.Event) {
+			if s.configController.HasSynced() {
+				s.XDSServer.ConfigHistory.Record(old, curr, event)
+			}
+		}
+		for _, schema := range schemas {
+			s.configController.RegisterEventHandler(schema.Resource().GroupVersionKind(), historyHandler)
+		}
 	}
 }
 
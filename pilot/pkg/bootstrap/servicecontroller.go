@@ -20,6 +20,7 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
+	"istio.io/istio/pilot/pkg/serviceregistry/consul"
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pilot/pkg/serviceregistry/mock"
 	"istio.io/istio/pilot/pkg/serviceregistry/serviceentry"
@@ -56,6 +57,10 @@ func (s *Server) initServiceControllers(args *PilotArgs) error {
 			if err := s.initKubeRegistry(args); err != nil {
 				return err
 			}
+		case serviceregistry.Consul:
+			if err := s.initConsulRegistry(args); err != nil {
+				return err
+			}
 		case serviceregistry.Mock:
 			s.initMockRegistry()
 		default:
@@ -122,6 +127,20 @@ func (s *Server) initKubeRegistry(args *PilotArgs) (err error) {
 	return
 }
 
+// initConsulRegistry creates the service controller watching the Consul catalog
+func (s *Server) initConsulRegistry(args *PilotArgs) error {
+	if args.RegistryOptions.ConsulServerAddr == "" {
+		return fmt.Errorf("consul registry requires --consulserverURL")
+	}
+	log.Infof("Consul url: %v", args.RegistryOptions.ConsulServerAddr)
+	s.ServiceController().AddRegistry(consul.NewController(consul.Options{
+		ServerURL:  args.RegistryOptions.ConsulServerAddr,
+		ClusterID:  s.clusterID,
+		XDSUpdater: s.XDSServer,
+	}))
+	return nil
+}
+
 func (s *Server) initMockRegistry() {
 	// MemServiceDiscovery implementation
 	discovery := mock.NewDiscovery(map[host.Name]*model.Service{}, 2)
@@ -24,6 +24,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/leaderelection"
 	"istio.io/istio/pkg/kube/inject"
 	"istio.io/istio/pkg/webhooks"
 	"istio.io/pkg/env"
@@ -81,6 +82,9 @@ func (s *Server) initSidecarInjector(args *PilotArgs) (*inject.Webhook, error) {
 		Mux:      s.httpsMux,
 		Revision: args.Revision,
 	}
+	if s.kubeClient != nil && features.EnableNamespaceInjectionTemplates {
+		parameters.KubeClient = s.kubeClient
+	}
 
 	wh, err := inject.NewWebhook(parameters)
 	if err != nil {
@@ -91,17 +95,22 @@ func (s *Server) initSidecarInjector(args *PilotArgs) (*inject.Webhook, error) {
 	// operator or CI/CD
 	if features.InjectionWebhookConfigName != "" {
 		s.addStartFunc(func(stop <-chan struct{}) error {
-			// No leader election - different istiod revisions will patch their own cert.
-			caBundle := s.istiodCertBundleWatcher.GetCABundle()
-			// TODO(hzxuzhonghu): this should be consistent with validating webhook,
-			// update webhook configuration by watching the cabundle
-			patcher, err := webhooks.NewWebhookCertPatcher(s.kubeClient, args.Revision, webhookName, caBundle)
-			if err != nil {
-				log.Errorf("failed to create webhook cert patcher: %v", err)
-				return nil
-			}
+			// Different istiod revisions patch their own cert, so each revision has its own election.
+			go leaderelection.
+				NewLeaderElection(args.Namespace, args.PodName, leaderelection.WebhookPatcherElectionID(args.Revision), s.kubeClient).
+				AddRunFunction(func(leaderStop <-chan struct{}) {
+					caBundle := s.istiodCertBundleWatcher.GetCABundle()
+					// TODO(hzxuzhonghu): this should be consistent with validating webhook,
+					// update webhook configuration by watching the cabundle
+					patcher, err := webhooks.NewWebhookCertPatcher(s.kubeClient, args.Revision, webhookName, caBundle)
+					if err != nil {
+						log.Errorf("failed to create webhook cert patcher: %v", err)
+						return
+					}
 
-			patcher.Run(stop)
+					patcher.Run(leaderStop)
+					<-leaderStop
+				}).Run(stop)
 			return nil
 		})
 	}
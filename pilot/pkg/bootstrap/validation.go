@@ -15,6 +15,9 @@
 package bootstrap
 
 import (
+	"fmt"
+	"strings"
+
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/webhooks/validation/controller"
@@ -34,6 +37,21 @@ func (s *Server) initConfigValidation(args *PilotArgs) error {
 		DomainSuffix: args.RegistryOptions.KubeOptions.DomainSuffix,
 		Mux:          s.httpsMux,
 	}
+	if features.ValidationWebhookAnalyzers != "" {
+		var names []string
+		for _, name := range strings.Split(features.ValidationWebhookAnalyzers, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		analyzers, err := server.SelectAnalyzers(names, params.Schemas)
+		if err != nil {
+			return fmt.Errorf("invalid validation analyzers: %v", err)
+		}
+		log.Infof("running analyzers in the validation webhook: %v", names)
+		params.Analyzers = analyzers
+		params.ConfigStore = s.configController
+	}
 	whServer, err := server.New(params)
 	if err != nil {
 		return err
@@ -52,6 +52,10 @@ type StatusSyncer struct {
 	serviceLister      listerv1.ServiceLister
 	nodeLister         listerv1.NodeLister
 	ingressClassLister ingresslister.IngressClassLister
+
+	// Fence reports whether the status may still be written, for example because this instance still leads the
+	// election the syncer runs under. If nil, the status is always written.
+	Fence func() bool
 }
 
 // Run the syncer until stopCh is closed
@@ -137,6 +141,11 @@ func (s *StatusSyncer) updateStatus(status []coreV1.LoadBalancerIngress) error {
 			continue
 		}
 
+		if s.Fence != nil && !s.Fence() {
+			log.Infof("skipping update of Ingress %v/%v (no longer leader)", currIng.Namespace, currIng.Name)
+			return nil
+		}
+
 		currIng.Status.LoadBalancer.Ingress = status
 
 		_, err = s.client.NetworkingV1().Ingresses(currIng.Namespace).UpdateStatus(context.TODO(), currIng, metaV1.UpdateOptions{})
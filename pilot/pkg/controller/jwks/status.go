@@ -0,0 +1,137 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwks
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/meta/v1alpha1"
+	"istio.io/api/security/v1beta1"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/model/status"
+	"istio.io/istio/pkg/config/schema/gvk"
+	istiolog "istio.io/pkg/log"
+)
+
+var log = istiolog.RegisterScope("jwks", "JWKS status controller", 0)
+
+// statusUpdateInterval is the interval between the updates of the RequestAuthentication statuses.
+const statusUpdateInterval = 30 * time.Second
+
+// StatusController surfaces the failures of istiod to fetch the public keys of the JWT issuers as the JwksResolved
+// condition of the RequestAuthentication resources referencing them.
+type StatusController struct {
+	store model.ConfigStore
+
+	// resolver returns the current JWKS resolver, which is replaced when the discovery server is restarted.
+	resolver func() *model.JwksResolver
+}
+
+// NewStatusController creates a new StatusController.
+func NewStatusController(store model.ConfigStore, resolver func() *model.JwksResolver) *StatusController {
+	return &StatusController{
+		store:    store,
+		resolver: resolver,
+	}
+}
+
+// Run updates the statuses periodically until stop is closed.
+func (c *StatusController) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(statusUpdateInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.updateStatuses()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// updateStatuses sets the JwksResolved condition of the RequestAuthentication resources whose public keys were
+// fetched. The statuses are only written when the condition changes.
+func (c *StatusController) updateStatuses() {
+	resolver := c.resolver()
+	if resolver == nil {
+		return
+	}
+	configs, err := c.store.List(gvk.RequestAuthentication, metav1.NamespaceAll)
+	if err != nil {
+		log.Warnf("failed to list RequestAuthentications: %v", err)
+		return
+	}
+	for _, cfg := range configs {
+		condition := jwksCondition(resolver, cfg.Spec.(*v1beta1.RequestAuthentication))
+		if condition == nil {
+			continue
+		}
+		existing := status.GetConditionFromSpec(cfg, status.ConditionJwksResolved)
+		if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason &&
+			existing.Message == condition.Message {
+			continue
+		}
+		if _, err := c.store.UpdateStatus(status.UpdateConfigCondition(cfg, condition)); err != nil {
+			log.Warnf("failed to update the status of RequestAuthentication %s/%s: %v", cfg.Namespace, cfg.Name, err)
+			continue
+		}
+		log.Debugf("updated the JWKS status of RequestAuthentication %s/%s to %v", cfg.Namespace, cfg.Name, condition)
+	}
+}
+
+// jwksCondition returns the JwksResolved condition of a RequestAuthentication, or nil if none of its public keys
+// were fetched yet. Inline public keys are not fetched, so they do not affect the condition.
+func jwksCondition(resolver *model.JwksResolver, ra *v1beta1.RequestAuthentication) *v1alpha1.IstioCondition {
+	var failures []string
+	fetched := false
+	for _, rule := range ra.JwtRules {
+		if rule.Jwks != "" {
+			continue
+		}
+		s, found := resolver.GetStatus(rule.Issuer, rule.JwksUri)
+		if !found {
+			continue
+		}
+		if s.Error != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", rule.Issuer, s.Error))
+			continue
+		}
+		fetched = true
+	}
+
+	now := types.TimestampNow()
+	condition := &v1alpha1.IstioCondition{
+		Type:               status.ConditionJwksResolved,
+		LastProbeTime:      now,
+		LastTransitionTime: now,
+	}
+	switch {
+	case len(failures) != 0:
+		condition.Status = status.StatusFalse
+		condition.Reason = status.ReasonJwksFetchFailed
+		condition.Message = "failed to fetch the public keys of " + strings.Join(failures, "; ")
+	case fetched:
+		condition.Status = status.StatusTrue
+		condition.Reason = status.ReasonJwksFetched
+	default:
+		return nil
+	}
+	return condition
+}
@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwks
+
+import (
+	"strings"
+	"testing"
+
+	"istio.io/api/meta/v1alpha1"
+	"istio.io/api/security/v1beta1"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/model/status"
+	"istio.io/istio/pilot/pkg/model/test"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestUpdateStatuses(t *testing.T) {
+	ms, err := test.StartNewServer()
+	if err != nil {
+		t.Fatal("failed to start a mock server")
+	}
+	defer ms.Stop()
+	resolver := model.NewJwksResolver(model.JwtPubKeyEvictionDuration, model.JwtPubKeyRefreshInterval,
+		model.JwtPubKeyRefreshIntervalOnFailure, model.JwtPubKeyRetryInterval)
+	defer resolver.Close()
+
+	certURL := ms.URL + "/oauth2/v3/certs"
+	invalidURL := ms.URL + "/invalid"
+	_, _ = resolver.GetPublicKey("good", certURL)
+	_, _ = resolver.GetPublicKey("bad", invalidURL)
+
+	store := memory.NewController(memory.Make(collections.All))
+	for name, rules := range map[string][]*v1beta1.JWTRule{
+		"fetched":     {{Issuer: "good", JwksUri: certURL}},
+		"failed":      {{Issuer: "good", JwksUri: certURL}, {Issuer: "bad", JwksUri: invalidURL}},
+		"inline":      {{Issuer: "inline", Jwks: test.JwtPubKey1}},
+		"not-fetched": {{Issuer: "other", JwksUri: certURL}},
+	} {
+		if _, err := store.Create(config.Config{
+			Meta: config.Meta{GroupVersionKind: gvk.RequestAuthentication, Name: name, Namespace: "default"},
+			Spec: &v1beta1.RequestAuthentication{JwtRules: rules},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := NewStatusController(store, func() *model.JwksResolver { return resolver })
+	c.updateStatuses()
+
+	condition := func(name string) *v1alpha1.IstioCondition {
+		cfg := store.Get(gvk.RequestAuthentication, name, "default")
+		return status.GetConditionFromSpec(*cfg, status.ConditionJwksResolved)
+	}
+	if got := condition("fetched"); got == nil || got.Status != status.StatusTrue || got.Reason != status.ReasonJwksFetched {
+		t.Errorf("expected the fetched condition, got %v", got)
+	}
+	got := condition("failed")
+	if got == nil || got.Status != status.StatusFalse || got.Reason != status.ReasonJwksFetchFailed {
+		t.Fatalf("expected the fetch failed condition, got %v", got)
+	}
+	if !strings.Contains(got.Message, "bad: ") || strings.Contains(got.Message, "good: ") {
+		t.Errorf("expected the message to list the failing issuer, got %q", got.Message)
+	}
+	for _, name := range []string{"inline", "not-fetched"} {
+		if got := condition(name); got != nil {
+			t.Errorf("expected no condition for %s, got %v", name, got)
+		}
+	}
+
+	// The status is not written again while the condition does not change.
+	before := store.Get(gvk.RequestAuthentication, "failed", "default").ResourceVersion
+	c.updateStatuses()
+	if after := store.Get(gvk.RequestAuthentication, "failed", "default").ResourceVersion; after != before {
+		t.Errorf("expected the status not to be updated, resource version changed from %s to %s", before, after)
+	}
+}
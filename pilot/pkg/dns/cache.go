@@ -0,0 +1,188 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+	"github.com/miekg/dns"
+)
+
+const (
+	// defaultCacheSize is the maximum number of upstream responses kept in the cache.
+	defaultCacheSize = 4096
+	// maxCacheTTL caps the time a positive response is cached, regardless of its TTL.
+	maxCacheTTL = time.Hour
+	// maxNegativeCacheTTL caps the time a negative (NXDOMAIN or NODATA) response is cached.
+	maxNegativeCacheTTL = 5 * time.Minute
+)
+
+type cacheKey struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+	// DNSSEC records are only returned when requested, so these responses are cached separately.
+	dnssec bool
+}
+
+type cacheEntry struct {
+	msg     *dns.Msg
+	stored  time.Time
+	expires time.Time
+}
+
+// responseCache holds the responses of the upstream resolvers until their TTL expires, so that
+// repeated queries for names outside of the mesh do not all go to the upstream resolvers.
+// Negative responses are cached for the negative TTL of the SOA record in the authority section,
+// as described in RFC 2308. Responses without a SOA record, failures and truncated responses are
+// never cached.
+type responseCache struct {
+	mu    sync.Mutex
+	cache *simplelru.LRU
+	// now is overridden in tests
+	now func() time.Time
+}
+
+func newResponseCache(size int) *responseCache {
+	cache, err := simplelru.NewLRU(size, nil)
+	if err != nil {
+		// Only happens for a non positive size
+		panic(err)
+	}
+	return &responseCache{cache: cache, now: time.Now}
+}
+
+func keyFor(req *dns.Msg) cacheKey {
+	q := req.Question[0]
+	key := cacheKey{name: strings.ToLower(q.Name), qtype: q.Qtype, qclass: q.Qclass}
+	if opt := req.IsEdns0(); opt != nil {
+		key.dnssec = opt.Do()
+	}
+	return key
+}
+
+// get returns the cached response for the request, with the TTLs adjusted for the time spent in
+// the cache, or nil if there is none.
+func (c *responseCache) get(req *dns.Msg) *dns.Msg {
+	key := keyFor(req)
+	now := c.now()
+	c.mu.Lock()
+	v, f := c.cache.Get(key)
+	if !f {
+		c.mu.Unlock()
+		return nil
+	}
+	entry := v.(*cacheEntry)
+	if !now.Before(entry.expires) {
+		c.cache.Remove(key)
+		c.mu.Unlock()
+		return nil
+	}
+	c.mu.Unlock()
+
+	response := entry.msg.Copy()
+	response.Id = req.Id
+	response.Question = req.Question
+	elapsed := uint32(now.Sub(entry.stored) / time.Second)
+	for _, section := range [][]dns.RR{response.Answer, response.Ns, response.Extra} {
+		for _, rr := range section {
+			if rr.Header().Ttl > elapsed {
+				rr.Header().Ttl -= elapsed
+			} else {
+				rr.Header().Ttl = 0
+			}
+		}
+	}
+	return response
+}
+
+// add caches the upstream response to the request, if it is cacheable.
+func (c *responseCache) add(req *dns.Msg, response *dns.Msg) {
+	if response.Truncated || len(response.Question) == 0 {
+		return
+	}
+	ttl := cacheTTL(response)
+	if ttl <= 0 {
+		return
+	}
+	msg := response.Copy()
+	// The OPT record is specific to the request it answers.
+	extra := msg.Extra[:0]
+	for _, rr := range msg.Extra {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			extra = append(extra, rr)
+		}
+	}
+	msg.Extra = extra
+
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.Add(keyFor(req), &cacheEntry{msg: msg, stored: now, expires: now.Add(ttl)})
+}
+
+// cacheTTL returns how long the response can be cached, or 0 if it cannot be cached.
+func cacheTTL(response *dns.Msg) time.Duration {
+	switch response.Rcode {
+	case dns.RcodeSuccess:
+		if len(response.Answer) > 0 {
+			ttl := minTTL(response.Answer)
+			if ttl > maxCacheTTL {
+				ttl = maxCacheTTL
+			}
+			return ttl
+		}
+		// NODATA
+		return negativeTTL(response)
+	case dns.RcodeNameError:
+		return negativeTTL(response)
+	default:
+		return 0
+	}
+}
+
+// negativeTTL returns the negative TTL of the response, which is the minimum of the SOA record
+// TTL and its MINIMUM field.
+func negativeTTL(response *dns.Msg) time.Duration {
+	for _, rr := range response.Ns {
+		soa, ok := rr.(*dns.SOA)
+		if !ok {
+			continue
+		}
+		ttl := soa.Hdr.Ttl
+		if soa.Minttl < ttl {
+			ttl = soa.Minttl
+		}
+		d := time.Duration(ttl) * time.Second
+		if d > maxNegativeCacheTTL {
+			d = maxNegativeCacheTTL
+		}
+		return d
+	}
+	return 0
+}
+
+func minTTL(records []dns.RR) time.Duration {
+	var ttl uint32
+	for i, rr := range records {
+		if i == 0 || rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+	}
+	return time.Duration(ttl) * time.Second
+}
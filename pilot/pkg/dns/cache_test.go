@@ -0,0 +1,169 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func soa(name string, ttl, minTTL uint32) dns.RR {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: name, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: ttl},
+		Ns:      "ns." + name,
+		Mbox:    "admin." + name,
+		Serial:  1,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  minTTL,
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	cases := []struct {
+		name     string
+		response func(req *dns.Msg) *dns.Msg
+		// expected cache duration, 0 if the response should not be cached
+		ttl time.Duration
+	}{
+		{
+			name: "answer",
+			response: func(req *dns.Msg) *dns.Msg {
+				m := new(dns.Msg).SetReply(req)
+				m.Answer = append(a("example.com.", []net.IP{net.ParseIP("1.1.1.1")}), cname("example.com.", "other.com.")...)
+				m.Answer[1].Header().Ttl = 10
+				return m
+			},
+			ttl: 10 * time.Second,
+		},
+		{
+			name: "answer with long ttl",
+			response: func(req *dns.Msg) *dns.Msg {
+				m := new(dns.Msg).SetReply(req)
+				m.Answer = a("example.com.", []net.IP{net.ParseIP("1.1.1.1")})
+				m.Answer[0].Header().Ttl = 86400
+				return m
+			},
+			ttl: maxCacheTTL,
+		},
+		{
+			name: "nxdomain",
+			response: func(req *dns.Msg) *dns.Msg {
+				m := new(dns.Msg).SetRcode(req, dns.RcodeNameError)
+				m.Ns = []dns.RR{soa("com.", 60, 20)}
+				return m
+			},
+			ttl: 20 * time.Second,
+		},
+		{
+			name: "nodata",
+			response: func(req *dns.Msg) *dns.Msg {
+				m := new(dns.Msg).SetReply(req)
+				m.Ns = []dns.RR{soa("com.", 15, 20)}
+				return m
+			},
+			ttl: 15 * time.Second,
+		},
+		{
+			name: "nxdomain with long negative ttl",
+			response: func(req *dns.Msg) *dns.Msg {
+				m := new(dns.Msg).SetRcode(req, dns.RcodeNameError)
+				m.Ns = []dns.RR{soa("com.", 86400, 86400)}
+				return m
+			},
+			ttl: maxNegativeCacheTTL,
+		},
+		{
+			name: "nxdomain without soa",
+			response: func(req *dns.Msg) *dns.Msg {
+				return new(dns.Msg).SetRcode(req, dns.RcodeNameError)
+			},
+		},
+		{
+			name: "server failure",
+			response: func(req *dns.Msg) *dns.Msg {
+				m := new(dns.Msg).SetRcode(req, dns.RcodeServerFailure)
+				m.Ns = []dns.RR{soa("com.", 60, 60)}
+				return m
+			},
+		},
+		{
+			name: "truncated",
+			response: func(req *dns.Msg) *dns.Msg {
+				m := new(dns.Msg).SetReply(req)
+				m.Answer = a("example.com.", []net.IP{net.ParseIP("1.1.1.1")})
+				m.Truncated = true
+				return m
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			c := newResponseCache(10)
+			c.now = func() time.Time { return now }
+
+			req := new(dns.Msg).SetQuestion("example.com.", dns.TypeA)
+			response := tt.response(req)
+			c.add(req, response)
+
+			// The cache is keyed by question, not by request ID or name case.
+			next := new(dns.Msg).SetQuestion("Example.COM.", dns.TypeA)
+			cached := c.get(next)
+			if tt.ttl == 0 {
+				if cached != nil {
+					t.Fatalf("expected response not to be cached, got %v", cached)
+				}
+				return
+			}
+			if cached == nil {
+				t.Fatalf("expected response to be cached")
+			}
+			if cached.Id != next.Id || cached.Question[0].Name != "Example.COM." {
+				t.Fatalf("cached response does not answer the request: %v", cached)
+			}
+			if cached.Rcode != response.Rcode || len(cached.Answer) != len(response.Answer) {
+				t.Fatalf("expected %v, got %v", response, cached)
+			}
+
+			if c.get(new(dns.Msg).SetQuestion("example.com.", dns.TypeAAAA)) != nil {
+				t.Fatalf("unexpected response for a different type")
+			}
+
+			// TTLs count down while the response is in the cache.
+			now = now.Add(tt.ttl - time.Second)
+			cached = c.get(next)
+			if cached == nil {
+				t.Fatalf("expected response to be cached until it expires")
+			}
+			elapsed := uint32((tt.ttl - time.Second) / time.Second)
+			got := append(cached.Answer, cached.Ns...)
+			for i, rr := range append(response.Answer, response.Ns...) {
+				if got[i].Header().Ttl != rr.Header().Ttl-elapsed {
+					t.Fatalf("expected ttl to be decremented by %d, got %v for %v", elapsed, got[i], rr)
+				}
+			}
+
+			now = now.Add(time.Second)
+			if cached := c.get(next); cached != nil {
+				t.Fatalf("expected response to expire, got %v", cached)
+			}
+		})
+	}
+}
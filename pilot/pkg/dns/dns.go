@@ -16,8 +16,10 @@ package dns
 
 import (
 	"net"
+	"sort"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/miekg/dns"
@@ -40,6 +42,9 @@ type LocalDNSServer struct {
 	udpDNSProxy *dnsProxy
 	tcpDNSProxy *dnsProxy
 
+	// responseCache holds the responses of the upstream resolvers, shared by the UDP and TCP proxies.
+	responseCache *responseCache
+
 	resolvConfServers []string
 	searchNamespaces  []string
 	// The namespace where the proxy resides
@@ -67,6 +72,12 @@ type LookupTable struct {
 	// The cname records here (comprised of different variants of the hosts above,
 	// expanded by the search namespaces) pointing to the actual host.
 	cname map[string][]dns.RR
+	// The key is a service name of the form _port._tcp.host. (for each variant of the host), the value
+	// is the SRV record for the service port.
+	srv map[string][]dns.RR
+	// The key is a reverse lookup name (like 1.1.1.1.in-addr.arpa.), the value is the PTR records
+	// pointing to the hosts with that IP.
+	ptr map[string][]dns.RR
 }
 
 const (
@@ -79,6 +90,7 @@ const (
 func NewLocalDNSServer(proxyNamespace, proxyDomain string) (*LocalDNSServer, error) {
 	h := &LocalDNSServer{
 		proxyNamespace: proxyNamespace,
+		responseCache:  newResponseCache(defaultCacheSize),
 	}
 
 	// proxyDomain could contain the namespace making it redundant.
@@ -137,6 +149,8 @@ func (h *LocalDNSServer) UpdateLookupTable(nt *nds.NameTable) {
 		name4:    map[string][]dns.RR{},
 		name6:    map[string][]dns.RR{},
 		cname:    map[string][]dns.RR{},
+		srv:      map[string][]dns.RR{},
+		ptr:      map[string][]dns.RR{},
 	}
 	for host, ni := range nt.Table {
 		// Given a host
@@ -155,6 +169,17 @@ func (h *LocalDNSServer) UpdateLookupTable(nt *nds.NameTable) {
 			continue
 		}
 		lookupTable.buildDNSAnswers(altHosts, ipv4, ipv6, h.searchNamespaces)
+		if !strings.HasPrefix(host, "*") {
+			// SRV and PTR records cannot be built for wildcard hosts, as we do not know the actual names.
+			lookupTable.buildSRVAnswers(host, altHosts, ni.Ports)
+			lookupTable.buildPTRAnswers(host, ipv4, ipv6)
+		}
+	}
+	// The name table is a map, sort the PTR records to get consistent responses.
+	for _, records := range lookupTable.ptr {
+		sort.Slice(records, func(i, j int) bool {
+			return records[i].(*dns.PTR).Ptr < records[j].(*dns.PTR).Ptr
+		})
 	}
 	h.lookupTable.Store(lookupTable)
 	h.nameTable.Store(nt)
@@ -211,11 +236,18 @@ func (h *LocalDNSServer) ServeDNS(proxy *dnsProxy, w dns.ResponseWriter, req *dn
 		// This matches standard kube-dns behavior. We only do this for cached responses as the
 		// upstream DNS server would already round robin if desired.
 		roundRobinResponse(response)
+		lookupTableHits.Increment()
 		log.Debugf("response for hostname %q (found=true): %v", hostname, response)
+	} else if response = h.responseCache.get(req); response != nil {
+		// We already got the response from upstream, and it has not expired yet.
+		responseCacheHits.Increment()
+		log.Debugf("cached upstream response for hostname %q : %v", hostname, response)
 	} else {
 		// We did not find the host in our internal cache. Query upstream and return the response as is.
+		cacheMisses.Increment()
 		log.Debugf("response for hostname %q not found in dns proxy, querying upstream", hostname)
 		response = h.queryUpstream(proxy.upstreamClient, req, log)
+		h.responseCache.add(req, response)
 		log.Debugf("upstream response for hostname %q : %v", hostname, response)
 	}
 	// Compress the response - we don't know if the incoming response was compressed or not. If it was,
@@ -308,6 +340,10 @@ func (h *LocalDNSServer) Close() {
 // TODO: Figure out how to send parallel queries to all nameservers
 func (h *LocalDNSServer) queryUpstream(upstreamClient *dns.Client, req *dns.Msg, scope *istiolog.Scope) *dns.Msg {
 	var response *dns.Msg
+	start := time.Now()
+	defer func() {
+		upstreamRequestDuration.Record(time.Since(start).Seconds())
+	}()
 	for _, upstream := range h.resolvConfServers {
 		cResponse, _, err := upstreamClient.Exchange(req, upstream)
 		if err == nil {
@@ -318,6 +354,7 @@ func (h *LocalDNSServer) queryUpstream(upstreamClient *dns.Client, req *dns.Msg,
 		}
 	}
 	if response == nil {
+		upstreamFailures.Increment()
 		response = new(dns.Msg)
 		response.SetReply(req)
 		response.Rcode = dns.RcodeServerFailure
@@ -400,21 +437,24 @@ func (table *LookupTable) lookupHost(qtype uint16, hostname string) ([]dns.RR, b
 		// this was a cname match
 		hostname = cn[0].(*dns.CNAME).Target
 	}
-	var ipAnswers []dns.RR
+	var answers []dns.RR
 	switch qtype {
 	case dns.TypeA:
-		ipAnswers = table.name4[hostname]
+		answers = table.name4[hostname]
 	case dns.TypeAAAA:
-		ipAnswers = table.name6[hostname]
+		answers = table.name6[hostname]
+	case dns.TypeSRV:
+		answers = table.srv[hostname]
+	case dns.TypePTR:
+		answers = table.ptr[hostname]
 	default:
-		// TODO: handle PTR records for reverse dns lookups
 		return nil, false
 	}
 
-	if len(ipAnswers) > 0 {
+	if len(answers) > 0 {
 		// For wildcard hosts, set the host that is being queried for.
 		if wildcard {
-			for _, answer := range ipAnswers {
+			for _, answer := range answers {
 				answer.Header().Name = string(question)
 			}
 		}
@@ -424,7 +464,7 @@ func (table *LookupTable) lookupHost(qtype uint16, hostname string) ([]dns.RR, b
 		// big DNS response (presumably assuming that a recursive DNS query should do the deed, resolve
 		// cname et al and return the composite response).
 		out = append(out, cn...)
-		out = append(out, ipAnswers...)
+		out = append(out, answers...)
 	}
 	return out, hostFound
 }
@@ -477,6 +517,36 @@ func (table *LookupTable) buildDNSAnswers(altHosts map[string]struct{}, ipv4 []n
 	}
 }
 
+// buildSRVAnswers stores the SRV records for the named ports of the host, for each of its variants.
+// Following the Kubernetes DNS specification, the records are named _port._tcp.host. and point
+// to the fully qualified host.
+func (table *LookupTable) buildSRVAnswers(hostname string, altHosts map[string]struct{}, ports map[string]uint32) {
+	target := strings.ToLower(hostname) + "."
+	for name, port := range ports {
+		for h := range altHosts {
+			service := strings.ToLower("_" + name + "._tcp." + h)
+			table.allHosts[service] = struct{}{}
+			table.srv[service] = srv(service, target, port)
+		}
+	}
+}
+
+// buildPTRAnswers stores the PTR records for the reverse lookup of each IP of the host.
+// An IP may be used by several hosts, in which case all of them are returned.
+func (table *LookupTable) buildPTRAnswers(hostname string, ipv4 []net.IP, ipv6 []net.IP) {
+	target := strings.ToLower(hostname) + "."
+	for _, ips := range [][]net.IP{ipv4, ipv6} {
+		for _, ip := range ips {
+			reverse, err := dns.ReverseAddr(ip.String())
+			if err != nil {
+				continue
+			}
+			table.allHosts[reverse] = struct{}{}
+			table.ptr[reverse] = append(table.ptr[reverse], ptr(reverse, target))
+		}
+	}
+}
+
 // Borrowed from https://github.com/coredns/coredns/blob/master/plugin/hosts/hosts.go
 // a takes a slice of net.IPs and returns a slice of A RRs.
 func a(host string, ips []net.IP) []dns.RR {
@@ -514,6 +584,32 @@ func cname(host string, targetHost string) []dns.RR {
 	return []dns.RR{answer}
 }
 
+func srv(service string, target string, port uint32) []dns.RR {
+	answer := new(dns.SRV)
+	answer.Hdr = dns.RR_Header{
+		Name:   service,
+		Rrtype: dns.TypeSRV,
+		Class:  dns.ClassINET,
+		Ttl:    defaultTTLInSeconds,
+	}
+	answer.Weight = 100
+	answer.Port = uint16(port)
+	answer.Target = target
+	return []dns.RR{answer}
+}
+
+func ptr(reverse string, targetHost string) dns.RR {
+	answer := new(dns.PTR)
+	answer.Hdr = dns.RR_Header{
+		Name:   reverse,
+		Rrtype: dns.TypePTR,
+		Class:  dns.ClassINET,
+		Ttl:    defaultTTLInSeconds,
+	}
+	answer.Ptr = targetHost
+	return answer
+}
+
 // Size returns if buffer size *advertised* in the requests OPT record.
 // Or when the request was over TCP, we return the maximum allowed size of 64K.
 func size(proto string, r *dns.Msg) int {
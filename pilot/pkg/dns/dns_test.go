@@ -33,11 +33,16 @@ var testAgentDNSAddr = "127.0.0.1:15053"
 
 func TestDNS(t *testing.T) {
 	initDNS(t)
+	ipv6Reverse, err := dns.ReverseAddr("2001:db8:0:0:0:ff00:42:8329")
+	if err != nil {
+		t.Fatal(err)
+	}
 	testCases := []struct {
 		name                     string
 		host                     string
 		id                       int
 		queryAAAA                bool
+		queryType                uint16
 		expected                 []dns.RR
 		expectResolutionFailure  int
 		expectExternalResolution bool
@@ -166,6 +171,65 @@ func TestDNS(t *testing.T) {
 			host:      "ipv4.localhost.",
 			queryAAAA: true,
 		},
+		{
+			name:      "success: SRV query for k8s host - fqdn",
+			host:      "_http._tcp.productpage.ns1.svc.cluster.local.",
+			queryType: dns.TypeSRV,
+			expected:  srv("_http._tcp.productpage.ns1.svc.cluster.local.", "productpage.ns1.svc.cluster.local.", 9080),
+		},
+		{
+			name:      "success: SRV query for k8s host - shortname",
+			host:      "_http._tcp.productpage.",
+			queryType: dns.TypeSRV,
+			expected:  srv("_http._tcp.productpage.", "productpage.ns1.svc.cluster.local.", 9080),
+		},
+		{
+			name:      "success: SRV query for non k8s host",
+			host:      "_https._tcp.www.google.com.",
+			queryType: dns.TypeSRV,
+			expected:  srv("_https._tcp.www.google.com.", "www.google.com.", 443),
+		},
+		{
+			name:                    "failure: SRV query for unknown port",
+			host:                    "_grpc._tcp.productpage.",
+			queryType:               dns.TypeSRV,
+			expectResolutionFailure: dns.RcodeNameError,
+		},
+		{
+			name:      "success: SRV query for host without ports",
+			host:      "productpage.",
+			queryType: dns.TypeSRV,
+		},
+		{
+			name:      "success: PTR query",
+			host:      "9.9.9.9.in-addr.arpa.",
+			queryType: dns.TypePTR,
+			expected:  []dns.RR{ptr("9.9.9.9.in-addr.arpa.", "productpage.ns1.svc.cluster.local.")},
+		},
+		{
+			name:      "success: PTR query for IP of multiple hosts",
+			host:      "2.2.2.2.in-addr.arpa.",
+			queryType: dns.TypePTR,
+			expected: []dns.RR{
+				ptr("2.2.2.2.in-addr.arpa.", "dual.localhost."),
+				ptr("2.2.2.2.in-addr.arpa.", "ipv4.localhost."),
+			},
+		},
+		{
+			name:      "success: PTR query for IPv6",
+			host:      ipv6Reverse,
+			queryType: dns.TypePTR,
+			expected: []dns.RR{
+				ptr(ipv6Reverse, "dual.localhost."),
+				ptr(ipv6Reverse, "ipv6.localhost."),
+			},
+		},
+		{
+			name:                    "failure: PTR query for unknown IP",
+			host:                    "8.8.8.8.in-addr.arpa.",
+			queryType:               dns.TypePTR,
+			expectResolutionFailure: dns.RcodeNameError,
+		},
 		{
 			name: "udp: large request",
 			host: "giant.",
@@ -223,6 +287,9 @@ func TestDNS(t *testing.T) {
 				if tt.queryAAAA {
 					q = dns.TypeAAAA
 				}
+				if tt.queryType != 0 {
+					q = tt.queryType
+				}
 				m.SetQuestion(tt.host, q)
 				if tt.modifyReq != nil {
 					tt.modifyReq(m)
@@ -423,12 +490,14 @@ func initDNS(t test.Failer) *LocalDNSServer {
 			"www.google.com": {
 				Ips:      []string{"1.1.1.1"},
 				Registry: "External",
+				Ports:    map[string]uint32{"https": 443},
 			},
 			"productpage.ns1.svc.cluster.local": {
 				Ips:       []string{"9.9.9.9"},
 				Registry:  "Kubernetes",
 				Namespace: "ns1",
 				Shortname: "productpage",
+				Ports:     map[string]uint32{"http": 9080},
 			},
 			"example.ns2.svc.cluster.local": {
 				Ips:       []string{"10.10.10.10"},
@@ -0,0 +1,59 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	typeTag = monitoring.MustCreateLabel("type")
+
+	cacheHits = monitoring.NewSum(
+		"dns_cache_hits_total",
+		"Total number of DNS requests answered without querying the upstream resolvers.",
+		monitoring.WithLabels(typeTag),
+	)
+
+	// lookupTableHits counts requests answered from the name table sent by istiod.
+	lookupTableHits = cacheHits.With(typeTag.Value("lookup_table"))
+	// responseCacheHits counts requests answered from previous upstream responses.
+	responseCacheHits = cacheHits.With(typeTag.Value("response_cache"))
+
+	cacheMisses = monitoring.NewSum(
+		"dns_cache_misses_total",
+		"Total number of DNS requests forwarded to the upstream resolvers.",
+	)
+
+	upstreamFailures = monitoring.NewSum(
+		"dns_upstream_failures_total",
+		"Total number of DNS requests which could not be answered by any upstream resolver.",
+	)
+
+	upstreamRequestDuration = monitoring.NewDistribution(
+		"dns_upstream_request_duration_seconds",
+		"Time in seconds taken to get a response from the upstream resolvers.",
+		[]float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 5},
+	)
+)
+
+func init() {
+	monitoring.MustRegister(
+		cacheHits,
+		cacheMisses,
+		upstreamFailures,
+		upstreamRequestDuration,
+	)
+}
@@ -91,6 +91,20 @@ var (
 			"for this time, we'll trigger a push.",
 	).Get()
 
+	ProxyMinPushInterval = env.RegisterDurationVar(
+		"PILOT_PROXY_MIN_PUSH_INTERVAL",
+		0,
+		"The minimum time between two pushes to the same proxy. Pushes triggered sooner are delayed and merged, "+
+			"so proxies are not pushed repeatedly during large configuration changes. A value of 0s disables it.",
+	).Get()
+
+	PushQueueMaxPriorityWait = env.RegisterDurationVar(
+		"PILOT_PUSH_QUEUE_MAX_PRIORITY_WAIT",
+		5*time.Second,
+		"The maximum time a proxy waits in the push queue behind proxies of higher priority classes. Proxies which "+
+			"waited longer are pushed first, so the lower classes are not starved. A value of 0s disables it.",
+	).Get()
+
 	EnableEDSDebounce = env.RegisterBoolVar(
 		"PILOT_ENABLE_EDS_DEBOUNCE",
 		true,
@@ -214,6 +228,13 @@ var (
 		"If enabled, Pilot will keep track of old versions of distributed config for this duration.",
 	).Get()
 
+	ConfigHistorySize = env.RegisterIntVar(
+		"PILOT_CONFIG_HISTORY_SIZE",
+		1000,
+		"The number of config changes Pilot keeps in memory, exposed on /debug/config_history. "+
+			"Set to 0 to disable the config history.",
+	).Get()
+
 	EnableEndpointSliceController = env.RegisterBoolVar(
 		"PILOT_USE_ENDPOINT_SLICE",
 		false,
@@ -328,9 +349,20 @@ var (
 	InjectionWebhookConfigName = env.RegisterStringVar("INJECTION_WEBHOOK_CONFIG_NAME", "istio-sidecar-injector",
 		"Name of the mutatingwebhookconfiguration to patch, if istioctl is not used.").Get()
 
+	EnableNamespaceInjectionTemplates = env.RegisterBoolVar("ENABLE_NAMESPACE_INJECTION_TEMPLATES", false,
+		"If enabled, ConfigMaps labeled with istio.io/inject-templates override the injection templates "+
+			"for the pods of their namespace. These templates cannot use the functions reading the environment "+
+			"of istiod, such as env and expandenv.").Get()
+
 	ValidationWebhookConfigName = env.RegisterStringVar("VALIDATION_WEBHOOK_CONFIG_NAME", "istio-istio-system",
 		"Name of the validatingwebhookconfiguration to patch. Empty will skip using cluster admin to patch.").Get()
 
+	ValidationWebhookAnalyzers = env.RegisterStringVar("PILOT_VALIDATION_ANALYZERS", "",
+		"Comma separated list of analyzers run by the validation webhook on created and updated resources, for "+
+			"example virtualservice.DestinationRuleAnalyzer,gateway.ConflictingGatewayAnalyzer. Errors reported "+
+			"for the resource deny the request, warnings are returned as admission warnings. Only the analyzers "+
+			"reading Istio configuration are supported.").Get()
+
 	SpiffeBundleEndpoints = env.RegisterStringVar("SPIFFE_BUNDLE_ENDPOINTS", "",
 		"The SPIFFE bundle trust domain to endpoint mappings. Istiod retrieves the root certificate from each SPIFFE "+
 			"bundle endpoint and uses it to verify client certifiates from that trust domain. The endpoint must be "+
@@ -349,6 +381,15 @@ var (
 	XDSCacheMaxSize = env.RegisterIntVar("PILOT_XDS_CACHE_SIZE", 20000,
 		"The maximum number of cache entries for the XDS cache.").Get()
 
+	XDSCacheMaxBytes = env.RegisterIntVar("PILOT_XDS_CACHE_MAX_BYTES", 0,
+		"The maximum size in bytes of the serialized resources of each xDS type for the XDS cache. "+
+			"A value of 0 does not bound the size.").Get()
+
+	XDSCacheTypeLimits = env.RegisterStringVar("PILOT_XDS_CACHE_TYPE_LIMITS", "",
+		"Comma separated limits of the XDS cache for some xDS types, as <type>=<entries>[/<bytes>], for example "+
+			"EDS=10000/256Mi,SDS=/16Mi. The entries of a type default to PILOT_XDS_CACHE_SIZE, and its bytes to "+
+			"PILOT_XDS_CACHE_MAX_BYTES. The entries of all the types are still bounded by PILOT_XDS_CACHE_SIZE.").Get()
+
 	// EnableLegacyFSGroupInjection has first-party-jwt as allowed because we only
 	// need the fsGroup configuration for the projected service account volume mount,
 	// which is only used by first-party-jwt. The installer will automatically
@@ -422,6 +463,13 @@ var (
 		"The interval for istiod to fetch the jwks_uri for the jwks public key.",
 	).Get()
 
+	JwksCacheFile = env.RegisterStringVar(
+		"PILOT_JWKS_CACHE_FILE",
+		"",
+		"If set, istiod persists the fetched jwks public keys to this file, and loads them on startup so that "+
+			"they are available even if the issuers can not be reached. The file should be on a persistent volume.",
+	).Get()
+
 	EnableInboundPassthrough = env.RegisterBoolVar(
 		"PILOT_ENABLE_INBOUND_PASSTHROUGH",
 		true,
@@ -486,6 +534,19 @@ var (
 	// New behavior (true): we create listener 0.0.0.0_8080 and route http.8080. This has no conflicts; routes are 1:1 with listener.
 	UseTargetPortForGatewayRoutes = env.RegisterBoolVar("PILOT_USE_TARGET_PORT_FOR_GATEWAY_ROUTES", true,
 		"If true, routes will use the target port of the gateway service in the route name, not the service port.").Get()
+
+	LeaderElectionLock = env.RegisterStringVar("PILOT_LEADER_ELECTION_LOCK", "configmaps",
+		"The resource used as lock by the leader elections of Istiod: configmaps, leases, or configmapsleases to hold "+
+			"both while migrating from config maps to leases.").Get()
+
+	EnableLeaderElectionSpread = env.RegisterBoolVar("PILOT_ENABLE_LEADER_ELECTION_SPREAD", false,
+		"If true, an Istiod instance already leading some controllers defers to other instances when acquiring the "+
+			"leadership of other controllers, so the controllers are spread across replicas.").Get()
+
+	EnableCertificateRequests = env.RegisterBoolVar("PILOT_ENABLE_CERTIFICATE_REQUESTS", false,
+		"If true, Istiod issues DNS certificates signed by the Kubernetes CA to the secrets of type "+
+			"istio.io/dns-certificate-request of any namespace, for the services of their namespace listed in the "+
+			"certificates.istio.io/dns-names annotation, and renews them before they expire.").Get()
 )
 
 // UnsafeFeaturesEnabled returns true if any unsafe features are enabled.
@@ -16,14 +16,15 @@ package leaderelection
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"go.uber.org/atomic"
-	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/pkg/log"
 )
 
@@ -36,23 +37,57 @@ const (
 	IngressController = "istio-leader"
 	StatusController  = "istio-status-leader"
 	AnalyzeController = "istio-analyze-leader"
+	// CertificateRequestController issues the DNS certificates requested by secrets, see chiron.RequestController.
+	CertificateRequestController = "istio-certificate-request-leader"
+	// The webhook patcher lock is suffixed by the revision, see WebhookPatcherElectionID.
+	WebhookPatcherController = "istio-webhook-patcher-leader"
 )
 
+// WebhookPatcherElectionID returns the election ID of the webhook patcher of a revision: each revision patches its
+// own webhooks, so the revisions do not compete for the same lock.
+func WebhookPatcherElectionID(revision string) string {
+	if revision == "" {
+		revision = "default"
+	}
+	return WebhookPatcherController + "-" + revision
+}
+
 type LeaderElection struct {
 	namespace string
 	name      string
 	runFns    []func(stop <-chan struct{})
 	client    kubernetes.Interface
 	ttl       time.Duration
+	// lockType is the resource used as lock, one of the resourcelock lock types.
+	lockType string
+	// spread defers the acquisition of the lock while this instance leads other elections.
+	spread bool
 
 	// Records which "cycle" the election is on. This is incremented each time an election is won and then lost
 	// This is mostly just for testing
 	cycle      *atomic.Int32
 	electionID string
+
+	// runs tracks the run functions of the current term. They must return before the next term starts, so the
+	// controllers never run twice in the same instance.
+	runs sync.WaitGroup
+
+	mu sync.Mutex
+	// holding is set once the lock was written by this instance in the current cycle.
+	holding bool
+	leading bool
+	// token is the fencing token of the term led by this instance.
+	token int64
+	// observed is the last record read from or written to the lock.
+	observed *resourcelock.LeaderElectionRecord
+	// deferSince is when the acquisition of a free lock was first deferred to other instances.
+	deferSince time.Time
 }
 
 // Run will start leader election, calling all runFns when we become the leader.
 func (l *LeaderElection) Run(stop <-chan struct{}) {
+	register(l)
+	defer unregister(l)
 	for {
 		le, err := l.create()
 		if err != nil {
@@ -66,6 +101,9 @@ func (l *LeaderElection) Run(stop <-chan struct{}) {
 			cancel()
 		}()
 		le.Run(ctx)
+		l.mu.Lock()
+		l.holding = false
+		l.mu.Unlock()
 		select {
 		case <-stop:
 			// We were told to stop explicitly. Exit now
@@ -76,6 +114,8 @@ func (l *LeaderElection) Run(stop <-chan struct{}) {
 			// Typically this means something went wrong, such as API server downtime, etc
 			// If this does happen, we will start the cycle over again
 			log.Errorf("Leader election cycle %v lost. Trying again", l.cycle.Load())
+			// Fence the previous term: its run functions were told to stop and must be done before we lead again.
+			l.runs.Wait()
 		}
 	}
 }
@@ -83,23 +123,34 @@ func (l *LeaderElection) Run(stop <-chan struct{}) {
 func (l *LeaderElection) create() (*leaderelection.LeaderElector, error) {
 	callbacks := leaderelection.LeaderCallbacks{
 		OnStartedLeading: func(ctx context.Context) {
+			l.mu.Lock()
+			l.leading = true
+			log.Infof("leader election lock obtained: %v (token %d)", l.electionID, l.token)
+			l.mu.Unlock()
+			l.runs.Add(len(l.runFns))
 			for _, f := range l.runFns {
-				go f(ctx.Done())
+				go func(f func(stop <-chan struct{})) {
+					defer l.runs.Done()
+					f(ctx.Done())
+				}(f)
 			}
 		},
 		OnStoppedLeading: func() {
+			l.mu.Lock()
+			l.leading = false
+			l.mu.Unlock()
 			log.Infof("leader election lock lost: %v", l.electionID)
 		},
 	}
-	lock := resourcelock.ConfigMapLock{
-		ConfigMapMeta: metaV1.ObjectMeta{Namespace: l.namespace, Name: l.electionID},
-		Client:        l.client.CoreV1(),
-		LockConfig: resourcelock.ResourceLockConfig{
+	lock, err := resourcelock.New(l.lockType, l.namespace, l.electionID, l.client.CoreV1(), l.client.CoordinationV1(),
+		resourcelock.ResourceLockConfig{
 			Identity: l.name,
-		},
+		})
+	if err != nil {
+		return nil, err
 	}
 	return leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
-		Lock:          &lock,
+		Lock:          &observedLock{Interface: lock, election: l},
 		LeaseDuration: l.ttl,
 		RenewDeadline: l.ttl / 2,
 		RetryPeriod:   l.ttl / 4,
@@ -118,6 +169,94 @@ func (l *LeaderElection) AddRunFunction(f func(stop <-chan struct{})) *LeaderEle
 	return l
 }
 
+// Token returns the fencing token of the current term, and whether this instance is the leader. The token is the
+// number of leadership transitions of the lock, so it increases each time a leader is elected: writes tagged with
+// the token can be rejected once a newer leader wrote.
+func (l *LeaderElection) Token() (int64, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.token, l.leading
+}
+
+// IsLeader returns true if this instance leads the election and renewed the lock recently enough that no other
+// instance can have acquired it.
+func (l *LeaderElection) IsLeader() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.leading && l.observed != nil && l.observed.HolderIdentity == l.name &&
+		time.Since(l.observed.RenewTime.Time) < l.ttl/2
+}
+
+// Fence returns a function reporting whether the writes of the current term may proceed. It must be called by the
+// run functions when they start. The function returns false once this instance no longer leads the term in which
+// it was created, or did not renew the lock recently enough, so the writes of a stale leader are dropped rather than
+// overwriting the ones of a newer leader.
+func (l *LeaderElection) Fence() func() bool {
+	token, _ := l.Token()
+	// The token is unchanged when this instance leads again, so the cycle identifies the term as well.
+	cycle := l.cycle.Load()
+	return func() bool {
+		current, leading := l.Token()
+		if !leading || current != token || l.cycle.Load() != cycle || !l.IsLeader() {
+			log.Debugf("leader election %v: writes of term %d fenced", l.electionID, token)
+			return false
+		}
+		return true
+	}
+}
+
+// observe records the state of the lock, as read from the API server.
+func (l *LeaderElection) observe(record *resourcelock.LeaderElectionRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.observed = record
+	if record.HolderIdentity != "" && record.HolderIdentity != l.name && time.Since(record.RenewTime.Time) < l.ttl {
+		// Another instance holds the lock, the deferral starts over when it is released.
+		l.deferSince = time.Time{}
+	}
+}
+
+// acquiring returns true if the lock is written to acquire it rather than renew it.
+func (l *LeaderElection) acquiring() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.holding
+}
+
+// written records a successful write of the lock by this instance.
+func (l *LeaderElection) written(record resourcelock.LeaderElectionRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.holding {
+		l.token = int64(record.LeaderTransitions)
+		l.holding = true
+		l.deferSince = time.Time{}
+	}
+	l.observed = &record
+}
+
+// deferAcquire returns an error if the acquisition of the lock should be left to other instances. An instance
+// leading n other elections waits for n retry periods, so instances leading fewer elections acquire the lock first.
+func (l *LeaderElection) deferAcquire() error {
+	if !l.spread {
+		return nil
+	}
+	n := leading(l)
+	if n == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.deferSince.IsZero() {
+		l.deferSince = now
+	}
+	if wait := time.Duration(n) * l.ttl / 2; now.Sub(l.deferSince) < wait {
+		return errDeferred{electionID: l.electionID, leading: n}
+	}
+	return nil
+}
+
 func NewLeaderElection(namespace, name, electionID string, client kubernetes.Interface) *LeaderElection {
 	if name == "" {
 		name = "unknown"
@@ -128,7 +267,9 @@ func NewLeaderElection(namespace, name, electionID string, client kubernetes.Int
 		electionID: electionID,
 		client:     client,
 		// Default to a 30s ttl. Overridable for tests
-		ttl:   time.Second * 30,
-		cycle: atomic.NewInt32(0),
+		ttl:      time.Second * 30,
+		lockType: features.LeaderElectionLock,
+		spread:   features.EnableLeaderElectionSpread,
+		cycle:    atomic.NewInt32(0),
 	}
 }
@@ -26,6 +26,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 
 	"istio.io/istio/pkg/test/util/retry"
 )
@@ -37,6 +38,11 @@ func createElection(t *testing.T, name string, expectLeader bool, client kuberne
 	t.Helper()
 	l := NewLeaderElection("ns", name, testLock, client)
 	l.ttl = time.Second
+	return runElection(t, l, expectLeader, fns...)
+}
+
+func runElection(t *testing.T, l *LeaderElection, expectLeader bool, fns ...func(stop <-chan struct{})) (*LeaderElection, chan struct{}) {
+	t.Helper()
 	gotLeader := make(chan struct{})
 	l.AddRunFunction(func(stop <-chan struct{}) {
 		gotLeader <- struct{}{}
@@ -127,6 +133,200 @@ func TestLeaderElectionNoPermission(t *testing.T) {
 	close(stop)
 }
 
+func TestLeaderElectionLease(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	l := NewLeaderElection("ns", "pod1", testLock, client)
+	l.ttl = time.Second
+	l.lockType = resourcelock.LeasesResourceLock
+	_, stop := runElection(t, l, true)
+	lease, err := client.CoordinationV1().Leases("ns").Get(context.TODO(), testLock, v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder := lease.Spec.HolderIdentity; holder == nil || *holder != "pod1" {
+		t.Fatalf("unexpected lease holder: %v", holder)
+	}
+	if _, err := client.CoreV1().ConfigMaps("ns").Get(context.TODO(), testLock, v1.GetOptions{}); err == nil {
+		t.Fatal("unexpected config map lock")
+	}
+	close(stop)
+}
+
+func TestLeaderElectionToken(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	l1, stop := createElection(t, "pod1", true, client)
+	if token, leading := l1.Token(); token != 0 || !leading {
+		t.Fatalf("unexpected token %v, leading %v", token, leading)
+	}
+	if !l1.IsLeader() {
+		t.Fatal("expected pod1 to be the leader")
+	}
+	l2, stop2 := createElection(t, "pod2", false, client)
+	if _, leading := l2.Token(); leading || l2.IsLeader() {
+		t.Fatal("unexpected leadership of pod2")
+	}
+	// The token of the next term is higher, so writes of the previous leader can be fenced
+	close(stop)
+	retry.UntilSuccessOrFail(t, func() error {
+		if token, leading := l2.Token(); token != 1 || !leading {
+			return fmt.Errorf("unexpected token %v, leading %v", token, leading)
+		}
+		return nil
+	})
+	close(stop2)
+}
+
+func TestLeaderElectionFencing(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	allowRbac := atomic.NewBool(true)
+	client.Fake.PrependReactor("update", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if allowRbac.Load() {
+			return false, nil, nil
+		}
+		return true, nil, fmt.Errorf("nope, out of luck")
+	})
+
+	running := atomic.NewInt32(0)
+	overlap := atomic.NewBool(false)
+	release := make(chan struct{})
+	l, stop := createElection(t, "pod1", true, client, func(stop <-chan struct{}) {
+		if running.Inc() > 1 {
+			overlap.Store(true)
+		}
+		<-stop
+		// Slow shutdown of the controller, the next term must not start before it returns
+		<-release
+		running.Dec()
+	})
+	expectInt(t, running.Load, 1)
+
+	// Lose the lock, and allow to get it back
+	allowRbac.Store(false)
+	retry.UntilSuccessOrFail(t, func() error {
+		if _, leading := l.Token(); leading {
+			return fmt.Errorf("still leading")
+		}
+		return nil
+	}, retry.Timeout(time.Second*5))
+	allowRbac.Store(true)
+	time.Sleep(time.Second * 2)
+	if _, leading := l.Token(); leading || l.cycle.Load() != 1 {
+		t.Fatal("leadership acquired before the previous term stopped")
+	}
+	close(release)
+	retry.UntilSuccessOrFail(t, func() error {
+		if !l.IsLeader() {
+			return fmt.Errorf("not the leader")
+		}
+		return nil
+	}, retry.Timeout(time.Second*15))
+	if overlap.Load() {
+		t.Fatal("run functions of two terms overlapped")
+	}
+	close(stop)
+}
+
+func TestLeaderElectionFence(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	allowRbac := atomic.NewBool(true)
+	client.Fake.PrependReactor("update", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if allowRbac.Load() {
+			return false, nil, nil
+		}
+		return true, nil, fmt.Errorf("nope, out of luck")
+	})
+
+	l := NewLeaderElection("ns", "pod1", testLock, client)
+	l.ttl = time.Second
+	fences := make(chan func() bool, 2)
+	_, stop := runElection(t, l, true, func(stop <-chan struct{}) {
+		fences <- l.Fence()
+	})
+	fence := <-fences
+	if !fence() {
+		t.Fatal("expected writes of the current term to proceed")
+	}
+
+	// Lose the lock: the writes of the term are fenced, even once the lock is acquired again
+	allowRbac.Store(false)
+	retry.UntilSuccessOrFail(t, func() error {
+		if _, leading := l.Token(); leading {
+			return fmt.Errorf("still leading")
+		}
+		return nil
+	}, retry.Timeout(time.Second*5))
+	if fence() {
+		t.Fatal("writes not fenced")
+	}
+	allowRbac.Store(true)
+	next := <-fences
+	retry.UntilSuccessOrFail(t, func() error {
+		if !next() {
+			return fmt.Errorf("writes of the next term fenced")
+		}
+		return nil
+	}, retry.Timeout(time.Second*15))
+	if fence() {
+		t.Fatal("writes of the previous term not fenced")
+	}
+	close(stop)
+}
+
+func TestLeaderElectionSpread(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	newElection := func(name, electionID string) *LeaderElection {
+		l := NewLeaderElection("ns", name, electionID, client)
+		l.ttl = time.Second * 2
+		l.spread = true
+		return l
+	}
+	// pod1 leads a first controller
+	_, stop := runElection(t, newElection("spread1", "first"), true)
+	// spread1 starts campaigning first for a second controller, but defers to spread2
+	l1 := newElection("spread1", "second")
+	stop1 := make(chan struct{})
+	go l1.Run(stop1)
+	time.Sleep(time.Millisecond * 100)
+	_, stop2 := runElection(t, newElection("spread2", "second"), true)
+	if _, leading := l1.Token(); leading {
+		t.Fatal("spread1 unexpectedly leads the second controller")
+	}
+	close(stop)
+	close(stop1)
+	close(stop2)
+}
+
+func TestElections(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	newElection := func(name string) *LeaderElection {
+		l := NewLeaderElection("ns", name, "status-lock", client)
+		l.ttl = time.Second
+		return l
+	}
+	_, stop := runElection(t, newElection("pod1"), true)
+	_, stop2 := runElection(t, newElection("pod2"), false)
+	retry.UntilSuccessOrFail(t, func() error {
+		var got []Status
+		for _, s := range Elections() {
+			if s.ElectionID == "status-lock" {
+				got = append(got, s)
+			}
+		}
+		if len(got) != 2 {
+			return fmt.Errorf("unexpected elections: %v", got)
+		}
+		if got[0].Identity != "pod1" || !got[0].Leading || got[0].Leader != "pod1" || got[0].RenewTime == nil {
+			return fmt.Errorf("unexpected status of pod1: %+v", got[0])
+		}
+		if got[1].Identity != "pod2" || got[1].Leading || got[1].Leader != "pod1" || got[1].LockType != "configmaps" {
+			return fmt.Errorf("unexpected status of pod2: %+v", got[1])
+		}
+		return nil
+	})
+	close(stop)
+	close(stop2)
+}
+
 func expectInt(t *testing.T, f func() int32, expected int32) {
 	t.Helper()
 	retry.UntilSuccessOrFail(t, func() error {
@@ -0,0 +1,174 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leaderelection
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Status is the state of a leader election run by this instance, as reported by /debug/leaderz.
+type Status struct {
+	ElectionID string `json:"electionID"`
+	Namespace  string `json:"namespace"`
+	LockType   string `json:"lockType"`
+	// Identity is the identity of this instance in the election.
+	Identity string `json:"identity"`
+	// Leader is the identity of the current leader, if known.
+	Leader  string `json:"leader,omitempty"`
+	Leading bool   `json:"leading"`
+	// Token is the fencing token of the term of the current leader.
+	Token       int64      `json:"token"`
+	Cycle       int32      `json:"cycle"`
+	AcquireTime *time.Time `json:"acquireTime,omitempty"`
+	RenewTime   *time.Time `json:"renewTime,omitempty"`
+}
+
+var (
+	electionsMu sync.Mutex
+	// elections are the leader elections running in this instance.
+	elections = map[*LeaderElection]struct{}{}
+)
+
+func register(l *LeaderElection) {
+	electionsMu.Lock()
+	defer electionsMu.Unlock()
+	elections[l] = struct{}{}
+}
+
+func unregister(l *LeaderElection) {
+	electionsMu.Lock()
+	defer electionsMu.Unlock()
+	delete(elections, l)
+}
+
+// leading returns the number of elections other than l held with the same identity.
+func leading(l *LeaderElection) int {
+	electionsMu.Lock()
+	defer electionsMu.Unlock()
+	n := 0
+	for e := range elections {
+		if e == l || e.name != l.name {
+			continue
+		}
+		e.mu.Lock()
+		if e.holding {
+			n++
+		}
+		e.mu.Unlock()
+	}
+	return n
+}
+
+// Elections returns the status of the leader elections running in this instance, sorted by election ID.
+func Elections() []Status {
+	electionsMu.Lock()
+	out := make([]Status, 0, len(elections))
+	for l := range elections {
+		out = append(out, l.status())
+	}
+	electionsMu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ElectionID != out[j].ElectionID {
+			return out[i].ElectionID < out[j].ElectionID
+		}
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].Identity < out[j].Identity
+	})
+	return out
+}
+
+func (l *LeaderElection) status() Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := Status{
+		ElectionID: l.electionID,
+		Namespace:  l.namespace,
+		LockType:   l.lockType,
+		Identity:   l.name,
+		Leading:    l.leading,
+		Cycle:      l.cycle.Load(),
+	}
+	if r := l.observed; r != nil {
+		s.Leader = r.HolderIdentity
+		s.Token = int64(r.LeaderTransitions)
+		if !r.AcquireTime.IsZero() {
+			t := r.AcquireTime.Time
+			s.AcquireTime = &t
+		}
+		if !r.RenewTime.IsZero() {
+			t := r.RenewTime.Time
+			s.RenewTime = &t
+		}
+	}
+	return s
+}
+
+// errDeferred is returned by the lock while the acquisition is left to instances leading fewer elections.
+type errDeferred struct {
+	electionID string
+	leading    int
+}
+
+func (e errDeferred) Error() string {
+	return fmt.Sprintf("deferring acquisition of %s to other instances, this instance leads %d elections", e.electionID, e.leading)
+}
+
+// observedLock records the reads and writes of the lock in the election, and defers its acquisition if the
+// controllers are spread across instances.
+type observedLock struct {
+	resourcelock.Interface
+	election *LeaderElection
+}
+
+func (o *observedLock) Get(ctx context.Context) (*resourcelock.LeaderElectionRecord, []byte, error) {
+	record, raw, err := o.Interface.Get(ctx)
+	if err == nil {
+		o.election.observe(record)
+	}
+	return record, raw, err
+}
+
+func (o *observedLock) Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	return o.write(ctx, ler, o.Interface.Create)
+}
+
+func (o *observedLock) Update(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	return o.write(ctx, ler, o.Interface.Update)
+}
+
+func (o *observedLock) write(ctx context.Context, ler resourcelock.LeaderElectionRecord,
+	write func(context.Context, resourcelock.LeaderElectionRecord) error) error {
+	// Releasing the lock on exit is not an acquisition.
+	if ler.HolderIdentity != "" && o.election.acquiring() {
+		if err := o.election.deferAcquire(); err != nil {
+			return err
+		}
+	}
+	if err := write(ctx, ler); err != nil {
+		return err
+	}
+	if ler.HolderIdentity != "" {
+		o.election.written(ler)
+	}
+	return nil
+}
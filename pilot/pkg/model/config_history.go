@@ -0,0 +1,173 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/config"
+)
+
+// maxConfigHistoryDiffSize bounds the size of the spec diff stored for a single change.
+const maxConfigHistoryDiffSize = 16 * 1024
+
+// ConfigHistoryEntry records a single change to a config.
+type ConfigHistoryEntry struct {
+	// Time the change was observed by istiod.
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"`
+	// Kind is the kind of the changed config, e.g. VirtualService.
+	Kind            string `json:"kind"`
+	Group           string `json:"group,omitempty"`
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	Generation      int64  `json:"generation,omitempty"`
+	// Diff is a unified diff of the spec, in YAML, before and after the change.
+	Diff string `json:"diff,omitempty"`
+}
+
+// ConfigHistoryFilter selects entries of the config history. Empty fields match everything.
+type ConfigHistoryFilter struct {
+	// Kind is matched case insensitively.
+	Kind      string
+	Name      string
+	Namespace string
+	// Since excludes entries recorded before it.
+	Since time.Time
+	// Limit returns only the most recent entries, if positive.
+	Limit int
+}
+
+func (f ConfigHistoryFilter) matches(e *ConfigHistoryEntry) bool {
+	if f.Kind != "" && !strings.EqualFold(f.Kind, e.Kind) {
+		return false
+	}
+	if f.Name != "" && f.Name != e.Name {
+		return false
+	}
+	if f.Namespace != "" && f.Namespace != e.Namespace {
+		return false
+	}
+	return f.Since.IsZero() || !e.Time.Before(f.Since)
+}
+
+// ConfigHistory is a bounded, in memory history of config changes. Once full, the oldest entries are dropped.
+type ConfigHistory struct {
+	mu      sync.RWMutex
+	entries []*ConfigHistoryEntry
+	// next is the position of the next entry in entries, once the history is full.
+	next int
+	size int
+	// now is overridden in tests.
+	now func() time.Time
+}
+
+// NewConfigHistory returns a history keeping the last size changes. A non-positive size disables the history.
+func NewConfigHistory(size int) *ConfigHistory {
+	return &ConfigHistory{size: size, now: time.Now}
+}
+
+// Record adds a config change to the history. It can be registered directly as a config event handler.
+func (h *ConfigHistory) Record(old config.Config, curr config.Config, event Event) {
+	if h == nil || h.size <= 0 {
+		return
+	}
+	entry := &ConfigHistoryEntry{
+		Time:            h.now(),
+		Event:           event.String(),
+		Kind:            curr.GroupVersionKind.Kind,
+		Group:           curr.GroupVersionKind.Group,
+		Name:            curr.Name,
+		Namespace:       curr.Namespace,
+		ResourceVersion: curr.ResourceVersion,
+		Generation:      curr.Generation,
+	}
+	switch event {
+	case EventAdd:
+		entry.Diff = specDiff(nil, curr.Spec)
+	case EventUpdate:
+		entry.Diff = specDiff(old.Spec, curr.Spec)
+	case EventDelete:
+		entry.Diff = specDiff(curr.Spec, nil)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.entries) < h.size {
+		h.entries = append(h.entries, entry)
+		return
+	}
+	h.entries[h.next] = entry
+	h.next = (h.next + 1) % h.size
+}
+
+// List returns the entries matching the filter, oldest first.
+func (h *ConfigHistory) List(filter ConfigHistoryFilter) []ConfigHistoryEntry {
+	out := []ConfigHistoryEntry{}
+	if h == nil {
+		return out
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for i := range h.entries {
+		e := h.entries[(h.next+i)%len(h.entries)]
+		if filter.matches(e) {
+			out = append(out, *e)
+		}
+	}
+	if filter.Limit > 0 && len(out) > filter.Limit {
+		out = out[len(out)-filter.Limit:]
+	}
+	return out
+}
+
+// specDiff returns a unified diff of the YAML representation of two specs. A nil spec is treated as empty.
+func specDiff(old, curr config.Spec) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(specYAML(old)),
+		B:        difflib.SplitLines(specYAML(curr)),
+		FromFile: "old",
+		ToFile:   "new",
+		Context:  2,
+	})
+	if err != nil {
+		return ""
+	}
+	if len(diff) > maxConfigHistoryDiffSize {
+		diff = diff[:maxConfigHistoryDiffSize] + "\n... (truncated)\n"
+	}
+	return diff
+}
+
+func specYAML(spec config.Spec) string {
+	if spec == nil {
+		return ""
+	}
+	js, err := config.ToJSON(spec)
+	if err != nil {
+		return ""
+	}
+	by, err := yaml.JSONToYAML(js)
+	if err != nil {
+		return ""
+	}
+	return string(by)
+}
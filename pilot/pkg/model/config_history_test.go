@@ -0,0 +1,112 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func historyConfig(name string, hosts ...string) config.Config {
+	return config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.VirtualService,
+			Name:             name,
+			Namespace:        "default",
+			ResourceVersion:  strings.Join(hosts, ","),
+		},
+		Spec: &networking.VirtualService{Hosts: hosts},
+	}
+}
+
+func TestConfigHistory(t *testing.T) {
+	h := NewConfigHistory(3)
+	start := time.Now()
+	now := start
+	h.now = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+
+	a1 := historyConfig("a", "a.example.com")
+	a2 := historyConfig("a", "a.example.com", "b.example.com")
+	h.Record(config.Config{}, a1, EventAdd)
+	h.Record(a1, a2, EventUpdate)
+	h.Record(config.Config{}, historyConfig("b", "b.example.com"), EventAdd)
+
+	all := h.List(ConfigHistoryFilter{})
+	if len(all) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(all))
+	}
+	update := all[1]
+	if update.Event != "update" || update.Kind != "VirtualService" || update.Name != "a" || update.ResourceVersion != a2.ResourceVersion {
+		t.Errorf("unexpected entry: %+v", update)
+	}
+	if !strings.Contains(update.Diff, "+- b.example.com") || strings.Contains(update.Diff, "-- a.example.com") {
+		t.Errorf("unexpected diff:\n%s", update.Diff)
+	}
+
+	// The history is bounded, the oldest entry is dropped.
+	h.Record(a2, a2, EventDelete)
+	all = h.List(ConfigHistoryFilter{})
+	if len(all) != 3 || all[0].Event != "update" || all[2].Event != "delete" {
+		t.Fatalf("expected oldest entry to be dropped, got %+v", all)
+	}
+	if !strings.Contains(all[2].Diff, "-- a.example.com") {
+		t.Errorf("expected delete diff to remove the spec, got:\n%s", all[2].Diff)
+	}
+
+	cases := []struct {
+		name   string
+		filter ConfigHistoryFilter
+		want   []string
+	}{
+		{"by name", ConfigHistoryFilter{Name: "a"}, []string{"update", "delete"}},
+		{"by kind", ConfigHistoryFilter{Kind: "virtualservice", Name: "b"}, []string{"add"}},
+		{"other kind", ConfigHistoryFilter{Kind: "Gateway"}, []string{}},
+		{"other namespace", ConfigHistoryFilter{Namespace: "istio-system"}, []string{}},
+		{"since", ConfigHistoryFilter{Since: start.Add(3 * time.Minute)}, []string{"add", "delete"}},
+		{"limit", ConfigHistoryFilter{Limit: 1}, []string{"delete"}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, e := range h.List(tt.filter) {
+				got = append(got, e.Event)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigHistoryDisabled(t *testing.T) {
+	h := NewConfigHistory(0)
+	h.Record(config.Config{}, historyConfig("a", "a.example.com"), EventAdd)
+	if got := h.List(ConfigHistoryFilter{}); len(got) != 0 {
+		t.Errorf("expected disabled history to be empty, got %v", got)
+	}
+	var nilHistory *ConfigHistory
+	nilHistory.Record(config.Config{}, historyConfig("a", "a.example.com"), EventAdd)
+	if got := nilHistory.List(ConfigHistoryFilter{}); len(got) != 0 {
+		t.Errorf("expected nil history to be empty, got %v", got)
+	}
+}
@@ -36,6 +36,7 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/trustbundle"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/extensionprovider"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/mesh"
@@ -95,6 +96,16 @@ func (e *Environment) Mesh() *meshconfig.MeshConfig {
 	return nil
 }
 
+// ExtensionProviders returns the mesh extension providers of the extensionprovider package.
+func (e *Environment) ExtensionProviders() []*extensionprovider.ExtensionProvider {
+	if e != nil {
+		if h, ok := e.Watcher.(mesh.ExtensionProvidersHolder); ok {
+			return h.ExtensionProviders()
+		}
+	}
+	return nil
+}
+
 // GetDiscoveryAddress parses the DiscoveryAddress specified via MeshConfig.
 func (e *Environment) GetDiscoveryAddress() (host.Name, string, error) {
 	proxyConfig := mesh.DefaultProxyConfig()
@@ -169,6 +180,9 @@ func (e *Environment) SetLedger(l ledger.Ledger) {
 // Resources is an alias for array of marshaled resources.
 type Resources = []*discovery.Resource
 
+// DeletedResources is the list of the names of resources removed since the last response.
+type DeletedResources = []string
+
 func AnyToUnnamedResources(r []*any.Any) Resources {
 	a := make(Resources, 0, len(r))
 	for _, rr := range r {
@@ -208,6 +222,17 @@ type XdsResourceGenerator interface {
 	Generate(proxy *Proxy, push *PushContext, w *WatchedResource, updates *PushRequest) (Resources, XdsLogDetails, error)
 }
 
+// XdsDeltaResourceGenerator generates incremental responses for Delta XDS. Generators which do not implement it
+// always return all the resources, and the removed resources are computed by the server.
+type XdsDeltaResourceGenerator interface {
+	XdsResourceGenerator
+	// GenerateDeltas returns the resources changed by the updates, along with the names of the removed resources.
+	// If usedDelta is false, the generator could not compute the changes: all the resources are returned, and
+	// the removed resources are ignored.
+	GenerateDeltas(proxy *Proxy, push *PushContext, updates *PushRequest,
+		w *WatchedResource) (res Resources, deleted DeletedResources, logdata XdsLogDetails, usedDelta bool, err error)
+}
+
 // Proxy contains information about an specific instance of a proxy (envoy sidecar, gateway,
 // etc). The Proxy is initialized when a sidecar connects to Pilot, and populated from
 // 'node' info in the protocol as well as data extracted from registries.
@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 	"reflect"
 	"sort"
 	"strings"
@@ -102,6 +103,36 @@ type jwtPubKeyEntry struct {
 
 	// Cached item's last used time, which is set in GetPublicKey.
 	lastUsedTime time.Time
+
+	// The last time the pubKey was fetched, successfully or not.
+	lastFetchTime time.Time
+
+	// The error of the last fetch, empty if it succeeded.
+	lastError string
+}
+
+// JwksStatus is the status of the public key of an issuer.
+type JwksStatus struct {
+	Issuer  string `json:"issuer"`
+	JwksURI string `json:"jwks_uri,omitempty"`
+
+	// KeyIDs are the IDs of the keys of the public key.
+	KeyIDs []string `json:"key_ids,omitempty"`
+
+	LastRefreshedTime time.Time `json:"last_refreshed_time"`
+	LastFetchTime     time.Time `json:"last_fetch_time"`
+	LastUsedTime      time.Time `json:"last_used_time"`
+
+	// Error of the last fetch, empty if it succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// jwksCacheEntry is a public key persisted in the cache file.
+type jwksCacheEntry struct {
+	Issuer            string    `json:"issuer"`
+	JwksURI           string    `json:"jwks_uri,omitempty"`
+	PubKey            string    `json:"pub_key"`
+	LastRefreshedTime time.Time `json:"last_refreshed_time"`
 }
 
 // jwtKey is a key in the JwksResolver keyEntries map.
@@ -142,6 +173,10 @@ type JwksResolver struct {
 
 	// How many times refresh job failed to fetch the public key from network, used in unit test.
 	refreshJobFetchFailedCount uint64
+
+	// cacheFile persists the public keys, if set.
+	cacheFile  string
+	cacheMutex sync.Mutex
 }
 
 func init() {
@@ -248,15 +283,159 @@ func (r *JwksResolver) GetPublicKey(issuer string, jwksURI string) (string, erro
 		pubKey = string(resp)
 	}
 
-	r.keyEntries.Store(key, jwtPubKeyEntry{
+	e := jwtPubKeyEntry{
 		pubKey:            pubKey,
 		lastRefreshedTime: now,
 		lastUsedTime:      now,
-	})
+		lastFetchTime:     now,
+	}
+	if err != nil {
+		e.lastError = err.Error()
+	}
+	r.keyEntries.Store(key, e)
+	if err == nil {
+		r.saveCache()
+	}
 
 	return pubKey, err
 }
 
+// Status returns the status of the public keys of all the issuers, sorted by issuer.
+func (r *JwksResolver) Status() []JwksStatus {
+	status := []JwksStatus{}
+	r.keyEntries.Range(func(key interface{}, value interface{}) bool {
+		status = append(status, newJwksStatus(key.(jwtKey), value.(jwtPubKeyEntry)))
+		return true
+	})
+	sort.Slice(status, func(i, j int) bool {
+		if status[i].Issuer != status[j].Issuer {
+			return status[i].Issuer < status[j].Issuer
+		}
+		return status[i].JwksURI < status[j].JwksURI
+	})
+	return status
+}
+
+// GetStatus returns the status of the public key of an issuer, or false if the public key has not been fetched yet.
+func (r *JwksResolver) GetStatus(issuer string, jwksURI string) (JwksStatus, bool) {
+	key := jwtKey{issuer: issuer, jwksURI: jwksURI}
+	val, found := r.keyEntries.Load(key)
+	if !found {
+		return JwksStatus{}, false
+	}
+	return newJwksStatus(key, val.(jwtPubKeyEntry)), true
+}
+
+func newJwksStatus(k jwtKey, e jwtPubKeyEntry) JwksStatus {
+	return JwksStatus{
+		Issuer:            k.issuer,
+		JwksURI:           k.jwksURI,
+		KeyIDs:            jwksKeyIDs(e.pubKey),
+		LastRefreshedTime: e.lastRefreshedTime,
+		LastFetchTime:     e.lastFetchTime,
+		LastUsedTime:      e.lastUsedTime,
+		Error:             e.lastError,
+	}
+}
+
+// jwksKeyIDs returns the sorted IDs of the keys of a JWKS.
+func jwksKeyIDs(jwks string) []string {
+	var keys struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal([]byte(jwks), &keys); err != nil {
+		return nil
+	}
+	var ids []string
+	for _, k := range keys.Keys {
+		if k.Kid != "" {
+			ids = append(ids, k.Kid)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// SetCacheFile persists the public keys to path, so that they are available after a restart of istiod even if the
+// issuers can not be reached. The public keys already persisted to path are loaded, unless they were not refreshed
+// for longer than the eviction duration.
+func (r *JwksResolver) SetCacheFile(path string) error {
+	r.cacheMutex.Lock()
+	r.cacheFile = path
+	r.cacheMutex.Unlock()
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var entries []jwksCacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("invalid JWKS cache file %s: %v", path, err)
+	}
+	now := time.Now()
+	for _, e := range entries {
+		if e.PubKey == "" || now.Sub(e.LastRefreshedTime) >= r.evictionDuration {
+			continue
+		}
+		r.keyEntries.LoadOrStore(jwtKey{issuer: e.Issuer, jwksURI: e.JwksURI}, jwtPubKeyEntry{
+			pubKey:            e.PubKey,
+			lastRefreshedTime: e.LastRefreshedTime,
+			lastUsedTime:      now,
+			lastFetchTime:     e.LastRefreshedTime,
+		})
+	}
+	return nil
+}
+
+// saveCache writes the fetched public keys to the cache file, if set.
+func (r *JwksResolver) saveCache() {
+	r.cacheMutex.Lock()
+	defer r.cacheMutex.Unlock()
+	if r.cacheFile == "" {
+		return
+	}
+
+	entries := []jwksCacheEntry{}
+	r.keyEntries.Range(func(key interface{}, value interface{}) bool {
+		k := key.(jwtKey)
+		e := value.(jwtPubKeyEntry)
+		if e.pubKey != "" {
+			entries = append(entries, jwksCacheEntry{
+				Issuer:            k.issuer,
+				JwksURI:           k.jwksURI,
+				PubKey:            e.pubKey,
+				LastRefreshedTime: e.lastRefreshedTime,
+			})
+		}
+		return true
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Issuer != entries[j].Issuer {
+			return entries[i].Issuer < entries[j].Issuer
+		}
+		return entries[i].JwksURI < entries[j].JwksURI
+	})
+	data, err := json.Marshal(entries)
+	if err != nil {
+		log.Warnf("Failed to marshal the JWKS cache: %v", err)
+		return
+	}
+	// Write to a temporary file first, so that the cache file is never partially written.
+	tmp := r.cacheFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0o600); err != nil {
+		log.Warnf("Failed to write the JWKS cache to %s: %v", tmp, err)
+		return
+	}
+	if err := os.Rename(tmp, r.cacheFile); err != nil {
+		log.Warnf("Failed to write the JWKS cache to %s: %v", r.cacheFile, err)
+	}
+}
+
 // BuildLocalJwks builds local Jwks by fetching the Jwt Public Key from the URL passed if it is empty.
 func (r *JwksResolver) BuildLocalJwks(jwksURI, jwtIssuer, jwtPubKey string) *envoy_jwt.JwtProvider_LocalJwks {
 	if jwtPubKey == "" {
@@ -437,6 +616,7 @@ func (r *JwksResolver) refresh() bool {
 					hasErrors = true
 					log.Errorf("Failed to resolve Jwks from issuer %q: %v", k.issuer, err)
 					atomic.AddUint64(&r.refreshJobFetchFailedCount, 1)
+					r.recordFetchFailure(k, e, now, err)
 					return
 				}
 			}
@@ -446,6 +626,7 @@ func (r *JwksResolver) refresh() bool {
 				hasErrors = true
 				log.Errorf("Failed to refresh JWT public key from %q: %v", jwksURI, err)
 				atomic.AddUint64(&r.refreshJobFetchFailedCount, 1)
+				r.recordFetchFailure(k, e, now, err)
 				return
 			}
 			newPubKey := string(resp)
@@ -453,6 +634,7 @@ func (r *JwksResolver) refresh() bool {
 				pubKey:            newPubKey,
 				lastRefreshedTime: now,            // update the lastRefreshedTime if we get a success response from the network.
 				lastUsedTime:      e.lastUsedTime, // keep original lastUsedTime.
+				lastFetchTime:     now,
 			})
 			isNewKey, err := compareJWKSResponse(oldPubKey, newPubKey)
 			if err != nil {
@@ -471,6 +653,7 @@ func (r *JwksResolver) refresh() bool {
 
 	// Wait for all go routine to complete.
 	wg.Wait()
+	r.saveCache()
 
 	if hasChange {
 		atomic.AddUint64(&r.refreshJobKeyChangedCount, 1)
@@ -482,6 +665,13 @@ func (r *JwksResolver) refresh() bool {
 	return hasErrors
 }
 
+// recordFetchFailure keeps the previous public key of an entry, recording the error of the last fetch.
+func (r *JwksResolver) recordFetchFailure(k jwtKey, e jwtPubKeyEntry, now time.Time, err error) {
+	e.lastFetchTime = now
+	e.lastError = err.Error()
+	r.keyEntries.Store(k, e)
+}
+
 // Close will shut down the refresher job.
 // TODO: may need to figure out the right place to call this function.
 // (right now calls it from initDiscoveryService in pkg/bootstrap/server.go).
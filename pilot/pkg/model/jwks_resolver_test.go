@@ -16,6 +16,8 @@ package model
 
 import (
 	"fmt"
+	"path/filepath"
+	"reflect"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -116,6 +118,63 @@ func TestGetPublicKey(t *testing.T) {
 	}
 }
 
+func TestJwksCacheFile(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "jwks.json")
+	ms := startMockServer(t)
+	mockCertURL := ms.URL + "/oauth2/v3/certs"
+
+	r := NewJwksResolver(JwtPubKeyEvictionDuration, JwtPubKeyRefreshInterval, JwtPubKeyRefreshIntervalOnFailure, testRetryInterval)
+	defer r.Close()
+	if err := r.SetCacheFile(cacheFile); err != nil {
+		t.Fatalf("SetCacheFile() with a missing file fails: %v", err)
+	}
+	if _, err := r.GetPublicKey("testIssuer", mockCertURL); err != nil {
+		t.Fatalf("GetPublicKey() fails: %v", err)
+	}
+	// The issuer is unavailable when istiod restarts.
+	_ = ms.Stop()
+
+	restarted := NewJwksResolver(JwtPubKeyEvictionDuration, JwtPubKeyRefreshInterval, JwtPubKeyRefreshIntervalOnFailure, testRetryInterval)
+	defer restarted.Close()
+	if err := restarted.SetCacheFile(cacheFile); err != nil {
+		t.Fatalf("SetCacheFile() fails: %v", err)
+	}
+	pk, err := restarted.GetPublicKey("testIssuer", mockCertURL)
+	if err != nil {
+		t.Fatalf("GetPublicKey() fails after restart: %v", err)
+	}
+	if pk != test.JwtPubKey1 {
+		t.Errorf("GetPublicKey() after restart: expected (%s), got (%s)", test.JwtPubKey1, pk)
+	}
+}
+
+func TestJwksStatus(t *testing.T) {
+	r := NewJwksResolver(JwtPubKeyEvictionDuration, JwtPubKeyRefreshInterval, JwtPubKeyRefreshIntervalOnFailure, testRetryInterval)
+	defer r.Close()
+	ms := startMockServer(t)
+	defer ms.Stop()
+
+	mockCertURL := ms.URL + "/oauth2/v3/certs"
+	invalidURL := ms.URL + "/invalid"
+	_, _ = r.GetPublicKey("b-issuer", mockCertURL)
+	_, _ = r.GetPublicKey("a-issuer", invalidURL)
+
+	status := r.Status()
+	if len(status) != 2 {
+		t.Fatalf("expected the status of 2 issuers, got %v", status)
+	}
+	if status[0].Issuer != "a-issuer" || status[0].Error == "" || len(status[0].KeyIDs) != 0 {
+		t.Errorf("expected a fetch error for a-issuer, got %+v", status[0])
+	}
+	if status[1].Issuer != "b-issuer" || status[1].Error != "" || status[1].LastFetchTime.IsZero() ||
+		!reflect.DeepEqual(status[1].KeyIDs, []string{"fakeKey1_1", "fakeKey1_2"}) {
+		t.Errorf("expected the keys of b-issuer, got %+v", status[1])
+	}
+	if _, found := r.GetStatus("c-issuer", mockCertURL); found {
+		t.Errorf("expected no status for an issuer not fetched yet")
+	}
+}
+
 func TestGetPublicKeyReorderedKey(t *testing.T) {
 	r := NewJwksResolver(JwtPubKeyEvictionDuration, testRetryInterval*20, testRetryInterval*10, testRetryInterval)
 	defer r.Close()
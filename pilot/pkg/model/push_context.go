@@ -31,9 +31,11 @@ import (
 	"istio.io/istio/pilot/pkg/util/sets"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/extensionprovider"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/ratelimit"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/config/visibility"
 	"istio.io/pkg/monitoring"
@@ -92,6 +94,8 @@ type virtualServiceIndex struct {
 	publicByGateway map[string][]config.Config
 	// root vs namespace/name ->delegate vs virtualservice gvk/namespace/name
 	delegates map[ConfigKey][]ConfigKey
+	// root vs gvk/namespace/name -> rate limit policies set on the virtual service
+	rateLimitPolicies map[ConfigKey][]*ratelimit.Policy
 }
 
 func newVirtualServiceIndex() virtualServiceIndex {
@@ -100,6 +104,7 @@ func newVirtualServiceIndex() virtualServiceIndex {
 		privateByNamespaceAndGateway: map[string]map[string][]config.Config{},
 		exportedToNamespaceByGateway: map[string]map[string][]config.Config{},
 		delegates:                    map[ConfigKey][]ConfigKey{},
+		rateLimitPolicies:            map[ConfigKey][]*ratelimit.Policy{},
 	}
 }
 
@@ -169,6 +174,12 @@ type PushContext struct {
 	// clusterLocalHosts extracted from the MeshConfig
 	clusterLocalHosts ClusterLocalHosts
 
+	// envoyRateLimitProviders are the Envoy rate limit extension providers of the mesh, by name
+	envoyRateLimitProviders map[string]*EnvoyRateLimitProvider
+
+	// openTelemetryProviders are the OpenTelemetry extension providers of the mesh, by name
+	openTelemetryProviders map[string]*extensionprovider.OpenTelemetryProvider
+
 	// sidecars for each namespace
 	sidecarsByNamespace map[string][]*SidecarScope
 
@@ -945,6 +956,7 @@ func (ps *PushContext) InitContext(env *Environment, oldPushContext *PushContext
 
 	ps.Mesh = env.Mesh()
 	ps.LedgerVersion = env.Version()
+	ps.initExtensionProviders(env)
 
 	// Must be initialized first
 	// as initServiceRegistry/VirtualServices/Destrules
@@ -1266,6 +1278,19 @@ func (ps *PushContext) initVirtualServices(env *Environment) error {
 
 	vservices, ps.virtualServiceIndex.delegates = mergeVirtualServicesIfNeeded(vservices, ps.exportToDefaults.virtualService)
 
+	ps.virtualServiceIndex.rateLimitPolicies = map[ConfigKey][]*ratelimit.Policy{}
+	for _, virtualService := range vservices {
+		policies, err := ratelimit.Parse(virtualService.Annotations)
+		if err != nil {
+			log.Warnf("ignoring rate limit policies of virtual service %s/%s: %v", virtualService.Namespace, virtualService.Name, err)
+			continue
+		}
+		if len(policies) > 0 {
+			key := ConfigKey{Kind: gvk.VirtualService, Namespace: virtualService.Namespace, Name: virtualService.Name}
+			ps.virtualServiceIndex.rateLimitPolicies[key] = policies
+		}
+	}
+
 	for _, virtualService := range vservices {
 		ns := virtualService.Namespace
 		rule := virtualService.Spec.(*networking.VirtualService)
@@ -1873,6 +1898,29 @@ func instancesEmpty(m map[int][]*ServiceInstance) bool {
 }
 
 // pre computes gateways for each network
+// initExtensionProviders indexes the extension providers of the extensionprovider package.
+func (ps *PushContext) initExtensionProviders(env *Environment) {
+	ps.envoyRateLimitProviders = map[string]*EnvoyRateLimitProvider{}
+	ps.openTelemetryProviders = map[string]*extensionprovider.OpenTelemetryProvider{}
+	for _, p := range env.ExtensionProviders() {
+		switch {
+		case p.EnvoyRateLimit != nil:
+			ps.envoyRateLimitProviders[p.Name] = &EnvoyRateLimitProvider{
+				EnvoyRateLimitProvider: p.EnvoyRateLimit,
+				Name:                   p.Name,
+				Stage:                  uint32(len(ps.envoyRateLimitProviders)),
+			}
+		case p.OpenTelemetry != nil:
+			ps.openTelemetryProviders[p.Name] = p.OpenTelemetry
+		}
+	}
+}
+
+// OpenTelemetryProvider returns the OpenTelemetry extension provider with the given name, or nil if there is none.
+func (ps *PushContext) OpenTelemetryProvider(name string) *extensionprovider.OpenTelemetryProvider {
+	return ps.openTelemetryProviders[name]
+}
+
 func (ps *PushContext) initMeshNetworks(env *Environment) {
 	ps.networkGateways = newNetworkGateways(env)
 }
@@ -0,0 +1,102 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/extensionprovider"
+	"istio.io/istio/pkg/config/ratelimit"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+// EnvoyRateLimitProvider is an envoyRateLimit extension provider of the mesh config.
+type EnvoyRateLimitProvider struct {
+	*extensionprovider.EnvoyRateLimitProvider
+
+	// Name of the provider.
+	Name string
+
+	// Stage of the rate limit filter sending the requests to the provider, and of the route rate limits applied by
+	// this filter. Envoy supports one rate limit service and domain per filter, each provider is assigned its own stage
+	// following the order of the extension providers.
+	Stage uint32
+}
+
+// EnvoyRateLimitProvider returns the envoyRateLimit extension provider with the given name, or nil if there is none.
+func (ps *PushContext) EnvoyRateLimitProvider(name string) *EnvoyRateLimitProvider {
+	return ps.envoyRateLimitProviders[name]
+}
+
+// RateLimitPolicies returns the rate limit policies of the virtual service applying to the proxy.
+func (ps *PushContext) RateLimitPolicies(proxy *Proxy, vs config.Config) []*ratelimit.Policy {
+	policies := ps.virtualServiceIndex.rateLimitPolicies[ConfigKey{Kind: gvk.VirtualService, Namespace: vs.Namespace, Name: vs.Name}]
+	if len(policies) == 0 {
+		return nil
+	}
+	out := make([]*ratelimit.Policy, 0, len(policies))
+	for _, p := range policies {
+		if p.Selects(proxy.Metadata.Labels) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// RateLimitPoliciesForProxy returns the rate limit policies applying to the proxy, from all the virtual services
+// used by the proxy. Policies are ordered by the creation time of their virtual service.
+func (ps *PushContext) RateLimitPoliciesForProxy(proxy *Proxy) []*ratelimit.Policy {
+	if len(ps.virtualServiceIndex.rateLimitPolicies) == 0 {
+		return nil
+	}
+	var vses []config.Config
+	switch proxy.Type {
+	case Router:
+		if proxy.MergedGateway == nil {
+			return nil
+		}
+		gateways := map[string]struct{}{}
+		for _, gw := range proxy.MergedGateway.GatewayNameForServer {
+			gateways[gw] = struct{}{}
+		}
+		for gw := range gateways {
+			vses = append(vses, ps.VirtualServicesForGateway(proxy, gw)...)
+		}
+	case SidecarProxy:
+		if proxy.SidecarScope == nil {
+			return nil
+		}
+		for _, l := range proxy.SidecarScope.EgressListeners {
+			vses = append(vses, l.VirtualServices()...)
+		}
+	}
+
+	seen := map[ConfigKey]struct{}{}
+	unique := make([]config.Config, 0, len(vses))
+	for _, vs := range vses {
+		key := ConfigKey{Kind: gvk.VirtualService, Namespace: vs.Namespace, Name: vs.Name}
+		if _, f := seen[key]; f {
+			continue
+		}
+		seen[key] = struct{}{}
+		unique = append(unique, vs)
+	}
+	sortConfigByCreationTime(unique)
+
+	var out []*ratelimit.Policy
+	for _, vs := range unique {
+		out = append(out, ps.RateLimitPolicies(proxy, vs)...)
+	}
+	return out
+}
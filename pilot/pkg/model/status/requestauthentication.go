@@ -0,0 +1,28 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+const (
+	// ConditionJwksResolved defines a status field to declare if the public keys of the JWT issuers of a
+	// RequestAuthentication were fetched by istiod.
+	ConditionJwksResolved = "JwksResolved"
+
+	// ReasonJwksFetched is the reason of the JwksResolved condition when all the public keys were fetched.
+	ReasonJwksFetched = "Fetched"
+
+	// ReasonJwksFetchFailed is the reason of the JwksResolved condition when some public keys could not be fetched.
+	// Requests with a JWT of these issuers are rejected until the public keys can be fetched.
+	ReasonJwksFetchFailed = "FetchFailed"
+)
@@ -18,6 +18,7 @@ import (
 	tpb "istio.io/api/telemetry/v1alpha1"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/schema/collections"
+	telemetrycfg "istio.io/istio/pkg/config/telemetry"
 	istiolog "istio.io/pkg/log"
 )
 
@@ -28,6 +29,12 @@ type Telemetry struct {
 	Name      string         `json:"name"`
 	Namespace string         `json:"namespace"`
 	Spec      *tpb.Telemetry `json:"spec"`
+
+	// AccessLogging is set from the access logging annotation of the resource.
+	AccessLogging *telemetrycfg.AccessLogging `json:"access_logging,omitempty"`
+
+	// Metrics is set from the metrics annotation of the resource.
+	Metrics *telemetrycfg.Metrics `json:"metrics,omitempty"`
 }
 
 // Telemetries organizes Telemetry configuration by namespace.
@@ -57,6 +64,16 @@ func GetTelemetries(env *Environment) (*Telemetries, error) {
 			Namespace: config.Namespace,
 			Spec:      config.Spec.(*tpb.Telemetry),
 		}
+		al, err := telemetrycfg.ParseAccessLogging(config.Annotations)
+		if err != nil {
+			telemetryLog.Warnf("ignoring access logging of Telemetry %s/%s: %v", config.Namespace, config.Name, err)
+		}
+		telemetry.AccessLogging = al
+		metrics, err := telemetrycfg.ParseMetrics(config.Annotations)
+		if err != nil {
+			telemetryLog.Warnf("ignoring metrics of Telemetry %s/%s: %v", config.Namespace, config.Name, err)
+		}
+		telemetry.Metrics = metrics
 		telemetries.NamespaceToTelemetries[config.Namespace] =
 			append(telemetries.NamespaceToTelemetries[config.Namespace], telemetry)
 	}
@@ -70,35 +87,75 @@ func (t *Telemetries) EffectiveTelemetry(namespace string, workload labels.Colle
 	}
 
 	var effectiveSpec *tpb.Telemetry
+	for _, telemetry := range t.applicableTelemetries(namespace, workload) {
+		effectiveSpec = shallowMerge(effectiveSpec, telemetry.Spec)
+	}
+	return effectiveSpec
+}
+
+// EffectiveAccessLogging returns the access logging of the workload, or nil if no Telemetry sets it.
+func (t *Telemetries) EffectiveAccessLogging(namespace string, workload labels.Collection) *telemetrycfg.AccessLogging {
+	if t == nil {
+		return nil
+	}
+
+	var effective *telemetrycfg.AccessLogging
+	for _, tel := range t.applicableTelemetries(namespace, workload) {
+		effective = telemetrycfg.MergeAccessLogging(effective, tel.AccessLogging)
+	}
+	return effective
+}
+
+// EffectiveMetrics returns the metrics overrides of the workload, or nil if no Telemetry sets them.
+func (t *Telemetries) EffectiveMetrics(namespace string, workload labels.Collection) *telemetrycfg.Metrics {
+	if t == nil {
+		return nil
+	}
+
+	var effective *telemetrycfg.Metrics
+	for _, tel := range t.applicableTelemetries(namespace, workload) {
+		effective = telemetrycfg.MergeMetrics(effective, tel.Metrics)
+	}
+	return effective
+}
+
+// applicableTelemetries returns the Telemetries applying to the workload, from the least to the most specific: the
+// one of the root namespace, the one of the namespace, and the first one selecting the workload.
+func (t *Telemetries) applicableTelemetries(namespace string, workload labels.Collection) []*Telemetry {
+	var applicable []*Telemetry
 	if t.RootNamespace != "" {
-		effectiveSpec = t.namespaceWideTelemetry(t.RootNamespace)
+		if tel := t.namespaceWideTelemetry(t.RootNamespace); tel != nil {
+			applicable = append(applicable, tel)
+		}
 	}
 
 	if namespace != t.RootNamespace {
-		nsSpec := t.namespaceWideTelemetry(namespace)
-		effectiveSpec = shallowMerge(effectiveSpec, nsSpec)
+		if tel := t.namespaceWideTelemetry(namespace); tel != nil {
+			applicable = append(applicable, tel)
+		}
 	}
 
-	for _, telemetry := range t.NamespaceToTelemetries[namespace] {
-		spec := telemetry.Spec
+	telemetries := t.NamespaceToTelemetries[namespace]
+	for i := range telemetries {
+		spec := telemetries[i].Spec
 		if len(spec.GetSelector().GetMatchLabels()) == 0 {
 			continue
 		}
 		selector := labels.Instance(spec.GetSelector().GetMatchLabels())
 		if workload.IsSupersetOf(selector) {
-			effectiveSpec = shallowMerge(effectiveSpec, spec)
+			applicable = append(applicable, &telemetries[i])
 			break
 		}
 	}
 
-	return effectiveSpec
+	return applicable
 }
 
-func (t *Telemetries) namespaceWideTelemetry(namespace string) *tpb.Telemetry {
-	for _, tel := range t.NamespaceToTelemetries[namespace] {
-		spec := tel.Spec
-		if len(spec.GetSelector().GetMatchLabels()) == 0 {
-			return spec
+func (t *Telemetries) namespaceWideTelemetry(namespace string) *Telemetry {
+	telemetries := t.NamespaceToTelemetries[namespace]
+	for i := range telemetries {
+		if len(telemetries[i].Spec.GetSelector().GetMatchLabels()) == 0 {
+			return &telemetries[i]
 		}
 	}
 	return nil
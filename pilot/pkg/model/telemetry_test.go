@@ -29,6 +29,7 @@ import (
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/config/schema/collections"
+	telemetrycfg "istio.io/istio/pkg/config/telemetry"
 )
 
 func TestTelemetries_EffectiveTelemetry(t *testing.T) {
@@ -231,6 +232,188 @@ func TestTelemetries_EffectiveTelemetry(t *testing.T) {
 	}
 }
 
+func TestTelemetries_EffectiveAccessLogging(t *testing.T) {
+	withAccessLogging := func(cfg config.Config, accessLogging string) config.Config {
+		cfg.Annotations = map[string]string{telemetrycfg.AccessLoggingAnnotation: accessLogging}
+		return cfg
+	}
+	fooSelector := &tpb.Telemetry{
+		Selector: &v1beta1.WorkloadSelector{
+			MatchLabels: map[string]string{"app": "foo"},
+		},
+	}
+	disabled := true
+	enabled := false
+
+	cases := []struct {
+		name           string
+		configs        []config.Config
+		ns             string
+		workloadLabels map[string]string
+		want           *telemetrycfg.AccessLogging
+	}{
+		{
+			name: "no access logging",
+			ns:   "foo",
+			configs: []config.Config{
+				newTelemetry("root", "istio-system", &tpb.Telemetry{}),
+			},
+		},
+		{
+			name: "root namespace",
+			ns:   "foo",
+			configs: []config.Config{
+				withAccessLogging(newTelemetry("root", "istio-system", &tpb.Telemetry{}), `providers: [{name: envoy}]`),
+			},
+			want: &telemetrycfg.AccessLogging{Providers: []telemetrycfg.ProviderRef{{Name: "envoy"}}},
+		},
+		{
+			name: "namespace filter",
+			ns:   "foo",
+			configs: []config.Config{
+				withAccessLogging(newTelemetry("root", "istio-system", &tpb.Telemetry{}), `providers: [{name: envoy}]`),
+				withAccessLogging(newTelemetry("foo", "foo", &tpb.Telemetry{}), `filter: {expression: "response.code >= 500"}`),
+			},
+			want: &telemetrycfg.AccessLogging{
+				Providers: []telemetrycfg.ProviderRef{{Name: "envoy"}},
+				Filter:    &telemetrycfg.AccessLogFilter{Expression: "response.code >= 500"},
+			},
+		},
+		{
+			name:           "workload disabled",
+			ns:             "foo",
+			workloadLabels: map[string]string{"app": "foo"},
+			configs: []config.Config{
+				withAccessLogging(newTelemetry("root", "istio-system", &tpb.Telemetry{}), `providers: [{name: envoy}]`),
+				withAccessLogging(newTelemetry("foo", "foo", fooSelector), `disabled: true`),
+			},
+			want: &telemetrycfg.AccessLogging{
+				Providers: []telemetrycfg.ProviderRef{{Name: "envoy"}},
+				Disabled:  &disabled,
+			},
+		},
+		{
+			name:           "workload re-enabled",
+			ns:             "foo",
+			workloadLabels: map[string]string{"app": "foo"},
+			configs: []config.Config{
+				withAccessLogging(newTelemetry("ns", "foo", &tpb.Telemetry{}), `disabled: true`),
+				withAccessLogging(newTelemetry("foo", "foo", fooSelector), `disabled: false`),
+			},
+			want: &telemetrycfg.AccessLogging{Disabled: &enabled},
+		},
+		{
+			name:           "other workload",
+			ns:             "foo",
+			workloadLabels: map[string]string{"app": "bar"},
+			configs: []config.Config{
+				withAccessLogging(newTelemetry("foo", "foo", fooSelector), `disabled: true`),
+			},
+		},
+		{
+			name: "invalid annotation ignored",
+			ns:   "foo",
+			configs: []config.Config{
+				withAccessLogging(newTelemetry("root", "istio-system", &tpb.Telemetry{}), `providers: envoy`),
+			},
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(tt *testing.T) {
+			telemetries := createTestTelemetries(v.configs, tt)
+			got := telemetries.EffectiveAccessLogging(v.ns, []labels.Instance{v.workloadLabels})
+			if diff := cmp.Diff(v.want, got); diff != "" {
+				tt.Errorf("EffectiveAccessLogging(%s, %v) returned unexpected diff (-want +got):\n%s", v.ns, v.workloadLabels, diff)
+			}
+		})
+	}
+}
+
+func TestTelemetries_EffectiveMetrics(t *testing.T) {
+	withMetrics := func(cfg config.Config, metrics string) config.Config {
+		cfg.Annotations = map[string]string{telemetrycfg.MetricsAnnotation: metrics}
+		return cfg
+	}
+	fooSelector := &tpb.Telemetry{
+		Selector: &v1beta1.WorkloadSelector{
+			MatchLabels: map[string]string{"app": "foo"},
+		},
+	}
+	disabled := true
+	hostOverride := telemetrycfg.MetricsOverride{
+		Match:        &telemetrycfg.MetricSelector{Metric: "REQUEST_COUNT"},
+		TagOverrides: map[string]telemetrycfg.TagOverride{"request_host": {Value: "request.host"}},
+	}
+	disableSizes := telemetrycfg.MetricsOverride{
+		Match:    &telemetrycfg.MetricSelector{Metric: "REQUEST_SIZE", Mode: "CLIENT"},
+		Disabled: &disabled,
+	}
+
+	cases := []struct {
+		name           string
+		configs        []config.Config
+		ns             string
+		workloadLabels map[string]string
+		want           *telemetrycfg.Metrics
+	}{
+		{
+			name: "no metrics",
+			ns:   "foo",
+			configs: []config.Config{
+				newTelemetry("root", "istio-system", &tpb.Telemetry{}),
+			},
+		},
+		{
+			name: "root namespace",
+			ns:   "foo",
+			configs: []config.Config{
+				withMetrics(newTelemetry("root", "istio-system", &tpb.Telemetry{}),
+					`overrides: [{match: {metric: REQUEST_COUNT}, tagOverrides: {request_host: {value: request.host}}}]`),
+			},
+			want: &telemetrycfg.Metrics{Overrides: []telemetrycfg.MetricsOverride{hostOverride}},
+		},
+		{
+			name:           "workload overrides appended",
+			ns:             "foo",
+			workloadLabels: map[string]string{"app": "foo"},
+			configs: []config.Config{
+				withMetrics(newTelemetry("root", "istio-system", &tpb.Telemetry{}),
+					`overrides: [{match: {metric: REQUEST_COUNT}, tagOverrides: {request_host: {value: request.host}}}]`),
+				withMetrics(newTelemetry("foo", "foo", fooSelector),
+					`overrides: [{match: {metric: REQUEST_SIZE, mode: CLIENT}, disabled: true}]`),
+			},
+			want: &telemetrycfg.Metrics{Overrides: []telemetrycfg.MetricsOverride{hostOverride, disableSizes}},
+		},
+		{
+			name:           "other workload",
+			ns:             "foo",
+			workloadLabels: map[string]string{"app": "bar"},
+			configs: []config.Config{
+				withMetrics(newTelemetry("foo", "foo", fooSelector),
+					`overrides: [{match: {metric: REQUEST_SIZE, mode: CLIENT}, disabled: true}]`),
+			},
+		},
+		{
+			name: "invalid annotation ignored",
+			ns:   "foo",
+			configs: []config.Config{
+				withMetrics(newTelemetry("root", "istio-system", &tpb.Telemetry{}), `overrides: REQUEST_COUNT`),
+			},
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(tt *testing.T) {
+			telemetries := createTestTelemetries(v.configs, tt)
+			got := telemetries.EffectiveMetrics(v.ns, []labels.Instance{v.workloadLabels})
+			if diff := cmp.Diff(v.want, got); diff != "" {
+				tt.Errorf("EffectiveMetrics(%s, %v) returned unexpected diff (-want +got):\n%s", v.ns, v.workloadLabels, diff)
+			}
+		})
+	}
+}
+
 func createTestTelemetries(configs []config.Config, t *testing.T) *Telemetries {
 	t.Helper()
 
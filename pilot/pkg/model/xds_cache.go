@@ -16,6 +16,9 @@ package model
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,9 +27,11 @@ import (
 	"github.com/hashicorp/golang-lru/simplelru"
 	"go.uber.org/atomic"
 	"google.golang.org/protobuf/testing/protocmp"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/util/sets"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config"
 	"istio.io/pkg/monitoring"
 )
@@ -35,6 +40,7 @@ func init() {
 	monitoring.MustRegister(xdsCacheReads)
 	monitoring.MustRegister(xdsCacheEvictions)
 	monitoring.MustRegister(xdsCacheSize)
+	monitoring.MustRegister(xdsCacheBytes)
 }
 
 var (
@@ -54,6 +60,12 @@ var (
 		"Current size of xds cache",
 	)
 
+	xdsCacheBytes = monitoring.NewGauge(
+		"xds_cache_bytes",
+		"Current size in bytes of the serialized resources of the xds cache, by xDS type.",
+		monitoring.WithLabels(typeTag),
+	)
+
 	xdsCacheHits   = xdsCacheReads.With(typeTag.Value("hit"))
 	xdsCacheMisses = xdsCacheReads.With(typeTag.Value("miss"))
 )
@@ -82,6 +94,12 @@ func size(cs int) {
 	}
 }
 
+func sizeBytes(typeURL string, bytes int) {
+	if features.EnableXDSCacheMetrics {
+		xdsCacheBytes.With(typeTag.Value(v3.GetShortType(typeURL))).Record(float64(bytes))
+	}
+}
+
 func indexConfig(configIndex map[ConfigKey]sets.Set, k string, entry XdsCacheEntry) {
 	for _, config := range entry.DependentConfigs() {
 		if configIndex[config] == nil {
@@ -115,6 +133,9 @@ type XdsCacheEntry interface {
 	// Cacheable indicates whether this entry is valid for cache. For example
 	// for EDS to be cacheable, the Endpoint should have corresponding service.
 	Cacheable() bool
+	// TypeURL is the xDS type of the cached resource. The entries of each type are stored and bounded
+	// separately.
+	TypeURL() string
 }
 
 type CacheToken uint64
@@ -147,55 +168,284 @@ type XdsCache interface {
 	Keys() []string
 	// Snapshot returns a snapshot of all keys and values. This is for testing/debug only
 	Snapshot() map[string]*discovery.Resource
+	// Stats returns the statistics of the cache of each xDS type, sorted by type. This is for debug only
+	Stats() []XdsCacheStats
+}
+
+// XdsCacheStats are the statistics of the cache of an xDS type.
+type XdsCacheStats struct {
+	Type       string `json:"type"`
+	Entries    int    `json:"entries"`
+	Bytes      int    `json:"bytes"`
+	MaxEntries int    `json:"maxEntries"`
+	MaxBytes   int    `json:"maxBytes,omitempty"`
+	Hits       uint64 `json:"hits"`
+	Misses     uint64 `json:"misses"`
+	Evictions  uint64 `json:"evictions"`
 }
 
 // NewXdsCache returns an instance of a cache.
 func NewXdsCache() XdsCache {
-	return &lruCache{
-		enableAssertions: features.EnableUnsafeAssertions,
-		store:            newLru(),
-		configIndex:      map[ConfigKey]sets.Set{},
-		typesIndex:       map[config.GroupVersionKind]sets.Set{},
-		nextToken:        atomic.NewUint64(0),
-	}
+	return newShardedCache(features.EnableUnsafeAssertions)
 }
 
 // NewLenientXdsCache returns an instance of a cache that does not validate token based get/set and enable assertions.
 func NewLenientXdsCache() XdsCache {
-	return &lruCache{
-		enableAssertions: false,
-		store:            newLru(),
-		configIndex:      map[ConfigKey]sets.Set{},
-		typesIndex:       map[config.GroupVersionKind]sets.Set{},
+	return newShardedCache(false)
+}
+
+// shardedCache holds a cache of each xDS type, so the types are bounded separately and the writers of different
+// types do not contend on the same lock.
+type shardedCache struct {
+	enableAssertions bool
+	// maxEntries bounds the number of entries of all the shards.
+	maxEntries    int
+	defaultLimits cacheLimits
+	// limits holds the limits of the types overriding the default limits, by short type (EDS, SDS...).
+	limits map[string]cacheLimits
+	// nextToken stores the next token to use. The content here doesn't matter, we just need a cheap
+	// unique identifier. It is shared by the shards, so a token is never reused across types.
+	nextToken *atomic.Uint64
+	// entries is the number of entries of all the shards.
+	entries *atomic.Int64
+
+	mu     sync.RWMutex
+	shards map[string]*lruCache
+}
+
+var _ XdsCache = &shardedCache{}
+
+// cacheLimits bound the cache of an xDS type.
+type cacheLimits struct {
+	// entries is the maximum number of entries.
+	entries int
+	// bytes is the maximum size of the serialized resources, or 0 if the size is not bounded.
+	bytes int
+}
+
+func newShardedCache(enableAssertions bool) *shardedCache {
+	defaults := cacheLimits{entries: features.XDSCacheMaxSize, bytes: features.XDSCacheMaxBytes}
+	if defaults.entries <= 0 {
+		defaults.entries = 20000
+	}
+	limits, err := parseCacheLimits(features.XDSCacheTypeLimits, defaults)
+	if err != nil {
+		log.Errorf("ignoring invalid PILOT_XDS_CACHE_TYPE_LIMITS: %v", err)
+	}
+	return &shardedCache{
+		enableAssertions: enableAssertions,
+		maxEntries:       defaults.entries,
+		defaultLimits:    defaults,
+		limits:           limits,
 		nextToken:        atomic.NewUint64(0),
+		entries:          atomic.NewInt64(0),
+		shards:           map[string]*lruCache{},
+	}
+}
+
+// parseCacheLimits parses the limits of the types, as a comma separated list of <type>=<entries>[/<bytes>], for
+// example EDS=10000/256Mi,SDS=1000. The entries or the bytes may be omitted to use the default.
+func parseCacheLimits(s string, defaults cacheLimits) (map[string]cacheLimits, error) {
+	limits := map[string]cacheLimits{}
+	if s == "" {
+		return limits, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid limit %q, expected <type>=<entries>[/<bytes>]", item)
+		}
+		l := defaults
+		parts := strings.SplitN(kv[1], "/", 2)
+		if parts[0] != "" {
+			n, err := strconv.Atoi(parts[0])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid number of entries %q for %s", parts[0], kv[0])
+			}
+			l.entries = n
+		}
+		if len(parts) == 2 {
+			q, err := resource.ParseQuantity(parts[1])
+			if err != nil || q.Sign() < 0 {
+				return nil, fmt.Errorf("invalid size %q for %s", parts[1], kv[0])
+			}
+			l.bytes = int(q.Value())
+		}
+		limits[strings.ToUpper(kv[0])] = l
+	}
+	return limits, nil
+}
+
+// shard returns the cache of an xDS type, creating it on first use.
+func (s *shardedCache) shard(typeURL string) *lruCache {
+	s.mu.RLock()
+	l := s.shards[typeURL]
+	s.mu.RUnlock()
+	if l != nil {
+		return l
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l = s.shards[typeURL]; l != nil {
+		return l
+	}
+	limits, f := s.limits[v3.GetShortType(typeURL)]
+	if !f {
+		limits = s.defaultLimits
+	}
+	l = newLruCache(typeURL, limits, s.enableAssertions, s.nextToken, s.entries)
+	s.shards[typeURL] = l
+	return l
+}
+
+// allShards returns the shards, sorted by type.
+func (s *shardedCache) allShards() []*lruCache {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	shards := make([]*lruCache, 0, len(s.shards))
+	for _, l := range s.shards {
+		shards = append(shards, l)
+	}
+	sort.Slice(shards, func(i, j int) bool {
+		return shards[i].typeURL < shards[j].typeURL
+	})
+	return shards
+}
+
+func (s *shardedCache) Add(entry XdsCacheEntry, token CacheToken, value *discovery.Resource) {
+	if !entry.Cacheable() {
+		return
+	}
+	s.shard(entry.TypeURL()).Add(entry, token, value)
+	s.evictOverLimit()
+}
+
+func (s *shardedCache) Get(entry XdsCacheEntry) (*discovery.Resource, CacheToken, bool) {
+	if !entry.Cacheable() {
+		return nil, 0, false
+	}
+	res, token, f := s.shard(entry.TypeURL()).Get(entry)
+	s.evictOverLimit()
+	return res, token, f
+}
+
+// evictOverLimit evicts the least recently used entries of the largest shards while all the shards hold more
+// entries than PILOT_XDS_CACHE_SIZE. Each shard is also bounded by the limits of its type.
+func (s *shardedCache) evictOverLimit() {
+	for s.entries.Load() > int64(s.maxEntries) {
+		var largest *lruCache
+		largestLen := 0
+		for _, l := range s.allShards() {
+			if n := l.len(); n > largestLen {
+				largest, largestLen = l, n
+			}
+		}
+		if largest == nil || !largest.removeOldest() {
+			return
+		}
+	}
+}
+
+func (s *shardedCache) Clear(configs map[ConfigKey]struct{}) {
+	for _, l := range s.allShards() {
+		l.Clear(configs)
+	}
+}
+
+func (s *shardedCache) ClearAll() {
+	for _, l := range s.allShards() {
+		l.ClearAll()
+	}
+}
+
+func (s *shardedCache) Keys() []string {
+	var keys []string
+	for _, l := range s.allShards() {
+		keys = append(keys, l.Keys()...)
+	}
+	return keys
+}
+
+func (s *shardedCache) Snapshot() map[string]*discovery.Resource {
+	res := map[string]*discovery.Resource{}
+	for _, l := range s.allShards() {
+		l.snapshot(res)
+	}
+	return res
+}
+
+func (s *shardedCache) Stats() []XdsCacheStats {
+	shards := s.allShards()
+	stats := make([]XdsCacheStats, 0, len(shards))
+	for _, l := range shards {
+		stats = append(stats, l.stats())
 	}
+	return stats
 }
 
+// lruCache is the cache of an xDS type, evicting the least recently used entries once it holds more entries or
+// bytes than its limits.
 type lruCache struct {
+	typeURL          string
 	enableAssertions bool
-	store            simplelru.LRUCache
-	// nextToken stores the next token to use. The content here doesn't matter, we just need a cheap
-	// unique identifier.
-	nextToken   *atomic.Uint64
+	maxEntries       int
+	maxBytes         int
+	nextToken        *atomic.Uint64
+	// entries is the number of entries of all the shards, updated with the changes of this shard.
+	entries *atomic.Int64
+
 	mu          sync.RWMutex
+	store       simplelru.LRUCache
 	configIndex map[ConfigKey]sets.Set
 	typesIndex  map[config.GroupVersionKind]sets.Set
+	// bytes is the size of the serialized resources of the store.
+	bytes int
+	// clearing is set while entries are removed because of a config change, so they are not counted as evictions.
+	clearing  bool
+	hits      uint64
+	misses    uint64
+	evictions uint64
 }
 
-var _ XdsCache = &lruCache{}
-
-func newLru() simplelru.LRUCache {
-	sz := features.XDSCacheMaxSize
-	if sz <= 0 {
-		sz = 20000
+func newLruCache(typeURL string, limits cacheLimits, enableAssertions bool, nextToken *atomic.Uint64,
+	entries *atomic.Int64) *lruCache {
+	l := &lruCache{
+		typeURL:          typeURL,
+		enableAssertions: enableAssertions,
+		maxEntries:       limits.entries,
+		maxBytes:         limits.bytes,
+		nextToken:        nextToken,
+		entries:          entries,
+		configIndex:      map[ConfigKey]sets.Set{},
+		typesIndex:       map[config.GroupVersionKind]sets.Set{},
 	}
-	l, err := simplelru.NewLRU(sz, evict)
+	store, err := simplelru.NewLRU(limits.entries, l.onEvict)
 	if err != nil {
 		panic(fmt.Errorf("invalid lru configuration: %v", err))
 	}
+	l.store = store
 	return l
 }
 
+// onEvict is called with the lock held for every entry removed from the store.
+func (l *lruCache) onEvict(k interface{}, v interface{}) {
+	l.bytes -= resourceSize(v.(cacheValue).value)
+	if !l.clearing {
+		l.evictions++
+		evict(k, v)
+	}
+}
+
+func resourceSize(value *discovery.Resource) int {
+	return len(value.GetResource().GetValue())
+}
+
+// updated records the size of the store after a change, given its number of entries before the change.
+func (l *lruCache) updated(before int) {
+	size(int(l.entries.Add(int64(l.store.Len() - before))))
+	sizeBytes(l.typeURL, l.bytes)
+}
+
 // assertUnchanged checks that a cache entry is not changed. This helps catch bad cache invalidation
 // We should never have a case where we overwrite an existing item with a new change. Instead, when
 // config sources change, Clear/ClearAll should be called. At this point, we may get multiple writes
@@ -223,9 +473,6 @@ func (l *lruCache) assertUnchanged(key string, existing *discovery.Resource, rep
 }
 
 func (l *lruCache) Add(entry XdsCacheEntry, token CacheToken, value *discovery.Resource) {
-	if !entry.Cacheable() {
-		return
-	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	k := entry.Key()
@@ -251,10 +498,46 @@ func (l *lruCache) Add(entry XdsCacheEntry, token CacheToken, value *discovery.R
 		}
 		l.assertUnchanged(k, cur.(cacheValue).value, value)
 	}
+	before := l.store.Len()
+	if l.maxBytes > 0 && resourceSize(value) > l.maxBytes {
+		// The value alone is larger than the limit. It is not cached, rather than evicting all the other entries.
+		log.Debugf("not caching %s of %d bytes, larger than the cache of %s", k, resourceSize(value), l.typeURL)
+		l.clearing = true
+		l.store.Remove(k)
+		l.clearing = false
+		l.updated(before)
+		return
+	}
+	// The key is present, so the store replaces the value without evicting it.
 	l.store.Add(k, toWrite)
-	indexConfig(l.configIndex, entry.Key(), entry)
-	indexType(l.typesIndex, entry.Key(), entry)
-	size(l.store.Len())
+	l.bytes += resourceSize(value) - resourceSize(cur.(cacheValue).value)
+	for l.maxBytes > 0 && l.bytes > l.maxBytes && l.store.Len() > 0 {
+		l.store.RemoveOldest()
+	}
+	if l.store.Contains(k) {
+		indexConfig(l.configIndex, k, entry)
+		indexType(l.typesIndex, k, entry)
+	}
+	l.updated(before)
+}
+
+// len returns the number of entries of the store.
+func (l *lruCache) len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.store.Len()
+}
+
+// removeOldest evicts the least recently used entry, and returns false if the store is empty.
+func (l *lruCache) removeOldest() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	before := l.store.Len()
+	if _, _, ok := l.store.RemoveOldest(); !ok {
+		return false
+	}
+	l.updated(before)
+	return true
 }
 
 type cacheValue struct {
@@ -263,35 +546,39 @@ type cacheValue struct {
 }
 
 func (l *lruCache) Get(entry XdsCacheEntry) (*discovery.Resource, CacheToken, bool) {
-	if !entry.Cacheable() {
-		return nil, 0, false
-	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	k := entry.Key()
 	val, ok := l.store.Get(k)
 	if !ok {
 		miss()
+		l.misses++
 		// If the entry is not found at all, this is our first read of it. We will generate and store
 		// a new token. Subsequent writes must include it.
 		tok := CacheToken(l.nextToken.Inc())
+		before := l.store.Len()
 		l.store.Add(k, cacheValue{token: tok})
+		l.updated(before)
 		return nil, tok, false
 	}
 	cv := val.(cacheValue)
 	if cv.value == nil {
 		miss()
+		l.misses++
 		// We have generated a token previously, so return that, but this is still a cache miss as
 		// no value is stored.
 		return nil, cv.token, false
 	}
 	hit()
+	l.hits++
 	return cv.value, cv.token, true
 }
 
 func (l *lruCache) Clear(configs map[ConfigKey]struct{}) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	before := l.store.Len()
+	l.clearing = true
 	for ckey := range configs {
 		referenced := l.configIndex[ckey]
 		delete(l.configIndex, ckey)
@@ -304,16 +591,20 @@ func (l *lruCache) Clear(configs map[ConfigKey]struct{}) {
 			l.store.Remove(key)
 		}
 	}
-	size(l.store.Len())
+	l.clearing = false
+	l.updated(before)
 }
 
 func (l *lruCache) ClearAll() {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	before := l.store.Len()
+	l.clearing = true
 	l.store.Purge()
+	l.clearing = false
 	l.configIndex = map[ConfigKey]sets.Set{}
 	l.typesIndex = map[config.GroupVersionKind]sets.Set{}
-	size(l.store.Len())
+	l.updated(before)
 }
 
 func (l *lruCache) Keys() []string {
@@ -327,20 +618,34 @@ func (l *lruCache) Keys() []string {
 	return keys
 }
 
-func (l *lruCache) Snapshot() map[string]*discovery.Resource {
+// snapshot adds the keys and values of the store to res.
+func (l *lruCache) snapshot(res map[string]*discovery.Resource) {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
-	iKeys := l.store.Keys()
-	res := make(map[string]*discovery.Resource, len(iKeys))
-	for _, ik := range iKeys {
-		v, ok := l.store.Get(ik)
+	for _, ik := range l.store.Keys() {
+		// Peek does not update the recency of the entry, so it is safe with the read lock.
+		v, ok := l.store.Peek(ik)
 		if !ok {
 			continue
 		}
 
 		res[ik.(string)] = v.(cacheValue).value
 	}
-	return res
+}
+
+func (l *lruCache) stats() XdsCacheStats {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return XdsCacheStats{
+		Type:       v3.GetShortType(l.typeURL),
+		Entries:    l.store.Len(),
+		Bytes:      l.bytes,
+		MaxEntries: l.maxEntries,
+		MaxBytes:   l.maxBytes,
+		Hits:       l.hits,
+		Misses:     l.misses,
+		Evictions:  l.evictions,
+	}
 }
 
 // DisabledCache is a cache that is always empty
@@ -361,3 +666,5 @@ func (d DisabledCache) ClearAll() {}
 func (d DisabledCache) Keys() []string { return nil }
 
 func (d DisabledCache) Snapshot() map[string]*discovery.Resource { return nil }
+
+func (d DisabledCache) Stats() []XdsCacheStats { return nil }
@@ -0,0 +1,58 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCacheLimits(t *testing.T) {
+	defaults := cacheLimits{entries: 100, bytes: 0}
+	cases := []struct {
+		in   string
+		want map[string]cacheLimits
+		err  bool
+	}{
+		{in: "", want: map[string]cacheLimits{}},
+		{in: "EDS=10", want: map[string]cacheLimits{"EDS": {entries: 10}}},
+		{in: "eds=10/1Ki, SDS=/512", want: map[string]cacheLimits{
+			"EDS": {entries: 10, bytes: 1024},
+			"SDS": {entries: 100, bytes: 512},
+		}},
+		{in: "EDS", err: true},
+		{in: "EDS=0", err: true},
+		{in: "EDS=ten", err: true},
+		{in: "EDS=/lots", err: true},
+		{in: "=10", err: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseCacheLimits(tt.in, defaults)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -37,9 +37,20 @@ type ConfigGenerator interface {
 	// BuildClusters returns the list of clusters for the given proxy. This is the CDS output
 	BuildClusters(node *model.Proxy, push *model.PushContext) []*cluster.Cluster
 
+	// BuildDeltaClusters returns both a list of resources that need to be pushed for a given proxy and a list of
+	// resources that have been deleted and should be removed from a given proxy. This is the Delta CDS output.
+	// If usedDelta is false, the full set of clusters was built and the removed clusters are not known.
+	BuildDeltaClusters(node *model.Proxy, push *model.PushContext, updates *model.PushRequest,
+		watched *model.WatchedResource) ([]*cluster.Cluster, []string, bool)
+
 	// BuildHTTPRoutes returns the list of HTTP routes for the given proxy. This is the RDS output
 	BuildHTTPRoutes(node *model.Proxy, push *model.PushContext, routeNames []string) []*route.RouteConfiguration
 
+	// BuildDeltaHTTPRoutes returns the list of HTTP routes of the watched routes changed by the updates. This is the
+	// Delta RDS output. If usedDelta is false, all the watched routes were built.
+	BuildDeltaHTTPRoutes(node *model.Proxy, push *model.PushContext, updates *model.PushRequest,
+		watched *model.WatchedResource) ([]*route.RouteConfiguration, bool)
+
 	// BuildNameTable returns list of hostnames and the associated IPs
 	BuildNameTable(node *model.Proxy, push *model.PushContext) *nds.NameTable
 
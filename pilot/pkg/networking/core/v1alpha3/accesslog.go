@@ -22,13 +22,18 @@ import (
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	fileaccesslog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/file/v3"
 	grpcaccesslog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/grpc/v3"
+	otelaccesslog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/open_telemetry/v3alpha"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	structpb "github.com/golang/protobuf/ptypes/struct"
+	otlpcommon "go.opentelemetry.io/proto/otlp/common/v1"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/telemetry"
 	"istio.io/istio/pkg/util/protomarshal"
 	"istio.io/pkg/log"
 )
@@ -52,14 +57,41 @@ const (
 	httpEnvoyAccessLogFriendlyName     = "http_envoy_accesslog"
 	tcpEnvoyAccessLogFriendlyName      = "tcp_envoy_accesslog"
 	listenerEnvoyAccessLogFriendlyName = "listener_envoy_accesslog"
+	otelEnvoyAccessLogFriendlyName     = "otel_envoy_accesslog"
 
 	tcpEnvoyALSName = "envoy.tcp_grpc_access_log"
 
+	otelEnvoyAccessLogName = "envoy.access_loggers.open_telemetry"
+
 	// EnvoyAccessLogCluster is the cluster name that has details for server implementing Envoy ALS.
 	// This cluster is created in bootstrap.
 	EnvoyAccessLogCluster = "envoy_accesslog_service"
+
+	// defaultFileAccessLogPath is the file of the envoy access log provider if MeshConfig sets none.
+	defaultFileAccessLogPath = "/dev/stdout"
+
+	// statusCodeFilterRuntimeKey is the runtime key of the status code filters, which Envoy requires.
+	statusCodeFilterRuntimeKey = "access_log.status_code"
 )
 
+// accessLogClass is the kind of Envoy configuration an access log is built for.
+type accessLogClass int
+
+const (
+	httpAccessLog accessLogClass = iota
+	tcpAccessLog
+	listenerAccessLog
+)
+
+// telemetryAccessLogKey identifies an access log built from the access logging of Telemetry resources.
+type telemetryAccessLogKey struct {
+	provider string
+	class    accessLogClass
+	filter   string
+	// cluster receiving the access logs of an extension provider.
+	cluster string
+}
+
 var (
 	// EnvoyJSONLogFormatIstio map of values for envoy json based access logs for Istio 1.9 onwards.
 	// This includes the additional log operator RESPONSE_CODE_DETAILS and CONNECTION_TERMINATION_DETAILS that tells
@@ -115,6 +147,8 @@ type AccessLogBuilder struct {
 	mutex                 sync.RWMutex
 	fileAccesslog         *accesslog.AccessLog
 	listenerFileAccessLog *accesslog.AccessLog
+	// access logs of the providers of Telemetry resources, which are cached and reset on MeshConfig change.
+	telemetryAccessLogs map[telemetryAccessLogKey]*accesslog.AccessLog
 }
 
 func newAccessLogBuilder() *AccessLogBuilder {
@@ -122,10 +156,17 @@ func newAccessLogBuilder() *AccessLogBuilder {
 		tcpGrpcAccessLog:         buildTCPGrpcAccessLog(false),
 		httpGrpcAccessLog:        buildHTTPGrpcAccessLog(),
 		tcpGrpcListenerAccessLog: buildTCPGrpcAccessLog(true),
+		telemetryAccessLogs:      map[telemetryAccessLogKey]*accesslog.AccessLog{},
 	}
 }
 
-func (b *AccessLogBuilder) setTCPAccessLog(mesh *meshconfig.MeshConfig, config *tcp.TcpProxy) {
+func (b *AccessLogBuilder) setTCPAccessLog(push *model.PushContext, proxy *model.Proxy, config *tcp.TcpProxy) {
+	mesh := push.Mesh
+	if al := effectiveAccessLogging(push, proxy); al != nil {
+		config.AccessLog = append(config.AccessLog, b.buildTelemetryAccessLogs(push, al, tcpAccessLog)...)
+		return
+	}
+
 	if mesh.AccessLogFile != "" {
 		config.AccessLog = append(config.AccessLog, b.buildFileAccessLog(mesh))
 	}
@@ -135,7 +176,13 @@ func (b *AccessLogBuilder) setTCPAccessLog(mesh *meshconfig.MeshConfig, config *
 	}
 }
 
-func (b *AccessLogBuilder) setHTTPAccessLog(mesh *meshconfig.MeshConfig, connectionManager *hcm.HttpConnectionManager) {
+func (b *AccessLogBuilder) setHTTPAccessLog(push *model.PushContext, proxy *model.Proxy, connectionManager *hcm.HttpConnectionManager) {
+	mesh := push.Mesh
+	if al := effectiveAccessLogging(push, proxy); al != nil {
+		connectionManager.AccessLog = append(connectionManager.AccessLog, b.buildTelemetryAccessLogs(push, al, httpAccessLog)...)
+		return
+	}
+
 	if mesh.AccessLogFile != "" {
 		connectionManager.AccessLog = append(connectionManager.AccessLog, b.buildFileAccessLog(mesh))
 	}
@@ -145,10 +192,15 @@ func (b *AccessLogBuilder) setHTTPAccessLog(mesh *meshconfig.MeshConfig, connect
 	}
 }
 
-func (b *AccessLogBuilder) setListenerAccessLog(mesh *meshconfig.MeshConfig, listener *listener.Listener) {
+func (b *AccessLogBuilder) setListenerAccessLog(push *model.PushContext, proxy *model.Proxy, listener *listener.Listener) {
+	mesh := push.Mesh
 	if mesh.DisableEnvoyListenerLog {
 		return
 	}
+	if al := effectiveAccessLogging(push, proxy); al != nil {
+		listener.AccessLog = append(listener.AccessLog, b.buildTelemetryAccessLogs(push, al, listenerAccessLog)...)
+		return
+	}
 	if mesh.AccessLogFile != "" {
 		listener.AccessLog = append(listener.AccessLog, b.buildListenerFileAccessLog(mesh))
 	}
@@ -159,10 +211,192 @@ func (b *AccessLogBuilder) setListenerAccessLog(mesh *meshconfig.MeshConfig, lis
 	}
 }
 
-func buildFileAccessLogHelper(mesh *meshconfig.MeshConfig) *accesslog.AccessLog {
+// effectiveAccessLogging returns the access logging of the Telemetry resources applying to the proxy, or nil if
+// the access logs are configured by MeshConfig only.
+func effectiveAccessLogging(push *model.PushContext, proxy *model.Proxy) *telemetry.AccessLogging {
+	if proxy == nil || proxy.Metadata == nil {
+		return nil
+	}
+	return push.Telemetry.EffectiveAccessLogging(proxy.ConfigNamespace, labels.Collection{proxy.Metadata.Labels})
+}
+
+// buildTelemetryAccessLogs returns the access logs of the providers of the access logging. If it names no provider,
+// the access logs enabled by MeshConfig are used.
+func (b *AccessLogBuilder) buildTelemetryAccessLogs(push *model.PushContext, al *telemetry.AccessLogging,
+	class accessLogClass) []*accesslog.AccessLog {
+	mesh := push.Mesh
+	if al.IsDisabled() {
+		return nil
+	}
+
+	providers := make([]string, 0, len(al.Providers))
+	for _, p := range al.Providers {
+		providers = append(providers, p.Name)
+	}
+	if len(providers) == 0 {
+		if mesh.AccessLogFile != "" {
+			providers = append(providers, telemetry.EnvoyFileAccessLogProvider)
+		}
+		if mesh.EnableEnvoyAccessLogService {
+			providers = append(providers, telemetry.EnvoyGrpcAccessLogProvider)
+		}
+	}
+
+	logs := make([]*accesslog.AccessLog, 0, len(providers))
+	for _, provider := range providers {
+		key := telemetryAccessLogKey{provider: provider, class: class, filter: al.GetFilterExpression()}
+		if provider != telemetry.EnvoyFileAccessLogProvider && provider != telemetry.EnvoyGrpcAccessLogProvider {
+			p := push.OpenTelemetryProvider(provider)
+			if p == nil {
+				log.Debugf("access log provider %q is not found or does not support access logging", provider)
+				continue
+			}
+			// The cluster is part of the key, so the access log follows the changes of the collector service.
+			_, cluster, err := clusterLookupFn(push, p.Service, int(p.Port))
+			if err != nil {
+				log.Warnf("could not find cluster for access log provider %q: %v", provider, err)
+				continue
+			}
+			key.cluster = cluster
+		}
+		if l := b.telemetryAccessLog(mesh, key); l != nil {
+			logs = append(logs, l)
+		}
+	}
+	return logs
+}
+
+func (b *AccessLogBuilder) telemetryAccessLog(mesh *meshconfig.MeshConfig, key telemetryAccessLogKey) *accesslog.AccessLog {
+	b.mutex.RLock()
+	al, f := b.telemetryAccessLogs[key]
+	b.mutex.RUnlock()
+	if f {
+		return al
+	}
+
+	al = buildProviderAccessLog(mesh, key)
+	if al != nil && key.filter != "" {
+		filter, err := buildCodeAccessLogFilter(key.filter)
+		if err != nil {
+			// Validation rejects such filters, so this is only reached by configs which skipped it.
+			log.Warnf("not logging with access log provider %q: %v", key.provider, err)
+			al = nil
+		} else if al.Filter != nil {
+			filter = &accesslog.AccessLogFilter{
+				FilterSpecifier: &accesslog.AccessLogFilter_AndFilter{
+					AndFilter: &accesslog.AndFilter{Filters: []*accesslog.AccessLogFilter{al.Filter, filter}},
+				},
+			}
+		}
+		al.Filter = filter
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.telemetryAccessLogs[key] = al
+
+	return al
+}
+
+// buildProviderAccessLog builds the access log of a provider, or returns nil if the provider is unknown.
+func buildProviderAccessLog(mesh *meshconfig.MeshConfig, key telemetryAccessLogKey) *accesslog.AccessLog {
+	if key.cluster != "" {
+		return buildOpenTelemetryAccessLog(mesh, key.cluster)
+	}
+	class := key.class
+	switch key.provider {
+	case telemetry.EnvoyFileAccessLogProvider:
+		path := mesh.AccessLogFile
+		if path == "" {
+			path = defaultFileAccessLogPath
+		}
+		al := buildFileAccessLogHelper(path, mesh)
+		if class == listenerAccessLog {
+			al.Filter = addAccessLogFilter()
+		}
+		return al
+	case telemetry.EnvoyGrpcAccessLogProvider:
+		if class == httpAccessLog {
+			return buildHTTPGrpcAccessLog()
+		}
+		return buildTCPGrpcAccessLog(class == listenerAccessLog)
+	}
+	return nil
+}
+
+// buildOpenTelemetryAccessLog sends the access logs to the cluster with OTLP over gRPC. The body of the log records
+// uses the text format of MeshConfig.
+func buildOpenTelemetryAccessLog(mesh *meshconfig.MeshConfig, cluster string) *accesslog.AccessLog {
+	format := EnvoyTextLogFormat
+	if mesh.AccessLogEncoding == meshconfig.MeshConfig_TEXT && mesh.AccessLogFormat != "" {
+		format = mesh.AccessLogFormat
+	}
+	cfg := &otelaccesslog.OpenTelemetryAccessLogConfig{
+		CommonConfig: &grpcaccesslog.CommonGrpcAccessLogConfig{
+			LogName: otelEnvoyAccessLogFriendlyName,
+			GrpcService: &core.GrpcService{
+				TargetSpecifier: &core.GrpcService_EnvoyGrpc_{
+					EnvoyGrpc: &core.GrpcService_EnvoyGrpc{
+						ClusterName: cluster,
+					},
+				},
+			},
+			TransportApiVersion: core.ApiVersion_V3,
+		},
+		Body: &otlpcommon.AnyValue{
+			Value: &otlpcommon.AnyValue_StringValue{StringValue: format},
+		},
+	}
+
+	return &accesslog.AccessLog{
+		Name:       otelEnvoyAccessLogName,
+		ConfigType: &accesslog.AccessLog_TypedConfig{TypedConfig: util.MessageToAny(cfg)},
+	}
+}
+
+// buildCodeAccessLogFilter maps a filter expression onto the status code filters of Envoy.
+func buildCodeAccessLogFilter(expression string) (*accesslog.AccessLogFilter, error) {
+	f, err := telemetry.ParseFilterExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	filters := make([]*accesslog.AccessLogFilter, 0, len(f.Comparisons))
+	for _, c := range f.Comparisons {
+		op := accesslog.ComparisonFilter_EQ
+		switch c.Op {
+		case ">=":
+			op = accesslog.ComparisonFilter_GE
+		case "<=":
+			op = accesslog.ComparisonFilter_LE
+		}
+		filters = append(filters, &accesslog.AccessLogFilter{
+			FilterSpecifier: &accesslog.AccessLogFilter_StatusCodeFilter{
+				StatusCodeFilter: &accesslog.StatusCodeFilter{
+					Comparison: &accesslog.ComparisonFilter{
+						Op:    op,
+						Value: &core.RuntimeUInt32{DefaultValue: c.Value, RuntimeKey: statusCodeFilterRuntimeKey},
+					},
+				},
+			},
+		})
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	if f.AnyOf {
+		return &accesslog.AccessLogFilter{
+			FilterSpecifier: &accesslog.AccessLogFilter_OrFilter{OrFilter: &accesslog.OrFilter{Filters: filters}},
+		}, nil
+	}
+	return &accesslog.AccessLogFilter{
+		FilterSpecifier: &accesslog.AccessLogFilter_AndFilter{AndFilter: &accesslog.AndFilter{Filters: filters}},
+	}, nil
+}
+
+func buildFileAccessLogHelper(path string, mesh *meshconfig.MeshConfig) *accesslog.AccessLog {
 	// We need to build access log. This is needed either on first access or when mesh config changes.
 	fl := &fileaccesslog.FileAccessLog{
-		Path: mesh.AccessLogFile,
+		Path: path,
 	}
 
 	switch mesh.AccessLogEncoding {
@@ -213,7 +447,7 @@ func (b *AccessLogBuilder) buildFileAccessLog(mesh *meshconfig.MeshConfig) *acce
 	}
 
 	// We need to build access log. This is needed either on first access or when mesh config changes.
-	al := buildFileAccessLogHelper(mesh)
+	al := buildFileAccessLogHelper(mesh.AccessLogFile, mesh)
 
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
@@ -236,7 +470,7 @@ func (b *AccessLogBuilder) buildListenerFileAccessLog(mesh *meshconfig.MeshConfi
 	}
 
 	// We need to build access log. This is needed either on first access or when mesh config changes.
-	lal := buildFileAccessLogHelper(mesh)
+	lal := buildFileAccessLogHelper(mesh.AccessLogFile, mesh)
 	// We add ResponseFlagFilter here, as we want to get listener access logs only on scenarios where we might
 	// not get filter Access Logs like in cases like NR to upstream.
 	lal.Filter = addAccessLogFilter()
@@ -317,5 +551,6 @@ func (b *AccessLogBuilder) reset() {
 	b.mutex.Lock()
 	b.fileAccesslog = nil
 	b.listenerFileAccessLog = nil
+	b.telemetryAccessLogs = map[telemetryAccessLogKey]*accesslog.AccessLog{}
 	b.mutex.Unlock()
 }
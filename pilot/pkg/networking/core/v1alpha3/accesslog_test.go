@@ -15,16 +15,33 @@
 package v1alpha3
 
 import (
+	"context"
+	"net"
 	"testing"
+	"time"
 
 	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	otelaccesslog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/open_telemetry/v3alpha"
 	httppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	tcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/conversion"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	otlpcommon "go.opentelemetry.io/proto/otlp/common/v1"
+	otlplogs "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	tpb "istio.io/api/telemetry/v1alpha1"
+	"istio.io/api/type/v1beta1"
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config/extensionprovider"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/telemetry"
 	"istio.io/istio/pkg/util/protomarshal"
 )
 
@@ -148,3 +165,310 @@ func verify(t *testing.T, encoding meshconfig.MeshConfig_AccessLogEncoding, got
 		}
 	}
 }
+
+func TestTelemetryAccessLog(t *testing.T) {
+	disabled := true
+	telemetries := &model.Telemetries{
+		RootNamespace: "istio-system",
+		NamespaceToTelemetries: map[string][]model.Telemetry{
+			"istio-system": {{
+				Name:          "default",
+				Namespace:     "istio-system",
+				Spec:          &tpb.Telemetry{},
+				AccessLogging: &telemetry.AccessLogging{Providers: []telemetry.ProviderRef{{Name: telemetry.EnvoyFileAccessLogProvider}}},
+			}},
+			"errors": {{
+				Name:      "errors",
+				Namespace: "errors",
+				Spec:      &tpb.Telemetry{},
+				AccessLogging: &telemetry.AccessLogging{
+					Providers: []telemetry.ProviderRef{{Name: telemetry.EnvoyGrpcAccessLogProvider}, {Name: "unknown"}},
+					Filter:    &telemetry.AccessLogFilter{Expression: "response.code >= 500"},
+				},
+			}},
+			"noisy": {{
+				Name:          "noisy",
+				Namespace:     "noisy",
+				Spec:          &tpb.Telemetry{Selector: &v1beta1.WorkloadSelector{MatchLabels: map[string]string{"app": "noisy"}}},
+				AccessLogging: &telemetry.AccessLogging{Disabled: &disabled},
+			}},
+		},
+	}
+	push := &model.PushContext{Mesh: &meshconfig.MeshConfig{}, Telemetry: telemetries}
+	proxy := func(namespace string, labels map[string]string) *model.Proxy {
+		return &model.Proxy{ConfigNamespace: namespace, Metadata: &model.NodeMetadata{Labels: labels}}
+	}
+	accessLogBuilder.reset()
+
+	// The root namespace enables the file provider, logging to stdout as MeshConfig sets no file.
+	hcm := &httppb.HttpConnectionManager{}
+	accessLogBuilder.setHTTPAccessLog(push, proxy("default", nil), hcm)
+	if len(hcm.AccessLog) != 1 || hcm.AccessLog[0].Name != wellknown.FileAccessLog || hcm.AccessLog[0].Filter != nil {
+		t.Fatalf("expected an unfiltered file access log, got %v", hcm.AccessLog)
+	}
+	cfg, _ := conversion.MessageToStruct(hcm.AccessLog[0].GetTypedConfig())
+	if path := cfg.GetFields()["path"].GetStringValue(); path != defaultFileAccessLogPath {
+		t.Fatalf("expected path %s, got %s", defaultFileAccessLogPath, path)
+	}
+	cached := &httppb.HttpConnectionManager{}
+	accessLogBuilder.setHTTPAccessLog(push, proxy("default", nil), cached)
+	if cached.AccessLog[0] != hcm.AccessLog[0] {
+		t.Fatalf("expected the access log to be cached")
+	}
+
+	// The namespace overrides the providers and filters the access logs; unknown providers are skipped.
+	tcpProxy := &tcp.TcpProxy{}
+	accessLogBuilder.setTCPAccessLog(push, proxy("errors", nil), tcpProxy)
+	if len(tcpProxy.AccessLog) != 1 || tcpProxy.AccessLog[0].Name != tcpEnvoyALSName {
+		t.Fatalf("expected a gRPC access log, got %v", tcpProxy.AccessLog)
+	}
+	cmp := tcpProxy.AccessLog[0].GetFilter().GetStatusCodeFilter().GetComparison()
+	if cmp.GetOp() != accesslog.ComparisonFilter_GE || cmp.GetValue().GetDefaultValue() != 500 {
+		t.Fatalf("expected a status code filter, got %v", tcpProxy.AccessLog[0].GetFilter())
+	}
+
+	// Listener access logs keep logging only the requests without route.
+	l := &listener.Listener{}
+	accessLogBuilder.setListenerAccessLog(push, proxy("errors", nil), l)
+	if len(l.AccessLog) != 1 || len(l.AccessLog[0].GetFilter().GetAndFilter().GetFilters()) != 2 {
+		t.Fatalf("expected the listener access log to combine filters, got %v", l.AccessLog)
+	}
+
+	// Disabled workloads have no access logs, the other workloads of the namespace are not affected.
+	hcm = &httppb.HttpConnectionManager{}
+	accessLogBuilder.setHTTPAccessLog(push, proxy("noisy", map[string]string{"app": "noisy"}), hcm)
+	if len(hcm.AccessLog) != 0 {
+		t.Fatalf("expected no access log, got %v", hcm.AccessLog)
+	}
+	accessLogBuilder.setHTTPAccessLog(push, proxy("noisy", map[string]string{"app": "other"}), hcm)
+	if len(hcm.AccessLog) != 1 {
+		t.Fatalf("expected the access log of the root namespace, got %v", hcm.AccessLog)
+	}
+}
+
+const openTelemetryConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: otel-collector
+  namespace: istio-system
+spec:
+  hosts:
+  - otel-collector.istio-system.svc.cluster.local
+  ports:
+  - number: {{.Port}}
+    name: grpc
+    protocol: GRPC
+  resolution: DNS
+  endpoints:
+  - address: 127.0.0.1
+---
+apiVersion: telemetry.istio.io/v1alpha1
+kind: Telemetry
+metadata:
+  name: default
+  namespace: istio-system
+  annotations:
+    telemetry.istio.io/accessLogging: |
+      providers:
+      - name: otel
+      - name: missing
+spec: {}
+`
+
+func TestTelemetryOpenTelemetryAccessLog(t *testing.T) {
+	collector := newOpenTelemetryCollector(t)
+	cg := NewConfigGenTest(t, TestOptions{
+		ConfigString:        openTelemetryConfig,
+		ConfigTemplateInput: map[string]int{"Port": collector.port},
+		MeshConfig: func() *meshconfig.MeshConfig {
+			m := mesh.DefaultMeshConfig()
+			m.AccessLogEncoding = meshconfig.MeshConfig_TEXT
+			m.AccessLogFormat = "%REQ(:PATH)%"
+			return &m
+		}(),
+		MeshExtensionProviders: []*extensionprovider.ExtensionProvider{
+			{
+				Name: "otel",
+				OpenTelemetry: &extensionprovider.OpenTelemetryProvider{
+					Service: "istio-system/otel-collector.istio-system.svc.cluster.local",
+					Port:    uint32(collector.port),
+				},
+			},
+			{
+				Name: "missing",
+				OpenTelemetry: &extensionprovider.OpenTelemetryProvider{
+					Service: "missing.default.svc.cluster.local",
+					Port:    4317,
+				},
+			},
+		},
+	})
+	proxy := cg.SetupProxy(nil)
+	accessLogBuilder.reset()
+
+	// The provider with a collector is sent the logs, the one whose collector is missing is skipped.
+	hcm := &httppb.HttpConnectionManager{}
+	accessLogBuilder.setHTTPAccessLog(cg.PushContext(), proxy, hcm)
+	if len(hcm.AccessLog) != 1 || hcm.AccessLog[0].Name != otelEnvoyAccessLogName {
+		t.Fatalf("expected an OpenTelemetry access log, got %v", hcm.AccessLog)
+	}
+	cfg := &otelaccesslog.OpenTelemetryAccessLogConfig{}
+	if err := hcm.AccessLog[0].GetTypedConfig().UnmarshalTo(cfg); err != nil {
+		t.Fatal(err)
+	}
+	body := cfg.GetBody().GetStringValue()
+	if body != "%REQ(:PATH)%" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	// The access log cluster must reach the collector. Send it a log record as the proxy would.
+	clusterName := cfg.GetCommonConfig().GetGrpcService().GetEnvoyGrpc().GetClusterName()
+	c := xdstest.ExtractCluster(clusterName, cg.Clusters(proxy))
+	if c == nil {
+		t.Fatalf("collector cluster %q not found", clusterName)
+	}
+	endpoints := xdstest.ExtractEndpoints(c.LoadAssignment)
+	if len(endpoints) != 1 {
+		t.Fatalf("expected a single collector endpoint, got %v", endpoints)
+	}
+	collector.export(t, endpoints[0], body)
+	select {
+	case got := <-collector.bodies:
+		if got != body {
+			t.Fatalf("collector received %q, expected %q", got, body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the collector to receive the log")
+	}
+}
+
+// openTelemetryCollector is an OTLP gRPC collector stub, receiving the bodies of the exported log records. The
+// generated collector service is not used as it depends on grpc-gateway, so the requests are encoded by otlpLogsCodec.
+type openTelemetryCollector struct {
+	port   int
+	bodies chan string
+}
+
+const otlpLogsService = "opentelemetry.proto.collector.logs.v1.LogsService"
+
+func newOpenTelemetryCollector(t *testing.T) *openTelemetryCollector {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &openTelemetryCollector{port: l.Addr().(*net.TCPAddr).Port, bodies: make(chan string, 10)}
+	server := grpc.NewServer(grpc.ForceServerCodec(otlpLogsCodec{}))
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: otlpLogsService,
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Export",
+			Handler: func(_ interface{}, _ context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := &otlpExportLogsRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				for _, rl := range req.resourceLogs {
+					for _, ill := range rl.InstrumentationLibraryLogs {
+						for _, record := range ill.Logs {
+							c.bodies <- record.GetBody().GetStringValue()
+						}
+					}
+				}
+				return &otlpExportLogsResponse{}, nil
+			},
+		}},
+	}, struct{}{})
+	go func() {
+		_ = server.Serve(l)
+	}()
+	t.Cleanup(server.Stop)
+	return c
+}
+
+// export sends a log record with the given body to the collector at address.
+func (c *openTelemetryCollector) export(t *testing.T, address, body string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	req := &otlpExportLogsRequest{resourceLogs: []*otlplogs.ResourceLogs{{
+		InstrumentationLibraryLogs: []*otlplogs.InstrumentationLibraryLogs{{
+			Logs: []*otlplogs.LogRecord{{
+				Name: otelEnvoyAccessLogFriendlyName,
+				Body: &otlpcommon.AnyValue{Value: &otlpcommon.AnyValue_StringValue{StringValue: body}},
+			}},
+		}},
+	}}}
+	if err := conn.Invoke(ctx, "/"+otlpLogsService+"/Export", req, &otlpExportLogsResponse{}, grpc.ForceCodec(otlpLogsCodec{})); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// otlpExportLogsRequest is an ExportLogsServiceRequest, holding the resource logs in field 1.
+type otlpExportLogsRequest struct {
+	resourceLogs []*otlplogs.ResourceLogs
+}
+
+// otlpExportLogsResponse is an empty ExportLogsServiceResponse.
+type otlpExportLogsResponse struct{}
+
+type otlpLogsCodec struct{}
+
+func (otlpLogsCodec) Name() string {
+	return "proto"
+}
+
+func (otlpLogsCodec) Marshal(v interface{}) ([]byte, error) {
+	req, ok := v.(*otlpExportLogsRequest)
+	if !ok {
+		return nil, nil
+	}
+	var b []byte
+	for _, rl := range req.resourceLogs {
+		m, err := proto.Marshal(rl)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, m)
+	}
+	return b, nil
+}
+
+func (otlpLogsCodec) Unmarshal(data []byte, v interface{}) error {
+	req, ok := v.(*otlpExportLogsRequest)
+	if !ok {
+		return nil
+	}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		if num == 1 && typ == protowire.BytesType {
+			m, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			rl := &otlplogs.ResourceLogs{}
+			if err := proto.Unmarshal(m, rl); err != nil {
+				return err
+			}
+			req.resourceLogs = append(req.resourceLogs, rl)
+			data = data[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+	return nil
+}
@@ -25,6 +25,7 @@ import (
 	xdstype "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	structpb "github.com/golang/protobuf/ptypes/struct"
 	"github.com/golang/protobuf/ptypes/wrappers"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
@@ -34,8 +35,11 @@ import (
 	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/loadbalancer"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pilot/pkg/util/sets"
+	"istio.io/istio/pkg/config/healthcheck"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/util/gogo"
 )
 
@@ -79,7 +83,7 @@ func (configgen *ConfigGeneratorImpl) BuildClusters(proxy *model.Proxy, push *mo
 	case model.SidecarProxy:
 		// Setup outbound clusters
 		outboundPatcher := clusterPatcher{efw: envoyFilterPatches, pctx: networking.EnvoyFilter_SIDECAR_OUTBOUND}
-		clusters = append(clusters, configgen.buildOutboundClusters(cb, outboundPatcher)...)
+		clusters = append(clusters, configgen.buildOutboundClusters(cb, outboundPatcher, outboundServices(cb))...)
 		// Add a blackhole and passthrough cluster for catching traffic to unresolved routes
 		clusters = outboundPatcher.conditionallyAppend(clusters, nil, cb.buildBlackHoleCluster(), cb.buildDefaultPassthroughCluster())
 		clusters = append(clusters, outboundPatcher.insertedClusters()...)
@@ -92,7 +96,7 @@ func (configgen *ConfigGeneratorImpl) BuildClusters(proxy *model.Proxy, push *mo
 		clusters = append(clusters, inboundPatcher.insertedClusters()...)
 	default: // Gateways
 		patcher := clusterPatcher{efw: envoyFilterPatches, pctx: networking.EnvoyFilter_GATEWAY}
-		clusters = append(clusters, configgen.buildOutboundClusters(cb, patcher)...)
+		clusters = append(clusters, configgen.buildOutboundClusters(cb, patcher, outboundServices(cb))...)
 		// Gateways do not require the default passthrough cluster as they do not have original dst listeners.
 		clusters = patcher.conditionallyAppend(clusters, nil, cb.buildBlackHoleCluster())
 		if proxy.Type == model.Router && proxy.MergedGateway != nil && proxy.MergedGateway.ContainsAutoPassthroughGateways {
@@ -104,16 +108,89 @@ func (configgen *ConfigGeneratorImpl) BuildClusters(proxy *model.Proxy, push *mo
 	return cb.normalizeClusters(clusters)
 }
 
-func (configgen *ConfigGeneratorImpl) buildOutboundClusters(cb *ClusterBuilder, cp clusterPatcher) []*cluster.Cluster {
-	clusters := make([]*cluster.Cluster, 0)
-	networkView := cb.proxy.GetNetworkView()
+// BuildDeltaClusters returns the clusters changed by the updates, along with the names of the removed clusters.
+// Only updates limited to services can be computed incrementally, in which case only the outbound clusters of the
+// updated services are built. Otherwise, usedDelta is false and all the clusters are returned.
+func (configgen *ConfigGeneratorImpl) BuildDeltaClusters(proxy *model.Proxy, push *model.PushContext, updates *model.PushRequest,
+	watched *model.WatchedResource) ([]*cluster.Cluster, []string, bool) {
+	if !canBuildDeltaClusters(proxy, updates, watched) {
+		return configgen.BuildClusters(proxy, push), nil, false
+	}
+	updatedServices := model.ConfigNamesOfKind(updates.ConfigsUpdated, gvk.ServiceEntry)
 
+	cb := NewClusterBuilder(proxy, push)
 	var services []*model.Service
+	for _, svc := range outboundServices(cb) {
+		if _, f := updatedServices[string(svc.Hostname)]; f {
+			services = append(services, svc)
+		}
+	}
+	pctx := networking.EnvoyFilter_GATEWAY
+	if proxy.Type == model.SidecarProxy {
+		pctx = networking.EnvoyFilter_SIDECAR_OUTBOUND
+	}
+	patcher := clusterPatcher{efw: push.EnvoyFilters(proxy), pctx: pctx}
+	clusters := cb.normalizeClusters(configgen.buildOutboundClusters(cb, patcher, services))
+
+	// Any outbound cluster previously sent for an updated service which was not built again has been removed,
+	// either because the service was deleted, is no longer visible to the proxy or lost a port.
+	built := sets.NewSet()
+	for _, c := range clusters {
+		built.Insert(c.Name)
+	}
+	var deleted []string
+	for _, name := range watched.ResourceNames {
+		direction, _, hostname, _ := model.ParseSubsetKey(name)
+		if direction != model.TrafficDirectionOutbound {
+			continue
+		}
+		if _, f := updatedServices[string(hostname)]; f && !built.Contains(name) {
+			deleted = append(deleted, name)
+		}
+	}
+	return clusters, deleted, true
+}
+
+// canBuildDeltaClusters returns true if the clusters changed by the updates can be computed incrementally.
+func canBuildDeltaClusters(proxy *model.Proxy, updates *model.PushRequest, watched *model.WatchedResource) bool {
+	// Without the previously sent clusters, we cannot tell which clusters were removed.
+	if updates == nil || !updates.Full || len(updates.ConfigsUpdated) == 0 || watched == nil || len(watched.ResourceNames) == 0 {
+		return false
+	}
+	for key := range updates.ConfigsUpdated {
+		if key.Kind != gvk.ServiceEntry {
+			return false
+		}
+	}
+	// SNI-DNAT clusters are built from the services as well, but are not worth handling incrementally.
+	if proxy.Type == model.Router && proxy.MergedGateway != nil && proxy.MergedGateway.ContainsAutoPassthroughGateways {
+		return false
+	}
+	// Inbound clusters depend on the services of the proxy itself.
+	for _, instance := range proxy.ServiceInstances {
+		if _, f := updates.ConfigsUpdated[model.ConfigKey{
+			Kind:      gvk.ServiceEntry,
+			Name:      string(instance.Service.Hostname),
+			Namespace: instance.Service.Attributes.Namespace,
+		}]; f {
+			return false
+		}
+	}
+	return true
+}
+
+// outboundServices returns the services for which the proxy gets outbound clusters.
+func outboundServices(cb *ClusterBuilder) []*model.Service {
 	if features.FilterGatewayClusterConfig && cb.proxy.Type == model.Router {
-		services = cb.push.GatewayServices(cb.proxy)
-	} else {
-		services = cb.push.Services(cb.proxy)
+		return cb.push.GatewayServices(cb.proxy)
 	}
+	return cb.push.Services(cb.proxy)
+}
+
+func (configgen *ConfigGeneratorImpl) buildOutboundClusters(cb *ClusterBuilder, cp clusterPatcher, services []*model.Service) []*cluster.Cluster {
+	clusters := make([]*cluster.Cluster, 0)
+	networkView := cb.proxy.GetNetworkView()
+
 	for _, service := range services {
 		for _, port := range service.Ports {
 			if port.Protocol == protocol.UDP {
@@ -506,6 +583,8 @@ type buildClusterOpts struct {
 	serviceMTLSMode model.MutualTLSMode
 	// Indicates the service registry of the cluster being built.
 	serviceRegistry string
+	// The active health check of the destination rule, if any.
+	healthCheck *healthcheck.HealthCheck
 }
 
 type upgradeTuple struct {
@@ -613,6 +692,53 @@ func applyOutlierDetection(c *cluster.Cluster, outlier *networking.OutlierDetect
 	}
 }
 
+// applyHealthCheck adds the active health check to the cluster.
+func applyHealthCheck(c *cluster.Cluster, hc *healthcheck.HealthCheck) {
+	if hc == nil {
+		return
+	}
+
+	out := &core.HealthCheck{
+		Timeout:            durationpb.New(hc.Timeout.Duration),
+		Interval:           durationpb.New(hc.Interval.Duration),
+		UnhealthyThreshold: &wrappers.UInt32Value{Value: hc.GetUnhealthyThreshold()},
+		HealthyThreshold:   &wrappers.UInt32Value{Value: hc.GetHealthyThreshold()},
+	}
+	if hc.NoTrafficInterval != nil {
+		out.NoTrafficInterval = durationpb.New(hc.NoTrafficInterval.Duration)
+	}
+
+	switch {
+	case hc.HTTP != nil:
+		check := &core.HealthCheck_HttpHealthCheck{
+			Path: hc.HTTP.Path,
+			Host: hc.HTTP.Host,
+		}
+		for _, status := range hc.HTTP.ExpectedStatuses {
+			check.ExpectedStatuses = append(check.ExpectedStatuses, &xdstype.Int64Range{Start: status, End: status + 1})
+		}
+		out.HealthChecker = &core.HealthCheck_HttpHealthCheck_{HttpHealthCheck: check}
+	case hc.GRPC != nil:
+		out.HealthChecker = &core.HealthCheck_GrpcHealthCheck_{GrpcHealthCheck: &core.HealthCheck_GrpcHealthCheck{
+			ServiceName: hc.GRPC.ServiceName,
+			Authority:   hc.GRPC.Authority,
+		}}
+	case hc.TCP != nil:
+		check := &core.HealthCheck_TcpHealthCheck{}
+		if hc.TCP.Send != "" {
+			check.Send = &core.HealthCheck_Payload{Payload: &core.HealthCheck_Payload_Binary{Binary: []byte(hc.TCP.Send)}}
+		}
+		for _, r := range hc.TCP.Receive {
+			check.Receive = append(check.Receive, &core.HealthCheck_Payload{Payload: &core.HealthCheck_Payload_Binary{Binary: []byte(r)}})
+		}
+		out.HealthChecker = &core.HealthCheck_TcpHealthCheck_{TcpHealthCheck: check}
+	default:
+		return
+	}
+
+	c.HealthChecks = []*core.HealthCheck{out}
+}
+
 func applyLoadBalancer(c *cluster.Cluster, lb *networking.LoadBalancerSettings, port *model.Port, proxy *model.Proxy, meshConfig *meshconfig.MeshConfig) {
 	localityLbSetting := loadbalancer.GetLocalityLbSetting(meshConfig.GetLocalityLbSetting(), lb.GetLocalityLbSetting())
 	if localityLbSetting != nil && (localityLbSetting.Distribute != nil || localityLbSetting.Failover != nil) {
@@ -16,6 +16,7 @@ package v1alpha3
 
 import (
 	"fmt"
+	"sync"
 
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
@@ -38,6 +39,7 @@ import (
 	"istio.io/istio/pilot/pkg/util/sets"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/healthcheck"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/util/gogo"
 	"istio.io/pkg/log"
@@ -45,6 +47,20 @@ import (
 
 var defaultDestinationRule = networking.DestinationRule{}
 
+// invalidHealthChecks holds the last resource version reported of the destination rules with an invalid health check,
+// by namespace and name. Clusters are built for every proxy on every push, so each revision is reported once.
+var invalidHealthChecks sync.Map
+
+// warnInvalidHealthCheck reports the invalid health check of a destination rule, which is then ignored.
+func warnInvalidHealthCheck(destRule *config.Config, err error) {
+	key := destRule.Namespace + "/" + destRule.Name
+	if reported, f := invalidHealthChecks.Load(key); f && reported == destRule.ResourceVersion {
+		return
+	}
+	invalidHealthChecks.Store(key, destRule.ResourceVersion)
+	log.Warnf("ignoring invalid health check of destination rule %s/%s: %v", destRule.Namespace, destRule.Name, err)
+}
+
 var istioMtlsTransportSocketMatch = &structpb.Struct{
 	Fields: map[string]*structpb.Value{
 		model.TLSModeLabelShortname: {Kind: &structpb.Value_StringValue{StringValue: model.IstioMutualTLSModeLabel}},
@@ -146,6 +162,9 @@ func (cb *ClusterBuilder) buildSubsetCluster(opts buildClusterOpts, destRule *co
 	opts.policy = MergeTrafficPolicy(opts.policy, subset.TrafficPolicy, opts.port)
 	// Apply traffic policy for the subset cluster.
 	cb.applyTrafficPolicy(opts)
+	if opts.healthCheck != nil && opts.healthCheck.AppliesToSubset(subset.Name) {
+		applyHealthCheck(subsetCluster.cluster, opts.healthCheck)
+	}
 
 	maybeApplyEdsConfig(subsetCluster.cluster)
 
@@ -174,6 +193,13 @@ func (cb *ClusterBuilder) applyDestinationRule(mc *MutableCluster, clusterMode C
 		direction:   model.TrafficDirectionOutbound,
 		proxy:       cb.proxy,
 	}
+	if destRule != nil {
+		hc, err := healthcheck.Parse(destRule.Annotations)
+		if err != nil {
+			warnInvalidHealthCheck(destRule, err)
+		}
+		opts.healthCheck = hc
+	}
 
 	if clusterMode == DefaultClusterMode {
 		opts.serviceAccounts = cb.push.ServiceAccounts[service.Hostname][port.Port]
@@ -185,6 +211,9 @@ func (cb *ClusterBuilder) applyDestinationRule(mc *MutableCluster, clusterMode C
 	}
 	// Apply traffic policy for the main default cluster.
 	cb.applyTrafficPolicy(opts)
+	if opts.healthCheck != nil && opts.healthCheck.AppliesToSubset("") {
+		applyHealthCheck(mc.cluster, opts.healthCheck)
+	}
 
 	// Apply EdsConfig if needed. This should be called after traffic policy is applied because, traffic policy might change
 	// discovery type.
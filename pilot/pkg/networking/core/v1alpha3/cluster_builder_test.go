@@ -2366,3 +2366,23 @@ func TestIsHttp2Cluster(t *testing.T) {
 		})
 	}
 }
+
+func TestWarnInvalidHealthCheck(t *testing.T) {
+	destRule := func(name, resourceVersion string) *config.Config {
+		return &config.Config{Meta: config.Meta{Name: name, Namespace: "health", ResourceVersion: resourceVersion}}
+	}
+	for _, dr := range []*config.Config{destRule("a", "1"), destRule("a", "1"), destRule("a", "2"), destRule("b", "1")} {
+		warnInvalidHealthCheck(dr, fmt.Errorf("invalid"))
+	}
+
+	reported := map[string]string{}
+	invalidHealthChecks.Range(func(key, value interface{}) bool {
+		if strings.HasPrefix(key.(string), "health/") {
+			reported[key.(string)] = value.(string)
+		}
+		return true
+	})
+	if want := map[string]string{"health/a": "2", "health/b": "1"}; !reflect.DeepEqual(reported, want) {
+		t.Fatalf("got reported health checks %v, want %v", reported, want)
+	}
+}
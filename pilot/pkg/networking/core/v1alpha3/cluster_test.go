@@ -26,6 +26,7 @@ import (
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	http "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
+	xdstype "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/gogo/protobuf/proto"
 	"github.com/gogo/protobuf/types"
 	structpb "github.com/golang/protobuf/ptypes/struct"
@@ -34,6 +35,7 @@ import (
 	. "github.com/onsi/gomega"
 	"google.golang.org/protobuf/testing/protocmp"
 	"google.golang.org/protobuf/types/known/durationpb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
@@ -46,6 +48,7 @@ import (
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pilot/test/xdstest"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/healthcheck"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -1109,6 +1112,90 @@ func TestApplyOutlierDetection(t *testing.T) {
 	}
 }
 
+func TestApplyHealthCheck(t *testing.T) {
+	base := func(checker *core.HealthCheck) *core.HealthCheck {
+		checker.Timeout = durationpb.New(time.Second)
+		checker.Interval = durationpb.New(5 * time.Second)
+		checker.UnhealthyThreshold = &wrappers.UInt32Value{Value: healthcheck.DefaultUnhealthyThreshold}
+		checker.HealthyThreshold = &wrappers.UInt32Value{Value: healthcheck.DefaultHealthyThreshold}
+		return checker
+	}
+	timing := healthcheck.HealthCheck{
+		Interval: metav1.Duration{Duration: 5 * time.Second},
+		Timeout:  metav1.Duration{Duration: time.Second},
+	}
+
+	tests := []struct {
+		name string
+		cfg  func(hc *healthcheck.HealthCheck)
+		want *core.HealthCheck
+	}{
+		{
+			"HTTP",
+			func(hc *healthcheck.HealthCheck) {
+				hc.HTTP = &healthcheck.HTTP{Path: "/healthz", Host: "example.org", ExpectedStatuses: []int64{200, 204}}
+			},
+			base(&core.HealthCheck{HealthChecker: &core.HealthCheck_HttpHealthCheck_{HttpHealthCheck: &core.HealthCheck_HttpHealthCheck{
+				Path: "/healthz",
+				Host: "example.org",
+				ExpectedStatuses: []*xdstype.Int64Range{
+					{Start: 200, End: 201},
+					{Start: 204, End: 205},
+				},
+			}}}),
+		},
+		{
+			"gRPC with thresholds",
+			func(hc *healthcheck.HealthCheck) {
+				hc.UnhealthyThreshold = 5
+				hc.HealthyThreshold = 2
+				hc.NoTrafficInterval = &metav1.Duration{Duration: time.Minute}
+				hc.GRPC = &healthcheck.GRPC{ServiceName: "reviews"}
+			},
+			func() *core.HealthCheck {
+				hc := base(&core.HealthCheck{HealthChecker: &core.HealthCheck_GrpcHealthCheck_{
+					GrpcHealthCheck: &core.HealthCheck_GrpcHealthCheck{ServiceName: "reviews"},
+				}})
+				hc.UnhealthyThreshold = &wrappers.UInt32Value{Value: 5}
+				hc.HealthyThreshold = &wrappers.UInt32Value{Value: 2}
+				hc.NoTrafficInterval = durationpb.New(time.Minute)
+				return hc
+			}(),
+		},
+		{
+			"TCP",
+			func(hc *healthcheck.HealthCheck) {
+				hc.TCP = &healthcheck.TCP{Send: "ping", Receive: []string{"pong"}}
+			},
+			base(&core.HealthCheck{HealthChecker: &core.HealthCheck_TcpHealthCheck_{TcpHealthCheck: &core.HealthCheck_TcpHealthCheck{
+				Send:    &core.HealthCheck_Payload{Payload: &core.HealthCheck_Payload_Binary{Binary: []byte("ping")}},
+				Receive: []*core.HealthCheck_Payload{{Payload: &core.HealthCheck_Payload_Binary{Binary: []byte("pong")}}},
+			}}}),
+		},
+		{
+			"No checker",
+			func(hc *healthcheck.HealthCheck) {},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := timing
+			tt.cfg(&hc)
+			c := &cluster.Cluster{}
+			applyHealthCheck(c, &hc)
+			var want []*core.HealthCheck
+			if tt.want != nil {
+				want = []*core.HealthCheck{tt.want}
+			}
+			if diff := cmp.Diff(want, c.HealthChecks, protocmp.Transform()); diff != "" {
+				t.Fatalf("unexpected health checks (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestStatNamePattern(t *testing.T) {
 	g := NewWithT(t)
 
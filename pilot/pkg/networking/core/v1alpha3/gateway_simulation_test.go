@@ -1052,7 +1052,7 @@ func runSimulationTest(t *testing.T, proxy *model.Proxy, o xds.FakeOptions, tt s
 		o.KubernetesObjectString = tt.kubeConfig
 		s := xds.NewFakeDiscoveryServer(t, o)
 		sim := simulation.NewSimulation(t, s, s.SetupProxy(proxy))
-		sim.RunExpectations(tt.calls)
+		sim.RunExpectations(t, tt.calls)
 		if t.Failed() && debugMode {
 			t.Log(xdstest.MapKeys(xdstest.ExtractClusters(sim.Clusters)))
 			t.Log(xdstest.ExtractListenerNames(sim.Listeners))
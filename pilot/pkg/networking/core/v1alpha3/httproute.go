@@ -34,6 +34,7 @@ import (
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/proto"
 )
 
@@ -75,6 +76,87 @@ func (configgen *ConfigGeneratorImpl) BuildHTTPRoutes(node *model.Proxy, push *m
 	return routeConfigurations
 }
 
+// BuildDeltaHTTPRoutes returns the routes changed by the updates. Routes are only removed along with their listeners,
+// so no removed routes are returned. Only updates limited to services can be computed incrementally, in which case
+// only the watched routes of the ports the updated services had or now have are built. Otherwise, usedDelta is false
+// and all the watched routes are returned.
+func (configgen *ConfigGeneratorImpl) BuildDeltaHTTPRoutes(node *model.Proxy, push *model.PushContext,
+	updates *model.PushRequest, watched *model.WatchedResource) ([]*route.RouteConfiguration, bool) {
+	if !canBuildDeltaHTTPRoutes(node, updates, watched) {
+		return configgen.BuildHTTPRoutes(node, push, watched.ResourceNames), false
+	}
+	updatedServices := model.ConfigNamesOfKind(updates.ConfigsUpdated, gvk.ServiceEntry)
+
+	// The virtual hosts of a service are built on each of its ports, so the routes of the ports the service had
+	// before the update and of the ports it has now are affected.
+	ports := map[int]struct{}{}
+	for _, sc := range []*model.SidecarScope{node.PrevSidecarScope, node.SidecarScope} {
+		if virtualServicesRouteTo(sc, updatedServices) {
+			// The routes of the virtual services depend on the ports of their destinations, on any listener port.
+			return configgen.BuildHTTPRoutes(node, push, watched.ResourceNames), false
+		}
+		for _, svc := range sc.Services() {
+			if _, f := updatedServices[string(svc.Hostname)]; !f {
+				continue
+			}
+			for _, port := range svc.Ports {
+				ports[port.Port] = struct{}{}
+			}
+		}
+	}
+
+	var routeNames []string
+	for _, name := range watched.ResourceNames {
+		port, err := strconv.Atoi(name[strings.LastIndex(name, ":")+1:])
+		if err != nil {
+			// The http_proxy and unix domain socket routes hold the services of all the ports.
+			routeNames = append(routeNames, name)
+			continue
+		}
+		if _, f := ports[port]; f {
+			routeNames = append(routeNames, name)
+		}
+	}
+	return configgen.BuildHTTPRoutes(node, push, routeNames), true
+}
+
+// canBuildDeltaHTTPRoutes returns true if the routes changed by the updates can be computed incrementally.
+func canBuildDeltaHTTPRoutes(node *model.Proxy, updates *model.PushRequest, watched *model.WatchedResource) bool {
+	// Gateway routes are built from the servers of their gateways rather than by port.
+	if node.Type != model.SidecarProxy || node.PrevSidecarScope == nil {
+		return false
+	}
+	if updates == nil || !updates.Full || len(updates.ConfigsUpdated) == 0 || len(watched.ResourceNames) == 0 {
+		return false
+	}
+	for key := range updates.ConfigsUpdated {
+		if key.Kind != gvk.ServiceEntry {
+			return false
+		}
+	}
+	return true
+}
+
+// virtualServicesRouteTo returns true if an HTTP route of the virtual services of the sidecar scope has one of the
+// hosts as destination.
+func virtualServicesRouteTo(sc *model.SidecarScope, hosts map[string]struct{}) bool {
+	for _, el := range sc.EgressListeners {
+		for _, vs := range el.VirtualServices() {
+			for _, h := range vs.Spec.(*networking.VirtualService).Http {
+				for _, r := range h.Route {
+					if _, f := hosts[r.GetDestination().GetHost()]; f {
+						return true
+					}
+				}
+				if _, f := hosts[h.GetMirror().GetHost()]; f {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 // buildSidecarInboundHTTPRouteConfig builds the route config with a single wildcard virtual host on the inbound path
 // TODO: trace decorators, inbound timeouts
 func (configgen *ConfigGeneratorImpl) buildSidecarInboundHTTPRouteConfig(
@@ -351,6 +351,106 @@ func TestSidecarOutboundHTTPRouteConfigWithDuplicateHosts(t *testing.T) {
 	}
 }
 
+func TestBuildDeltaHTTPRoutes(t *testing.T) {
+	services := []*model.Service{
+		buildHTTPService("a.local", visibility.Public, "", "default", 80),
+		buildHTTPService("b.local", visibility.Public, "", "default", 8080),
+	}
+	routeToB := config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.VirtualService,
+			Name:             "route-to-b",
+			Namespace:        "default",
+		},
+		Spec: &networking.VirtualService{
+			Hosts: []string{"c.local"},
+			Http: []*networking.HTTPRoute{{
+				Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "b.local"}}},
+			}},
+		},
+	}
+	serviceUpdate := func(hostname string) *model.PushRequest {
+		return &model.PushRequest{Full: true, ConfigsUpdated: map[model.ConfigKey]struct{}{
+			{Kind: gvk.ServiceEntry, Name: hostname, Namespace: "default"}: {},
+		}}
+	}
+	cases := []struct {
+		name          string
+		configs       []config.Config
+		updates       *model.PushRequest
+		wantRoutes    []string
+		wantUsedDelta bool
+	}{
+		{
+			name:          "routes of the ports of the updated service",
+			updates:       serviceUpdate("b.local"),
+			wantRoutes:    []string{"8080", "http_proxy"},
+			wantUsedDelta: true,
+		},
+		{
+			name:          "service not visible to the proxy",
+			updates:       serviceUpdate("unknown.local"),
+			wantRoutes:    []string{"http_proxy"},
+			wantUsedDelta: true,
+		},
+		{
+			name: "other config updated",
+			updates: &model.PushRequest{Full: true, ConfigsUpdated: map[model.ConfigKey]struct{}{
+				{Kind: gvk.VirtualService, Name: "vs", Namespace: "default"}: {},
+			}},
+			wantRoutes: []string{"80", "8080", "9090", "http_proxy"},
+		},
+		{
+			name:       "virtual service routing to the updated service",
+			configs:    []config.Config{routeToB},
+			updates:    serviceUpdate("b.local"),
+			wantRoutes: []string{"80", "8080", "9090", "http_proxy"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cg := NewConfigGenTest(t, TestOptions{
+				Services: services,
+				Configs:  tt.configs,
+			})
+			proxy := cg.SetupProxy(nil)
+			proxy.SetSidecarScope(cg.PushContext())
+			watched := &model.WatchedResource{ResourceNames: []string{"80", "8080", "9090", "http_proxy"}}
+
+			routes, usedDelta := cg.ConfigGen.BuildDeltaHTTPRoutes(proxy, cg.PushContext(), tt.updates, watched)
+			var got []string
+			for _, r := range routes {
+				got = append(got, r.Name)
+			}
+			if !reflect.DeepEqual(got, tt.wantRoutes) {
+				t.Errorf("got routes %v, want %v", got, tt.wantRoutes)
+			}
+			if usedDelta != tt.wantUsedDelta {
+				t.Errorf("got usedDelta %v, want %v", usedDelta, tt.wantUsedDelta)
+			}
+		})
+	}
+
+	t.Run("routes of the ports the updated service had", func(t *testing.T) {
+		before := NewConfigGenTest(t, TestOptions{
+			Services: []*model.Service{buildHTTPService("b.local", visibility.Public, "", "default", 9090)},
+		})
+		cg := NewConfigGenTest(t, TestOptions{Services: services})
+		proxy := before.SetupProxy(nil)
+		proxy.SetSidecarScope(cg.PushContext())
+		watched := &model.WatchedResource{ResourceNames: []string{"80", "8080", "9090"}}
+
+		routes, usedDelta := cg.ConfigGen.BuildDeltaHTTPRoutes(proxy, cg.PushContext(), serviceUpdate("b.local"), watched)
+		var got []string
+		for _, r := range routes {
+			got = append(got, r.Name)
+		}
+		if want := []string{"8080", "9090"}; !reflect.DeepEqual(got, want) || !usedDelta {
+			t.Errorf("got routes %v with usedDelta %v, want %v incrementally", got, usedDelta, want)
+		}
+	})
+}
+
 func TestSidecarOutboundHTTPRouteConfig(t *testing.T) {
 	services := []*model.Service{
 		buildHTTPService("bookinfo.com", visibility.Public, wildcardIP, "default", 9999, 70),
@@ -0,0 +1,252 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	udpa "github.com/cncf/udpa/go/udpa/type/v1"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	httpwasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	networkwasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/wasm/v3"
+	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/wasm/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/conversion"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/telemetry"
+	"istio.io/pkg/log"
+)
+
+const (
+	// statsFilterName is the name of the HTTP and network filters of the stats extension.
+	statsFilterName = "istio.stats"
+
+	// The root IDs of the stats extension, reporting the metrics as server and as client.
+	statsInboundRootID  = "stats_inbound"
+	statsOutboundRootID = "stats_outbound"
+
+	typedStructTypeURL = "type.googleapis.com/udpa.type.v1.TypedStruct"
+	httpWasmTypeURL    = "type.googleapis.com/envoy.extensions.filters.http.wasm.v3.Wasm"
+	networkWasmTypeURL = "type.googleapis.com/envoy.extensions.filters.network.wasm.v3.Wasm"
+)
+
+// wasmFilter is implemented by the configs of the HTTP and network Wasm filters.
+type wasmFilter interface {
+	proto.Message
+	GetConfig() *wasm.PluginConfig
+}
+
+// applyMetricsOverrides customizes the stats filters of the listeners with the metrics overrides of the Telemetry
+// resources applying to the proxy. The stats filters are installed by the telemetry EnvoyFilters, so this runs after
+// the EnvoyFilter patches; proxies without stats filters are not affected.
+func applyMetricsOverrides(push *model.PushContext, proxy *model.Proxy, listeners []*listener.Listener) {
+	if proxy.Metadata == nil {
+		return
+	}
+	metrics := push.Telemetry.EffectiveMetrics(proxy.ConfigNamespace, labels.Collection{proxy.Metadata.Labels})
+	if metrics == nil || len(metrics.Overrides) == 0 {
+		return
+	}
+
+	// Filter chains may be shared by several listeners, make sure the overrides are only applied once.
+	customized := map[*listener.Filter]struct{}{}
+	for _, l := range listeners {
+		for _, fc := range l.FilterChains {
+			applyFilterChainMetricsOverrides(fc, metrics, customized)
+		}
+		if l.DefaultFilterChain != nil {
+			applyFilterChainMetricsOverrides(l.DefaultFilterChain, metrics, customized)
+		}
+	}
+}
+
+func applyFilterChainMetricsOverrides(fc *listener.FilterChain, metrics *telemetry.Metrics,
+	customized map[*listener.Filter]struct{}) {
+	for _, filter := range fc.Filters {
+		if _, f := customized[filter]; f {
+			continue
+		}
+		customized[filter] = struct{}{}
+
+		switch filter.Name {
+		case statsFilterName:
+			if cfg := customizeStatsFilter(filter.GetTypedConfig(), metrics); cfg != nil {
+				filter.ConfigType = &listener.Filter_TypedConfig{TypedConfig: cfg}
+			}
+		case wellknown.HTTPConnectionManager:
+			httpconn := &hcm.HttpConnectionManager{}
+			if filter.GetTypedConfig() == nil || filter.GetTypedConfig().UnmarshalTo(httpconn) != nil {
+				continue
+			}
+			changed := false
+			for _, httpFilter := range httpconn.HttpFilters {
+				if httpFilter.Name != statsFilterName {
+					continue
+				}
+				if cfg := customizeStatsFilter(httpFilter.GetTypedConfig(), metrics); cfg != nil {
+					httpFilter.ConfigType = &hcm.HttpFilter_TypedConfig{TypedConfig: cfg}
+					changed = true
+				}
+			}
+			if changed {
+				filter.ConfigType = &listener.Filter_TypedConfig{TypedConfig: util.MessageToAny(httpconn)}
+			}
+		}
+	}
+}
+
+// customizeStatsFilter returns the config of the stats filter with the metrics overrides, or nil if the config can
+// not be customized. The stats filter is either a Wasm filter, or a TypedStruct holding one as installed by the
+// telemetry EnvoyFilters; the customized config is always a Wasm filter.
+func customizeStatsFilter(cfg *anypb.Any, metrics *telemetry.Metrics) *anypb.Any {
+	filter, err := statsWasmFilter(cfg)
+	if err != nil {
+		log.Debugf("not applying metrics overrides to stats filter: %v", err)
+		return nil
+	}
+
+	var mode string
+	switch filter.GetConfig().GetRootId() {
+	case statsInboundRootID:
+		mode = telemetry.ServerMode
+	case statsOutboundRootID:
+		mode = telemetry.ClientMode
+	default:
+		log.Debugf("not applying metrics overrides to stats filter with unknown root ID %q", filter.GetConfig().GetRootId())
+		return nil
+	}
+	if !metrics.AppliesToMode(mode) {
+		return nil
+	}
+
+	configuration := &wrapperspb.StringValue{}
+	if c := filter.GetConfig().GetConfiguration(); c != nil {
+		if err := c.UnmarshalTo(configuration); err != nil {
+			log.Debugf("not applying metrics overrides to stats filter: %v", err)
+			return nil
+		}
+	}
+	customized, err := overrideStatsConfig(configuration.Value, metrics, mode)
+	if err != nil {
+		log.Debugf("not applying metrics overrides to stats filter: %v", err)
+		return nil
+	}
+	filter.GetConfig().Configuration = util.MessageToAny(wrapperspb.String(customized))
+	return util.MessageToAny(filter)
+}
+
+func statsWasmFilter(cfg *anypb.Any) (wasmFilter, error) {
+	newFilter := func(typeURL string) wasmFilter {
+		switch typeURL {
+		case httpWasmTypeURL:
+			return &httpwasm.Wasm{}
+		case networkWasmTypeURL:
+			return &networkwasm.Wasm{}
+		}
+		return nil
+	}
+
+	if cfg.GetTypeUrl() == typedStructTypeURL {
+		ts := &udpa.TypedStruct{}
+		if err := ptypes.UnmarshalAny(cfg, ts); err != nil {
+			return nil, err
+		}
+		filter := newFilter(ts.TypeUrl)
+		if filter == nil {
+			return nil, fmt.Errorf("unexpected type %s", ts.TypeUrl)
+		}
+		if err := conversion.StructToMessage(ts.Value, filter); err != nil {
+			return nil, err
+		}
+		return filter, nil
+	}
+
+	filter := newFilter(cfg.GetTypeUrl())
+	if filter == nil {
+		return nil, fmt.Errorf("unexpected type %s", cfg.GetTypeUrl())
+	}
+	if err := ptypes.UnmarshalAny(cfg, filter); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// overrideStatsConfig appends the metrics overrides applying to mode to the metric definitions of the stats config,
+// keeping the other settings of the config. The later metric definitions take precedence in the stats extension.
+func overrideStatsConfig(config string, metrics *telemetry.Metrics, mode string) (string, error) {
+	statsConfig := map[string]interface{}{}
+	if config != "" {
+		decoder := json.NewDecoder(bytes.NewBufferString(config))
+		decoder.UseNumber()
+		if err := decoder.Decode(&statsConfig); err != nil {
+			return "", fmt.Errorf("invalid stats config: %v", err)
+		}
+	}
+
+	definitions, _ := statsConfig["metrics"].([]interface{})
+	for i := range metrics.Overrides {
+		o := &metrics.Overrides[i]
+		if !o.AppliesToMode(mode) {
+			continue
+		}
+		definitions = append(definitions, statsMetricDefinition(o))
+	}
+	statsConfig["metrics"] = definitions
+
+	out, err := json.Marshal(statsConfig)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// statsMetricDefinition translates an override into a metric definition of the stats extension.
+func statsMetricDefinition(o *telemetry.MetricsOverride) map[string]interface{} {
+	definition := map[string]interface{}{}
+	if name := o.Match.MetricName(); name != "" {
+		definition["name"] = name
+	}
+	dimensions := map[string]string{}
+	var tagsToRemove []string
+	for tag, to := range o.TagOverrides {
+		if to.Operation == telemetry.RemoveTagOperation {
+			tagsToRemove = append(tagsToRemove, tag)
+		} else {
+			dimensions[tag] = to.Value
+		}
+	}
+	if len(dimensions) != 0 {
+		definition["dimensions"] = dimensions
+	}
+	if len(tagsToRemove) != 0 {
+		sort.Strings(tagsToRemove)
+		definition["tags_to_remove"] = tagsToRemove
+	}
+	if o.IsDisabled() {
+		definition["drop"] = true
+	}
+	return definition
+}
@@ -112,6 +112,7 @@ func (configgen *ConfigGeneratorImpl) BuildNameTable(node *model.Proxy, push *mo
 		nameInfo := &nds.NameTable_NameInfo{
 			Ips:      addressList,
 			Registry: svc.Attributes.ServiceRegistry,
+			Ports:    nameTablePorts(svc),
 		}
 		if svc.Attributes.ServiceRegistry == string(serviceregistry.Kubernetes) {
 			// The agent will take care of resolving a, a.ns, a.ns.svc, etc.
@@ -123,3 +124,18 @@ func (configgen *ConfigGeneratorImpl) BuildNameTable(node *model.Proxy, push *mo
 	}
 	return out
 }
+
+// nameTablePorts returns the named ports of the service, used by the agent to answer SRV queries.
+func nameTablePorts(svc *model.Service) map[string]uint32 {
+	var out map[string]uint32
+	for _, p := range svc.Ports {
+		if p.Name == "" {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]uint32, len(svc.Ports))
+		}
+		out[p.Name] = uint32(p.Port)
+	}
+	return out
+}
@@ -167,6 +167,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     map[string]uint32{"tcp-port": 9000},
 					},
 				},
 			},
@@ -182,6 +183,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "wildcard-svc",
 						Namespace: "testns",
+						Ports:     map[string]uint32{"http-port": 8000, "tcp-port": 9000},
 					},
 				},
 			},
@@ -0,0 +1,116 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package route
+
+import (
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	localratelimit "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/local_ratelimit/v3"
+	xdstype "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/ratelimit"
+)
+
+// RateLimitRouteDescriptorKey is the key of the descriptor sent to the rate limit service when a global policy does
+// not set descriptors. Its value identifies the virtual service and the route.
+const RateLimitRouteDescriptorKey = "route"
+
+// translateRateLimits applies the rate limit policies of the virtual service to the route. If several local
+// policies apply to the route, the first one is used. All the global policies are applied, at the stage of their
+// rate limit provider. Global policies whose provider does not exist are ignored.
+func translateRateLimits(out *route.Route, push *model.PushContext, policies []*ratelimit.Policy, routeName string,
+	virtualService config.Config) {
+	for _, p := range policies {
+		if !p.AppliesToRoute(routeName) {
+			continue
+		}
+		if p.Local != nil {
+			if _, f := out.TypedPerFilterConfig[xdsfilters.LocalRateLimitFilterName]; !f {
+				if out.TypedPerFilterConfig == nil {
+					out.TypedPerFilterConfig = make(map[string]*any.Any)
+				}
+				out.TypedPerFilterConfig[xdsfilters.LocalRateLimitFilterName] = util.MessageToAny(translateLocalRateLimit(p.Local))
+			}
+		}
+		// Global rate limits are route actions, so they do not apply to redirects.
+		if action := out.GetRoute(); p.Global != nil && action != nil {
+			provider := push.EnvoyRateLimitProvider(p.Global.Provider)
+			if provider == nil {
+				continue
+			}
+			rateLimit := translateGlobalRateLimit(p.Global, routeName, virtualService)
+			rateLimit.Stage = &wrappers.UInt32Value{Value: provider.Stage}
+			action.RateLimits = append(action.RateLimits, rateLimit)
+		}
+	}
+}
+
+func translateLocalRateLimit(in *ratelimit.Local) *localratelimit.LocalRateLimit {
+	enabled := &core.RuntimeFractionalPercent{
+		DefaultValue: &xdstype.FractionalPercent{Numerator: 100, Denominator: xdstype.FractionalPercent_HUNDRED},
+	}
+	return &localratelimit.LocalRateLimit{
+		StatPrefix: xdsfilters.LocalRateLimitStatPrefix,
+		TokenBucket: &xdstype.TokenBucket{
+			MaxTokens:     in.MaxTokens,
+			TokensPerFill: &wrappers.UInt32Value{Value: in.GetTokensPerFill()},
+			FillInterval:  durationpb.New(in.FillInterval.Duration),
+		},
+		FilterEnabled:  enabled,
+		FilterEnforced: enabled,
+	}
+}
+
+func translateGlobalRateLimit(in *ratelimit.Global, routeName string, virtualService config.Config) *route.RateLimit {
+	out := &route.RateLimit{}
+	if len(in.Descriptors) == 0 {
+		value := virtualService.Namespace + "/" + virtualService.Name
+		if routeName != "" {
+			value += "/" + routeName
+		}
+		out.Actions = append(out.Actions, &route.RateLimit_Action{
+			ActionSpecifier: &route.RateLimit_Action_GenericKey_{GenericKey: &route.RateLimit_Action_GenericKey{
+				DescriptorKey:   RateLimitRouteDescriptorKey,
+				DescriptorValue: value,
+			}},
+		})
+		return out
+	}
+	for _, d := range in.Descriptors {
+		if d.Header != "" {
+			out.Actions = append(out.Actions, &route.RateLimit_Action{
+				ActionSpecifier: &route.RateLimit_Action_RequestHeaders_{RequestHeaders: &route.RateLimit_Action_RequestHeaders{
+					HeaderName:    d.Header,
+					DescriptorKey: d.Key,
+				}},
+			})
+		} else {
+			out.Actions = append(out.Actions, &route.RateLimit_Action{
+				ActionSpecifier: &route.RateLimit_Action_GenericKey_{GenericKey: &route.RateLimit_Action_GenericKey{
+					DescriptorKey:   d.Key,
+					DescriptorValue: d.Value,
+				}},
+			})
+		}
+	}
+	return out
+}
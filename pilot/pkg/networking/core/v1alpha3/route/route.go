@@ -493,6 +493,9 @@ func translateRoute(push *model.PushContext, node *model.Proxy, in *networking.H
 		out.TypedPerFilterConfig = make(map[string]*any.Any)
 		out.TypedPerFilterConfig[wellknown.Fault] = util.MessageToAny(translateFault(in.Fault))
 	}
+	if push != nil {
+		translateRateLimits(out, push, push.RateLimitPolicies(node, virtualService), in.Name, virtualService)
+	}
 
 	return out
 }
@@ -24,7 +24,9 @@ import (
 
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	httpwasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
 	"github.com/golang/protobuf/jsonpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
@@ -1309,3 +1311,244 @@ func TestLoop(t *testing.T) {
 		},
 	})
 }
+
+func TestDestinationRuleHealthCheck(t *testing.T) {
+	config := `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+spec:
+  hosts:
+  - reviews.example.com
+  addresses:
+  - 1.2.3.4
+  location: MESH_INTERNAL
+  resolution: STATIC
+  endpoints:
+  - address: 10.0.0.1
+    labels:
+      version: v1
+  - address: 10.0.0.2
+    labels:
+      version: v2
+  ports:
+  - name: http
+    number: 80
+    protocol: HTTP
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: reviews
+  annotations:
+    networking.istio.io/healthCheck: |
+      subsets: [v1]
+      interval: 5s
+      timeout: 1s
+      unhealthyThreshold: 2
+      http:
+        path: /healthz
+        expectedStatuses: [200]
+spec:
+  host: reviews.example.com
+  subsets:
+  - name: v1
+    labels:
+      version: v1
+  - name: v2
+    labels:
+      version: v2
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+spec:
+  hosts:
+  - reviews.example.com
+  http:
+  - route:
+    - destination:
+        host: reviews.example.com
+        subset: v1
+`
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: config})
+	sim := simulation.NewSimulation(t, s, s.SetupProxy(nil))
+	sim.RunExpectations(t, []simulation.Expect{{
+		Name: "routed to the checked subset",
+		Call: simulation.Call{
+			Address:    "1.2.3.4",
+			Port:       80,
+			Protocol:   simulation.HTTP,
+			HostHeader: "reviews.example.com",
+		},
+		Result: simulation.Result{
+			ClusterMatched: "outbound|80|v1|reviews.example.com",
+		},
+	}})
+	xdstest.ValidateClusters(t, sim.Clusters)
+
+	clusters := xdstest.ExtractClusters(sim.Clusters)
+	checked := clusters["outbound|80|v1|reviews.example.com"]
+	if checked == nil {
+		t.Fatalf("cluster of subset v1 not found in %v", xdstest.MapKeys(clusters))
+	}
+	if len(checked.HealthChecks) != 1 {
+		t.Fatalf("expected one health check, got %v", checked.HealthChecks)
+	}
+	hc := checked.HealthChecks[0]
+	if got := hc.GetHttpHealthCheck().GetPath(); got != "/healthz" {
+		t.Errorf("expected path /healthz, got %q", got)
+	}
+	if got := hc.GetUnhealthyThreshold().GetValue(); got != 2 {
+		t.Errorf("expected unhealthy threshold 2, got %d", got)
+	}
+	for _, name := range []string{"outbound|80||reviews.example.com", "outbound|80|v2|reviews.example.com"} {
+		if c := clusters[name]; c == nil || len(c.HealthChecks) != 0 {
+			t.Errorf("expected cluster %s without health checks, got %v", name, c)
+		}
+	}
+}
+
+func TestTelemetryMetricsOverrides(t *testing.T) {
+	config := `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+spec:
+  hosts:
+  - reviews.example.com
+  addresses:
+  - 1.2.3.4
+  location: MESH_INTERNAL
+  resolution: STATIC
+  endpoints:
+  - address: 10.0.0.1
+  ports:
+  - name: http
+    number: 80
+    protocol: HTTP
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: EnvoyFilter
+metadata:
+  name: stats-filter
+  namespace: istio-system
+spec:
+  configPatches:
+  - applyTo: HTTP_FILTER
+    match:
+      context: SIDECAR_OUTBOUND
+      listener:
+        filterChain:
+          filter:
+            name: envoy.filters.network.http_connection_manager
+            subFilter:
+              name: envoy.filters.http.router
+    patch:
+      operation: INSERT_BEFORE
+      value:
+        name: istio.stats
+        typed_config:
+          "@type": type.googleapis.com/udpa.type.v1.TypedStruct
+          type_url: type.googleapis.com/envoy.extensions.filters.http.wasm.v3.Wasm
+          value:
+            config:
+              root_id: stats_outbound
+              configuration:
+                "@type": type.googleapis.com/google.protobuf.StringValue
+                value: |
+                  {"debug": "false", "stat_prefix": "istio"}
+              vm_config:
+                vm_id: stats_outbound
+                runtime: envoy.wasm.runtime.null
+                code:
+                  local:
+                    inline_string: envoy.wasm.stats
+---
+apiVersion: telemetry.istio.io/v1alpha1
+kind: Telemetry
+metadata:
+  name: default
+  namespace: istio-system
+  annotations:
+    telemetry.istio.io/metrics: |
+      overrides:
+      - match:
+          metric: REQUEST_COUNT
+        tagOverrides:
+          request_host:
+            value: request.host
+          response_flags:
+            operation: REMOVE
+      - match:
+          metric: REQUEST_DURATION
+          mode: SERVER
+        disabled: true
+spec: {}
+---
+apiVersion: telemetry.istio.io/v1alpha1
+kind: Telemetry
+metadata:
+  name: quiet
+  namespace: default
+  annotations:
+    telemetry.istio.io/metrics: |
+      overrides:
+      - match:
+          metric: REQUEST_SIZE
+          mode: CLIENT
+        disabled: true
+spec:
+  selector:
+    matchLabels:
+      app: quiet
+`
+	statsConfig := func(t *testing.T, labels map[string]string) string {
+		t.Helper()
+		s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: config})
+		sim := simulation.NewSimulation(t, s, s.SetupProxy(&model.Proxy{Metadata: &model.NodeMetadata{Labels: labels}}))
+		l := xdstest.ExtractListener("0.0.0.0_80", sim.Listeners)
+		if l == nil {
+			t.Fatalf("listener 0.0.0.0_80 not found in %v", xdstest.ExtractListenerNames(sim.Listeners))
+		}
+		for _, fc := range l.FilterChains {
+			hcm := xdstest.ExtractHTTPConnectionManager(t, fc)
+			if hcm == nil {
+				continue
+			}
+			for _, f := range hcm.HttpFilters {
+				if f.Name != "istio.stats" {
+					continue
+				}
+				filter := &httpwasm.Wasm{}
+				if err := f.GetTypedConfig().UnmarshalTo(filter); err != nil {
+					t.Fatalf("stats filter is not customized: %v", err)
+				}
+				configuration := &wrapperspb.StringValue{}
+				if err := filter.GetConfig().GetConfiguration().UnmarshalTo(configuration); err != nil {
+					t.Fatal(err)
+				}
+				return configuration.Value
+			}
+		}
+		t.Fatalf("stats filter not found")
+		return ""
+	}
+
+	// Only the client overrides apply to the outbound stats filter.
+	want := `{"debug":"false","metrics":[{"dimensions":{"request_host":"request.host"},"name":"requests_total",` +
+		`"tags_to_remove":["response_flags"]}],"stat_prefix":"istio"}`
+	if got := statsConfig(t, map[string]string{"app": "reviews"}); got != want {
+		t.Errorf("unexpected stats config, want %s got %s", want, got)
+	}
+
+	// The overrides of the Telemetry selecting the workload are applied after the ones of the root namespace.
+	want = `{"debug":"false","metrics":[{"dimensions":{"request_host":"request.host"},"name":"requests_total",` +
+		`"tags_to_remove":["response_flags"]},{"drop":true,"name":"request_bytes"}],"stat_prefix":"istio"}`
+	if got := statsConfig(t, map[string]string{"app": "quiet"}); got != want {
+		t.Errorf("unexpected stats config, want %s got %s", want, got)
+	}
+}
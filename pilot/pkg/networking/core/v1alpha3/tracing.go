@@ -38,6 +38,7 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	authz_model "istio.io/istio/pilot/pkg/security/authz/model"
 	"istio.io/istio/pkg/bootstrap/platform"
+	"istio.io/istio/pkg/config/extensionprovider"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/pkg/log"
 )
@@ -100,6 +101,16 @@ func configureTracingFromSpec(spec *telemetrypb.Telemetry, opts buildListenerOpt
 		}
 	}
 
+	if p := opts.push.OpenTelemetryProvider(providerName); p != nil && !providerConfigured {
+		tcfg, err := configureFromOpenTelemetryProvider(providerName, p)
+		if err != nil {
+			log.Warnf("Not able to configure requested tracing provider %q: %v", providerName, err)
+		} else {
+			hcm.Tracing = tcfg
+			providerConfigured = true
+		}
+	}
+
 	if !providerConfigured {
 		log.Debug("No provider was configured for tracing")
 		hcm.Tracing = &hpb.HttpConnectionManager_Tracing{}
@@ -240,6 +251,24 @@ func configureFromProviderConfig(pushCtx *model.PushContext, meta *model.NodeMet
 	return &hpb.HttpConnectionManager_Tracing{}, nil
 }
 
+// configureFromOpenTelemetryProvider sends the spans to the OpenCensus receiver of an OpenTelemetry collector, as
+// the proxies have no OpenTelemetry tracer. The W3C trace context used by OpenTelemetry is propagated along with B3.
+func configureFromOpenTelemetryProvider(name string, provider *extensionprovider.OpenTelemetryProvider) (*hpb.HttpConnectionManager_Tracing, error) {
+	return buildHCMTracingOpenCensus(name, 0, func() (*anypb.Any, error) {
+		svc := provider.Service
+		if i := strings.Index(svc, "/"); i >= 0 {
+			svc = svc[i+1:]
+		}
+		oc := &tracingcfg.OpenCensusConfig{
+			OcagentAddress:         fmt.Sprintf("%s:%d", svc, provider.GetTracingPort()),
+			OcagentExporterEnabled: true,
+			IncomingTraceContext:   openTelemetryContexts,
+			OutgoingTraceContext:   openTelemetryContexts,
+		}
+		return anypb.New(oc)
+	})
+}
+
 type typedConfigGenFromClusterFn func(clusterName string) (*anypb.Any, error)
 
 func zipkinConfigGen(cluster string) (*anypb.Any, error) {
@@ -305,6 +334,11 @@ func buildHCMTracingOpenCensus(provider string, maxTagLen uint32, anyFn typedCon
 	return config, nil
 }
 
+var openTelemetryContexts = []tracingcfg.OpenCensusConfig_TraceContext{
+	tracingcfg.OpenCensusConfig_TRACE_CONTEXT,
+	tracingcfg.OpenCensusConfig_B3,
+}
+
 var allContexts = []tracingcfg.OpenCensusConfig_TraceContext{
 	tracingcfg.OpenCensusConfig_B3,
 	tracingcfg.OpenCensusConfig_CLOUD_TRACE_CONTEXT,
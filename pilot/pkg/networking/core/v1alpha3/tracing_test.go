@@ -32,6 +32,7 @@ import (
 	tpb "istio.io/api/telemetry/v1alpha1"
 	"istio.io/istio/pilot/pkg/extensionproviders"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/extensionprovider"
 )
 
 func TestConfigureTracing(t *testing.T) {
@@ -246,6 +247,48 @@ func fakeOptsMeshAndTelemetryAPI(enableTracing bool) buildListenerOpts {
 	return opts
 }
 
+func TestConfigureOpenTelemetryTracing(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{
+		MeshExtensionProviders: []*extensionprovider.ExtensionProvider{
+			{
+				Name: "otel",
+				OpenTelemetry: &extensionprovider.OpenTelemetryProvider{
+					Service: "istio-system/otel-collector.istio-system.svc.cluster.local",
+					Port:    4317,
+				},
+			},
+		},
+	})
+	var opts buildListenerOpts
+	opts.push = cg.PushContext()
+	opts.proxy = &model.Proxy{
+		Metadata: &model.NodeMetadata{
+			ProxyConfig: &model.NodeMetaProxyConfig{},
+		},
+	}
+
+	hcm := &hpb.HttpConnectionManager{}
+	configureTracingFromSpec(fakeTracingSpec(fakeProviders([]string{"otel"}), 99.999, false), opts, hcm)
+
+	// The spans are sent with the OpenCensus tracer to the OpenCensus receiver of the collector.
+	oc := &tracingcfg.OpenCensusConfig{}
+	if err := hcm.GetTracing().GetProvider().GetTypedConfig().UnmarshalTo(oc); err != nil {
+		t.Fatalf("expected an OpenCensus tracer, got %v: %v", hcm.GetTracing().GetProvider(), err)
+	}
+	want := &tracingcfg.OpenCensusConfig{
+		OcagentAddress:         "otel-collector.istio-system.svc.cluster.local:55678",
+		OcagentExporterEnabled: true,
+		IncomingTraceContext:   []tracingcfg.OpenCensusConfig_TraceContext{tracingcfg.OpenCensusConfig_TRACE_CONTEXT, tracingcfg.OpenCensusConfig_B3},
+		OutgoingTraceContext:   []tracingcfg.OpenCensusConfig_TraceContext{tracingcfg.OpenCensusConfig_TRACE_CONTEXT, tracingcfg.OpenCensusConfig_B3},
+	}
+	if diff := cmp.Diff(want, oc, protocmp.Transform()); diff != "" {
+		t.Fatalf("unexpected OpenCensus tracer (-want +got):\n%s", diff)
+	}
+	if hcm.GetTracing().GetRandomSampling().GetValue() != 99.999 {
+		t.Fatalf("unexpected sampling %v", hcm.GetTracing().GetRandomSampling())
+	}
+}
+
 func fakeOptsOnlySkywalkingTelemetryAPI() buildListenerOpts {
 	var opts buildListenerOpts
 	opts.push = &model.PushContext{
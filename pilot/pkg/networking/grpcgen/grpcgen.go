@@ -23,8 +23,10 @@ import (
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	router "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
@@ -49,6 +51,11 @@ import (
 // using the generic structures. "Classical" CDS/LDS/RDS/EDS use separate logic -
 // this is used for the API-based LDS and generic messages.
 
+// ServerListenerNamePrefix is the prefix of the inbound listeners requested by proxyless gRPC servers. The gRPC
+// bootstrap should set server_listener_resource_name_template to ServerListenerNamePrefix + "%s", which gRPC
+// expands to the listening ip:port.
+const ServerListenerNamePrefix = "xds.istio.io/grpc/lds/inbound/"
+
 type GrpcConfigGenerator struct{}
 
 func (g *GrpcConfigGenerator) Generate(proxy *model.Proxy, push *model.PushContext,
@@ -73,6 +80,12 @@ func (g *GrpcConfigGenerator) BuildListeners(node *model.Proxy, push *model.Push
 
 	filter := map[string]bool{}
 	for _, name := range names {
+		if strings.HasPrefix(name, ServerListenerNamePrefix) {
+			if l := buildInboundListener(node, push, name); l != nil {
+				resp = append(resp, l)
+			}
+			continue
+		}
 		if strings.Contains(name, ":") {
 			n, _, err := net.SplitHostPort(name)
 			if err == nil {
@@ -82,6 +95,11 @@ func (g *GrpcConfigGenerator) BuildListeners(node *model.Proxy, push *model.Push
 		filter[name] = true
 	}
 
+	if len(names) > 0 && len(filter) == 0 {
+		// Only inbound listeners were requested
+		return resp
+	}
+
 	for _, el := range node.SidecarScope.EgressListeners {
 		for _, sv := range el.Services() {
 			shost := string(sv.Hostname)
@@ -147,6 +165,11 @@ func (g *GrpcConfigGenerator) BuildClusters(node *model.Proxy, push *model.PushC
 			log.Warn("Failed to parse ", n, " ", err)
 			continue
 		}
+		port, err := strconv.Atoi(portn)
+		if err != nil {
+			log.Warn("Failed to parse port ", n, " ", err)
+			continue
+		}
 		rc := &cluster.Cluster{
 			Name:                 n,
 			ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
@@ -158,6 +181,7 @@ func (g *GrpcConfigGenerator) BuildClusters(node *model.Proxy, push *model.PushC
 					},
 				},
 			},
+			TransportSocket: buildUpstreamTransportSocket(node, push, hn, port),
 		}
 		resp = append(resp, &discovery.Resource{
 			Name:     n,
@@ -167,6 +191,78 @@ func (g *GrpcConfigGenerator) BuildClusters(node *model.Proxy, push *model.PushC
 	return resp
 }
 
+// buildInboundListener builds the listener for a proxyless gRPC server. The name is ServerListenerNamePrefix
+// followed by the ip:port the server is listening on.
+func buildInboundListener(node *model.Proxy, push *model.PushContext, name string) *discovery.Resource {
+	hostPort := strings.TrimPrefix(name, ServerListenerNamePrefix)
+	ip, portn, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		log.Warn("Failed to parse ", name, " ", err)
+		return nil
+	}
+	port, err := strconv.Atoi(portn)
+	if err != nil {
+		log.Warn("Failed to parse port ", name, " ", err)
+		return nil
+	}
+
+	// gRPC requires the router filter to be last.
+	httpFilters := append(buildRBACFilters(node, push), &hcm.HttpFilter{
+		Name:       wellknown.Router,
+		ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: util.MessageToAny(&router.Router{})},
+	})
+	hcm := &hcm.HttpConnectionManager{
+		RouteSpecifier: &hcm.HttpConnectionManager_RouteConfig{
+			RouteConfig: &route.RouteConfiguration{
+				Name: portn,
+				VirtualHosts: []*route.VirtualHost{
+					{
+						Name:    "inbound|http|" + portn,
+						Domains: []string{"*"},
+						Routes: []*route.Route{
+							{
+								Match: &route.RouteMatch{
+									PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+								},
+								Action: &route.Route_NonForwardingAction{NonForwardingAction: &route.NonForwardingAction{}},
+							},
+						},
+					},
+				},
+			},
+		},
+		HttpFilters: httpFilters,
+	}
+	ll := &listener.Listener{
+		Name: name,
+		Address: &core.Address{
+			Address: &core.Address_SocketAddress{
+				SocketAddress: &core.SocketAddress{
+					Address: ip,
+					PortSpecifier: &core.SocketAddress_PortValue{
+						PortValue: uint32(port),
+					},
+				},
+			},
+		},
+		FilterChains: []*listener.FilterChain{
+			{
+				Name: "inbound|" + portn,
+				Filters: []*listener.Filter{{
+					Name:       wellknown.HTTPConnectionManager,
+					ConfigType: &listener.Filter_TypedConfig{TypedConfig: util.MessageToAny(hcm)},
+				}},
+				TransportSocket: buildDownstreamTransportSocket(node, push, port),
+			},
+		},
+		TrafficDirection: core.TrafficDirection_INBOUND,
+	}
+	return &discovery.Resource{
+		Name:     name,
+		Resource: util.MessageToAny(ll),
+	}
+}
+
 // handleSplitRDS supports per-VIP routes, as used by GRPC.
 // This mode is indicated by using names containing full host:port instead of just port.
 // Returns true of the request is of this type.
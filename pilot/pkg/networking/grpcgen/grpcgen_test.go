@@ -16,10 +16,19 @@ package grpcgen_test
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	rbachttp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/balancer"
 	"google.golang.org/grpc/resolver"
@@ -33,6 +42,7 @@ import (
 
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/grpcgen"
 	"istio.io/istio/pilot/pkg/xds"
 
 	"istio.io/istio/pkg/config"
@@ -152,6 +162,309 @@ func TestGRPC(t *testing.T) {
 
 }
 
+const securityConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: echo
+  namespace: default
+spec:
+  hosts:
+  - echo.default.svc.cluster.local
+  ports:
+  - number: 7070
+    name: grpc
+    protocol: GRPC
+  location: MESH_INTERNAL
+  resolution: STATIC
+  endpoints:
+  - address: 10.0.0.1
+    serviceAccount: echo
+    labels:
+      security.istio.io/tlsMode: istio
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: plaintext
+  namespace: default
+spec:
+  hosts:
+  - plaintext.default.svc.cluster.local
+  ports:
+  - number: 7070
+    name: grpc
+    protocol: GRPC
+  location: MESH_INTERNAL
+  resolution: STATIC
+  endpoints:
+  - address: 10.0.0.2
+    serviceAccount: plaintext
+    labels:
+      security.istio.io/tlsMode: disabled
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: explicit
+  namespace: default
+spec:
+  hosts:
+  - explicit.default.svc.cluster.local
+  ports:
+  - number: 7070
+    name: grpc
+    protocol: GRPC
+  - number: 7071
+    name: grpc-plain
+    protocol: GRPC
+  location: MESH_INTERNAL
+  resolution: STATIC
+  endpoints:
+  - address: 10.0.0.3
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: explicit
+  namespace: default
+spec:
+  host: explicit.default.svc.cluster.local
+  trafficPolicy:
+    tls:
+      mode: ISTIO_MUTUAL
+      subjectAltNames:
+      - spiffe://cluster.local/ns/default/sa/explicit
+    portLevelSettings:
+    - port:
+        number: 7071
+      tls:
+        mode: DISABLE
+---
+apiVersion: security.istio.io/v1beta1
+kind: PeerAuthentication
+metadata:
+  name: default
+  namespace: default
+spec:
+  mtls:
+    mode: STRICT
+---
+apiVersion: security.istio.io/v1beta1
+kind: PeerAuthentication
+metadata:
+  name: echo
+  namespace: default
+spec:
+  selector:
+    matchLabels:
+      app: echo
+  portLevelMtls:
+    7071:
+      mode: DISABLE
+---
+apiVersion: security.istio.io/v1beta1
+kind: AuthorizationPolicy
+metadata:
+  name: echo
+  namespace: default
+spec:
+  selector:
+    matchLabels:
+      app: echo
+  rules:
+  - from:
+    - source:
+        principals: ["cluster.local/ns/default/sa/client"]
+`
+
+func TestGRPCSecurity(t *testing.T) {
+	f := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: securityConfig})
+	proxy := f.SetupProxy(&model.Proxy{
+		Metadata: &model.NodeMetadata{
+			Generator: "grpc",
+			Labels:    map[string]string{"app": "echo"},
+		},
+	})
+	gen := &grpcgen.GrpcConfigGenerator{}
+
+	t.Run("clusters", func(t *testing.T) {
+		cases := []struct {
+			name string
+			sans []string
+			tls  bool
+		}{
+			{name: "echo.default.svc.cluster.local:7070", tls: true, sans: []string{"spiffe://cluster.local/ns/default/sa/echo"}},
+			{name: "plaintext.default.svc.cluster.local:7070", tls: false},
+			{name: "explicit.default.svc.cluster.local:7070", tls: true, sans: []string{"spiffe://cluster.local/ns/default/sa/explicit"}},
+			{name: "explicit.default.svc.cluster.local:7071", tls: false},
+		}
+		for _, tt := range cases {
+			t.Run(tt.name, func(t *testing.T) {
+				res := gen.BuildClusters(proxy, f.PushContext(), []string{tt.name})
+				if len(res) != 1 {
+					t.Fatalf("expected 1 cluster, got %d", len(res))
+				}
+				c := &cluster.Cluster{}
+				if err := res[0].Resource.UnmarshalTo(c); err != nil {
+					t.Fatal(err)
+				}
+				if !tt.tls {
+					if c.TransportSocket != nil {
+						t.Fatalf("expected plaintext, got %v", c.TransportSocket)
+					}
+					return
+				}
+				ctx := &tls.UpstreamTlsContext{}
+				if err := c.GetTransportSocket().GetTypedConfig().UnmarshalTo(ctx); err != nil {
+					t.Fatal(err)
+				}
+				checkCertificateProviders(t, ctx.CommonTlsContext)
+				sans := []string{}
+				for _, m := range ctx.CommonTlsContext.GetCombinedValidationContext().GetDefaultValidationContext().GetMatchSubjectAltNames() {
+					sans = append(sans, m.GetExact())
+				}
+				if !reflect.DeepEqual(sans, tt.sans) {
+					t.Fatalf("expected SANs %v, got %v", tt.sans, sans)
+				}
+			})
+		}
+	})
+
+	t.Run("listeners", func(t *testing.T) {
+		cases := []struct {
+			port string
+			tls  bool
+		}{
+			{port: "7070", tls: true},
+			{port: "7071", tls: false},
+		}
+		for _, tt := range cases {
+			t.Run(tt.port, func(t *testing.T) {
+				name := grpcgen.ServerListenerNamePrefix + "0.0.0.0:" + tt.port
+				res := gen.BuildListeners(proxy, f.PushContext(), []string{name})
+				if len(res) != 1 {
+					t.Fatalf("expected 1 listener, got %d", len(res))
+				}
+				l := &listener.Listener{}
+				if err := res[0].Resource.UnmarshalTo(l); err != nil {
+					t.Fatal(err)
+				}
+				if l.Name != name || l.GetAddress().GetSocketAddress().GetAddress() != "0.0.0.0" || l.TrafficDirection != core.TrafficDirection_INBOUND {
+					t.Fatalf("unexpected listener %v", l)
+				}
+				if len(l.FilterChains) != 1 {
+					t.Fatalf("expected 1 filter chain, got %d", len(l.FilterChains))
+				}
+				fc := l.FilterChains[0]
+				if tt.tls {
+					ctx := &tls.DownstreamTlsContext{}
+					if err := fc.GetTransportSocket().GetTypedConfig().UnmarshalTo(ctx); err != nil {
+						t.Fatal(err)
+					}
+					if !ctx.GetRequireClientCertificate().GetValue() {
+						t.Fatalf("expected client certificate to be required")
+					}
+					checkCertificateProviders(t, ctx.CommonTlsContext)
+				} else if fc.TransportSocket != nil {
+					t.Fatalf("expected plaintext, got %v", fc.TransportSocket)
+				}
+
+				h := &hcm.HttpConnectionManager{}
+				if err := fc.Filters[0].GetTypedConfig().UnmarshalTo(h); err != nil {
+					t.Fatal(err)
+				}
+				filters := []string{}
+				for _, hf := range h.HttpFilters {
+					filters = append(filters, hf.Name)
+				}
+				if !reflect.DeepEqual(filters, []string{wellknown.HTTPRoleBasedAccessControl, wellknown.Router}) {
+					t.Fatalf("unexpected http filters %v", filters)
+				}
+				rbac := &rbachttp.RBAC{}
+				if err := h.HttpFilters[0].GetTypedConfig().UnmarshalTo(rbac); err != nil {
+					t.Fatal(err)
+				}
+				if len(rbac.GetRules().GetPolicies()) != 1 {
+					t.Fatalf("expected 1 RBAC policy, got %v", rbac.GetRules())
+				}
+				if h.GetRouteConfig().GetVirtualHosts()[0].GetRoutes()[0].GetNonForwardingAction() == nil {
+					t.Fatalf("expected non forwarding route, got %v", h.GetRouteConfig())
+				}
+			})
+		}
+	})
+}
+
+func TestGRPCSecurityCustomAction(t *testing.T) {
+	f := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: securityConfig + `---
+apiVersion: security.istio.io/v1beta1
+kind: AuthorizationPolicy
+metadata:
+  name: ext-authz
+  namespace: default
+spec:
+  selector:
+    matchLabels:
+      app: echo
+  action: CUSTOM
+  provider:
+    name: ext-authz
+  rules:
+  - to:
+    - operation:
+        paths: ["/admin"]
+`})
+	proxy := f.SetupProxy(&model.Proxy{
+		Metadata: &model.NodeMetadata{
+			Generator: "grpc",
+			Labels:    map[string]string{"app": "echo"},
+		},
+	})
+	gen := &grpcgen.GrpcConfigGenerator{}
+
+	name := grpcgen.ServerListenerNamePrefix + "0.0.0.0:7070"
+	res := gen.BuildListeners(proxy, f.PushContext(), []string{name})
+	if len(res) != 1 {
+		t.Fatalf("expected 1 listener, got %d", len(res))
+	}
+	l := &listener.Listener{}
+	if err := res[0].Resource.UnmarshalTo(l); err != nil {
+		t.Fatal(err)
+	}
+	h := &hcm.HttpConnectionManager{}
+	if err := l.FilterChains[0].Filters[0].GetTypedConfig().UnmarshalTo(h); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.HttpFilters) != 2 || h.HttpFilters[0].Name != wellknown.HTTPRoleBasedAccessControl {
+		t.Fatalf("expected a single RBAC filter before the router, got %v", h.HttpFilters)
+	}
+	rbac := &rbachttp.RBAC{}
+	if err := h.HttpFilters[0].GetTypedConfig().UnmarshalTo(rbac); err != nil {
+		t.Fatal(err)
+	}
+	// The CUSTOM action is not supported, all the requests are denied rather than allowed.
+	if rbac.GetRules().GetAction() != rbacpb.RBAC_DENY || len(rbac.GetRules().GetPolicies()) != 1 {
+		t.Fatalf("expected a deny all RBAC policy, got %v", rbac.GetRules())
+	}
+	for _, p := range rbac.GetRules().GetPolicies() {
+		if !p.GetPermissions()[0].GetAny() || !p.GetPrincipals()[0].GetAny() {
+			t.Fatalf("expected the policy to match all requests, got %v", p)
+		}
+	}
+}
+
+func checkCertificateProviders(t *testing.T, ctx *tls.CommonTlsContext) {
+	t.Helper()
+	if ctx.GetTlsCertificateCertificateProviderInstance().GetInstanceName() != grpcgen.FileWatcherCertProviderInstance {
+		t.Fatalf("expected certificate provider for identity, got %v", ctx)
+	}
+	if ctx.GetCombinedValidationContext().GetValidationContextCertificateProviderInstance().GetInstanceName() !=
+		grpcgen.FileWatcherCertProviderInstance {
+		t.Fatalf("expected certificate provider for root, got %v", ctx)
+	}
+}
+
 type testLBClientConn struct {
 	balancer.ClientConn
 }
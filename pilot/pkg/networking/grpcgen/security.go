@@ -0,0 +1,208 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcgen
+
+import (
+	"fmt"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	rbachttp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
+	"istio.io/istio/pilot/pkg/networking/plugin"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/pkg/security/authn/factory"
+	"istio.io/istio/pilot/pkg/security/authz/builder"
+	authzmodel "istio.io/istio/pilot/pkg/security/authz/model"
+	"istio.io/istio/pilot/pkg/security/trustdomain"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/security"
+	"istio.io/pkg/log"
+)
+
+// gRPC does not support SDS. Certificates are instead loaded by a certificate provider plugin, configured
+// in the gRPC bootstrap under this instance name (for example using the file_watcher plugin reading the
+// certificates written by the agent).
+const FileWatcherCertProviderInstance = "default"
+
+// buildCommonTLSContext returns a TLS context using the workload certificate and root certificate from the
+// certificate provider. If sans is not empty, the peer certificate must match one of them.
+func buildCommonTLSContext(sans []string) *tls.CommonTlsContext {
+	var sanMatchers []*matcher.StringMatcher
+	for _, san := range sans {
+		sanMatchers = append(sanMatchers, &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_Exact{Exact: san}})
+	}
+	return &tls.CommonTlsContext{
+		TlsCertificateCertificateProviderInstance: &tls.CommonTlsContext_CertificateProviderInstance{
+			InstanceName:    FileWatcherCertProviderInstance,
+			CertificateName: security.WorkloadKeyCertResourceName,
+		},
+		ValidationContextType: &tls.CommonTlsContext_CombinedValidationContext{
+			CombinedValidationContext: &tls.CommonTlsContext_CombinedCertificateValidationContext{
+				DefaultValidationContext: &tls.CertificateValidationContext{MatchSubjectAltNames: sanMatchers},
+				ValidationContextCertificateProviderInstance: &tls.CommonTlsContext_CertificateProviderInstance{
+					InstanceName:    FileWatcherCertProviderInstance,
+					CertificateName: security.RootCertReqResourceName,
+				},
+			},
+		},
+	}
+}
+
+// buildUpstreamTransportSocket returns the transport socket for the cluster of the given service and port, based
+// on the DestinationRule TLS settings and auto mTLS. Returns nil if the connection is plaintext.
+func buildUpstreamTransportSocket(node *model.Proxy, push *model.PushContext, hostname string, port int) *core.TransportSocket {
+	svc := push.ServiceForHostname(node, host.Name(hostname))
+	if svc == nil {
+		return nil
+	}
+	p, f := svc.Ports.GetByPort(port)
+	if !f {
+		return nil
+	}
+	var policy *networking.TrafficPolicy
+	if dr := push.DestinationRule(node, svc); dr != nil {
+		policy = v1alpha3.MergeTrafficPolicy(nil, dr.Spec.(*networking.DestinationRule).TrafficPolicy, p)
+	}
+
+	sans := push.ServiceAccounts[svc.Hostname][port]
+	tlsSettings := policy.GetTls()
+	switch {
+	case tlsSettings == nil:
+		if !autoMTLS(push, policy, svc, p) {
+			return nil
+		}
+	case tlsSettings.Mode == networking.ClientTLSSettings_ISTIO_MUTUAL:
+		if len(tlsSettings.SubjectAltNames) > 0 {
+			sans = tlsSettings.SubjectAltNames
+		}
+	case tlsSettings.Mode == networking.ClientTLSSettings_DISABLE:
+		return nil
+	default:
+		// gRPC can only load certificates from a certificate provider, so user supplied certificates cannot be used.
+		log.Warnf("TLS mode %v is not supported by proxyless gRPC, ignoring for %s:%d", tlsSettings.Mode, hostname, port)
+		return nil
+	}
+
+	return &core.TransportSocket{
+		Name: util.EnvoyTLSSocketName,
+		ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: util.MessageToAny(&tls.UpstreamTlsContext{
+			CommonTlsContext: buildCommonTLSContext(sans),
+		})},
+	}
+}
+
+// autoMTLS returns true if mTLS should be used for a service without explicit TLS settings.
+// Unlike Envoy, gRPC does not support transport socket matches, so the decision is made for the service as a whole:
+// mTLS is only used when the service requires it and all endpoints are able to terminate it. Plaintext is accepted
+// by servers in PERMISSIVE mode, both sidecars and proxyless.
+func autoMTLS(push *model.PushContext, policy *networking.TrafficPolicy, svc *model.Service, port *model.Port) bool {
+	if !push.Mesh.GetEnableAutoMtls().GetValue() || svc.MeshExternal {
+		return false
+	}
+	if push.BestEffortInferServiceMTLSMode(policy, svc, port) != model.MTLSStrict {
+		return false
+	}
+	instances := push.ServiceInstancesByPort(svc, port.Port, nil)
+	if len(instances) == 0 {
+		return false
+	}
+	for _, i := range instances {
+		if i.Endpoint.TLSMode != model.IstioMutualTLSModeLabel {
+			return false
+		}
+	}
+	return true
+}
+
+// buildDownstreamTransportSocket returns the transport socket for an inbound listener on the given port,
+// based on the PeerAuthentication policies. Returns nil if the listener is plaintext.
+func buildDownstreamTransportSocket(node *model.Proxy, push *model.PushContext, port int) *core.TransportSocket {
+	applier := factory.NewPolicyApplier(push, node.ConfigNamespace, labels.Collection{node.Metadata.Labels})
+	// gRPC filter chain matching only supports the raw_buffer transport protocol, so PERMISSIVE mode cannot
+	// be expressed and is served as plaintext. This is consistent with auto mTLS, which does not use mTLS
+	// unless the server is STRICT.
+	if applier.GetMutualTLSModeForPort(uint32(port)) != model.MTLSStrict {
+		return nil
+	}
+	return &core.TransportSocket{
+		Name: util.EnvoyTLSSocketName,
+		ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: util.MessageToAny(&tls.DownstreamTlsContext{
+			// Subject alt name matching is not supported by gRPC on the server side.
+			CommonTlsContext:         buildCommonTLSContext(nil),
+			RequireClientCertificate: &wrappers.BoolValue{Value: true},
+		})},
+	}
+}
+
+// buildRBACFilters returns the RBAC HTTP filters enforcing the AuthorizationPolicies selecting the proxy.
+func buildRBACFilters(node *model.Proxy, push *model.PushContext) []*hcm.HttpFilter {
+	if push.AuthzPolicies == nil {
+		return nil
+	}
+	in := &plugin.InputParams{Node: node, Push: push}
+	tdBundle := trustdomain.NewBundle(push.Mesh.TrustDomain, push.Mesh.TrustDomainAliases)
+	option := builder.Option{Logger: &builder.AuthzLogger{}}
+	defer option.Logger.Report(in)
+	// CUSTOM action requires ext_authz, which is not supported by gRPC. Deny all the requests rather than allowing the
+	// ones the external authorizer would deny.
+	policies := push.AuthzPolicies.ListAuthorizationPolicies(node.ConfigNamespace, labels.Collection{node.Metadata.Labels})
+	if len(policies.Custom) > 0 {
+		option.Logger.AppendError(fmt.Errorf("found %d CUSTOM actions not supported by gRPC, will generate a deny all config",
+			len(policies.Custom)))
+		return []*hcm.HttpFilter{buildDenyAllRBACFilter()}
+	}
+	b := builder.New(tdBundle, in, option)
+	if b == nil {
+		return nil
+	}
+	var filters []*hcm.HttpFilter
+	for _, f := range b.BuildHTTP() {
+		// AUDIT action is not supported by gRPC.
+		rbac := &rbachttp.RBAC{}
+		if err := f.GetTypedConfig().UnmarshalTo(rbac); err == nil && rbac.GetRules().GetAction() == rbacpb.RBAC_LOG {
+			continue
+		}
+		filters = append(filters, f)
+	}
+	return filters
+}
+
+// buildDenyAllRBACFilter returns an RBAC HTTP filter denying all the requests.
+func buildDenyAllRBACFilter() *hcm.HttpFilter {
+	rbac := &rbachttp.RBAC{
+		Rules: &rbacpb.RBAC{
+			Action: rbacpb.RBAC_DENY,
+			Policies: map[string]*rbacpb.Policy{
+				"default-deny-all-due-to-unsupported-CUSTOM-action": {
+					Permissions: []*rbacpb.Permission{{Rule: &rbacpb.Permission_Any{Any: true}}},
+					Principals:  []*rbacpb.Principal{{Identifier: &rbacpb.Principal_Any{Any: true}}},
+				},
+			},
+		},
+	}
+	return &hcm.HttpFilter{
+		Name:       authzmodel.RBACHTTPFilterName,
+		ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: util.MessageToAny(rbac)},
+	}
+}
@@ -30,6 +30,8 @@ const (
 	Authz = "authz"
 	// MetadataExchange is the name of the telemetry plugin passed through the command line
 	MetadataExchange = "metadata_exchange"
+	// RateLimit is the name of the rate limit plugin passed through the command line
+	RateLimit = "ratelimit"
 )
 
 // InputParams is a set of values passed to Plugin callback methods. Not all fields are guaranteed to
@@ -0,0 +1,146 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ratelimit adds the rate limit HTTP filters enforcing the rate limit policies of virtual services.
+// The limits themselves are set on the routes.
+package ratelimit
+
+import (
+	"sort"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	ratelimitconfig "github.com/envoyproxy/go-control-plane/envoy/config/ratelimit/v3"
+	ratelimitfilter "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ratelimit/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"istio.io/istio/pilot/pkg/extensionproviders"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking"
+	"istio.io/istio/pilot/pkg/networking/plugin"
+	"istio.io/istio/pilot/pkg/networking/util"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
+	"istio.io/pkg/log"
+)
+
+// defaultTimeout of the calls to the rate limit service.
+var defaultTimeout = durationpb.New(20 * time.Millisecond)
+
+// Plugin implements the rate limit policies of virtual services.
+type Plugin struct{}
+
+// NewPlugin returns an instance of the rate limit plugin
+func NewPlugin() plugin.Plugin {
+	return Plugin{}
+}
+
+// OnOutboundListener adds the rate limit filters to the HTTP filter chains of sidecar outbound and gateway listeners,
+// if rate limit policies apply to the proxy.
+func (p Plugin) OnOutboundListener(in *plugin.InputParams, mutable *networking.MutableObjects) error {
+	filters := buildFilters(in.Node, in.Push)
+	if len(filters) == 0 {
+		return nil
+	}
+	for i := range mutable.FilterChains {
+		if mutable.FilterChains[i].ListenerProtocol == networking.ListenerProtocolHTTP {
+			mutable.FilterChains[i].HTTP = append(mutable.FilterChains[i].HTTP, filters...)
+		}
+	}
+	return nil
+}
+
+// OnInboundListener is a no-op, rate limit policies are set on virtual services and do not apply to inbound traffic.
+func (p Plugin) OnInboundListener(in *plugin.InputParams, mutable *networking.MutableObjects) error {
+	return nil
+}
+
+// OnInboundPassthrough is a no-op.
+func (p Plugin) OnInboundPassthrough(in *plugin.InputParams, mutable *networking.MutableObjects) error {
+	return nil
+}
+
+func (p Plugin) InboundMTLSConfiguration(in *plugin.InputParams, passthrough bool) []plugin.MTLSSettings {
+	return nil
+}
+
+// buildFilters returns the rate limit filters needed by the policies applying to the proxy. Envoy supports a
+// single rate limit service and domain per filter, so a filter is added for each rate limit provider used by the
+// global policies, at the stage of the provider.
+func buildFilters(node *model.Proxy, push *model.PushContext) []*hcm.HttpFilter {
+	var local bool
+	providers := map[string]*model.EnvoyRateLimitProvider{}
+	for _, p := range push.RateLimitPoliciesForProxy(node) {
+		if p.Local != nil {
+			local = true
+		}
+		if p.Global == nil {
+			continue
+		}
+		provider := push.EnvoyRateLimitProvider(p.Global.Provider)
+		if provider == nil {
+			log.Warnf("rate limit provider %s not found for proxy %s", p.Global.Provider, node.ID)
+			continue
+		}
+		providers[provider.Name] = provider
+	}
+	sorted := make([]*model.EnvoyRateLimitProvider, 0, len(providers))
+	for _, p := range providers {
+		sorted = append(sorted, p)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Stage < sorted[j].Stage
+	})
+
+	var filters []*hcm.HttpFilter
+	if local {
+		filters = append(filters, xdsfilters.LocalRateLimit)
+	}
+	for _, p := range sorted {
+		_, cluster, err := extensionproviders.LookupCluster(push, p.Service, int(p.Port))
+		if err != nil {
+			log.Warnf("failed to find the cluster of rate limit provider %s: %v", p.Name, err)
+			continue
+		}
+		filters = append(filters, buildGlobalFilter(p, cluster))
+	}
+	return filters
+}
+
+func buildGlobalFilter(provider *model.EnvoyRateLimitProvider, cluster string) *hcm.HttpFilter {
+	timeout := defaultTimeout
+	if provider.Timeout != nil {
+		timeout = durationpb.New(provider.Timeout.Duration)
+	}
+	return &hcm.HttpFilter{
+		Name: wellknown.HTTPRateLimit,
+		ConfigType: &hcm.HttpFilter_TypedConfig{
+			TypedConfig: util.MessageToAny(&ratelimitfilter.RateLimit{
+				Domain:          provider.Domain,
+				Stage:           provider.Stage,
+				Timeout:         timeout,
+				FailureModeDeny: provider.FailureModeDeny,
+				RateLimitService: &ratelimitconfig.RateLimitServiceConfig{
+					GrpcService: &core.GrpcService{
+						TargetSpecifier: &core.GrpcService_EnvoyGrpc_{
+							EnvoyGrpc: &core.GrpcService_EnvoyGrpc{ClusterName: cluster},
+						},
+					},
+					TransportApiVersion: core.ApiVersion_V3,
+				},
+			}),
+		},
+	}
+}
@@ -0,0 +1,315 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit_test
+
+import (
+	"testing"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	localratelimit "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/local_ratelimit/v3"
+	ratelimitfilter "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ratelimit/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config/extensionprovider"
+)
+
+const services = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+  - reviews.default.svc.cluster.local
+  addresses:
+  - 10.0.0.1
+  ports:
+  - number: 9080
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 10.10.10.10
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: details
+  namespace: default
+spec:
+  hosts:
+  - details.default.svc.cluster.local
+  addresses:
+  - 10.0.0.2
+  ports:
+  - number: 9080
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 10.10.10.11
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: ratelimit
+  namespace: istio-system
+spec:
+  hosts:
+  - ratelimit.istio-system.svc.cluster.local
+  ports:
+  - number: 8081
+    name: grpc
+    protocol: GRPC
+  resolution: DNS
+---
+`
+
+const virtualService = `
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+  namespace: default
+  annotations:
+    networking.istio.io/rateLimit: |
+      - routes: [limited]
+        workloadSelector:
+          app: productpage
+        local:
+          maxTokens: 10
+          tokensPerFill: 5
+          fillInterval: 1s
+      - global:
+          provider: reviews
+          descriptors:
+          - key: path
+            header: ":path"
+spec:
+  hosts:
+  - reviews.default.svc.cluster.local
+  http:
+  - name: limited
+    match:
+    - uri:
+        prefix: /limited
+    route:
+    - destination:
+        host: reviews.default.svc.cluster.local
+  - name: default
+    route:
+    - destination:
+        host: reviews.default.svc.cluster.local
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: details
+  namespace: default
+  annotations:
+    networking.istio.io/rateLimit: |
+      - global:
+          provider: details
+spec:
+  hosts:
+  - details.default.svc.cluster.local
+  http:
+  - name: details
+    route:
+    - destination:
+        host: details.default.svc.cluster.local
+`
+
+// providers are the rate limit providers of the mesh. The stages of the providers follow their order, including the
+// unused ones.
+var providers = []*extensionprovider.ExtensionProvider{
+	{
+		Name: "unused",
+		EnvoyRateLimit: &extensionprovider.EnvoyRateLimitProvider{
+			Service: "ratelimit.istio-system.svc.cluster.local",
+			Port:    8081,
+			Domain:  "unused",
+		},
+	},
+	{
+		Name: "reviews",
+		EnvoyRateLimit: &extensionprovider.EnvoyRateLimitProvider{
+			Service: "ratelimit.istio-system.svc.cluster.local",
+			Port:    8081,
+			Domain:  "reviews",
+		},
+	},
+	{
+		Name: "details",
+		EnvoyRateLimit: &extensionprovider.EnvoyRateLimitProvider{
+			Service:         "istio-system/ratelimit.istio-system.svc.cluster.local",
+			Port:            8081,
+			Domain:          "details",
+			FailureModeDeny: true,
+		},
+	},
+}
+
+func TestRateLimit(t *testing.T) {
+	cg := v1alpha3.NewConfigGenTest(t, v1alpha3.TestOptions{
+		ConfigString:           services + virtualService,
+		MeshExtensionProviders: providers,
+	})
+
+	cases := []struct {
+		name   string
+		labels map[string]string
+		local  bool
+	}{
+		{name: "selected", labels: map[string]string{"app": "productpage"}, local: true},
+		{name: "not selected", labels: map[string]string{"app": "details"}, local: false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := cg.SetupProxy(&model.Proxy{Metadata: &model.NodeMetadata{Labels: tt.labels}})
+
+			l := xdstest.ExtractListener("0.0.0.0_9080", cg.Listeners(proxy))
+			if l == nil {
+				t.Fatalf("listener not found")
+			}
+			filters := map[string]int{}
+			hcm := xdstest.ExtractHTTPConnectionManager(t, l.FilterChains[0])
+			for i, f := range hcm.HttpFilters {
+				filters[f.Name] = i
+			}
+			localIdx, hasLocal := filters[xdsfilters.LocalRateLimitFilterName]
+			if hasLocal != tt.local {
+				t.Fatalf("expected local rate limit filter %v, got filters %v", tt.local, filters)
+			}
+			var globals []*ratelimitfilter.RateLimit
+			for i, f := range hcm.HttpFilters {
+				if f.Name != wellknown.HTTPRateLimit {
+					continue
+				}
+				if hasLocal && localIdx > i {
+					t.Errorf("local rate limit filter should be before the global filters")
+				}
+				if i > filters[wellknown.Router] {
+					t.Errorf("rate limit filters should be before the router")
+				}
+				global := &ratelimitfilter.RateLimit{}
+				if err := f.GetTypedConfig().UnmarshalTo(global); err != nil {
+					t.Fatal(err)
+				}
+				globals = append(globals, global)
+			}
+			if len(globals) != 2 {
+				t.Fatalf("expected a global rate limit filter per provider, got filters %v", filters)
+			}
+			for i, expected := range []struct {
+				domain          string
+				stage           uint32
+				failureModeDeny bool
+			}{{"reviews", 1, false}, {"details", 2, true}} {
+				global := globals[i]
+				if global.Domain != expected.domain || global.Stage != expected.stage || global.FailureModeDeny != expected.failureModeDeny ||
+					global.RateLimitService.GrpcService.GetEnvoyGrpc().ClusterName != "outbound|8081||ratelimit.istio-system.svc.cluster.local" {
+					t.Errorf("unexpected global rate limit config %v", global)
+				}
+			}
+
+			rc := xdstest.ExtractRouteConfigurations(cg.Routes(proxy))["9080"]
+			if rc == nil {
+				t.Fatalf("route configuration not found")
+			}
+			routes := map[string]*route.Route{}
+			for _, vh := range rc.VirtualHosts {
+				for _, r := range vh.Routes {
+					routes[r.Name] = r
+				}
+			}
+			if r := routes["details"]; r == nil {
+				t.Fatalf("route details not found")
+			} else if len(r.GetRoute().RateLimits) != 1 || r.GetRoute().RateLimits[0].Stage.GetValue() != 2 ||
+				r.GetRoute().RateLimits[0].Actions[0].GetGenericKey().GetDescriptorValue() != "default/details/details" {
+				t.Errorf("route details: unexpected rate limits %v", r.GetRoute().RateLimits)
+			}
+			for _, name := range []string{"limited", "default"} {
+				r := routes[name]
+				if r == nil {
+					t.Fatalf("route %s not found", name)
+				}
+				if len(r.GetRoute().RateLimits) != 1 {
+					t.Errorf("route %s: expected global rate limit, got %v", name, r.GetRoute().RateLimits)
+				} else if rl := r.GetRoute().RateLimits[0]; rl.Stage.GetValue() != 1 || rl.Actions[0].GetRequestHeaders().GetHeaderName() != ":path" {
+					t.Errorf("route %s: unexpected rate limit %v", name, rl)
+				}
+				_, hasLocal := r.TypedPerFilterConfig[xdsfilters.LocalRateLimitFilterName]
+				if expected := tt.local && name == "limited"; hasLocal != expected {
+					t.Errorf("route %s: expected local rate limit %v", name, expected)
+				}
+			}
+			if tt.local {
+				local := &localratelimit.LocalRateLimit{}
+				if err := routes["limited"].TypedPerFilterConfig[xdsfilters.LocalRateLimitFilterName].UnmarshalTo(local); err != nil {
+					t.Fatal(err)
+				}
+				if local.TokenBucket.MaxTokens != 10 || local.TokenBucket.TokensPerFill.GetValue() != 5 ||
+					local.TokenBucket.FillInterval.AsDuration().Seconds() != 1 {
+					t.Errorf("unexpected token bucket %v", local.TokenBucket)
+				}
+			}
+		})
+	}
+}
+
+func TestNoRateLimit(t *testing.T) {
+	cg := v1alpha3.NewConfigGenTest(t, v1alpha3.TestOptions{ConfigString: services})
+	proxy := cg.SetupProxy(nil)
+	for _, l := range cg.Listeners(proxy) {
+		for _, fc := range l.FilterChains {
+			if len(fc.GetFilters()) == 0 || fc.GetFilters()[len(fc.GetFilters())-1].Name != wellknown.HTTPConnectionManager {
+				continue
+			}
+			for _, f := range xdstest.ExtractHTTPConnectionManager(t, fc).HttpFilters {
+				if f.Name == xdsfilters.LocalRateLimitFilterName || f.Name == wellknown.HTTPRateLimit {
+					t.Fatalf("unexpected filter %s in listener %s", f.Name, l.Name)
+				}
+			}
+		}
+	}
+}
+
+func TestRateLimitUnknownProvider(t *testing.T) {
+	cg := v1alpha3.NewConfigGenTest(t, v1alpha3.TestOptions{ConfigString: services + virtualService})
+	proxy := cg.SetupProxy(&model.Proxy{Metadata: &model.NodeMetadata{Labels: map[string]string{"app": "details"}}})
+
+	l := xdstest.ExtractListener("0.0.0.0_9080", cg.Listeners(proxy))
+	if l == nil {
+		t.Fatalf("listener not found")
+	}
+	for _, f := range xdstest.ExtractHTTPConnectionManager(t, l.FilterChains[0]).HttpFilters {
+		if f.Name == wellknown.HTTPRateLimit {
+			t.Fatalf("unexpected global rate limit filter without provider")
+		}
+	}
+	for _, vh := range xdstest.ExtractRouteConfigurations(cg.Routes(proxy))["9080"].GetVirtualHosts() {
+		for _, r := range vh.Routes {
+			if len(r.GetRoute().GetRateLimits()) != 0 {
+				t.Errorf("route %s: unexpected rate limits without provider %v", r.Name, r.GetRoute().RateLimits)
+			}
+		}
+	}
+}
@@ -69,11 +69,13 @@ type NameTable_NameInfo struct {
 	// the registry where this
 	Registry string `protobuf:"bytes,2,opt,name=registry,proto3" json:"registry,omitempty"`
 	// these are set only for k8s services
-	Shortname            string   `protobuf:"bytes,3,opt,name=shortname,proto3" json:"shortname,omitempty"`
-	Namespace            string   `protobuf:"bytes,4,opt,name=namespace,proto3" json:"namespace,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Shortname string `protobuf:"bytes,3,opt,name=shortname,proto3" json:"shortname,omitempty"`
+	Namespace string `protobuf:"bytes,4,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// service port name to port number, used to answer SRV queries
+	Ports                map[string]uint32 `protobuf:"bytes,5,rep,name=ports,proto3" json:"ports,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *NameTable_NameInfo) Reset()         { *m = NameTable_NameInfo{} }
@@ -129,10 +131,18 @@ func (m *NameTable_NameInfo) GetNamespace() string {
 	return ""
 }
 
+func (m *NameTable_NameInfo) GetPorts() map[string]uint32 {
+	if m != nil {
+		return m.Ports
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*NameTable)(nil), "istio.networking.nds.v1.NameTable")
 	proto.RegisterMapType((map[string]*NameTable_NameInfo)(nil), "istio.networking.nds.v1.NameTable.TableEntry")
 	proto.RegisterType((*NameTable_NameInfo)(nil), "istio.networking.nds.v1.NameTable.NameInfo")
+	proto.RegisterMapType((map[string]uint32)(nil), "istio.networking.nds.v1.NameTable.NameInfo.PortsEntry")
 }
 
 func init() {
@@ -140,20 +150,22 @@ func init() {
 }
 
 var fileDescriptor_3cd1956996ab4e55 = []byte{
-	// 229 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0xcc, 0x4b, 0x29, 0xd6,
-	0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x12, 0xcf, 0x2c, 0x2e, 0xc9, 0xcc, 0xd7, 0xcb, 0x4b, 0x2d,
-	0x29, 0xcf, 0x2f, 0xca, 0xce, 0xcc, 0x4b, 0xd7, 0x03, 0xc9, 0x95, 0x19, 0x2a, 0xad, 0x65, 0xe2,
-	0xe2, 0xf4, 0x4b, 0xcc, 0x4d, 0x0d, 0x49, 0x4c, 0xca, 0x49, 0x15, 0x72, 0xe6, 0x62, 0x2d, 0x01,
-	0x31, 0x24, 0x18, 0x15, 0x98, 0x35, 0xb8, 0x8d, 0x74, 0xf5, 0x70, 0x68, 0xd3, 0x83, 0x6b, 0xd1,
-	0x03, 0x93, 0xae, 0x79, 0x25, 0x45, 0x95, 0x41, 0x10, 0xbd, 0x52, 0x25, 0x5c, 0x1c, 0x20, 0x69,
-	0xcf, 0xbc, 0xb4, 0x7c, 0x21, 0x01, 0x2e, 0xe6, 0xcc, 0x82, 0x62, 0xb0, 0x71, 0x9c, 0x41, 0x20,
-	0xa6, 0x90, 0x14, 0x17, 0x47, 0x51, 0x6a, 0x7a, 0x66, 0x71, 0x49, 0x51, 0xa5, 0x04, 0x93, 0x02,
-	0xa3, 0x06, 0x67, 0x10, 0x9c, 0x2f, 0x24, 0xc3, 0xc5, 0x59, 0x9c, 0x91, 0x5f, 0x54, 0x92, 0x97,
-	0x98, 0x9b, 0x2a, 0xc1, 0x0c, 0x96, 0x44, 0x08, 0x80, 0x64, 0x41, 0x74, 0x71, 0x41, 0x62, 0x72,
-	0xaa, 0x04, 0x0b, 0x44, 0x16, 0x2e, 0x20, 0x95, 0xca, 0xc5, 0x85, 0x70, 0x0a, 0xc8, 0xde, 0xec,
-	0xd4, 0x4a, 0x09, 0x46, 0xb0, 0x2a, 0x10, 0x53, 0xc8, 0x91, 0x8b, 0xb5, 0x2c, 0x31, 0xa7, 0x34,
-	0x15, 0x6c, 0x29, 0xb7, 0x91, 0x36, 0x11, 0x5e, 0x83, 0xf9, 0x22, 0x08, 0xa2, 0xd3, 0x8a, 0xc9,
-	0x82, 0x31, 0x89, 0x0d, 0x1c, 0x9e, 0xc6, 0x80, 0x00, 0x00, 0x00, 0xff, 0xff, 0x4b, 0x59, 0x9f,
-	0x74, 0x5c, 0x01, 0x00, 0x00,
+	// 266 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x91, 0xc1, 0x4a, 0x03, 0x31,
+	0x10, 0x86, 0x49, 0xd7, 0x95, 0x66, 0x8a, 0x20, 0x41, 0x30, 0x2c, 0x1e, 0x8a, 0xa7, 0x82, 0x18,
+	0xb0, 0x82, 0x14, 0x6f, 0x22, 0x1e, 0x04, 0x11, 0x59, 0x7c, 0x81, 0x54, 0xc7, 0x1a, 0xda, 0x26,
+	0x4b, 0x12, 0x2b, 0xfb, 0x62, 0x3e, 0x9d, 0x07, 0x99, 0xac, 0xee, 0xee, 0x41, 0xc1, 0x5e, 0x92,
+	0x99, 0xf9, 0xf9, 0x66, 0xfe, 0x49, 0x80, 0xdb, 0xe7, 0xa0, 0x2a, 0xef, 0xa2, 0x13, 0x87, 0x26,
+	0x44, 0xe3, 0x94, 0xc5, 0xf8, 0xee, 0xfc, 0xd2, 0xd8, 0x85, 0x22, 0x6d, 0x73, 0x76, 0xfc, 0x91,
+	0x01, 0xbf, 0xd7, 0x6b, 0x7c, 0xd4, 0xf3, 0x15, 0x8a, 0x6b, 0xc8, 0x23, 0x05, 0x92, 0x8d, 0xb3,
+	0xc9, 0x68, 0x7a, 0xaa, 0xfe, 0xc0, 0x54, 0x8b, 0xa8, 0x74, 0xde, 0xd8, 0xe8, 0xeb, 0xb2, 0x61,
+	0x8b, 0x4f, 0x06, 0x43, 0xd2, 0x6f, 0xed, 0x8b, 0x13, 0xfb, 0x90, 0x99, 0x2a, 0xa4, 0x7e, 0xbc,
+	0xa4, 0x50, 0x14, 0x30, 0xf4, 0xb8, 0x30, 0x21, 0xfa, 0x5a, 0x0e, 0xc6, 0x6c, 0xc2, 0xcb, 0x36,
+	0x17, 0x47, 0xc0, 0xc3, 0xab, 0xf3, 0xd1, 0xea, 0x35, 0xca, 0x2c, 0x89, 0x5d, 0x81, 0x54, 0xba,
+	0x43, 0xa5, 0x9f, 0x50, 0xee, 0x34, 0x6a, 0x5b, 0x10, 0x77, 0x90, 0x57, 0xce, 0xc7, 0x20, 0xf3,
+	0xe4, 0xfd, 0xe2, 0x1f, 0xde, 0x7f, 0x5c, 0xaa, 0x07, 0x02, 0xbf, 0x97, 0x48, 0x4d, 0x8a, 0x19,
+	0x40, 0x57, 0xa4, 0x2d, 0x96, 0x58, 0x4b, 0x96, 0x66, 0x52, 0x28, 0x0e, 0x20, 0xdf, 0xe8, 0xd5,
+	0x1b, 0xa6, 0x15, 0xf6, 0xca, 0x26, 0xb9, 0x1c, 0xcc, 0x58, 0x81, 0x00, 0xdd, 0x9b, 0xfc, 0x42,
+	0x5e, 0xf5, 0xc9, 0xd1, 0xf4, 0x64, 0x0b, 0x9f, 0xbd, 0x31, 0xf3, 0xdd, 0xf4, 0xb1, 0xe7, 0x5f,
+	0x03, 0x00, 0x1a, 0x2b, 0x7c, 0xc9, 0xe5, 0x01, 0x00, 0x00,
 }
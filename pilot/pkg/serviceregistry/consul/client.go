@@ -0,0 +1,121 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// indexHeader is the header holding the catalog index, used for blocking queries.
+const indexHeader = "X-Consul-Index"
+
+// CatalogService is a service instance registered in the catalog, as returned by /v1/catalog/service/:service.
+type CatalogService struct {
+	Node           string
+	Address        string
+	Datacenter     string
+	ServiceID      string
+	ServiceName    string
+	ServiceAddress string
+	ServiceTags    []string
+	ServiceMeta    map[string]string
+	ServicePort    int
+}
+
+// catalogClient reads the catalog over the HTTP API.
+type catalogClient struct {
+	address string
+	client  *http.Client
+}
+
+func newCatalogClient(address string, client *http.Client) *catalogClient {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &catalogClient{
+		address: strings.TrimSuffix(address, "/"),
+		client:  client,
+	}
+}
+
+// services returns the names of the services in the catalog, along with the catalog index. If index is not
+// zero, this is a blocking query which returns once the catalog index is past index, or wait expires.
+func (c *catalogClient) services(ctx context.Context, index uint64, wait time.Duration) ([]string, uint64, error) {
+	query := url.Values{}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", fmt.Sprintf("%dms", wait.Milliseconds()))
+	}
+	services := map[string][]string{}
+	newIndex, err := c.get(ctx, "/v1/catalog/services", query, &services)
+	if err != nil {
+		return nil, 0, err
+	}
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	return names, newIndex, nil
+}
+
+// service returns the instances of a service in the catalog.
+func (c *catalogClient) service(ctx context.Context, name string) ([]*CatalogService, error) {
+	var instances []*CatalogService
+	if _, err := c.get(ctx, "/v1/catalog/service/"+url.PathEscape(name), nil, &instances); err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+func (c *catalogClient) get(ctx context.Context, path string, query url.Values, out interface{}) (uint64, error) {
+	u := c.address + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("catalog request %s failed with status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return 0, fmt.Errorf("failed to parse catalog response for %s: %v", path, err)
+	}
+	var index uint64
+	if h := resp.Header.Get(indexHeader); h != "" {
+		if index, err = strconv.ParseUint(h, 10, 64); err != nil {
+			return 0, fmt.Errorf("invalid catalog index %q: %v", h, err)
+		}
+	}
+	return index, nil
+}
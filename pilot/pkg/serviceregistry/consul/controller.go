@@ -0,0 +1,345 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	istiolog "istio.io/pkg/log"
+)
+
+var log = istiolog.RegisterScope("consul", "consul service registry controller", 0)
+
+const (
+	defaultWaitTime   = 5 * time.Minute
+	initialRetryDelay = 100 * time.Millisecond
+	maxRetryDelay     = 30 * time.Second
+)
+
+// Options for the catalog service registry.
+type Options struct {
+	// ServerURL is the address of the catalog HTTP API, for example http://127.0.0.1:8500.
+	ServerURL string
+	ClusterID string
+	// XDSUpdater is notified of service and endpoint changes.
+	XDSUpdater model.XDSUpdater
+	// WaitTime bounds the duration of a blocking query. Defaults to 5 minutes.
+	WaitTime time.Duration
+	// HTTPClient used to query the catalog. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+var _ serviceregistry.Instance = &Controller{}
+
+// Controller is a service registry watching a Consul compatible catalog over HTTP. Services registered in the
+// catalog are exposed as <name>.service.consul.
+type Controller struct {
+	opts   Options
+	client *catalogClient
+
+	mutex sync.RWMutex
+	// services and instances hold the converted state of the catalog, keyed by hostname.
+	services  map[host.Name]*model.Service
+	instances map[host.Name][]*model.ServiceInstance
+
+	handlersMutex sync.RWMutex
+	handlers      []func(*model.Service, model.Event)
+
+	synced atomic.Bool
+}
+
+// NewController creates a controller for the catalog at opts.ServerURL.
+func NewController(opts Options) *Controller {
+	if opts.WaitTime == 0 {
+		opts.WaitTime = defaultWaitTime
+	}
+	return &Controller{
+		opts:      opts,
+		client:    newCatalogClient(opts.ServerURL, opts.HTTPClient),
+		services:  map[host.Name]*model.Service{},
+		instances: map[host.Name][]*model.ServiceInstance{},
+	}
+}
+
+// Provider implements serviceregistry.Instance
+func (c *Controller) Provider() serviceregistry.ProviderID {
+	return serviceregistry.Consul
+}
+
+// Cluster implements serviceregistry.Instance
+func (c *Controller) Cluster() string {
+	return c.opts.ClusterID
+}
+
+// Services implements model.ServiceDiscovery
+func (c *Controller) Services() ([]*model.Service, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	out := make([]*model.Service, 0, len(c.services))
+	for _, svc := range c.services {
+		out = append(out, svc)
+	}
+	return out, nil
+}
+
+// GetService implements model.ServiceDiscovery
+func (c *Controller) GetService(hostname host.Name) (*model.Service, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.services[hostname], nil
+}
+
+// InstancesByPort implements model.ServiceDiscovery
+func (c *Controller) InstancesByPort(svc *model.Service, port int, labels labels.Collection) []*model.ServiceInstance {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	var out []*model.ServiceInstance
+	for _, instance := range c.instances[svc.Hostname] {
+		if instance.ServicePort.Port == port && labels.HasSubsetOf(instance.Endpoint.Labels) {
+			out = append(out, instance)
+		}
+	}
+	return out
+}
+
+// GetProxyServiceInstances implements model.ServiceDiscovery
+func (c *Controller) GetProxyServiceInstances(node *model.Proxy) []*model.ServiceInstance {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	var out []*model.ServiceInstance
+	for _, instances := range c.instances {
+		for _, instance := range instances {
+			for _, ip := range node.IPAddresses {
+				if instance.Endpoint.Address == ip {
+					out = append(out, instance)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// GetProxyWorkloadLabels implements model.ServiceDiscovery
+func (c *Controller) GetProxyWorkloadLabels(node *model.Proxy) labels.Collection {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	var out labels.Collection
+	for _, instances := range c.instances {
+		for _, instance := range instances {
+			for _, ip := range node.IPAddresses {
+				if instance.Endpoint.Address == ip {
+					out = append(out, instance.Endpoint.Labels)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// GetIstioServiceAccounts implements model.ServiceDiscovery
+// The catalog does not hold workload identities.
+func (c *Controller) GetIstioServiceAccounts(*model.Service, []int) []string {
+	return nil
+}
+
+// NetworkGateways implements model.ServiceDiscovery
+func (c *Controller) NetworkGateways() []*model.NetworkGateway {
+	return nil
+}
+
+// AppendServiceHandler implements model.Controller
+func (c *Controller) AppendServiceHandler(f func(*model.Service, model.Event)) {
+	c.handlersMutex.Lock()
+	defer c.handlersMutex.Unlock()
+	c.handlers = append(c.handlers, f)
+}
+
+// AppendWorkloadHandler implements model.Controller
+// The catalog only holds service instances, so workload handlers are never called.
+func (c *Controller) AppendWorkloadHandler(func(*model.WorkloadInstance, model.Event)) {}
+
+// HasSynced implements model.Controller
+func (c *Controller) HasSynced() bool {
+	return c.synced.Load()
+}
+
+// Run watches the catalog with blocking queries until stop is closed.
+func (c *Controller) Run(stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	var index uint64
+	delay := initialRetryDelay
+	// backoff waits for the retry delay and doubles it, returning false if the controller is stopped.
+	backoff := func() bool {
+		select {
+		case <-time.After(delay):
+		case <-stop:
+			return false
+		}
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+		return true
+	}
+	for {
+		names, newIndex, err := c.client.services(ctx, index, c.opts.WaitTime)
+		if err == nil {
+			if newIndex == index && index != 0 {
+				// Blocking query timed out without changes
+				continue
+			}
+			err = c.refresh(ctx, names)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warnf("failed to sync catalog %s, retrying in %v: %v", c.opts.ServerURL, delay, err)
+			if !backoff() {
+				return
+			}
+			continue
+		}
+		if !c.synced.Load() {
+			log.Infof("synced catalog %s with %d services", c.opts.ServerURL, len(names))
+			c.synced.Store(true)
+		}
+
+		switch {
+		case newIndex == 0:
+			// The catalog did not return its index, so the next query would not block. The index is clamped to 1, and
+			// we back off so that the catalog is not polled in a tight loop.
+			log.Warnf("catalog %s did not return a %s, polling again in %v", c.opts.ServerURL, indexHeader, delay)
+			index = 1
+			if !backoff() {
+				return
+			}
+		case newIndex < index:
+			// The index may go backwards if the catalog is restored, in which case we start over.
+			log.Infof("catalog %s index went backwards from %d to %d, resyncing", c.opts.ServerURL, index, newIndex)
+			index = 0
+			delay = initialRetryDelay
+		default:
+			index = newIndex
+			delay = initialRetryDelay
+		}
+	}
+}
+
+// refresh reads all the given services from the catalog, and notifies the changes since the last refresh.
+func (c *Controller) refresh(ctx context.Context, names []string) error {
+	services := make(map[host.Name]*model.Service, len(names))
+	instances := make(map[host.Name][]*model.ServiceInstance, len(names))
+	for _, name := range names {
+		entries, err := c.client.service(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to read service %s: %v", name, err)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		svc := convertService(name, entries)
+		for _, entry := range entries {
+			if instance := convertInstance(svc, entry, c.opts.ClusterID); instance != nil {
+				instances[svc.Hostname] = append(instances[svc.Hostname], instance)
+			}
+		}
+		sortInstances(instances[svc.Hostname])
+		services[svc.Hostname] = svc
+	}
+
+	c.mutex.Lock()
+	oldServices, oldInstances := c.services, c.instances
+	// Keep the existing service if it did not change, to preserve its creation time.
+	for hostname, svc := range services {
+		if old, f := oldServices[hostname]; f && serviceEqual(old, svc) {
+			services[hostname] = old
+			for _, instance := range instances[hostname] {
+				instance.Service = old
+			}
+		}
+	}
+	c.services, c.instances = services, instances
+	c.mutex.Unlock()
+
+	for hostname, old := range oldServices {
+		if _, f := services[hostname]; !f {
+			c.notify(old, model.EventDelete)
+		}
+	}
+	for hostname, svc := range services {
+		old, f := oldServices[hostname]
+		switch {
+		case !f:
+			// The full push for the new service will pick up the endpoints.
+			c.opts.XDSUpdater.EDSCacheUpdate(c.opts.ClusterID, string(hostname), svc.Attributes.Namespace, endpoints(instances[hostname]))
+			c.notify(svc, model.EventAdd)
+		case old != svc:
+			c.opts.XDSUpdater.EDSCacheUpdate(c.opts.ClusterID, string(hostname), svc.Attributes.Namespace, endpoints(instances[hostname]))
+			c.notify(svc, model.EventUpdate)
+		case !reflect.DeepEqual(endpoints(oldInstances[hostname]), endpoints(instances[hostname])):
+			c.opts.XDSUpdater.EDSUpdate(c.opts.ClusterID, string(hostname), svc.Attributes.Namespace, endpoints(instances[hostname]))
+		}
+	}
+	return nil
+}
+
+func (c *Controller) notify(svc *model.Service, event model.Event) {
+	log.Debugf("service %s %v", svc.Hostname, event)
+	c.opts.XDSUpdater.SvcUpdate(c.opts.ClusterID, string(svc.Hostname), svc.Attributes.Namespace, event)
+	c.handlersMutex.RLock()
+	defer c.handlersMutex.RUnlock()
+	for _, f := range c.handlers {
+		f(svc, event)
+	}
+}
+
+func serviceEqual(a, b *model.Service) bool {
+	return a.Hostname == b.Hostname && a.MeshExternal == b.MeshExternal && reflect.DeepEqual(a.Ports, b.Ports)
+}
+
+func endpoints(instances []*model.ServiceInstance) []*model.IstioEndpoint {
+	out := make([]*model.IstioEndpoint, 0, len(instances))
+	for _, instance := range instances {
+		out = append(out, instance.Endpoint)
+	}
+	return out
+}
+
+func sortInstances(instances []*model.ServiceInstance) {
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Endpoint.Address != instances[j].Endpoint.Address {
+			return instances[i].Endpoint.Address < instances[j].Endpoint.Address
+		}
+		return instances[i].Endpoint.EndpointPort < instances[j].Endpoint.EndpointPort
+	})
+}
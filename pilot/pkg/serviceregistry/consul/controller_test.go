@@ -0,0 +1,259 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func setupController(t *testing.T) (*FakeCatalog, *Controller, *controller.FakeXdsUpdater) {
+	catalog := NewFakeCatalog(t)
+	xds := controller.NewFakeXDS()
+	c := NewController(Options{
+		ServerURL:  catalog.URL,
+		ClusterID:  "cluster1",
+		XDSUpdater: xds,
+		WaitTime:   time.Second,
+	})
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	go c.Run(stop)
+	return catalog, c, xds
+}
+
+func waitEvent(t *testing.T, xds *controller.FakeXdsUpdater, eventType, id string) *controller.FakeXdsEvent {
+	t.Helper()
+	ev := xds.Wait(eventType)
+	if ev == nil {
+		t.Fatalf("timed out waiting for %s event", eventType)
+	}
+	if ev.ID != id {
+		t.Fatalf("expected %s event for %s, got %s", eventType, id, ev.ID)
+	}
+	return ev
+}
+
+func TestController(t *testing.T) {
+	catalog, c, xds := setupController(t)
+	catalog.Register(&CatalogService{
+		Node:        "node1",
+		Address:     "10.0.0.1",
+		Datacenter:  "dc1",
+		ServiceID:   "productpage-1",
+		ServiceName: "productpage",
+		ServicePort: 9080,
+		ServiceMeta: map[string]string{protocolMetaKey: "http", "version": "v1"},
+	})
+	retry.UntilSuccessOrFail(t, func() error {
+		if !c.HasSynced() {
+			return errors.New("not synced")
+		}
+		return nil
+	}, retry.Timeout(5*time.Second))
+
+	hostname := "productpage.service.consul"
+	ev := waitEvent(t, xds, "eds cache", hostname)
+	if len(ev.Endpoints) != 1 || ev.Endpoints[0].Address != "10.0.0.1" {
+		t.Fatalf("unexpected endpoints %v", ev.Endpoints)
+	}
+	waitEvent(t, xds, "service", hostname)
+
+	svc, _ := c.GetService("productpage.service.consul")
+	if svc == nil {
+		t.Fatalf("service not found")
+	}
+	if services, _ := c.Services(); len(services) != 1 {
+		t.Fatalf("expected 1 service, got %v", services)
+	}
+
+	// A new instance on the same port only updates the endpoints.
+	catalog.Register(&CatalogService{
+		Node:        "node2",
+		Address:     "10.0.0.2",
+		Datacenter:  "dc1",
+		ServiceID:   "productpage-2",
+		ServiceName: "productpage",
+		ServicePort: 9080,
+		ServiceMeta: map[string]string{protocolMetaKey: "http", "version": "v2"},
+	})
+	ev = waitEvent(t, xds, "eds", hostname)
+	if len(ev.Endpoints) != 2 {
+		t.Fatalf("unexpected endpoints %v", ev.Endpoints)
+	}
+	if got, _ := c.GetService("productpage.service.consul"); got != svc {
+		t.Fatalf("service should not be replaced when unchanged")
+	}
+
+	instances := c.InstancesByPort(svc, 9080, labels.Collection{{"version": "v2"}})
+	if len(instances) != 1 || instances[0].Endpoint.Address != "10.0.0.2" {
+		t.Fatalf("unexpected instances %v", instances)
+	}
+	if instances := c.InstancesByPort(svc, 9090, nil); len(instances) != 0 {
+		t.Fatalf("unexpected instances %v", instances)
+	}
+
+	proxy := &model.Proxy{IPAddresses: []string{"10.0.0.1"}}
+	instances = c.GetProxyServiceInstances(proxy)
+	if len(instances) != 1 || instances[0].Service.Hostname != svc.Hostname {
+		t.Fatalf("unexpected proxy instances %v", instances)
+	}
+	if lbls := c.GetProxyWorkloadLabels(proxy); len(lbls) != 1 || lbls[0]["version"] != "v1" {
+		t.Fatalf("unexpected proxy labels %v", lbls)
+	}
+
+	// A new port changes the service.
+	catalog.Register(&CatalogService{
+		Node:        "node2",
+		Address:     "10.0.0.2",
+		Datacenter:  "dc1",
+		ServiceID:   "productpage-admin",
+		ServiceName: "productpage",
+		ServicePort: 9090,
+	})
+	waitEvent(t, xds, "eds cache", hostname)
+	waitEvent(t, xds, "service", hostname)
+	svc, _ = c.GetService("productpage.service.consul")
+	if len(svc.Ports) != 2 {
+		t.Fatalf("unexpected ports %v", svc.Ports)
+	}
+
+	catalog.Deregister("productpage", "productpage-1")
+	catalog.Deregister("productpage", "productpage-2")
+	catalog.Deregister("productpage", "productpage-admin")
+	waitEvent(t, xds, "service", hostname)
+	retry.UntilSuccessOrFail(t, func() error {
+		if svc, _ := c.GetService("productpage.service.consul"); svc != nil {
+			return errors.New("service not deleted")
+		}
+		return nil
+	}, retry.Timeout(5*time.Second))
+}
+
+func TestControllerServiceHandler(t *testing.T) {
+	catalog, c, _ := setupController(t)
+	events := make(chan model.Event, 10)
+	c.AppendServiceHandler(func(_ *model.Service, event model.Event) {
+		events <- event
+	})
+	catalog.Register(&CatalogService{Address: "10.0.0.1", ServiceID: "details-1", ServiceName: "details", ServicePort: 9080})
+	expectEvent(t, events, model.EventAdd)
+	catalog.Deregister("details", "details-1")
+	expectEvent(t, events, model.EventDelete)
+}
+
+func expectEvent(t *testing.T, events chan model.Event, expected model.Event) {
+	t.Helper()
+	select {
+	case ev := <-events:
+		if ev != expected {
+			t.Fatalf("expected event %v, got %v", expected, ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for event %v", expected)
+	}
+}
+
+func TestControllerCatalogIndex(t *testing.T) {
+	cases := []struct {
+		name string
+		// indexes returned by the successive queries, the last one is repeated
+		indexes []string
+		// maximum number of queries expected
+		maxQueries int32
+		// expected index query parameters of the first queries
+		wantQueries []string
+	}{
+		{
+			name:        "missing index",
+			indexes:     []string{""},
+			maxQueries:  5,
+			wantQueries: []string{"", "1", "1"},
+		},
+		{
+			name:        "zero index",
+			indexes:     []string{"0"},
+			maxQueries:  5,
+			wantQueries: []string{"", "1", "1"},
+		},
+		{
+			name:        "index going backwards",
+			indexes:     []string{"10", "5", "0"},
+			maxQueries:  6,
+			wantQueries: []string{"", "10", "", "1"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			var queries []string
+			var count int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/catalog/services" {
+					_, _ = w.Write([]byte("[]"))
+					return
+				}
+				n := atomic.AddInt32(&count, 1)
+				mutex.Lock()
+				queries = append(queries, r.URL.Query().Get("index"))
+				mutex.Unlock()
+				index := tt.indexes[len(tt.indexes)-1]
+				if int(n) <= len(tt.indexes) {
+					index = tt.indexes[n-1]
+				}
+				if index != "" {
+					w.Header().Set(indexHeader, index)
+				}
+				_, _ = w.Write([]byte("{}"))
+			}))
+			t.Cleanup(server.Close)
+
+			c := NewController(Options{ServerURL: server.URL, XDSUpdater: controller.NewFakeXDS(), WaitTime: time.Second})
+			stop := make(chan struct{})
+			defer close(stop)
+			go c.Run(stop)
+
+			retry.UntilSuccessOrFail(t, func() error {
+				mutex.Lock()
+				defer mutex.Unlock()
+				if len(queries) < len(tt.wantQueries) {
+					return fmt.Errorf("expected at least %d queries, got %v", len(tt.wantQueries), queries)
+				}
+				return nil
+			}, retry.Timeout(5*time.Second))
+
+			if n := atomic.LoadInt32(&count); n > tt.maxQueries {
+				t.Fatalf("expected at most %d queries, got %d", tt.maxQueries, n)
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			if !reflect.DeepEqual(queries[:len(tt.wantQueries)], tt.wantQueries) {
+				t.Fatalf("expected queries with index %v, got %v", tt.wantQueries, queries)
+			}
+		})
+	}
+}
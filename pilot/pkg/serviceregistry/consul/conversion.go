@@ -0,0 +1,142 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"istio.io/api/label"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
+)
+
+const (
+	// serviceSuffix is appended to the catalog service name to build the service hostname.
+	serviceSuffix = "service.consul"
+
+	// protocolMetaKey is the service meta key holding the protocol of the service port. Defaults to TCP.
+	protocolMetaKey = "protocol"
+
+	// externalMetaKey is the service meta key marking the service as external to the mesh, if set to "true".
+	externalMetaKey = "external"
+)
+
+func serviceHostname(name string) host.Name {
+	return host.Name(name + "." + serviceSuffix)
+}
+
+// convertService builds the service for the instances registered in the catalog under name. Each instance
+// registers a single port, the service ports are the union of the instance ports.
+func convertService(name string, instances []*CatalogService) *model.Service {
+	meshExternal := false
+	ports := map[int]*model.Port{}
+	for _, instance := range instances {
+		if _, f := ports[instance.ServicePort]; !f {
+			p := convertProtocol(instance.ServiceMeta[protocolMetaKey])
+			ports[instance.ServicePort] = &model.Port{
+				Name:     fmt.Sprintf("%s-%d", strings.ToLower(string(p)), instance.ServicePort),
+				Port:     instance.ServicePort,
+				Protocol: p,
+			}
+		}
+		if instance.ServiceMeta[externalMetaKey] == "true" {
+			meshExternal = true
+		}
+	}
+
+	svcPorts := make(model.PortList, 0, len(ports))
+	for _, p := range ports {
+		svcPorts = append(svcPorts, p)
+	}
+	sort.Slice(svcPorts, func(i, j int) bool {
+		return svcPorts[i].Port < svcPorts[j].Port
+	})
+
+	return &model.Service{
+		Hostname:     serviceHostname(name),
+		Address:      constants.UnspecifiedIP,
+		Ports:        svcPorts,
+		MeshExternal: meshExternal,
+		Resolution:   model.ClientSideLB,
+		CreationTime: time.Now(),
+		Attributes: model.ServiceAttributes{
+			ServiceRegistry: string(serviceregistry.Consul),
+			Name:            string(serviceHostname(name)),
+		},
+	}
+}
+
+// convertInstance builds the service instance for an instance registered in the catalog.
+func convertInstance(svc *model.Service, instance *CatalogService, clusterID string) *model.ServiceInstance {
+	port, f := svc.Ports.GetByPort(instance.ServicePort)
+	if !f {
+		return nil
+	}
+	addr := instance.ServiceAddress
+	if addr == "" {
+		addr = instance.Address
+	}
+	lbls := convertLabels(instance.ServiceMeta)
+	tlsMode := model.DisabledTLSModeLabel
+	if mode, f := lbls[label.SecurityTlsMode.Name]; f {
+		tlsMode = mode
+	}
+	return &model.ServiceInstance{
+		Service:     svc,
+		ServicePort: port,
+		Endpoint: &model.IstioEndpoint{
+			Address:         addr,
+			EndpointPort:    uint32(instance.ServicePort),
+			ServicePortName: port.Name,
+			Labels:          lbls,
+			Locality: model.Locality{
+				Label:     instance.Datacenter,
+				ClusterID: clusterID,
+			},
+			TLSMode: tlsMode,
+		},
+	}
+}
+
+// convertLabels returns the labels of an instance, which are its service meta minus the keys reserved for Istio.
+func convertLabels(meta map[string]string) labels.Instance {
+	out := make(labels.Instance, len(meta))
+	for k, v := range meta {
+		if k == protocolMetaKey || k == externalMetaKey {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func convertProtocol(name string) protocol.Instance {
+	if name == "" {
+		return protocol.TCP
+	}
+	p := protocol.Parse(name)
+	if p == protocol.Unsupported {
+		log.Warnf("unsupported protocol value: %s", name)
+		return protocol.TCP
+	}
+	return p
+}
@@ -0,0 +1,126 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
+)
+
+func TestConvertService(t *testing.T) {
+	instances := []*CatalogService{
+		{
+			ServiceName: "productpage",
+			ServicePort: 9080,
+			ServiceMeta: map[string]string{protocolMetaKey: "http"},
+		},
+		{
+			ServiceName: "productpage",
+			ServicePort: 9090,
+		},
+		{
+			ServiceName: "productpage",
+			ServicePort: 9080,
+			ServiceMeta: map[string]string{protocolMetaKey: "grpc"},
+		},
+	}
+	svc := convertService("productpage", instances)
+	if svc.Hostname != "productpage.service.consul" {
+		t.Fatalf("unexpected hostname %v", svc.Hostname)
+	}
+	if svc.MeshExternal {
+		t.Fatalf("service should not be external")
+	}
+	expected := model.PortList{
+		{Name: "http-9080", Port: 9080, Protocol: protocol.HTTP},
+		{Name: "tcp-9090", Port: 9090, Protocol: protocol.TCP},
+	}
+	if len(svc.Ports) != len(expected) {
+		t.Fatalf("expected ports %v, got %v", expected, svc.Ports)
+	}
+	for i, p := range expected {
+		if *svc.Ports[i] != *p {
+			t.Errorf("expected port %v, got %v", p, svc.Ports[i])
+		}
+	}
+
+	external := convertService("db", []*CatalogService{{ServicePort: 5432, ServiceMeta: map[string]string{externalMetaKey: "true"}}})
+	if !external.MeshExternal {
+		t.Fatalf("service should be external")
+	}
+}
+
+func TestConvertInstance(t *testing.T) {
+	cases := []struct {
+		name            string
+		instance        *CatalogService
+		expectedAddress string
+		expectedLabels  labels.Instance
+		expectedTLSMode string
+	}{
+		{
+			name: "service address",
+			instance: &CatalogService{
+				Address:        "10.0.0.1",
+				ServiceAddress: "172.16.0.1",
+				ServicePort:    9080,
+				ServiceMeta:    map[string]string{"version": "v1", protocolMetaKey: "http"},
+			},
+			expectedAddress: "172.16.0.1",
+			expectedLabels:  labels.Instance{"version": "v1"},
+			expectedTLSMode: model.DisabledTLSModeLabel,
+		},
+		{
+			name: "node address",
+			instance: &CatalogService{
+				Address:     "10.0.0.1",
+				ServicePort: 9080,
+				ServiceMeta: map[string]string{"security.istio.io/tlsMode": "istio"},
+			},
+			expectedAddress: "10.0.0.1",
+			expectedLabels:  labels.Instance{"security.istio.io/tlsMode": "istio"},
+			expectedTLSMode: model.IstioMutualTLSModeLabel,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.instance.ServiceName = "productpage"
+			tt.instance.Datacenter = "dc1"
+			svc := convertService("productpage", []*CatalogService{tt.instance})
+			instance := convertInstance(svc, tt.instance, "cluster1")
+			if instance == nil {
+				t.Fatalf("expected instance")
+			}
+			if instance.Endpoint.Address != tt.expectedAddress {
+				t.Errorf("expected address %v, got %v", tt.expectedAddress, instance.Endpoint.Address)
+			}
+			if !instance.Endpoint.Labels.Equals(tt.expectedLabels) {
+				t.Errorf("expected labels %v, got %v", tt.expectedLabels, instance.Endpoint.Labels)
+			}
+			if instance.Endpoint.TLSMode != tt.expectedTLSMode {
+				t.Errorf("expected TLS mode %v, got %v", tt.expectedTLSMode, instance.Endpoint.TLSMode)
+			}
+			if instance.Endpoint.Locality.Label != "dc1" || instance.Endpoint.Locality.ClusterID != "cluster1" {
+				t.Errorf("unexpected locality %v", instance.Endpoint.Locality)
+			}
+			if instance.ServicePort.Port != 9080 || instance.Endpoint.EndpointPort != 9080 {
+				t.Errorf("unexpected port %v", instance.ServicePort)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/test"
+)
+
+// FakeCatalog is an in memory catalog served over HTTP, supporting blocking queries. It is intended for tests.
+type FakeCatalog struct {
+	// URL of the catalog HTTP API
+	URL string
+
+	mutex sync.Mutex
+	index uint64
+	// instances keyed by service name, then service id
+	instances map[string]map[string]*CatalogService
+	// changed is closed and replaced whenever the catalog changes
+	changed chan struct{}
+}
+
+// NewFakeCatalog starts a fake catalog, which is stopped at the end of the test.
+func NewFakeCatalog(t test.Failer) *FakeCatalog {
+	f := &FakeCatalog{
+		index:     1,
+		instances: map[string]map[string]*CatalogService{},
+		changed:   make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/catalog/services", f.handleServices)
+	mux.HandleFunc("/v1/catalog/service/", f.handleService)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	f.URL = server.URL
+	return f
+}
+
+// Register adds or updates an instance in the catalog.
+func (f *FakeCatalog) Register(instance *CatalogService) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.instances[instance.ServiceName] == nil {
+		f.instances[instance.ServiceName] = map[string]*CatalogService{}
+	}
+	f.instances[instance.ServiceName][instance.ServiceID] = instance
+	f.bump()
+}
+
+// Deregister removes an instance from the catalog.
+func (f *FakeCatalog) Deregister(serviceName, serviceID string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.instances[serviceName], serviceID)
+	if len(f.instances[serviceName]) == 0 {
+		delete(f.instances, serviceName)
+	}
+	f.bump()
+}
+
+// bump increments the catalog index and wakes up blocking queries. Must be called with mutex held.
+func (f *FakeCatalog) bump() {
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *FakeCatalog) handleServices(w http.ResponseWriter, r *http.Request) {
+	index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+	wait, err := time.ParseDuration(r.URL.Query().Get("wait"))
+	if err != nil {
+		wait = time.Minute
+	}
+
+	f.mutex.Lock()
+	for f.index <= index {
+		changed := f.changed
+		f.mutex.Unlock()
+		select {
+		case <-changed:
+		case <-time.After(wait):
+		case <-r.Context().Done():
+			return
+		}
+		f.mutex.Lock()
+		if f.changed == changed {
+			// Timed out
+			break
+		}
+	}
+	services := map[string][]string{}
+	for name, instances := range f.instances {
+		tags := []string{}
+		for _, instance := range instances {
+			tags = append(tags, instance.ServiceTags...)
+		}
+		services[name] = tags
+	}
+	f.writeResponse(w, services)
+	f.mutex.Unlock()
+}
+
+func (f *FakeCatalog) handleService(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/v1/catalog/service/")
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	out := []*CatalogService{}
+	for _, instance := range f.instances[name] {
+		out = append(out, instance)
+	}
+	f.writeResponse(w, out)
+}
+
+// writeResponse writes the response with the current index. Must be called with mutex held.
+func (f *FakeCatalog) writeResponse(w http.ResponseWriter, body interface{}) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(indexHeader, strconv.FormatUint(f.index, 10))
+	_, _ = w.Write(b)
+}
@@ -244,14 +244,24 @@ func (m *Multicluster) AddMemberCluster(clusterID string, rc *secretcontroller.C
 		// This requires RBAC permissions - a low-priv Istiod should not attempt to patch but rely on
 		// operator or CI/CD
 		if features.InjectionWebhookConfigName != "" && m.caBundleWatcher != nil {
-			// TODO prevent istiods in primary clusters from trying to patch eachother. should we also leader-elect?
-			log.Infof("initializing webhook cert patch for cluster %s", clusterID)
-			patcher, err := webhooks.NewWebhookCertPatcher(client.Kube(), m.revision, webhookName, m.caBundleWatcher.GetCABundle())
-			if err != nil {
-				log.Errorf("could not initialize webhook cert patcher: %v", err)
-			} else {
-				patcher.Run(clusterStopCh)
-			}
+			// Block server exit on graceful termination of the leader controller.
+			m.s.RunComponentAsyncAndWait(func(_ <-chan struct{}) error {
+				electionID := leaderelection.WebhookPatcherElectionID(m.revision)
+				log.Infof("joining leader-election for %s in %s on cluster %s", electionID, options.SystemNamespace, options.ClusterID)
+				leaderelection.
+					NewLeaderElection(options.SystemNamespace, m.serverID, electionID, client.Kube()).
+					AddRunFunction(func(leaderStop <-chan struct{}) {
+						log.Infof("initializing webhook cert patch for cluster %s", clusterID)
+						patcher, err := webhooks.NewWebhookCertPatcher(client.Kube(), m.revision, webhookName, m.caBundleWatcher.GetCABundle())
+						if err != nil {
+							log.Errorf("could not initialize webhook cert patcher: %v", err)
+							return
+						}
+						patcher.Run(leaderStop)
+						<-leaderStop
+					}).Run(clusterStopCh)
+				return nil
+			})
 		}
 		// Patch validation webhook cert
 		if m.caBundleWatcher != nil {
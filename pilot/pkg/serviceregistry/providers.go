@@ -24,4 +24,6 @@ const (
 	Kubernetes ProviderID = "Kubernetes"
 	// External is a service registry for externally provided ServiceEntries
 	External = "External"
+	// Consul is a service registry backed by a Consul compatible catalog HTTP API
+	Consul ProviderID = "Consul"
 )
@@ -98,6 +98,7 @@ type Call struct {
 	Address string
 	Port    int
 	Path    string
+	Method  string
 
 	// Protocol describes the protocol type. TLS encapsulation is separate
 	Protocol Protocol
@@ -130,6 +131,9 @@ func (c Call) FillDefaults() Call {
 	if c.Path == "" {
 		c.Path = "/"
 	}
+	if c.Method == "" {
+		c.Method = http.MethodGet
+	}
 	if c.TLS == "" {
 		c.TLS = Plaintext
 	}
@@ -202,13 +206,13 @@ func (r Result) Matches(t *testing.T, want Result) {
 }
 
 type Simulation struct {
-	t         *testing.T
+	t         test.Failer
 	Listeners []*listener.Listener
 	Clusters  []*cluster.Cluster
 	Routes    []*route.RouteConfiguration
 }
 
-func NewSimulationFromConfigGen(t *testing.T, s *v1alpha3.ConfigGenTest, proxy *model.Proxy) *Simulation {
+func NewSimulationFromConfigGen(t test.Failer, s *v1alpha3.ConfigGenTest, proxy *model.Proxy) *Simulation {
 	sim := &Simulation{
 		t:         t,
 		Listeners: s.Listeners(proxy),
@@ -222,16 +226,29 @@ func NewSimulation(t *testing.T, s *xds.FakeDiscoveryServer, proxy *model.Proxy)
 	return NewSimulationFromConfigGen(t, s.ConfigGenTest, proxy)
 }
 
+// NewSimulationFromResources builds a simulation from already generated xDS resources, such as those
+// extracted from an Envoy config dump. The Failer is used to report malformed configuration; outside
+// of tests, test.Wrap can be used to turn these failures into errors.
+func NewSimulationFromResources(t test.Failer, listeners []*listener.Listener,
+	clusters []*cluster.Cluster, routes []*route.RouteConfiguration) *Simulation {
+	return &Simulation{
+		t:         t,
+		Listeners: listeners,
+		Clusters:  clusters,
+		Routes:    routes,
+	}
+}
+
 // withT swaps out the testing struct. This allows executing sub tests.
-func (sim *Simulation) withT(t *testing.T) *Simulation {
+func (sim *Simulation) withT(t test.Failer) *Simulation {
 	cpy := *sim
 	cpy.t = t
 	return &cpy
 }
 
-func (sim *Simulation) RunExpectations(es []Expect) {
+func (sim *Simulation) RunExpectations(t *testing.T, es []Expect) {
 	for _, e := range es {
-		sim.t.Run(e.Name, func(t *testing.T) {
+		t.Run(e.Name, func(t *testing.T) {
 			sim.withT(t).Run(e.Call).Matches(t, e.Result)
 		})
 	}
@@ -387,13 +404,71 @@ func (sim *Simulation) matchRoute(vh *route.VirtualHost, input Call) *route.Rout
 			sim.t.Fatalf("unknown route path type")
 		}
 
-		// TODO this only handles path - we need to add headers, query params, etc to be complete.
+		if !sim.matchHeaders(r.Match.GetHeaders(), input) {
+			continue
+		}
+
+		// TODO this only handles path and headers - we need to add query params, etc to be complete.
 
 		return r
 	}
 	return nil
 }
 
+// headerValue returns the value of a header, including the pseudo headers Envoy matches on.
+func headerValue(input Call, name string) (string, bool) {
+	switch name {
+	case ":path":
+		return input.Path, true
+	case ":method":
+		return input.Method, true
+	case ":authority":
+		name = "Host"
+	}
+	v := input.Headers.Values(name)
+	if len(v) == 0 {
+		return "", false
+	}
+	return strings.Join(v, ","), true
+}
+
+func (sim *Simulation) matchHeaders(headers []*route.HeaderMatcher, input Call) bool {
+	for _, h := range headers {
+		v, found := headerValue(input, h.Name)
+		var match bool
+		switch hm := h.GetHeaderMatchSpecifier().(type) {
+		case *route.HeaderMatcher_PresentMatch:
+			match = found == hm.PresentMatch
+		case *route.HeaderMatcher_ExactMatch:
+			match = found && v == hm.ExactMatch
+		case *route.HeaderMatcher_PrefixMatch:
+			match = found && strings.HasPrefix(v, hm.PrefixMatch)
+		case *route.HeaderMatcher_SuffixMatch:
+			match = found && strings.HasSuffix(v, hm.SuffixMatch)
+		case *route.HeaderMatcher_ContainsMatch:
+			match = found && strings.Contains(v, hm.ContainsMatch)
+		case *route.HeaderMatcher_SafeRegexMatch:
+			r, err := regexp.Compile("^(?:" + hm.SafeRegexMatch.GetRegex() + ")$")
+			if err != nil {
+				sim.t.Fatalf("invalid regex %v: %v", hm.SafeRegexMatch.GetRegex(), err)
+			}
+			match = found && r.MatchString(v)
+		case nil:
+			// No specifier means the header must be present
+			match = found
+		default:
+			sim.t.Fatalf("unknown header match type %T", hm)
+		}
+		if h.InvertMatch {
+			match = !match
+		}
+		if !match {
+			return false
+		}
+	}
+	return true
+}
+
 func (sim *Simulation) matchVirtualHost(rc *route.RouteConfiguration, host string) *route.VirtualHost {
 	// Exact match
 	for _, vh := range rc.VirtualHosts {
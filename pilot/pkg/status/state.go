@@ -64,6 +64,9 @@ type DistributionController struct {
 	workers         WorkerQueue
 	StaleInterval   time.Duration
 	cmInformer      cache.SharedIndexInformer
+	// Fence reports whether the status may still be written, for example because this instance still leads the
+	// election the controller runs under. If nil, the status is always written.
+	Fence func() bool
 }
 
 func NewController(restConfig *rest.Config, namespace string, cs model.ConfigStore) *DistributionController {
@@ -191,6 +194,10 @@ func (c *DistributionController) writeStatus(config Resource, distributionState
 	if needsReconcile, desiredStatus := ReconcileStatuses(current, distributionState, current.Generation); needsReconcile {
 		// technically, we should be updating probe time even when reconciling isn't needed, but
 		// I'm skipping that for efficiency.
+		if c.Fence != nil && !c.Fence() {
+			scope.Infof("skipping status update for %v (no longer leader)", config)
+			return
+		}
 		current.Status = desiredStatus
 		_, err := c.configStore.UpdateStatus(*current)
 		if err != nil {
@@ -48,6 +48,11 @@ type TrustBundle struct {
 	endpoints          []string
 	endpointUpdateChan chan struct{}
 	remoteCaCertPool   *x509.CertPool
+
+	// trustDomainEndpoints are the SPIFFE bundle endpoints of the federated trust domains, guarded by endpointMutex.
+	trustDomainEndpoints map[string]string
+	// trustDomainCerts are the trust anchors of each federated trust domain, guarded by mutex.
+	trustDomainCerts map[string][]string
 }
 
 var (
@@ -115,6 +120,17 @@ func (tb *TrustBundle) GetTrustBundle() []string {
 	return trustedCerts
 }
 
+// GetTrustDomainBundles returns the trust anchors of each federated trust domain.
+func (tb *TrustBundle) GetTrustDomainBundles() map[string][]string {
+	tb.mutex.RLock()
+	defer tb.mutex.RUnlock()
+	bundles := make(map[string][]string, len(tb.trustDomainCerts))
+	for trustDomain, certs := range tb.trustDomainCerts {
+		bundles[trustDomain] = certs
+	}
+	return bundles
+}
+
 func verifyTrustAnchor(trustAnchor string) error {
 	block, _ := pem.Decode([]byte(trustAnchor))
 	if block == nil {
@@ -200,6 +216,18 @@ func (tb *TrustBundle) updateRemoteEndpoint(spiffeEndpoints []string) {
 	tb.endpointUpdateChan <- struct{}{}
 }
 
+// UpdateTrustDomainEndpoints sets the SPIFFE bundle endpoints of the federated trust domains. Their bundles are fetched
+// along with the remote trust anchors, but kept per trust domain instead of being merged into the trust bundle.
+func (tb *TrustBundle) UpdateTrustDomainEndpoints(endpoints map[string]string) {
+	tb.endpointMutex.Lock()
+	tb.trustDomainEndpoints = endpoints
+	tb.endpointMutex.Unlock()
+	select {
+	case tb.endpointUpdateChan <- struct{}{}:
+	default:
+	}
+}
+
 // AddMeshConfigUpdate : Update trustAnchor configurations from meshConfig
 func (tb *TrustBundle) AddMeshConfigUpdate(cfg *meshconfig.MeshConfig) error {
 	var err error
@@ -259,6 +287,56 @@ func (tb *TrustBundle) fetchRemoteTrustAnchors() {
 	if err != nil {
 		trustBundleLog.Errorf("failed to update meshConfig Spiffe trustAnchors: %v", err)
 	}
+	tb.fetchTrustDomainBundles()
+}
+
+// fetchTrustDomainBundles fetches the bundle of each federated trust domain from its endpoint. The last bundle fetched
+// of a trust domain is kept when its endpoint fails, so that one unreachable endpoint does not affect the others.
+func (tb *TrustBundle) fetchTrustDomainBundles() {
+	tb.endpointMutex.RLock()
+	endpoints := tb.trustDomainEndpoints
+	tb.endpointMutex.RUnlock()
+
+	bundles := make(map[string][]string, len(endpoints))
+	tb.mutex.RLock()
+	for trustDomain := range endpoints {
+		if certs, ok := tb.trustDomainCerts[trustDomain]; ok {
+			bundles[trustDomain] = certs
+		}
+	}
+	tb.mutex.RUnlock()
+
+	for trustDomain, endpoint := range endpoints {
+		trustDomainAnchorMap, err := spiffe.RetrieveSpiffeBundleRootCerts(
+			map[string]string{trustDomain: endpoint}, tb.remoteCaCertPool, remoteTimeout)
+		if err != nil {
+			trustBundleLog.Errorf("unable to fetch the bundle of trust domain %s from endpoint %s, keeping the last one: %s",
+				trustDomain, endpoint, err)
+			continue
+		}
+		certs := []string{}
+		for _, cert := range trustDomainAnchorMap[trustDomain] {
+			certs = append(certs, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})))
+		}
+		bundles[trustDomain] = certs
+	}
+
+	tb.mutex.Lock()
+	changed := len(bundles) != len(tb.trustDomainCerts)
+	for trustDomain, certs := range bundles {
+		if cached, ok := tb.trustDomainCerts[trustDomain]; !ok || !isEqSliceStr(certs, cached) {
+			changed = true
+		}
+	}
+	tb.trustDomainCerts = bundles
+	tb.mutex.Unlock()
+
+	if changed {
+		trustBundleLog.Infof("updated the bundles of %d federated trust domains", len(bundles))
+		if tb.updatecb != nil {
+			tb.updatecb()
+		}
+	}
 }
 
 func (tb *TrustBundle) ProcessRemoteTrustAnchors(stop <-chan struct{}, pollInterval time.Duration) {
@@ -333,3 +333,59 @@ func TestAddMeshConfigUpdate(t *testing.T) {
 	tb.AddMeshConfigUpdate(&meshconfig.MeshConfig{CaCertificates: []*meshconfig.MeshConfig_CertificateData{}})
 	expectTbCount(t, tb, 0, 3*time.Second, "trustAnchor not updated in bundle after meshConfig cleared")
 }
+
+func TestTrustDomainBundles(t *testing.T) {
+	caCertPool, err := x509.SystemCertPool()
+	if err != nil {
+		t.Fatalf("failed to get SystemCertPool: %v", err)
+	}
+	validHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(validSpiffeX509Bundle))
+	})
+	server1 := httptest.NewTLSServer(validHandler)
+	caCertPool.AddCert(server1.Certificate())
+	defer server1.Close()
+	server2 := httptest.NewTLSServer(validHandler)
+	caCertPool.AddCert(server2.Certificate())
+	defer server2.Close()
+
+	remoteTimeout = 300 * time.Millisecond
+	tb := NewTrustBundle(caCertPool)
+	updates := 0
+	tb.UpdateCb(func() { updates++ })
+	tb.UpdateTrustDomainEndpoints(map[string]string{
+		"foo.domain.com": server1.Listener.Addr().String(),
+		"bar.domain.com": server2.Listener.Addr().String(),
+	})
+
+	tb.fetchTrustDomainBundles()
+	bundles := tb.GetTrustDomainBundles()
+	if len(bundles) != 2 || len(bundles["foo.domain.com"]) != 1 || len(bundles["bar.domain.com"]) != 1 {
+		t.Fatalf("unexpected bundles of the trust domains: %v", bundles)
+	}
+	if updates != 1 {
+		t.Errorf("got %d updates, expected 1", updates)
+	}
+
+	// The last bundle of a trust domain is kept when its endpoint fails, and the others are still fetched.
+	server1.Close()
+	tb.fetchTrustDomainBundles()
+	if got := tb.GetTrustDomainBundles(); !isEqSliceStr(got["foo.domain.com"], bundles["foo.domain.com"]) ||
+		!isEqSliceStr(got["bar.domain.com"], bundles["bar.domain.com"]) {
+		t.Errorf("bundles of the trust domains not kept: %v", got)
+	}
+	if updates != 1 {
+		t.Errorf("got %d updates, expected 1", updates)
+	}
+
+	// The bundles of the trust domains which are no longer federated are removed.
+	tb.UpdateTrustDomainEndpoints(map[string]string{"bar.domain.com": server2.Listener.Addr().String()})
+	tb.fetchTrustDomainBundles()
+	if got := tb.GetTrustDomainBundles(); len(got) != 1 || len(got["bar.domain.com"]) != 1 {
+		t.Errorf("unexpected bundles of the trust domains: %v", got)
+	}
+	if updates != 2 {
+		t.Errorf("got %d updates, expected 2", updates)
+	}
+}
@@ -113,6 +113,14 @@ type Connection struct {
 	// (last push not ACKed). When we get an ACK from Envoy, if the type is populated here, we will trigger
 	// the push.
 	blockedPushes map[string]*model.PushRequest
+
+	// lastDequeued is when the connection was last dequeued from the push queue, to enforce the minimum interval
+	// between pushes. It is only accessed with the lock of the push queue held.
+	lastDequeued time.Time
+
+	// deltaResourceVersions holds, for each type, the version of the resources last sent on a Delta XDS stream,
+	// so that unchanged resources are not sent again. It is only accessed by the stream main loop.
+	deltaResourceVersions map[string]map[string]string
 }
 
 // Event represents a config or registry event that results in a push.
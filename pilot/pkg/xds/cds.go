@@ -27,7 +27,7 @@ type CdsGenerator struct {
 	Server *DiscoveryServer
 }
 
-var _ model.XdsResourceGenerator = &CdsGenerator{}
+var _ model.XdsDeltaResourceGenerator = &CdsGenerator{}
 
 // Map of all configs that do not impact CDS
 var skippedCdsConfigs = map[config.GroupVersionKind]struct{}{
@@ -86,3 +86,21 @@ func (c CdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w *m
 	}
 	return resources, model.DefaultXdsLogDetails, nil
 }
+
+// GenerateDeltas computes Delta CDS resources. When only services were updated, only the clusters of these
+// services are built.
+func (c CdsGenerator) GenerateDeltas(proxy *model.Proxy, push *model.PushContext, updates *model.PushRequest,
+	w *model.WatchedResource) (model.Resources, model.DeletedResources, model.XdsLogDetails, bool, error) {
+	if !cdsNeedsPush(updates, proxy) {
+		return nil, nil, model.DefaultXdsLogDetails, false, nil
+	}
+	updatedClusters, removed, usedDelta := c.Server.ConfigGenerator.BuildDeltaClusters(proxy, push, updates, w)
+	resources := make(model.Resources, 0, len(updatedClusters))
+	for _, c := range updatedClusters {
+		resources = append(resources, &discovery.Resource{
+			Name:     c.Name,
+			Resource: util.MessageToAny(c),
+		})
+	}
+	return resources, removed, model.XdsLogDetails{Incremental: usedDelta}, usedDelta, nil
+}
@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -36,6 +37,7 @@ import (
 
 	"istio.io/istio/pilot/pkg/config/kube/crd"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/leaderelection"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/serviceregistry"
@@ -182,12 +184,15 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/endpointShardz", "Info about the endpoint shards", s.endpointShardz)
 	s.addDebugHandler(mux, internalMux, "/debug/cachez", "Info about the internal XDS caches", s.cachez)
 	s.addDebugHandler(mux, internalMux, "/debug/cachez?sizes=true", "Info about the size of the internal XDS caches", s.cachez)
+	s.addDebugHandler(mux, internalMux, "/debug/cachez?stats=true", "Statistics of the internal XDS caches, by xDS type", s.cachez)
 	s.addDebugHandler(mux, internalMux, "/debug/configz", "Debug support for config", s.configz)
+	s.addDebugHandler(mux, internalMux, "/debug/config_history", "History of recent config changes", s.configHistory)
 	s.addDebugHandler(mux, internalMux, "/debug/sidecarz", "Debug sidecar scope for a proxy", s.sidecarz)
 	s.addDebugHandler(mux, internalMux, "/debug/resourcesz", "Debug support for watched resources", s.resourcez)
 	s.addDebugHandler(mux, internalMux, "/debug/instancesz", "Debug support for service instances", s.instancesz)
 
 	s.addDebugHandler(mux, internalMux, "/debug/authorizationz", "Internal authorization policies", s.Authorizationz)
+	s.addDebugHandler(mux, internalMux, "/debug/jwksz", "Status of the JWT public keys fetched from the issuers", s.jwksz)
 	s.addDebugHandler(mux, internalMux, "/debug/telemetryz", "Debug Telemetry configuration", s.telemetryz)
 	s.addDebugHandler(mux, internalMux, "/debug/config_dump", "ConfigDump in the form of the Envoy admin config dump API for passed in proxyID", s.ConfigDump)
 	s.addDebugHandler(mux, internalMux, "/debug/push_status", "Last PushContext Details", s.PushStatusHandler)
@@ -197,6 +202,7 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/inject", "Active inject template", s.InjectTemplateHandler(webhook))
 	s.addDebugHandler(mux, internalMux, "/debug/mesh", "Active mesh config", s.MeshHandler)
 	s.addDebugHandler(mux, internalMux, "/debug/networkz", "List cross-network gateways", s.networkz)
+	s.addDebugHandler(mux, internalMux, "/debug/leaderz", "Leader elections of this Istiod instance", s.leaderz)
 
 	s.addDebugHandler(mux, internalMux, "/debug/list", "List all supported debug commands in json", s.List)
 }
@@ -303,6 +309,10 @@ func (s *DiscoveryServer) cachez(w http.ResponseWriter, req *http.Request) {
 		_, _ = w.Write([]byte("Failed to parse request\n"))
 		return
 	}
+	if req.Form.Get("stats") != "" {
+		writeJSON(w, s.Cache.Stats())
+		return
+	}
 	if req.Form.Get("sizes") != "" {
 		snapshot := s.Cache.Snapshot()
 		res := make(map[string]string, len(snapshot))
@@ -449,6 +459,38 @@ func (s *DiscoveryServer) configz(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, configs)
 }
 
+// configHistory returns the recent config changes. The results can be filtered with the kind, name and namespace
+// query parameters, since (a RFC3339 timestamp or a duration before now) and limit.
+func (s *DiscoveryServer) configHistory(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	filter := model.ConfigHistoryFilter{
+		Kind:      q.Get("kind"),
+		Name:      q.Get("name"),
+		Namespace: q.Get("namespace"),
+	}
+	if since := q.Get("since"); since != "" {
+		if d, err := time.ParseDuration(since); err == nil {
+			filter.Since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			filter.Since = t
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "invalid since %q: expected a duration or a RFC3339 timestamp\n", since)
+			return
+		}
+	}
+	if limit := q.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "invalid limit %q: %v\n", limit, err)
+			return
+		}
+		filter.Limit = l
+	}
+	writeJSON(w, s.ConfigHistory.List(filter))
+}
+
 // SidecarScope debugging
 func (s *DiscoveryServer) sidecarz(w http.ResponseWriter, req *http.Request) {
 	con := s.getDebugConnection(w, req)
@@ -482,6 +524,15 @@ func (s *DiscoveryServer) Authorizationz(w http.ResponseWriter, req *http.Reques
 	writeJSON(w, info)
 }
 
+// jwksz lists the issuers of the JWT public keys, with the time and the error of their last fetch and their key IDs.
+func (s *DiscoveryServer) jwksz(w http.ResponseWriter, req *http.Request) {
+	if s.JwtKeyResolver == nil {
+		writeJSON(w, []model.JwksStatus{})
+		return
+	}
+	writeJSON(w, s.JwtKeyResolver.Status())
+}
+
 func (s *DiscoveryServer) telemetryz(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, s.globalPushContext().Telemetry)
 }
@@ -828,6 +879,11 @@ func (s *DiscoveryServer) networkz(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, s.Env.NetworkGateways())
 }
 
+// leaderz reports the leader elections run by this instance, and which instance leads each of them.
+func (s *DiscoveryServer) leaderz(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, leaderelection.Elections())
+}
+
 // handlePushRequest handles a ?push=true query param and triggers a push.
 // A boolean response is returned to indicate if the caller should continue
 func (s *DiscoveryServer) handlePushRequest(w http.ResponseWriter, req *http.Request) bool {
@@ -17,6 +17,8 @@ package xds
 import (
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"strconv"
 	"strings"
 	"time"
 
@@ -283,6 +285,8 @@ func (s *DiscoveryServer) processDeltaRequest(req *discovery.DeltaDiscoveryReque
 	if s.StatusReporter != nil {
 		s.StatusReporter.RegisterEvent(con.ConID, req.TypeUrl, req.ResponseNonce)
 	}
+	// Envoy no longer has the unsubscribed resources, they must be sent again if subscribed later.
+	con.forgetDeltaResources(req.TypeUrl, req.ResourceNamesUnsubscribe)
 	shouldRespond := s.shouldRespondDelta(con, req)
 
 	var request *model.PushRequest
@@ -345,10 +349,27 @@ func (s *DiscoveryServer) shouldRespondDelta(con *Connection, request *discovery
 	if previousInfo == nil {
 		// TODO: can we distinguish init and reconnect? Do we care?
 		log.Debugf("dADS:%s: INIT/RECONNECT %s %s", stype, con.ConID, request.ResponseNonce)
+		names := deltaWatchedResources(nil, request)
+		if len(request.InitialResourceVersions) > 0 {
+			// Envoy is reconnecting with the resources it already has. Unchanged resources will not be sent again.
+			versions := make(map[string]string, len(request.InitialResourceVersions))
+			for name, version := range request.InitialResourceVersions {
+				versions[name] = version
+			}
+			con.deltaResourceVersions[request.TypeUrl] = versions
+			if isWildcardTypeURL(request.TypeUrl) {
+				// For wildcard types, the resources Envoy already has must be removed if they no longer exist.
+				existing := sets.NewSet(names...)
+				for name := range versions {
+					existing.Insert(name)
+				}
+				names = existing.SortedList()
+			}
+		}
 		con.proxy.Lock()
 		con.proxy.WatchedResources[request.TypeUrl] = &model.WatchedResource{
 			TypeUrl:       request.TypeUrl,
-			ResourceNames: deltaWatchedResources(nil, request),
+			ResourceNames: names,
 			LastRequest:   deltaToSotwRequest(request),
 		}
 		con.proxy.Unlock()
@@ -416,8 +437,18 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection, push *model.PushContext,
 
 	t0 := time.Now()
 
-	res, logdata, err := gen.Generate(con.proxy, push, w, req)
-	if err != nil || res == nil {
+	var res model.Resources
+	var deletedRes model.DeletedResources
+	var logdata model.XdsLogDetails
+	var usedDelta bool
+	var err error
+	switch g := gen.(type) {
+	case model.XdsDeltaResourceGenerator:
+		res, deletedRes, logdata, usedDelta, err = g.GenerateDeltas(con.proxy, push, req, w)
+	default:
+		res, logdata, err = gen.Generate(con.proxy, push, w, req)
+	}
+	if err != nil || (res == nil && deletedRes == nil) {
 		// If we have nothing to send, report that we got an ACK for this version.
 		if s.StatusReporter != nil {
 			s.StatusReporter.RegisterEvent(con.ConID, w.TypeUrl, push.LedgerVersion)
@@ -448,20 +479,47 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection, push *model.PushContext,
 		Nonce:             nonce(push.LedgerVersion),
 		Resources:         res,
 	}
-	// We take the set of watched resources and anything not in the response is sent as RemovedResources
-	// This is similar to SotW, but done on the server side instead of the client.
-	cur := sets.NewSet(w.ResourceNames...)
-	cur.Delete(originalNames...)
-	resp.RemovedResources = cur.SortedList()
+	if usedDelta {
+		// The generator computed the changes, the other resources are left as they are.
+		resp.RemovedResources = deletedRes
+	} else {
+		// We take the set of watched resources and anything not in the response is sent as RemovedResources
+		// This is similar to SotW, but done on the server side instead of the client.
+		cur := sets.NewSet(w.ResourceNames...)
+		cur.Delete(originalNames...)
+		resp.RemovedResources = cur.SortedList()
+	}
 	if len(resp.RemovedResources) > 0 {
 		log.Infof("ADS:%v REMOVE %v", v3.GetShortType(w.TypeUrl), resp.RemovedResources)
 	}
 	if isWildcardTypeURL(w.TypeUrl) {
 		// this is probably a bad idea...
 		con.proxy.Lock()
-		w.ResourceNames = originalNames
+		if usedDelta {
+			names := sets.NewSet(w.ResourceNames...)
+			names.Insert(originalNames...)
+			names.Delete(deletedRes...)
+			w.ResourceNames = names.SortedList()
+		} else {
+			w.ResourceNames = originalNames
+		}
 		con.proxy.Unlock()
 	}
+	// Resources explicitly subscribed by this request are always sent, as Envoy may need them again, for example
+	// to warm a cluster. Otherwise, only the resources which changed since the last response are sent.
+	resp.Resources = con.deltaResourcesChanged(w.TypeUrl, res, sets.NewSet(subscribe...))
+	con.forgetDeltaResources(w.TypeUrl, resp.RemovedResources)
+	res = resp.Resources
+	con.proxy.RLock()
+	sentBefore := w.NonceSent != ""
+	con.proxy.RUnlock()
+	if len(res) == 0 && len(resp.RemovedResources) == 0 && sentBefore {
+		// Nothing changed since the last response. Envoy only waits for the first response to a request.
+		if s.StatusReporter != nil {
+			s.StatusReporter.RegisterEvent(con.ConID, w.TypeUrl, push.LedgerVersion)
+		}
+		return nil
+	}
 
 	configSize := ResourceSize(res)
 	configSizeBytes.With(typeTag.Value(w.TypeUrl)).Record(float64(configSize))
@@ -501,18 +559,61 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection, push *model.PushContext,
 
 func newDeltaConnection(peerAddr string, stream DeltaDiscoveryStream) *Connection {
 	return &Connection{
-		pushChannel:   make(chan *Event),
-		initialized:   make(chan struct{}),
-		stop:          make(chan struct{}),
-		PeerAddr:      peerAddr,
-		Connect:       time.Now(),
-		deltaStream:   stream,
-		deltaReqChan:  make(chan *discovery.DeltaDiscoveryRequest, 1),
-		errorChan:     make(chan error, 1),
-		blockedPushes: map[string]*model.PushRequest{},
+		pushChannel:           make(chan *Event),
+		initialized:           make(chan struct{}),
+		stop:                  make(chan struct{}),
+		PeerAddr:              peerAddr,
+		Connect:               time.Now(),
+		deltaStream:           stream,
+		deltaReqChan:          make(chan *discovery.DeltaDiscoveryRequest, 1),
+		errorChan:             make(chan error, 1),
+		blockedPushes:         map[string]*model.PushRequest{},
+		deltaResourceVersions: map[string]map[string]string{},
+	}
+}
+
+// deltaResourcesChanged sets the version of the resources, and returns the resources which are either in
+// subscribed or changed since they were last sent. The versions are recorded as sent.
+func (conn *Connection) deltaResourcesChanged(typeURL string, res model.Resources, subscribed sets.Set) model.Resources {
+	versions := conn.deltaResourceVersions[typeURL]
+	if versions == nil {
+		versions = map[string]string{}
+		conn.deltaResourceVersions[typeURL] = versions
+	}
+	out := make(model.Resources, 0, len(res))
+	for _, r := range res {
+		version := resourceVersion(r)
+		if versions[r.Name] == version && !subscribed.Contains(r.Name) {
+			continue
+		}
+		versions[r.Name] = version
+		// Resources may be shared through the cache, so they are copied rather than modified.
+		out = append(out, &discovery.Resource{
+			Name:     r.Name,
+			Aliases:  r.Aliases,
+			Resource: r.Resource,
+			Version:  version,
+		})
+	}
+	return out
+}
+
+// forgetDeltaResources removes the versions of resources which Envoy no longer has.
+func (conn *Connection) forgetDeltaResources(typeURL string, names []string) {
+	versions := conn.deltaResourceVersions[typeURL]
+	for _, name := range names {
+		delete(versions, name)
 	}
 }
 
+// resourceVersion returns a version of the resource, derived from its content.
+func resourceVersion(r *discovery.Resource) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(r.GetResource().GetTypeUrl()))
+	_, _ = h.Write(r.GetResource().GetValue())
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
 // To satisfy methods that need DiscoveryRequest. Not suitable for real usage
 func deltaToSotwRequest(request *discovery.DeltaDiscoveryRequest) *discovery.DiscoveryRequest {
 	return &discovery.DiscoveryRequest{
@@ -23,6 +23,7 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config/schema/gvk"
 )
 
 func TestDeltaAds(t *testing.T) {
@@ -50,6 +51,103 @@ func TestDeltaAdsClusterUpdate(t *testing.T) {
 	sendEDSReqAndVerify([]string{"outbound|80||local.default.svc.cluster.local"}, nil, []string{"outbound|80||local.default.svc.cluster.local"})
 	// Only send the one that is requested
 	sendEDSReqAndVerify([]string{"outbound|81||local.default.svc.cluster.local"}, nil, []string{"outbound|81||local.default.svc.cluster.local"})
-	// TODO: should we just respond with nothing here? Probably...
-	sendEDSReqAndVerify(nil, []string{"outbound|81||local.default.svc.cluster.local"}, []string{"outbound|80||local.default.svc.cluster.local"})
+	// Nothing changed for the remaining cluster, so there is nothing to respond.
+	ads.Request(&discovery.DeltaDiscoveryRequest{
+		ResourceNamesUnsubscribe: []string{"outbound|81||local.default.svc.cluster.local"},
+	})
+	ads.ExpectNoResponse()
+	// Resubscribing sends the cluster again, as Envoy no longer has it.
+	sendEDSReqAndVerify([]string{"outbound|81||local.default.svc.cluster.local"}, nil, []string{"outbound|81||local.default.svc.cluster.local"})
+}
+
+func TestDeltaCDSIncremental(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	ads := s.ConnectDeltaADS().WithType(v3.ClusterType)
+	ack := func(resp *discovery.DeltaDiscoveryResponse) {
+		ads.Request(&discovery.DeltaDiscoveryRequest{ResponseNonce: resp.Nonce})
+	}
+	serviceUpdate := func(hostname string) {
+		s.Discovery.ConfigUpdate(&model.PushRequest{Full: true, ConfigsUpdated: map[model.ConfigKey]struct{}{
+			{Kind: gvk.ServiceEntry, Name: hostname}: {},
+		}})
+	}
+
+	initial := ads.RequestResponseAck(nil)
+	if len(initial.Resources) == 0 {
+		t.Fatalf("expected clusters in the initial response")
+	}
+
+	// Only the cluster of the new service is sent.
+	s.Discovery.MemRegistry.AddHTTPService("delta.example.com", "10.10.10.10", 80)
+	serviceUpdate("delta.example.com")
+	resp := ads.ExpectResponse()
+	if got := extractNames(resp.Resources); !reflect.DeepEqual(got, []string{"outbound|80||delta.example.com"}) {
+		t.Fatalf("expected only the new cluster, got %v", got)
+	}
+	if len(resp.RemovedResources) != 0 {
+		t.Fatalf("expected no removed clusters, got %v", resp.RemovedResources)
+	}
+	ack(resp)
+
+	// A full push which does not change any cluster sends nothing.
+	s.Discovery.ConfigUpdate(&model.PushRequest{Full: true, ConfigsUpdated: map[model.ConfigKey]struct{}{
+		{Kind: gvk.DestinationRule, Name: "unknown", Namespace: "default"}: {},
+	}})
+	ads.ExpectNoResponse()
+
+	// Removing the service removes its cluster only.
+	s.Discovery.MemRegistry.RemoveService("delta.example.com")
+	serviceUpdate("delta.example.com")
+	resp = ads.ExpectResponse()
+	if len(resp.Resources) != 0 {
+		t.Fatalf("expected no clusters, got %v", extractNames(resp.Resources))
+	}
+	if !reflect.DeepEqual(resp.RemovedResources, []string{"outbound|80||delta.example.com"}) {
+		t.Fatalf("expected the cluster to be removed, got %v", resp.RemovedResources)
+	}
+	ack(resp)
+}
+
+func TestDeltaLDSRDSUnchanged(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	ads := s.ConnectDeltaADS()
+	if resp := ads.WithType(v3.ListenerType).RequestResponseAck(nil); len(resp.Resources) == 0 {
+		t.Fatalf("expected listeners in the initial response")
+	}
+	resp := ads.WithType(v3.RouteType).RequestResponseAck(&discovery.DeltaDiscoveryRequest{
+		ResourceNamesSubscribe: []string{"80"},
+	})
+	if got := extractNames(resp.Resources); !reflect.DeepEqual(got, []string{"80"}) {
+		t.Fatalf("expected route 80, got %v", got)
+	}
+
+	// Listeners and routes are built again, but they did not change, so nothing is sent.
+	s.Discovery.ConfigUpdate(&model.PushRequest{Full: true, ConfigsUpdated: map[model.ConfigKey]struct{}{
+		{Kind: gvk.DestinationRule, Name: "unknown", Namespace: "default"}: {},
+	}})
+	ads.ExpectNoResponse()
+}
+
+func TestDeltaReconnectInitialVersions(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	initial := s.ConnectDeltaADS().WithType(v3.ClusterType).RequestResponseAck(nil)
+	versions := map[string]string{}
+	for _, r := range initial.Resources {
+		if r.Version == "" {
+			t.Fatalf("expected version for %s", r.Name)
+		}
+		versions[r.Name] = r.Version
+	}
+	versions["outbound|80||stale.example.com"] = "stale"
+
+	// Reconnecting with the resources Envoy already has only removes the stale one.
+	resp := s.ConnectDeltaADS().WithType(v3.ClusterType).RequestResponseAck(&discovery.DeltaDiscoveryRequest{
+		InitialResourceVersions: versions,
+	})
+	if len(resp.Resources) != 0 {
+		t.Fatalf("expected no clusters, got %v", extractNames(resp.Resources))
+	}
+	if !reflect.DeepEqual(resp.RemovedResources, []string{"outbound|80||stale.example.com"}) {
+		t.Fatalf("expected the stale cluster to be removed, got %v", resp.RemovedResources)
+	}
 }
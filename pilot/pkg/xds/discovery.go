@@ -143,6 +143,9 @@ type DiscoveryServer struct {
 
 	// JwtKeyResolver holds a reference to the JWT key resolver instance.
 	JwtKeyResolver *model.JwksResolver
+
+	// ConfigHistory holds the recent config changes, exposed on /debug/config_history.
+	ConfigHistory *model.ConfigHistory
 }
 
 // EndpointShards holds the set of endpoint shards of a service. Registries update
@@ -184,8 +187,9 @@ func NewDiscoveryServer(env *model.Environment, plugins []string, instanceID str
 			debounceMax:       features.DebounceMax,
 			enableEDSDebounce: features.EnableEDSDebounce,
 		},
-		Cache:      model.DisabledCache{},
-		instanceID: instanceID,
+		Cache:         model.DisabledCache{},
+		instanceID:    instanceID,
+		ConfigHistory: model.NewConfigHistory(features.ConfigHistorySize),
 	}
 
 	out.initJwksResolver()
@@ -210,6 +214,12 @@ func (s *DiscoveryServer) initJwksResolver() {
 		model.JwtPubKeyEvictionDuration, model.JwtPubKeyRefreshInterval,
 		model.JwtPubKeyRefreshIntervalOnFailure, model.JwtPubKeyRetryInterval)
 
+	if features.JwksCacheFile != "" {
+		if err := s.JwtKeyResolver.SetCacheFile(features.JwksCacheFile); err != nil {
+			log.Warnf("failed to load the JWKS cache: %v", err)
+		}
+	}
+
 	// Flush cached discovery responses when detecting jwt public key change.
 	s.JwtKeyResolver.PushFunc = func() {
 		s.ConfigUpdate(&model.PushRequest{Full: true, Reason: []model.TriggerReason{model.UnknownTrigger}})
@@ -473,7 +483,7 @@ func doSendPushes(stopCh <-chan struct{}, semaphore chan struct{}, queue *PushQu
 				<-semaphore
 			}
 
-			proxiesQueueTime.Record(time.Since(push.Start).Seconds())
+			proxiesQueueTime.With(priorityTag.Value(pushPriority(client).String())).Record(time.Since(push.Start).Seconds())
 			var closed <-chan struct{}
 			if client.stream != nil {
 				closed = client.stream.Context().Done()
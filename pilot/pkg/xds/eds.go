@@ -350,7 +350,7 @@ type EdsGenerator struct {
 	Server *DiscoveryServer
 }
 
-var _ model.XdsResourceGenerator = &EdsGenerator{}
+var _ model.XdsDeltaResourceGenerator = &EdsGenerator{}
 
 // Map of all configs that do not impact EDS
 var skippedEdsConfigs = map[config.GroupVersionKind]struct{}{
@@ -427,6 +427,74 @@ func (eds *EdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w
 	}, nil
 }
 
+// GenerateDeltas computes Delta EDS resources. When only services were updated, only the endpoints of these
+// services are built, and the clusters of services which no longer exist are removed.
+func (eds *EdsGenerator) GenerateDeltas(proxy *model.Proxy, push *model.PushContext, req *model.PushRequest,
+	w *model.WatchedResource) (model.Resources, model.DeletedResources, model.XdsLogDetails, bool, error) {
+	if !edsNeedsPush(req.ConfigsUpdated) {
+		return nil, nil, model.DefaultXdsLogDetails, false, nil
+	}
+	if !onlyServicesUpdated(req.ConfigsUpdated) {
+		res, logs, err := eds.Generate(proxy, push, w, req)
+		return res, nil, logs, false, err
+	}
+	updatedServices := model.ConfigNamesOfKind(req.ConfigsUpdated, gvk.ServiceEntry)
+	resources := make(model.Resources, 0)
+	var removed model.DeletedResources
+	empty := 0
+	cached := 0
+	regenerated := 0
+	for _, clusterName := range w.ResourceNames {
+		_, _, hostname, _ := model.ParseSubsetKey(clusterName)
+		if _, ok := updatedServices[string(hostname)]; !ok {
+			continue
+		}
+		builder := NewEndpointBuilder(clusterName, proxy, push)
+		if builder.service == nil {
+			// The service was removed, or is no longer visible to the proxy.
+			removed = append(removed, clusterName)
+			continue
+		}
+		if marshalledEndpoint, token, f := eds.Server.Cache.Get(builder); f && !features.EnableUnsafeAssertions {
+			resources = append(resources, marshalledEndpoint)
+			cached++
+		} else {
+			l := eds.Server.generateEndpoints(builder)
+			if l == nil {
+				removed = append(removed, clusterName)
+				continue
+			}
+			regenerated++
+			if len(l.Endpoints) == 0 {
+				empty++
+			}
+			resource := &discovery.Resource{
+				Name:     l.ClusterName,
+				Resource: util.MessageToAny(l),
+			}
+			resources = append(resources, resource)
+			eds.Server.Cache.Add(builder, token, resource)
+		}
+	}
+	return resources, removed, model.XdsLogDetails{
+		Incremental:    true,
+		AdditionalInfo: fmt.Sprintf("empty:%v cached:%v/%v", empty, cached, cached+regenerated),
+	}, true, nil
+}
+
+// onlyServicesUpdated returns true if the updates are not empty and only contain services.
+func onlyServicesUpdated(updates model.XdsUpdates) bool {
+	if len(updates) == 0 {
+		return false
+	}
+	for config := range updates {
+		if config.Kind != gvk.ServiceEntry {
+			return false
+		}
+	}
+	return true
+}
+
 func getOutlierDetectionAndLoadBalancerSettings(
 	destinationRule *networkingapi.DestinationRule,
 	portNumber int,
@@ -29,6 +29,7 @@ import (
 	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/security/authn/factory"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
@@ -161,6 +162,10 @@ func (b EndpointBuilder) DependentConfigs() []model.ConfigKey {
 	return configs
 }
 
+func (b EndpointBuilder) TypeURL() string {
+	return v3.EndpointType
+}
+
 var edsDependentTypes = []config.GroupVersionKind{gvk.PeerAuthentication}
 
 func (b EndpointBuilder) DependentTypes() []config.GroupVersionKind {
@@ -43,6 +43,7 @@ import (
 	"istio.io/istio/pilot/test/xdstest"
 	"istio.io/istio/pkg/adsc"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/extensionprovider"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -70,6 +71,8 @@ type FakeOptions struct {
 	// If provided, this mesh config will be used
 	MeshConfig      *meshconfig.MeshConfig
 	NetworksWatcher mesh.NetworksWatcher
+	// If provided, these mesh extension providers of the extensionprovider package will be used
+	MeshExtensionProviders []*extensionprovider.ExtensionProvider
 
 	// Callback to modify the server before it is started
 	DiscoveryServerModifier func(s *DiscoveryServer)
@@ -101,7 +104,7 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 	}
 
 	// Init with a dummy environment, since we have a circular dependency with the env creation.
-	s := NewDiscoveryServer(&model.Environment{PushContext: model.NewPushContext()}, []string{plugin.AuthzCustom, plugin.Authn, plugin.Authz},
+	s := NewDiscoveryServer(&model.Environment{PushContext: model.NewPushContext()}, []string{plugin.AuthzCustom, plugin.Authn, plugin.Authz, plugin.RateLimit},
 		"pilot-123", "istio-system")
 	t.Cleanup(func() {
 		s.JwtKeyResolver.Close()
@@ -167,15 +170,16 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 	defaultKubeClient.RunAndWait(stop)
 
 	cg := v1alpha3.NewConfigGenTest(t, v1alpha3.TestOptions{
-		Configs:             opts.Configs,
-		ConfigString:        opts.ConfigString,
-		ConfigTemplateInput: opts.ConfigTemplateInput,
-		MeshConfig:          opts.MeshConfig,
-		NetworksWatcher:     opts.NetworksWatcher,
-		ServiceRegistries:   registries,
-		PushContextLock:     &s.updateMutex,
-		ConfigStoreCaches:   []model.ConfigStoreCache{ingr},
-		SkipRun:             true,
+		Configs:                opts.Configs,
+		ConfigString:           opts.ConfigString,
+		ConfigTemplateInput:    opts.ConfigTemplateInput,
+		MeshConfig:             opts.MeshConfig,
+		NetworksWatcher:        opts.NetworksWatcher,
+		MeshExtensionProviders: opts.MeshExtensionProviders,
+		ServiceRegistries:      registries,
+		PushContextLock:        &s.updateMutex,
+		ConfigStoreCaches:      []model.ConfigStoreCache{ingr},
+		SkipRun:                true,
 	})
 	cg.ServiceEntryRegistry.AppendServiceHandler(serviceHandler)
 	s.updateMutex.Lock()
@@ -22,6 +22,7 @@ import (
 	fault "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/fault/v3"
 	grpcstats "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/grpc_stats/v3"
 	grpcweb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/grpc_web/v3"
+	localratelimit "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/local_ratelimit/v3"
 	router "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
 	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
 	httpinspector "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/http_inspector/v3"
@@ -50,6 +51,11 @@ const (
 	RawBufferTransportProtocol = "raw_buffer"
 
 	MxFilterName = "istio.metadata_exchange"
+
+	// LocalRateLimitFilterName is the name of the local rate limit HTTP filter, configured per route.
+	LocalRateLimitFilterName = "envoy.filters.http.local_ratelimit"
+	// LocalRateLimitStatPrefix is the stat prefix of the local rate limit HTTP filter.
+	LocalRateLimitStatPrefix = "http_local_rate_limiter"
 )
 
 // Define static filters to be reused across the codebase. This avoids duplicate marshaling/unmarshaling
@@ -73,6 +79,15 @@ var (
 			TypedConfig: util.MessageToAny(&router.Router{}),
 		},
 	}
+	// LocalRateLimit does not rate limit by itself, the token buckets are set in the per route configuration.
+	LocalRateLimit = &hcm.HttpFilter{
+		Name: LocalRateLimitFilterName,
+		ConfigType: &hcm.HttpFilter_TypedConfig{
+			TypedConfig: util.MessageToAny(&localratelimit.LocalRateLimit{
+				StatPrefix: LocalRateLimitStatPrefix,
+			}),
+		},
+	}
 	GrpcWeb = &hcm.HttpFilter{
 		Name: wellknown.GRPCWeb,
 		ConfigType: &hcm.HttpFilter_TypedConfig{
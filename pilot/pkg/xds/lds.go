@@ -23,6 +23,9 @@ import (
 	"istio.io/istio/pkg/config/schema/gvk"
 )
 
+// LdsGenerator generates listeners. It does not implement model.XdsDeltaResourceGenerator: a listener aggregates
+// many services, virtual services and filters, so the listeners changed by an update are not known without building
+// them. For Delta XDS, all the listeners are built, and only the ones whose content changed are sent.
 type LdsGenerator struct {
 	Server *DiscoveryServer
 }
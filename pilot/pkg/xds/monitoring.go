@@ -26,10 +26,11 @@ import (
 )
 
 var (
-	errTag     = monitoring.MustCreateLabel("err")
-	nodeTag    = monitoring.MustCreateLabel("node")
-	typeTag    = monitoring.MustCreateLabel("type")
-	versionTag = monitoring.MustCreateLabel("version")
+	errTag      = monitoring.MustCreateLabel("err")
+	nodeTag     = monitoring.MustCreateLabel("node")
+	typeTag     = monitoring.MustCreateLabel("type")
+	versionTag  = monitoring.MustCreateLabel("version")
+	priorityTag = monitoring.MustCreateLabel("priority")
 
 	// pilot_total_xds_rejects should be used instead. This is for backwards compatibility
 	cdsReject = monitoring.NewGauge(
@@ -136,6 +137,7 @@ var (
 		"pilot_proxy_queue_time",
 		"Time in seconds, a proxy is in the push queue before being dequeued.",
 		[]float64{.1, .5, 1, 3, 5, 10, 20, 30},
+		monitoring.WithLabels(priorityTag),
 	)
 
 	pushTriggers = monitoring.NewSum(
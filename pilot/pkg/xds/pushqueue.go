@@ -16,10 +16,60 @@ package xds
 
 import (
 	"sync"
+	"time"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 )
 
+// PushPriorityAnnotation sets the priority class of the pushes to a proxy: "high" or "low". Gateways are always
+// pushed first, and proxies without the annotation are in the "normal" class.
+const PushPriorityAnnotation = "proxy.istio.io/push-priority"
+
+// PushPriority is the priority class of a proxy in the push queue. Lower values are dequeued first.
+type PushPriority int
+
+const (
+	PushPriorityGateway PushPriority = iota
+	PushPriorityHigh
+	PushPriorityNormal
+	PushPriorityLow
+
+	numPushPriorities = int(PushPriorityLow) + 1
+)
+
+func (p PushPriority) String() string {
+	switch p {
+	case PushPriorityGateway:
+		return "gateway"
+	case PushPriorityHigh:
+		return "high"
+	case PushPriorityLow:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// pushPriority returns the priority class of the proxy of a connection.
+func pushPriority(con *Connection) PushPriority {
+	if con.proxy == nil {
+		return PushPriorityNormal
+	}
+	if con.proxy.Type == model.Router {
+		return PushPriorityGateway
+	}
+	if con.proxy.Metadata != nil {
+		switch con.proxy.Metadata.Annotations[PushPriorityAnnotation] {
+		case "high":
+			return PushPriorityHigh
+		case "low":
+			return PushPriorityLow
+		}
+	}
+	return PushPriorityNormal
+}
+
 type PushQueue struct {
 	cond *sync.Cond
 
@@ -27,22 +77,37 @@ type PushQueue struct {
 	// the PushRequest will be merged.
 	pending map[*Connection]*model.PushRequest
 
-	// queue maintains ordering of the queue
-	queue []*Connection
+	// queues maintains ordering of the queue, for each priority class. Connections of a class are only dequeued
+	// when the queues of the classes of higher priority are empty, or once they waited for maxPriorityWait.
+	queues [numPushPriorities][]*Connection
+
+	// queuedSince stores when the connections of queues were added to them.
+	queuedSince map[*Connection]time.Time
 
 	// processing stores all connections that have been Dequeue(), but not MarkDone().
 	// The value stored will be initially be nil, but may be populated if the connection is Enqueue().
 	// If model.PushRequest is not nil, it will be Enqueued again once MarkDone has been called.
 	processing map[*Connection]*model.PushRequest
 
+	// minPushInterval is the minimum time between two pushes to the same connection. Pending connections pushed
+	// more recently are added to the queue once the interval has elapsed, merging the requests enqueued meanwhile.
+	minPushInterval time.Duration
+
+	// maxPriorityWait is the maximum time a connection waits behind connections of higher priority classes. The
+	// connections which waited longer are dequeued first, oldest first, so the lower classes are not starved.
+	maxPriorityWait time.Duration
+
 	shuttingDown bool
 }
 
 func NewPushQueue() *PushQueue {
 	return &PushQueue{
-		pending:    make(map[*Connection]*model.PushRequest),
-		processing: make(map[*Connection]*model.PushRequest),
-		cond:       sync.NewCond(&sync.Mutex{}),
+		pending:         make(map[*Connection]*model.PushRequest),
+		queuedSince:     make(map[*Connection]time.Time),
+		processing:      make(map[*Connection]*model.PushRequest),
+		minPushInterval: features.ProxyMinPushInterval,
+		maxPriorityWait: features.PushQueueMaxPriorityWait,
+		cond:            sync.NewCond(&sync.Mutex{}),
 	}
 }
 
@@ -68,37 +133,92 @@ func (p *PushQueue) Enqueue(con *Connection, pushRequest *model.PushRequest) {
 	}
 
 	p.pending[con] = pushRequest
-	p.queue = append(p.queue, con)
+	p.push(con)
+}
+
+// push adds a pending connection to the queue of its priority class, or once minPushInterval has elapsed since it
+// was last dequeued. Requests enqueued meanwhile are merged into the pending request.
+// The lock must be held.
+func (p *PushQueue) push(con *Connection) {
+	if wait := p.minPushInterval - time.Since(con.lastDequeued); wait > 0 {
+		time.AfterFunc(wait, func() {
+			p.cond.L.Lock()
+			defer p.cond.L.Unlock()
+			if !p.shuttingDown {
+				p.push(con)
+			}
+		})
+		return
+	}
+	priority := pushPriority(con)
+	p.queues[priority] = append(p.queues[priority], con)
+	p.queuedSince[con] = time.Now()
 	// Signal waiters on Dequeue that a new item is available
 	p.cond.Signal()
 }
 
+// queued returns the number of connections in the queues.
+func (p *PushQueue) queued() int {
+	n := 0
+	for _, q := range p.queues {
+		n += len(q)
+	}
+	return n
+}
+
 // Remove a proxy from the queue. If there are no proxies ready to be removed, this will block
 func (p *PushQueue) Dequeue() (con *Connection, request *model.PushRequest, shutdown bool) {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
 
 	// Block until there is one to remove. Enqueue will signal when one is added.
-	for len(p.queue) == 0 && !p.shuttingDown {
+	for p.queued() == 0 && !p.shuttingDown {
 		p.cond.Wait()
 	}
 
-	if len(p.queue) == 0 {
+	if p.queued() == 0 {
 		// We must be shutting down.
 		return nil, nil, true
 	}
 
-	con, p.queue = p.queue[0], p.queue[1:]
+	priority := p.nextPriority()
+	con, p.queues[priority] = p.queues[priority][0], p.queues[priority][1:]
 
 	request = p.pending[con]
 	delete(p.pending, con)
+	delete(p.queuedSince, con)
 
 	// Mark the connection as in progress
 	p.processing[con] = nil
+	con.lastDequeued = time.Now()
 
 	return con, request, false
 }
 
+// nextPriority returns the priority class of the next connection to dequeue: the class of highest priority, unless
+// the first connection of a lower class waited for more than maxPriorityWait and longer than the connection of that
+// class. The queues must not be empty, and the lock must be held.
+func (p *PushQueue) nextPriority() int {
+	next := -1
+	for i, q := range p.queues {
+		if len(q) == 0 {
+			continue
+		}
+		if next < 0 {
+			next = i
+			if p.maxPriorityWait <= 0 {
+				break
+			}
+			continue
+		}
+		since := p.queuedSince[q[0]]
+		if time.Since(since) > p.maxPriorityWait && since.Before(p.queuedSince[p.queues[next][0]]) {
+			next = i
+		}
+	}
+	return next
+}
+
 func (p *PushQueue) MarkDone(con *Connection) {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
@@ -109,16 +229,15 @@ func (p *PushQueue) MarkDone(con *Connection) {
 	// This means we need to add it back to the queue.
 	if request != nil {
 		p.pending[con] = request
-		p.queue = append(p.queue, con)
-		p.cond.Signal()
+		p.push(con)
 	}
 }
 
-// Get number of pending proxies
+// Get number of pending proxies, including the proxies waiting for their minimum push interval.
 func (p *PushQueue) Pending() int {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
-	return len(p.queue)
+	return len(p.pending)
 }
 
 // ShutDown will cause queue to ignore all new items added to it. As soon as the
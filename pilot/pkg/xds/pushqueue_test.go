@@ -369,3 +369,106 @@ func TestProxyQueue(t *testing.T) {
 		}
 	})
 }
+
+func TestProxyQueuePriority(t *testing.T) {
+	gateway := &Connection{ConID: "gateway", proxy: &model.Proxy{Type: model.Router, Metadata: &model.NodeMetadata{}}}
+	high := &Connection{ConID: "high", proxy: &model.Proxy{Type: model.SidecarProxy, Metadata: &model.NodeMetadata{
+		Annotations: map[string]string{PushPriorityAnnotation: "high"},
+	}}}
+	normal := &Connection{ConID: "normal", proxy: &model.Proxy{Type: model.SidecarProxy, Metadata: &model.NodeMetadata{}}}
+	low := &Connection{ConID: "low", proxy: &model.Proxy{Type: model.SidecarProxy, Metadata: &model.NodeMetadata{
+		Annotations: map[string]string{PushPriorityAnnotation: "low"},
+	}}}
+
+	t.Run("priority classes", func(t *testing.T) {
+		p := NewPushQueue()
+		defer p.ShutDown()
+
+		p.Enqueue(low, &model.PushRequest{})
+		p.Enqueue(normal, &model.PushRequest{})
+		p.Enqueue(high, &model.PushRequest{})
+		p.Enqueue(gateway, &model.PushRequest{})
+
+		ExpectDequeue(t, p, gateway)
+		ExpectDequeue(t, p, high)
+		ExpectDequeue(t, p, normal)
+		ExpectDequeue(t, p, low)
+		ExpectTimeout(t, p)
+	})
+
+	t.Run("lower classes are not starved", func(t *testing.T) {
+		p := NewPushQueue()
+		defer p.ShutDown()
+		p.maxPriorityWait = 100 * time.Millisecond
+
+		p.Enqueue(low, &model.PushRequest{})
+		p.Enqueue(normal, &model.PushRequest{})
+		time.Sleep(150 * time.Millisecond)
+		p.Enqueue(gateway, &model.PushRequest{})
+
+		// Both waited too long behind the gateway, the oldest goes first.
+		ExpectDequeue(t, p, low)
+		ExpectDequeue(t, p, normal)
+		ExpectDequeue(t, p, gateway)
+		ExpectTimeout(t, p)
+	})
+
+	t.Run("strict priority", func(t *testing.T) {
+		p := NewPushQueue()
+		defer p.ShutDown()
+		p.maxPriorityWait = 0
+
+		p.Enqueue(low, &model.PushRequest{})
+		time.Sleep(10 * time.Millisecond)
+		p.Enqueue(gateway, &model.PushRequest{})
+
+		ExpectDequeue(t, p, gateway)
+		ExpectDequeue(t, p, low)
+		ExpectTimeout(t, p)
+	})
+
+	t.Run("fifo within a class", func(t *testing.T) {
+		p := NewPushQueue()
+		defer p.ShutDown()
+		other := &Connection{ConID: "other", proxy: &model.Proxy{Type: model.SidecarProxy, Metadata: &model.NodeMetadata{}}}
+
+		p.Enqueue(normal, &model.PushRequest{})
+		p.Enqueue(other, &model.PushRequest{})
+		p.Enqueue(normal, &model.PushRequest{})
+
+		ExpectDequeue(t, p, normal)
+		ExpectDequeue(t, p, other)
+		ExpectTimeout(t, p)
+	})
+
+	t.Run("minimum push interval", func(t *testing.T) {
+		p := NewPushQueue()
+		defer p.ShutDown()
+		p.minPushInterval = 200 * time.Millisecond
+		con := &Connection{ConID: "interval"}
+
+		p.Enqueue(con, &model.PushRequest{})
+		ExpectDequeue(t, p, con)
+		p.MarkDone(con)
+
+		// Pushes within the interval are delayed and merged.
+		dequeued := time.Now()
+		p.Enqueue(con, &model.PushRequest{Full: false})
+		p.Enqueue(con, &model.PushRequest{Full: true})
+		if got := p.Pending(); got != 1 {
+			t.Fatalf("expected 1 pending proxy, got %v", got)
+		}
+		got, request, _ := p.Dequeue()
+		if got != con {
+			t.Fatalf("Expected proxy %v, got %v", con, got)
+		}
+		if elapsed := time.Since(dequeued); elapsed < 150*time.Millisecond {
+			t.Fatalf("proxy pushed again after %v, before the minimum interval", elapsed)
+		}
+		if !request.Full {
+			t.Fatalf("expected the delayed requests to be merged")
+		}
+		p.MarkDone(con)
+		ExpectTimeout(t, p)
+	})
+}
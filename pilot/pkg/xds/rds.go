@@ -23,11 +23,12 @@ import (
 	"istio.io/istio/pkg/config/schema/gvk"
 )
 
+// RdsGenerator generates routes.
 type RdsGenerator struct {
 	Server *DiscoveryServer
 }
 
-var _ model.XdsResourceGenerator = &RdsGenerator{}
+var _ model.XdsDeltaResourceGenerator = &RdsGenerator{}
 
 // Map of all configs that do not impact RDS
 var skippedRdsConfigs = map[config.GroupVersionKind]struct{}{
@@ -74,3 +75,21 @@ func (c RdsGenerator) Generate(proxy *model.Proxy, push *model.PushContext, w *m
 	}
 	return resources, model.DefaultXdsLogDetails, nil
 }
+
+// GenerateDeltas builds only the routes changed by service updates. Routes are removed along with their listeners, so
+// no removed routes are returned.
+func (c RdsGenerator) GenerateDeltas(proxy *model.Proxy, push *model.PushContext, updates *model.PushRequest,
+	w *model.WatchedResource) (model.Resources, model.DeletedResources, model.XdsLogDetails, bool, error) {
+	if !rdsNeedsPush(updates) {
+		return nil, nil, model.DefaultXdsLogDetails, false, nil
+	}
+	rawRoutes, usedDelta := c.Server.ConfigGenerator.BuildDeltaHTTPRoutes(proxy, push, updates, w)
+	resources := make(model.Resources, 0, len(rawRoutes))
+	for _, c := range rawRoutes {
+		resources = append(resources, &discovery.Resource{
+			Name:     c.Name,
+			Resource: util.MessageToAny(c),
+		})
+	}
+	return resources, nil, model.XdsLogDetails{Incremental: usedDelta}, usedDelta, nil
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/golang/protobuf/ptypes/any"
 	"go.uber.org/atomic"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -217,3 +218,138 @@ func TestXdsCache(t *testing.T) {
 		}
 	})
 }
+
+func TestXdsCacheLimits(t *testing.T) {
+	mkv := func(size int) *discovery.Resource {
+		return &discovery.Resource{Resource: &any.Any{TypeUrl: "foo", Value: make([]byte, size)}}
+	}
+	eps := make([]EndpointBuilder, 0, 3)
+	for i := 0; i < 3; i++ {
+		eps = append(eps, EndpointBuilder{
+			clusterName: fmt.Sprintf("outbound|%d||foo.com", i),
+			service:     &model.Service{Hostname: "foo.com"},
+		})
+	}
+	secret := SecretResource{Name: "secret", Namespace: "ns", ResourceName: "kubernetes://ns/secret"}
+	addWithToken := func(c model.XdsCache, entry model.XdsCacheEntry, value *discovery.Resource) {
+		_, tok, _ := c.Get(entry)
+		c.Add(entry, tok, value)
+	}
+	stats := func(c model.XdsCache, typ string) model.XdsCacheStats {
+		t.Helper()
+		for _, s := range c.Stats() {
+			if s.Type == typ {
+				return s
+			}
+		}
+		t.Fatalf("no stats for %s: %v", typ, c.Stats())
+		return model.XdsCacheStats{}
+	}
+
+	t.Run("bytes", func(t *testing.T) {
+		original := features.XDSCacheMaxBytes
+		features.XDSCacheMaxBytes = 250
+		t.Cleanup(func() {
+			features.XDSCacheMaxBytes = original
+		})
+		c := model.NewLenientXdsCache()
+
+		addWithToken(c, eps[0], mkv(100))
+		addWithToken(c, eps[1], mkv(100))
+		if got := stats(c, "EDS"); got.Bytes != 200 || got.Entries != 2 {
+			t.Fatalf("unexpected stats: %+v", got)
+		}
+		// The least recently used entry is evicted.
+		if _, _, f := c.Get(eps[0]); !f {
+			t.Fatalf("expected %v to be cached", eps[0].Key())
+		}
+		addWithToken(c, eps[2], mkv(100))
+		if _, _, f := c.Get(eps[1]); f {
+			t.Fatalf("expected %v to be evicted", eps[1].Key())
+		}
+		if got := stats(c, "EDS"); got.Bytes != 200 || got.Evictions != 1 || got.MaxBytes != 250 {
+			t.Fatalf("unexpected stats: %+v", got)
+		}
+
+		// A value larger than the limit is not cached, and does not evict the other entries.
+		addWithToken(c, eps[1], mkv(300))
+		if _, _, f := c.Get(eps[1]); f {
+			t.Fatalf("expected %v not to be cached", eps[1].Key())
+		}
+		for _, ep := range []EndpointBuilder{eps[0], eps[2]} {
+			if _, _, f := c.Get(ep); !f {
+				t.Fatalf("expected %v to be cached", ep.Key())
+			}
+		}
+
+		// Clearing entries is not an eviction.
+		c.ClearAll()
+		if got := stats(c, "EDS"); got.Bytes != 0 || got.Entries != 0 || got.Evictions != 1 {
+			t.Fatalf("unexpected stats: %+v", got)
+		}
+	})
+
+	t.Run("per type", func(t *testing.T) {
+		original := features.XDSCacheTypeLimits
+		features.XDSCacheTypeLimits = "EDS=2,SDS=/50"
+		t.Cleanup(func() {
+			features.XDSCacheTypeLimits = original
+		})
+		c := model.NewLenientXdsCache()
+
+		for _, ep := range eps {
+			addWithToken(c, ep, mkv(10))
+		}
+		addWithToken(c, secret, mkv(100))
+		if got := stats(c, "EDS"); got.Entries != 2 || got.MaxEntries != 2 || got.Evictions != 1 {
+			t.Fatalf("unexpected stats: %+v", got)
+		}
+		// The secret is larger than the limit of its type, it is not cached.
+		if got := stats(c, "SDS"); got.Entries != 0 || got.Bytes != 0 || got.MaxBytes != 50 {
+			t.Fatalf("unexpected stats: %+v", got)
+		}
+		if _, _, f := c.Get(eps[2]); !f {
+			t.Fatalf("expected %v to be cached", eps[2].Key())
+		}
+		if got := stats(c, "EDS"); got.Hits != 1 {
+			t.Fatalf("unexpected stats: %+v", got)
+		}
+	})
+
+	t.Run("total entries", func(t *testing.T) {
+		original := features.XDSCacheMaxSize
+		features.XDSCacheMaxSize = 3
+		t.Cleanup(func() {
+			features.XDSCacheMaxSize = original
+		})
+		c := model.NewLenientXdsCache()
+
+		addWithToken(c, secret, mkv(10))
+		for _, ep := range eps {
+			addWithToken(c, ep, mkv(10))
+		}
+		// The size bounds all the types: the oldest entry of the largest type is evicted.
+		if len(c.Keys()) != 3 {
+			t.Fatalf("expected 3 keys, got: %v", c.Keys())
+		}
+		if got := stats(c, "EDS"); got.Entries != 2 || got.Evictions != 1 {
+			t.Fatalf("unexpected stats: %+v", got)
+		}
+		if got := stats(c, "SDS"); got.Entries != 1 {
+			t.Fatalf("unexpected stats: %+v", got)
+		}
+	})
+
+	t.Run("clear across types", func(t *testing.T) {
+		c := model.NewLenientXdsCache()
+		addWithToken(c, eps[0], any1)
+		addWithToken(c, secret, any2)
+		if len(c.Keys()) != 2 {
+			t.Fatalf("expected 2 keys, got: %v", c.Keys())
+		}
+		c.Clear(map[model.ConfigKey]struct{}{{Kind: gvk.Secret, Name: "secret", Namespace: "ns"}: {}})
+		if !reflect.DeepEqual(c.Keys(), []string{eps[0].Key()}) {
+			t.Fatalf("unexpected keys: %v", c.Keys())
+		}
+	})
+}
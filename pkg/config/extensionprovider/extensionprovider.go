@@ -0,0 +1,101 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package extensionprovider defines the mesh extension providers which the MeshConfig API has no field for yet.
+//
+// These providers are set in the extensionProviders list of the mesh config, alongside the providers of the API, and
+// are cut out of the list before the mesh config is decoded, as the API would reject them. They are validated along
+// with the mesh config, and their names share the namespace of the providers of the API. For example:
+//
+//	extensionProviders:
+//	- name: ratelimit
+//	  envoyRateLimit:
+//	    service: ratelimit.istio-system.svc.cluster.local
+//	    port: 8081
+//	    domain: reviews
+//	- name: otel
+//	  opentelemetry:
+//	    service: opentelemetry-collector.istio-system.svc.cluster.local
+//	    port: 4317
+//
+// Once the API has fields for them, the providers are expected to move there with the same shape.
+package extensionprovider
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MaxEnvoyRateLimitProviders is the maximum number of Envoy rate limit providers. Each provider is enforced by its
+// own rate limit filter, and Envoy supports rate limit stages 0 to 10.
+const MaxEnvoyRateLimitProviders = 11
+
+// DefaultOpenTelemetryTracingPort is the default port of the OpenCensus receiver of OpenTelemetry collectors.
+const DefaultOpenTelemetryTracingPort = 55678
+
+// Kinds are the fields of the mesh config extension providers which identify the providers of this package.
+var Kinds = []string{"envoyRateLimit", "opentelemetry"}
+
+// ExtensionProvider is a mesh extension provider. Exactly one of its provider types must be set.
+type ExtensionProvider struct {
+	// Name of the provider, unique across all the extension providers of the mesh.
+	Name string `json:"name"`
+
+	// EnvoyRateLimit is an external rate limit service implementing the Envoy rate limit gRPC API.
+	EnvoyRateLimit *EnvoyRateLimitProvider `json:"envoyRateLimit,omitempty"`
+
+	// OpenTelemetry is a collector receiving access logs with OTLP over gRPC, and spans with OpenCensus.
+	OpenTelemetry *OpenTelemetryProvider `json:"opentelemetry,omitempty"`
+}
+
+// EnvoyRateLimitProvider is an external rate limit service implementing the Envoy rate limit gRPC API.
+type EnvoyRateLimitProvider struct {
+	// Service is the hostname of the rate limit service, optionally prefixed by its namespace as in
+	// "istio-system/ratelimit.istio-system.svc.cluster.local".
+	Service string `json:"service"`
+
+	// Port of the rate limit service.
+	Port uint32 `json:"port"`
+
+	// Domain sent to the rate limit service.
+	Domain string `json:"domain"`
+
+	// Timeout of the calls to the rate limit service. Defaults to 20ms.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// FailureModeDeny rejects the requests when the rate limit service cannot be reached. By default, requests are
+	// allowed.
+	FailureModeDeny bool `json:"failureModeDeny,omitempty"`
+}
+
+// OpenTelemetryProvider is a collector receiving access logs with OTLP over gRPC. The proxies have no OpenTelemetry
+// tracer, so the spans are sent with the OpenCensus tracer to the OpenCensus receiver of the collector.
+type OpenTelemetryProvider struct {
+	// Service is the hostname of the collector, optionally prefixed by its namespace as in
+	// "istio-system/opentelemetry-collector.istio-system.svc.cluster.local".
+	Service string `json:"service"`
+
+	// Port of the OTLP gRPC receiver of the collector.
+	Port uint32 `json:"port"`
+
+	// TracingPort is the port of the OpenCensus receiver of the collector. Defaults to 55678.
+	TracingPort uint32 `json:"tracingPort,omitempty"`
+}
+
+// GetTracingPort returns the port of the OpenCensus receiver of the collector.
+func (p *OpenTelemetryProvider) GetTracingPort() uint32 {
+	if p.TracingPort == 0 {
+		return DefaultOpenTelemetryTracingPort
+	}
+	return p.TracingPort
+}
@@ -0,0 +1,156 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package healthcheck defines the active health checks of the endpoints of destination rules.
+//
+// The health check is set on a DestinationRule with the networking.istio.io/healthCheck annotation, holding a YAML
+// or JSON object. For example:
+//
+//	networking.istio.io/healthCheck: |
+//	  interval: 5s
+//	  timeout: 1s
+//	  unhealthyThreshold: 2
+//	  http:
+//	    path: /healthz
+//
+// Each proxy sending traffic to the destination checks the endpoints on its own, and stops sending traffic to the
+// unhealthy endpoints. Health checks complement outlier detection for endpoints that receive little traffic, such as
+// the backends of failover.
+//
+// The DestinationRule API has no health check field in its traffic policy yet, so the annotation stands in for it.
+// The annotation holds the same fields as the proposed trafficPolicy.healthCheck, so that rules can move to the field
+// once the API has it. Invalid annotations are rejected by validation and reported by istioctl analyze.
+package healthcheck
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Annotation is the DestinationRule annotation holding the health check.
+const Annotation = "networking.istio.io/healthCheck"
+
+const (
+	// DefaultUnhealthyThreshold is the number of failed checks marking an endpoint unhealthy, if not set.
+	DefaultUnhealthyThreshold = 3
+	// DefaultHealthyThreshold is the number of successful checks marking an endpoint healthy, if not set.
+	DefaultHealthyThreshold = 1
+)
+
+// HealthCheck actively checks the endpoints of a destination. Exactly one of HTTP, GRPC or TCP is set.
+type HealthCheck struct {
+	// Subsets restricts the health check to the subsets with these names. If empty, the health check applies to
+	// the destination and to all its subsets.
+	Subsets []string `json:"subsets,omitempty"`
+
+	// Interval between two checks of an endpoint.
+	Interval metav1.Duration `json:"interval"`
+
+	// Timeout of a check.
+	Timeout metav1.Duration `json:"timeout"`
+
+	// NoTrafficInterval is the interval between two checks of an endpoint while the destination receives no
+	// traffic. Defaults to 60s.
+	NoTrafficInterval *metav1.Duration `json:"noTrafficInterval,omitempty"`
+
+	// UnhealthyThreshold is the number of consecutive failed checks marking an endpoint unhealthy. Defaults to 3.
+	UnhealthyThreshold uint32 `json:"unhealthyThreshold,omitempty"`
+
+	// HealthyThreshold is the number of consecutive successful checks marking an endpoint healthy. Defaults to 1.
+	HealthyThreshold uint32 `json:"healthyThreshold,omitempty"`
+
+	// HTTP checks the endpoints with HTTP requests.
+	HTTP *HTTP `json:"http,omitempty"`
+
+	// GRPC checks the endpoints with the gRPC health checking protocol.
+	GRPC *GRPC `json:"grpc,omitempty"`
+
+	// TCP checks the endpoints by opening connections.
+	TCP *TCP `json:"tcp,omitempty"`
+}
+
+// HTTP is a health check sending HTTP GET requests.
+type HTTP struct {
+	// Path of the requests.
+	Path string `json:"path"`
+
+	// Host of the requests. Defaults to the name of the cluster.
+	Host string `json:"host,omitempty"`
+
+	// ExpectedStatuses are the response status codes of healthy endpoints. Defaults to 200.
+	ExpectedStatuses []int64 `json:"expectedStatuses,omitempty"`
+}
+
+// GRPC is a health check calling the grpc.health.v1.Health service.
+type GRPC struct {
+	// ServiceName is the name of the checked service. If empty, the health of the server is checked.
+	ServiceName string `json:"serviceName,omitempty"`
+
+	// Authority of the requests. Defaults to the name of the cluster.
+	Authority string `json:"authority,omitempty"`
+}
+
+// TCP is a health check opening connections. If Send is empty, a successful connection is enough.
+type TCP struct {
+	// Send is sent on the connection.
+	Send string `json:"send,omitempty"`
+
+	// Receive are expected in the response, in order.
+	Receive []string `json:"receive,omitempty"`
+}
+
+// Parse returns the health check set in annotations, or nil if there is none.
+func Parse(annotations map[string]string) (*HealthCheck, error) {
+	value, f := annotations[Annotation]
+	if !f {
+		return nil, nil
+	}
+	hc := &HealthCheck{}
+	if err := yaml.UnmarshalStrict([]byte(value), hc); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", Annotation, err)
+	}
+	return hc, nil
+}
+
+// AppliesToSubset returns true if the health check applies to the subset with the given name, or to the destination
+// itself if the name is empty.
+func (hc *HealthCheck) AppliesToSubset(name string) bool {
+	if len(hc.Subsets) == 0 {
+		return true
+	}
+	for _, s := range hc.Subsets {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUnhealthyThreshold returns the number of consecutive failed checks marking an endpoint unhealthy.
+func (hc *HealthCheck) GetUnhealthyThreshold() uint32 {
+	if hc.UnhealthyThreshold == 0 {
+		return DefaultUnhealthyThreshold
+	}
+	return hc.UnhealthyThreshold
+}
+
+// GetHealthyThreshold returns the number of consecutive successful checks marking an endpoint healthy.
+func (hc *HealthCheck) GetHealthyThreshold() uint32 {
+	if hc.HealthyThreshold == 0 {
+		return DefaultHealthyThreshold
+	}
+	return hc.HealthyThreshold
+}
@@ -0,0 +1,115 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesh
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ghodss/yaml"
+
+	"istio.io/istio/pkg/config/extensionprovider"
+	"istio.io/istio/pkg/config/validation"
+)
+
+// ExtensionProvidersHolder holds the mesh extension providers of the extensionprovider package.
+type ExtensionProvidersHolder interface {
+	ExtensionProviders() []*extensionprovider.ExtensionProvider
+}
+
+// ParseExtensionProviders returns the extension providers of the mesh config yaml which belong to the
+// extensionprovider package.
+func ParseExtensionProviders(yamlText string) ([]*extensionprovider.ExtensionProvider, error) {
+	providers, _, _, err := splitExtensionProviders(yamlText)
+	if err != nil {
+		return nil, err
+	}
+	if err := validation.ValidateExtensionProviders(nil, providers); err != nil {
+		return nil, err
+	}
+	return providers, nil
+}
+
+// CutExtensionProviders returns the mesh config yaml without the extension providers of the extensionprovider
+// package, so that it can be decoded strictly into the MeshConfig API.
+func CutExtensionProviders(yamlText string) (string, error) {
+	_, _, out, err := splitExtensionProviders(yamlText)
+	return out, err
+}
+
+// splitExtensionProviders separates the extension providers of the extensionprovider package from the mesh config
+// yaml. It returns these providers, whether the yaml sets the extensionProviders list at all, and the yaml without
+// these providers.
+func splitExtensionProviders(yamlText string) ([]*extensionprovider.ExtensionProvider, bool, string, error) {
+	js, err := yaml.YAMLToJSON([]byte(yamlText))
+	if err != nil {
+		return nil, false, "", err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(js, &fields); err != nil {
+		// Not an object, leave the error to the mesh config decoding.
+		return nil, false, yamlText, nil
+	}
+	raw, f := fields["extensionProviders"]
+	if !f {
+		return nil, false, yamlText, nil
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, true, yamlText, nil
+	}
+
+	var providers []*extensionprovider.ExtensionProvider
+	remaining := make([]json.RawMessage, 0, len(entries))
+	for _, entry := range entries {
+		if !isExtensionProvider(entry) {
+			remaining = append(remaining, entry)
+			continue
+		}
+		provider := &extensionprovider.ExtensionProvider{}
+		decoder := json.NewDecoder(bytes.NewReader(entry))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(provider); err != nil {
+			return nil, true, "", fmt.Errorf("invalid extension provider %s: %v", entry, err)
+		}
+		providers = append(providers, provider)
+	}
+	if len(providers) == 0 {
+		return nil, true, yamlText, nil
+	}
+
+	if fields["extensionProviders"], err = json.Marshal(remaining); err != nil {
+		return nil, true, "", err
+	}
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return nil, true, "", err
+	}
+	return providers, true, string(out), nil
+}
+
+func isExtensionProvider(entry json.RawMessage) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(entry, &fields); err != nil {
+		return false
+	}
+	for _, kind := range extensionprovider.Kinds {
+		if _, f := fields[kind]; f {
+			return true
+		}
+	}
+	return false
+}
@@ -58,7 +58,12 @@ func NewConfigMapWatcher(client kube.Client, namespace, name, key string, multiW
 			log.Warnf("failed to read mesh config from ConfigMap: %v", err)
 			return
 		}
-		w.HandleMeshConfig(meshConfig)
+		providers, err := mesh.ParseExtensionProviders(meshConfigMapData(cm, key))
+		if err != nil {
+			log.Warnf("failed to read mesh config from ConfigMap: %v", err)
+			return
+		}
+		w.HandleMeshConfigWithExtensionProviders(meshConfig, providers)
 	})
 
 	go c.Run(stop)
@@ -28,6 +28,7 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/extensionprovider"
 	"istio.io/istio/pkg/config/validation"
 	"istio.io/istio/pkg/util/gogoprotomarshal"
 	"istio.io/pkg/log"
@@ -134,6 +135,18 @@ func extractProxyConfig(yamlText string) (string, error) {
 // ApplyMeshConfig returns a new MeshConfig decoded from the
 // input YAML with the provided defaults applied to omitted configuration values.
 func ApplyMeshConfig(yaml string, defaultConfig meshconfig.MeshConfig) (*meshconfig.MeshConfig, error) {
+	mc, _, _, err := applyMeshConfig(yaml, defaultConfig)
+	return mc, err
+}
+
+// applyMeshConfig behaves the same as ApplyMeshConfig, and also returns the extension providers of the
+// extensionprovider package, and whether the yaml sets the extensionProviders list.
+func applyMeshConfig(yaml string, defaultConfig meshconfig.MeshConfig) (*meshconfig.MeshConfig, []*extensionprovider.ExtensionProvider, bool, error) {
+	providers, providersSet, meshYaml, err := splitExtensionProviders(yaml)
+	if err != nil {
+		return nil, nil, false, multierror.Prefix(err, "failed to extract extension providers.")
+	}
+
 	// We want to keep semantics that all fields are overrides, except proxy config is a merge. This allows
 	// decent customization while also not requiring users to redefine the entire proxy config if they want to override
 	// Note: if we want to add more structure in the future, we will likely need to revisit this idea.
@@ -142,21 +155,21 @@ func ApplyMeshConfig(yaml string, defaultConfig meshconfig.MeshConfig) (*meshcon
 	prevProxyConfig := defaultConfig.DefaultConfig
 	defaultProxyConfig := DefaultProxyConfig()
 	defaultConfig.DefaultConfig = &defaultProxyConfig
-	if err := gogoprotomarshal.ApplyYAML(yaml, &defaultConfig); err != nil {
-		return nil, multierror.Prefix(err, "failed to convert to proto.")
+	if err := gogoprotomarshal.ApplyYAML(meshYaml, &defaultConfig); err != nil {
+		return nil, nil, false, multierror.Prefix(err, "failed to convert to proto.")
 	}
 	defaultConfig.DefaultConfig = prevProxyConfig
 
 	// Get just the proxy config yaml
 	pc, err := extractProxyConfig(yaml)
 	if err != nil {
-		return nil, multierror.Prefix(err, "failed to extract proxy config")
+		return nil, nil, false, multierror.Prefix(err, "failed to extract proxy config")
 	}
 	if pc != "" {
 		origMetadata := defaultConfig.DefaultConfig.ProxyMetadata
 		// Apply proxy config yaml on to the merged mesh config. This gives us "merge" semantics for proxy config
 		if err := gogoprotomarshal.ApplyYAML(pc, defaultConfig.DefaultConfig); err != nil {
-			return nil, multierror.Prefix(err, "failed to convert to proto.")
+			return nil, nil, false, multierror.Prefix(err, "failed to convert to proto.")
 		}
 		newMetadata := defaultConfig.DefaultConfig.ProxyMetadata
 		// we do a deep merge on proxy metadata to allow mesh-wide proxy config settings while still allowing
@@ -166,10 +179,13 @@ func ApplyMeshConfig(yaml string, defaultConfig meshconfig.MeshConfig) (*meshcon
 	}
 
 	if err := validation.ValidateMeshConfig(&defaultConfig); err != nil {
-		return nil, err
+		return nil, nil, false, err
+	}
+	if err := validation.ValidateExtensionProviders(&defaultConfig, providers); err != nil {
+		return nil, nil, false, err
 	}
 
-	return &defaultConfig, nil
+	return &defaultConfig, providers, providersSet, nil
 }
 
 func mergeMap(original map[string]string, merger map[string]string) map[string]string {
@@ -18,8 +18,10 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/extensionprovider"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/validation"
 	"istio.io/istio/pkg/util/gogoprotomarshal"
@@ -268,3 +270,93 @@ func TestResolveHostsInNetworksConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestExtensionProviders(t *testing.T) {
+	yaml := `
+ingressClass: foo
+extensionProviders:
+- name: authz
+  envoyExtAuthzGrpc:
+    service: authz.foo.svc.cluster.local
+    port: 9000
+- name: ratelimit
+  envoyRateLimit:
+    service: ratelimit.foo.svc.cluster.local
+    port: 8081
+    domain: reviews
+    timeout: 100ms
+- name: otel
+  opentelemetry:
+    service: otel-collector.foo.svc.cluster.local
+    port: 4317
+`
+	got, err := mesh.ApplyMeshConfigDefaults(yaml)
+	if err != nil {
+		t.Fatalf("ApplyMeshConfigDefaults() failed: %v", err)
+	}
+	if got.IngressClass != "foo" || len(got.ExtensionProviders) != 1 || got.ExtensionProviders[0].Name != "authz" {
+		t.Fatalf("unexpected mesh config %v", got)
+	}
+
+	providers, err := mesh.ParseExtensionProviders(yaml)
+	if err != nil {
+		t.Fatalf("ParseExtensionProviders() failed: %v", err)
+	}
+	if len(providers) != 2 || providers[0].Name != "ratelimit" || providers[0].EnvoyRateLimit.Domain != "reviews" ||
+		providers[0].EnvoyRateLimit.Timeout.Duration != 100*time.Millisecond ||
+		providers[1].Name != "otel" || providers[1].OpenTelemetry.Port != 4317 ||
+		providers[1].OpenTelemetry.GetTracingPort() != extensionprovider.DefaultOpenTelemetryTracingPort {
+		t.Fatalf("unexpected extension providers %v", providers)
+	}
+
+	for name, yaml := range map[string]string{
+		"missing domain": `
+extensionProviders:
+- name: ratelimit
+  envoyRateLimit:
+    service: ratelimit.foo.svc.cluster.local
+    port: 8081`,
+		"several types": `
+extensionProviders:
+- name: otel
+  opentelemetry:
+    service: otel-collector.foo.svc.cluster.local
+    port: 4317
+  envoyRateLimit:
+    service: ratelimit.foo.svc.cluster.local
+    port: 8081
+    domain: reviews`,
+		"invalid tracing port": `
+extensionProviders:
+- name: otel
+  opentelemetry:
+    service: otel-collector.foo.svc.cluster.local
+    port: 4317
+    tracingPort: 70000`,
+		"unknown field": `
+extensionProviders:
+- name: ratelimit
+  envoyRateLimit:
+    service: ratelimit.foo.svc.cluster.local
+    port: 8081
+    domain: reviews
+    foo: bar`,
+		"duplicate name": `
+extensionProviders:
+- name: ratelimit
+  envoyExtAuthzGrpc:
+    service: authz.foo.svc.cluster.local
+    port: 9000
+- name: ratelimit
+  envoyRateLimit:
+    service: ratelimit.foo.svc.cluster.local
+    port: 8081
+    domain: reviews`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := mesh.ApplyMeshConfigDefaults(yaml); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
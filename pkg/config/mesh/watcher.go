@@ -24,6 +24,8 @@ import (
 	"github.com/davecgh/go-spew/spew"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/extensionprovider"
+	"istio.io/istio/pkg/config/validation"
 	"istio.io/pkg/filewatcher"
 	"istio.io/pkg/log"
 )
@@ -51,13 +53,18 @@ type MultiWatcher struct {
 	InternalNetworkWatcher
 }
 
-var _ Watcher = &InternalWatcher{}
+var (
+	_ Watcher                  = &InternalWatcher{}
+	_ ExtensionProvidersHolder = &InternalWatcher{}
+)
 
 type InternalWatcher struct {
 	mutex    sync.Mutex
 	handlers []func()
 	// Current merged mesh config
 	MeshConfig *meshconfig.MeshConfig
+	// Current extension providers of the extensionprovider package
+	extensionProviders atomic.Value
 
 	userMeshConfig string
 	revMeshConfig  string
@@ -71,6 +78,16 @@ func NewFixedWatcher(mesh *meshconfig.MeshConfig) Watcher {
 	}
 }
 
+// NewFixedWatcherWithExtensionProviders behaves the same as NewFixedWatcher, and also holds the given
+// extension providers.
+func NewFixedWatcherWithExtensionProviders(mesh *meshconfig.MeshConfig, providers []*extensionprovider.ExtensionProvider) Watcher {
+	w := &InternalWatcher{
+		MeshConfig: mesh,
+	}
+	w.extensionProviders.Store(providers)
+	return w
+}
+
 // NewFileWatcher creates a new Watcher for changes to the given mesh config file. Returns an error
 // if the given file does not exist or failed during parsing.
 func NewFileWatcher(fileWatcher filewatcher.FileWatcher, filename string, multiWatch bool) (Watcher, error) {
@@ -79,7 +96,7 @@ func NewFileWatcher(fileWatcher filewatcher.FileWatcher, filename string, multiW
 		return nil, err
 	}
 
-	meshConfig, err := ApplyMeshConfigDefaults(meshConfigYaml)
+	meshConfig, providers, _, err := applyMeshConfig(meshConfigYaml, DefaultMeshConfig())
 	if err != nil {
 		return nil, err
 	}
@@ -88,6 +105,7 @@ func NewFileWatcher(fileWatcher filewatcher.FileWatcher, filename string, multiW
 		MeshConfig:    meshConfig,
 		revMeshConfig: meshConfigYaml,
 	}
+	w.extensionProviders.Store(providers)
 
 	// Watch the config file for changes and reload if it got modified
 	addFileWatcher(fileWatcher, filename, func() {
@@ -101,12 +119,17 @@ func NewFileWatcher(fileWatcher filewatcher.FileWatcher, filename string, multiW
 			return
 		}
 		// Reload the config file
-		meshConfig, err = ReadMeshConfig(filename)
+		meshConfigYaml, err := ReadMeshConfigData(filename)
 		if err != nil {
 			log.Warnf("failed to read mesh configuration, using default: %v", err)
 			return
 		}
-		w.HandleMeshConfig(meshConfig)
+		meshConfig, providers, _, err := applyMeshConfig(meshConfigYaml, DefaultMeshConfig())
+		if err != nil {
+			log.Warnf("failed to read mesh configuration, using default: %v", err)
+			return
+		}
+		w.HandleMeshConfigWithExtensionProviders(meshConfig, providers)
 	})
 	return w, nil
 }
@@ -116,6 +139,12 @@ func (w *InternalWatcher) Mesh() *meshconfig.MeshConfig {
 	return (*meshconfig.MeshConfig)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&w.MeshConfig))))
 }
 
+// ExtensionProviders returns the latest extension providers of the extensionprovider package.
+func (w *InternalWatcher) ExtensionProviders() []*extensionprovider.ExtensionProvider {
+	providers, _ := w.extensionProviders.Load().([]*extensionprovider.ExtensionProvider)
+	return providers
+}
+
 // AddMeshHandler registers a callback handler for changes to the mesh config.
 func (w *InternalWatcher) AddMeshHandler(h func()) {
 	w.mutex.Lock()
@@ -129,8 +158,8 @@ func (w *InternalWatcher) HandleMeshConfigData(yaml string) {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
 	w.revMeshConfig = yaml
-	merged := w.merged()
-	w.handleMeshConfigInternal(merged)
+	merged, providers := w.merged()
+	w.handleMeshConfigInternal(merged, providers)
 }
 
 // HandleUserMeshConfig keeps track of user mesh config overrides. These are merged with the standard
@@ -139,45 +168,72 @@ func (w *InternalWatcher) HandleUserMeshConfig(yaml string) {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
 	w.userMeshConfig = yaml
-	merged := w.merged()
-	w.handleMeshConfigInternal(merged)
+	merged, providers := w.merged()
+	w.handleMeshConfigInternal(merged, providers)
 }
 
-// merged returns the merged user and revision config.
-func (w *InternalWatcher) merged() *meshconfig.MeshConfig {
+// merged returns the merged user and revision config, and their extension providers. Like the extension providers
+// of the API, the providers of the revision config replace the ones of the user config.
+func (w *InternalWatcher) merged() (*meshconfig.MeshConfig, []*extensionprovider.ExtensionProvider) {
 	mc := DefaultMeshConfig()
+	var providers []*extensionprovider.ExtensionProvider
 	if w.userMeshConfig != "" {
-		mc1, err := ApplyMeshConfig(w.userMeshConfig, mc)
+		mc1, providers1, providersSet, err := applyMeshConfig(w.userMeshConfig, mc)
 		if err != nil {
 			log.Errorf("user config invalid, ignoring it %v %s", err, w.userMeshConfig)
 		} else {
 			mc = *mc1
+			if providersSet {
+				providers = providers1
+			}
 			log.Infoa("Applied user config: ", spew.Sdump(mc))
 		}
 	}
 	if w.revMeshConfig != "" {
-		mc1, err := ApplyMeshConfig(w.revMeshConfig, mc)
+		mc1, providers1, providersSet, err := applyMeshConfig(w.revMeshConfig, mc)
 		if err != nil {
 			log.Errorf("revision config invalid, ignoring it %v %s", err, w.userMeshConfig)
 		} else {
 			mc = *mc1
+			if providersSet {
+				providers = providers1
+			}
 			log.Infoa("Applied revision mesh config: ", spew.Sdump(mc))
 		}
 	}
-	return &mc
+	if err := validation.ValidateExtensionProviders(&mc, providers); err != nil {
+		log.Errorf("extension providers invalid, ignoring them %v", err)
+		providers = nil
+	}
+	return &mc, providers
 }
 
 // HandleMeshConfig calls all handlers for a given mesh configuration update. This must be called
-// with a lock on w.Mutex, or updates may be applied out of order.
+// with a lock on w.Mutex, or updates may be applied out of order. The extension providers are kept.
 func (w *InternalWatcher) HandleMeshConfig(meshConfig *meshconfig.MeshConfig) {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
-	w.handleMeshConfigInternal(meshConfig)
+	w.handleMeshConfigInternal(meshConfig, w.ExtensionProviders())
+}
+
+// HandleMeshConfigWithExtensionProviders behaves the same as HandleMeshConfig, and also replaces the
+// extension providers.
+func (w *InternalWatcher) HandleMeshConfigWithExtensionProviders(meshConfig *meshconfig.MeshConfig, providers []*extensionprovider.ExtensionProvider) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.handleMeshConfigInternal(meshConfig, providers)
 }
 
 // handleMeshConfigInternal behaves the same as HandleMeshConfig but must be called under a lock
-func (w *InternalWatcher) handleMeshConfigInternal(meshConfig *meshconfig.MeshConfig) {
+func (w *InternalWatcher) handleMeshConfigInternal(meshConfig *meshconfig.MeshConfig, providers []*extensionprovider.ExtensionProvider) {
 	var handlers []func()
+	changed := false
+
+	if !reflect.DeepEqual(providers, w.ExtensionProviders()) {
+		log.Infof("mesh extension providers updated to: %s", spew.Sdump(providers))
+		w.extensionProviders.Store(providers)
+		changed = true
+	}
 
 	if !reflect.DeepEqual(meshConfig, w.MeshConfig) {
 		log.Infof("mesh configuration updated to: %s", spew.Sdump(meshConfig))
@@ -187,6 +243,9 @@ func (w *InternalWatcher) handleMeshConfigInternal(meshConfig *meshconfig.MeshCo
 		}
 
 		atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&w.MeshConfig)), unsafe.Pointer(meshConfig))
+		changed = true
+	}
+	if changed {
 		handlers = append(handlers, w.handlers...)
 	}
 
@@ -25,6 +25,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/extensionprovider"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/util/protomarshal"
 	"istio.io/pkg/filewatcher"
@@ -143,3 +144,50 @@ func BenchmarkGetMesh(b *testing.B) {
 		handler(w.Mesh())
 	}
 }
+
+func TestWatcherExtensionProviders(t *testing.T) {
+	g := NewWithT(t)
+
+	path := newTempFile(t)
+	writeFile(t, path, `
+extensionProviders:
+- name: ratelimit
+  envoyRateLimit:
+    service: ratelimit.foo.svc.cluster.local
+    port: 8081
+    domain: reviews`)
+
+	w := newWatcher(t, path, true)
+	g.Expect(w.(mesh.ExtensionProvidersHolder).ExtensionProviders()).To(Equal([]*extensionprovider.ExtensionProvider{{
+		Name: "ratelimit",
+		EnvoyRateLimit: &extensionprovider.EnvoyRateLimitProvider{
+			Service: "ratelimit.foo.svc.cluster.local",
+			Port:    8081,
+			Domain:  "reviews",
+		},
+	}}))
+
+	doneCh := make(chan struct{}, 1)
+	w.AddMeshHandler(func() {
+		close(doneCh)
+	})
+
+	// The user config does not override the providers of the revision config.
+	w.HandleUserMeshConfig(`
+extensionProviders:
+- name: other
+  envoyRateLimit:
+    service: other.foo.svc.cluster.local
+    port: 8081
+    domain: other`)
+	g.Expect(w.(mesh.ExtensionProvidersHolder).ExtensionProviders()[0].Name).To(Equal("ratelimit"))
+
+	// Removing the providers from the revision config falls back to the user config, and notifies the handlers.
+	writeFile(t, path, `ingressClass: foo`)
+	select {
+	case <-doneCh:
+		g.Expect(w.(mesh.ExtensionProvidersHolder).ExtensionProviders()[0].Name).To(Equal("other"))
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for update")
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ratelimit defines the rate limit policies attached to virtual services.
+//
+// Policies are set on a VirtualService with the networking.istio.io/rateLimit annotation, holding a YAML or JSON list
+// of policies. For example:
+//
+//	networking.istio.io/rateLimit: |
+//	  - routes: [reviews-v2]
+//	    local:
+//	      maxTokens: 100
+//	      fillInterval: 1m
+//	  - global:
+//	      provider: ratelimit
+//	      descriptors:
+//	      - key: path
+//	        header: ":path"
+//
+// Local policies are enforced by each proxy using a token bucket. Global policies are enforced by an external rate
+// limit service implementing the Envoy rate limit gRPC API, set with an envoyRateLimit extension provider of the mesh
+// config:
+//
+//	extensionProviders:
+//	- name: ratelimit
+//	  envoyRateLimit:
+//	    service: ratelimit.istio-system.svc.cluster.local
+//	    port: 8081
+//	    domain: reviews
+package ratelimit
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/config/labels"
+)
+
+// Annotation is the VirtualService annotation holding the list of rate limit policies.
+const Annotation = "networking.istio.io/rateLimit"
+
+// Policy rate limits the requests matching the HTTP routes of a virtual service.
+type Policy struct {
+	// WorkloadSelector restricts the policy to the proxies with these labels. If empty, the policy applies to all
+	// the proxies using the virtual service, both sidecars and gateways.
+	WorkloadSelector map[string]string `json:"workloadSelector,omitempty"`
+
+	// Routes restricts the policy to the HTTP routes with these names. If empty, the policy applies to all routes.
+	Routes []string `json:"routes,omitempty"`
+
+	// Local enforces the limit in each proxy.
+	Local *Local `json:"local,omitempty"`
+
+	// Global enforces the limit with an external rate limit service.
+	Global *Global `json:"global,omitempty"`
+}
+
+// Local is a token bucket, enforced independently by each proxy.
+type Local struct {
+	// MaxTokens is the size of the bucket, and the number of tokens it initially holds.
+	MaxTokens uint32 `json:"maxTokens"`
+
+	// TokensPerFill is the number of tokens added to the bucket every fill interval. Defaults to MaxTokens.
+	TokensPerFill uint32 `json:"tokensPerFill,omitempty"`
+
+	// FillInterval is the interval at which the bucket is filled.
+	FillInterval metav1.Duration `json:"fillInterval"`
+}
+
+// Global sends the request descriptors to an external rate limit service.
+type Global struct {
+	// Provider is the name of the envoyRateLimit extension provider of the mesh config, setting the rate limit
+	// service and domain.
+	Provider string `json:"provider"`
+
+	// Descriptors sent to the rate limit service. If empty, a single descriptor with the "route" key, identifying
+	// the virtual service and route, is sent.
+	Descriptors []Descriptor `json:"descriptors,omitempty"`
+}
+
+// Descriptor is an entry of the descriptor sent to the rate limit service. Exactly one of Header or Value is set.
+type Descriptor struct {
+	// Key of the descriptor entry.
+	Key string `json:"key"`
+
+	// Header is the name of the request header holding the value of the entry. If the header is missing, the
+	// descriptor is not sent.
+	Header string `json:"header,omitempty"`
+
+	// Value is the literal value of the entry.
+	Value string `json:"value,omitempty"`
+}
+
+// Parse returns the rate limit policies set in annotations, or nil if there are none.
+func Parse(annotations map[string]string) ([]*Policy, error) {
+	value, f := annotations[Annotation]
+	if !f {
+		return nil, nil
+	}
+	var policies []*Policy
+	if err := yaml.UnmarshalStrict([]byte(value), &policies); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", Annotation, err)
+	}
+	return policies, nil
+}
+
+// Selects returns true if the policy applies to a proxy with the given labels.
+func (p *Policy) Selects(proxyLabels map[string]string) bool {
+	return labels.Instance(p.WorkloadSelector).SubsetOf(proxyLabels)
+}
+
+// AppliesToRoute returns true if the policy applies to the HTTP route with the given name.
+func (p *Policy) AppliesToRoute(name string) bool {
+	if len(p.Routes) == 0 {
+		return true
+	}
+	for _, r := range p.Routes {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTokensPerFill returns the number of tokens added to the bucket every fill interval.
+func (l *Local) GetTokensPerFill() uint32 {
+	if l.TokensPerFill == 0 {
+		return l.MaxTokens
+	}
+	return l.TokensPerFill
+}
@@ -574,6 +574,7 @@ resources:
     version: "v1alpha1"
     proto: "istio.telemetry.v1alpha1.Telemetry"
     protoPackage: "istio.io/api/telemetry/v1alpha1"
+    validate: "ValidateTelemetry"
     description: "describes telemetry configuration for workloads"
     statusProto: "istio.meta.v1alpha1.IstioStatus"
     statusProtoPackage: "istio.io/api/meta/v1alpha1"
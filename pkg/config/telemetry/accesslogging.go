@@ -0,0 +1,192 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package telemetry defines the telemetry settings of Telemetry resources that are not part of their spec.
+//
+// Access logging is set on a Telemetry with the telemetry.istio.io/accessLogging annotation, holding a YAML or JSON
+// object. For example, to only log the server errors of the workloads of a namespace:
+//
+//	telemetry.istio.io/accessLogging: |
+//	  providers:
+//	  - name: envoy
+//	  filter:
+//	    expression: response.code >= 500
+//
+// The proxies of this release have no CEL access log filter, so the filter expression is restricted to comparisons of
+// the response code, which are mapped onto the status code filters of Envoy. The comparisons may be combined with
+// either && or ||, for example "response.code >= 400 && response.code < 500".
+//
+// As for the other telemetry settings, the access logging of a workload is the one of the root namespace, overridden
+// by the one of the namespace of the workload, then by the one of the Telemetry selecting the workload.
+package telemetry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// AccessLoggingAnnotation is the Telemetry annotation holding the access logging settings.
+const AccessLoggingAnnotation = "telemetry.istio.io/accessLogging"
+
+const (
+	// EnvoyFileAccessLogProvider is the built-in provider writing the access logs to a file, as configured by the
+	// accessLogFile, accessLogFormat and accessLogEncoding settings of MeshConfig. The file defaults to /dev/stdout.
+	EnvoyFileAccessLogProvider = "envoy"
+
+	// EnvoyGrpcAccessLogProvider is the built-in provider sending the access logs to the Envoy access log service
+	// configured in the proxy config.
+	EnvoyGrpcAccessLogProvider = "envoy-als"
+)
+
+// AccessLogging configures the access logs of the selected workloads.
+type AccessLogging struct {
+	// Providers are the names of the providers receiving the access logs: the built-in providers, or the extension
+	// providers of MeshConfig supporting access logging. If empty, the access logs configured in MeshConfig are used.
+	Providers []ProviderRef `json:"providers,omitempty"`
+
+	// Filter restricts the access logs to the matching requests and connections. If not set, all are logged.
+	Filter *AccessLogFilter `json:"filter,omitempty"`
+
+	// Disabled turns off the access logs of the selected workloads.
+	Disabled *bool `json:"disabled,omitempty"`
+}
+
+// ProviderRef references a telemetry provider by name.
+type ProviderRef struct {
+	Name string `json:"name"`
+}
+
+// AccessLogFilter selects the requests and connections to log.
+type AccessLogFilter struct {
+	// Expression compares the response code with constants, for example "response.code >= 500".
+	Expression string `json:"expression"`
+}
+
+// responseCodeAttribute is the attribute of the response code in filter expressions.
+const responseCodeAttribute = "response.code"
+
+// CodeComparison compares the response code with a value. Op is one of ">=", "<=" or "==", the comparisons
+// supported by the status code filter of Envoy.
+type CodeComparison struct {
+	Op    string
+	Value uint32
+}
+
+// CodeFilter is a filter expression parsed into comparisons of the response code, all of which must match, or any of
+// which must match if AnyOf is set.
+type CodeFilter struct {
+	Comparisons []CodeComparison
+	AnyOf       bool
+}
+
+// ParseFilterExpression parses a filter expression into comparisons of the response code.
+func ParseFilterExpression(expression string) (*CodeFilter, error) {
+	f := &CodeFilter{}
+	terms := []string{expression}
+	if strings.Contains(expression, "&&") && strings.Contains(expression, "||") {
+		return nil, fmt.Errorf("filter expression %q mixes && and ||", expression)
+	} else if strings.Contains(expression, "&&") {
+		terms = strings.Split(expression, "&&")
+	} else if strings.Contains(expression, "||") {
+		terms = strings.Split(expression, "||")
+		f.AnyOf = true
+	}
+	for _, term := range terms {
+		c, err := parseCodeComparison(strings.TrimSpace(term))
+		if err != nil {
+			return nil, fmt.Errorf("filter expression %q: %v", expression, err)
+		}
+		f.Comparisons = append(f.Comparisons, c)
+	}
+	return f, nil
+}
+
+// parseCodeComparison parses a comparison of the response code with a value, such as "response.code > 499", into a
+// comparison supported by Envoy, here ">= 500".
+func parseCodeComparison(term string) (CodeComparison, error) {
+	if !strings.HasPrefix(term, responseCodeAttribute) {
+		return CodeComparison{}, fmt.Errorf("only comparisons of %s are supported: %q", responseCodeAttribute, term)
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(term, responseCodeAttribute))
+	for _, op := range []string{">=", "<=", "==", ">", "<"} {
+		if !strings.HasPrefix(rest, op) {
+			continue
+		}
+		value, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(rest, op)), 10, 32)
+		if err != nil || value > 999 {
+			return CodeComparison{}, fmt.Errorf("invalid response code in %q", term)
+		}
+		switch op {
+		case ">":
+			return CodeComparison{Op: ">=", Value: uint32(value) + 1}, nil
+		case "<":
+			if value == 0 {
+				return CodeComparison{}, fmt.Errorf("%q never matches", term)
+			}
+			return CodeComparison{Op: "<=", Value: uint32(value) - 1}, nil
+		}
+		return CodeComparison{Op: op, Value: uint32(value)}, nil
+	}
+	return CodeComparison{}, fmt.Errorf("unsupported comparison %q, expected one of >=, <=, ==, > or <", term)
+}
+
+// ParseAccessLogging returns the access logging set in annotations, or nil if there is none.
+func ParseAccessLogging(annotations map[string]string) (*AccessLogging, error) {
+	value, f := annotations[AccessLoggingAnnotation]
+	if !f {
+		return nil, nil
+	}
+	al := &AccessLogging{}
+	if err := yaml.UnmarshalStrict([]byte(value), al); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", AccessLoggingAnnotation, err)
+	}
+	return al, nil
+}
+
+// IsDisabled returns true if the access logs are turned off.
+func (al *AccessLogging) IsDisabled() bool {
+	return al != nil && al.Disabled != nil && *al.Disabled
+}
+
+// GetFilterExpression returns the expression of the filter, or an empty string if all requests are logged.
+func (al *AccessLogging) GetFilterExpression() string {
+	if al == nil || al.Filter == nil {
+		return ""
+	}
+	return al.Filter.Expression
+}
+
+// MergeAccessLogging returns the access logging of parent, overridden by the settings set in child.
+func MergeAccessLogging(parent, child *AccessLogging) *AccessLogging {
+	if parent == nil {
+		return child
+	}
+	if child == nil {
+		return parent
+	}
+	merged := *parent
+	if len(child.Providers) != 0 {
+		merged.Providers = child.Providers
+	}
+	if child.Filter != nil {
+		merged.Filter = child.Filter
+	}
+	if child.Disabled != nil {
+		merged.Disabled = child.Disabled
+	}
+	return &merged
+}
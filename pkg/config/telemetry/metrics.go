@@ -0,0 +1,174 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+)
+
+// MetricsAnnotation is the Telemetry annotation holding the metrics settings. For example, to add the request host to
+// the request count reported by the clients and to stop reporting the request sizes:
+//
+//	telemetry.istio.io/metrics: |
+//	  overrides:
+//	  - match:
+//	      metric: REQUEST_COUNT
+//	      mode: CLIENT
+//	    tagOverrides:
+//	      request_host:
+//	        value: request.host
+//	  - match:
+//	      metric: REQUEST_SIZE
+//	    disabled: true
+const MetricsAnnotation = "telemetry.istio.io/metrics"
+
+// Modes of the metrics, selecting the side of the traffic the proxy reports.
+const (
+	ClientAndServerMode = "CLIENT_AND_SERVER"
+	ClientMode          = "CLIENT"
+	ServerMode          = "SERVER"
+)
+
+// Operations of the tag overrides.
+const (
+	UpsertTagOperation = "UPSERT"
+	RemoveTagOperation = "REMOVE"
+)
+
+// AllMetrics selects all the standard metrics.
+const AllMetrics = "ALL_METRICS"
+
+// StandardMetrics maps the standard metrics to their names in the stats filter.
+var StandardMetrics = map[string]string{
+	"REQUEST_COUNT":          "requests_total",
+	"REQUEST_DURATION":       "request_duration_milliseconds",
+	"REQUEST_SIZE":           "request_bytes",
+	"RESPONSE_SIZE":          "response_bytes",
+	"TCP_OPENED_CONNECTIONS": "tcp_connections_opened_total",
+	"TCP_CLOSED_CONNECTIONS": "tcp_connections_closed_total",
+	"TCP_SENT_BYTES":         "tcp_sent_bytes_total",
+	"TCP_RECEIVED_BYTES":     "tcp_received_bytes_total",
+	"GRPC_REQUEST_MESSAGES":  "request_messages_total",
+	"GRPC_RESPONSE_MESSAGES": "response_messages_total",
+}
+
+// Metrics customizes the metrics of the selected workloads.
+type Metrics struct {
+	// Overrides are applied in order, the later ones taking precedence.
+	Overrides []MetricsOverride `json:"overrides,omitempty"`
+}
+
+// MetricsOverride customizes the metrics matching a selector.
+type MetricsOverride struct {
+	// Match selects the metrics to customize. If not set, all the standard metrics of both modes are customized.
+	Match *MetricSelector `json:"match,omitempty"`
+
+	// Disabled stops reporting the metrics.
+	Disabled *bool `json:"disabled,omitempty"`
+
+	// TagOverrides adds, changes or removes the dimensions of the metrics, keyed by tag name.
+	TagOverrides map[string]TagOverride `json:"tagOverrides,omitempty"`
+}
+
+// MetricSelector selects metrics by name and mode.
+type MetricSelector struct {
+	// Metric is one of the standard metrics, or ALL_METRICS. It defaults to ALL_METRICS.
+	Metric string `json:"metric,omitempty"`
+
+	// CustomMetric is the name of a metric defined in the stats filter, without prefix. It can not be set with Metric.
+	CustomMetric string `json:"customMetric,omitempty"`
+
+	// Mode is CLIENT, SERVER or CLIENT_AND_SERVER. It defaults to CLIENT_AND_SERVER.
+	Mode string `json:"mode,omitempty"`
+}
+
+// TagOverride changes a dimension of the metrics.
+type TagOverride struct {
+	// Operation is UPSERT or REMOVE. It defaults to UPSERT.
+	Operation string `json:"operation,omitempty"`
+
+	// Value is the CEL expression computing the dimension for UPSERT.
+	Value string `json:"value,omitempty"`
+}
+
+// ParseMetrics returns the metrics set in annotations, or nil if there are none.
+func ParseMetrics(annotations map[string]string) (*Metrics, error) {
+	value, f := annotations[MetricsAnnotation]
+	if !f {
+		return nil, nil
+	}
+	m := &Metrics{}
+	if err := yaml.UnmarshalStrict([]byte(value), m); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", MetricsAnnotation, err)
+	}
+	return m, nil
+}
+
+// MergeMetrics returns the overrides of parent followed by the ones of child, so that child takes precedence.
+func MergeMetrics(parent, child *Metrics) *Metrics {
+	if parent == nil {
+		return child
+	}
+	if child == nil {
+		return parent
+	}
+	overrides := make([]MetricsOverride, 0, len(parent.Overrides)+len(child.Overrides))
+	overrides = append(overrides, parent.Overrides...)
+	overrides = append(overrides, child.Overrides...)
+	return &Metrics{Overrides: overrides}
+}
+
+// AppliesToMode returns true if any override applies to the metrics reported in mode, CLIENT or SERVER.
+func (m *Metrics) AppliesToMode(mode string) bool {
+	for i := range m.Overrides {
+		if m.Overrides[i].AppliesToMode(mode) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDisabled returns true if the metrics are no longer reported.
+func (o *MetricsOverride) IsDisabled() bool {
+	return o.Disabled != nil && *o.Disabled
+}
+
+// AppliesToMode returns true if the override applies to the metrics reported in mode, CLIENT or SERVER.
+func (o *MetricsOverride) AppliesToMode(mode string) bool {
+	m := o.Match.GetMode()
+	return m == ClientAndServerMode || m == mode
+}
+
+// GetMode returns the mode of the selector, defaulting to CLIENT_AND_SERVER.
+func (s *MetricSelector) GetMode() string {
+	if s == nil || s.Mode == "" {
+		return ClientAndServerMode
+	}
+	return s.Mode
+}
+
+// MetricName returns the name of the selected metric in the stats filter, or an empty string if all the metrics are
+// selected.
+func (s *MetricSelector) MetricName() string {
+	if s == nil {
+		return ""
+	}
+	if s.CustomMetric != "" {
+		return s.CustomMetric
+	}
+	return StandardMetrics[s.Metric]
+}
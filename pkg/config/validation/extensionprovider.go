@@ -24,6 +24,7 @@ import (
 	"github.com/hashicorp/go-multierror"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/extensionprovider"
 )
 
 func validateExtensionProviderService(service string) error {
@@ -204,3 +205,56 @@ func validateExtensionProvider(config *meshconfig.MeshConfig) (errs error) {
 	}
 	return
 }
+
+// ValidateExtensionProviders validates the mesh extension providers of the extensionprovider package.
+// If mesh is set, the names of the providers must not conflict with the names of its extension providers.
+func ValidateExtensionProviders(mesh *meshconfig.MeshConfig, providers []*extensionprovider.ExtensionProvider) (errs error) {
+	names := map[string]struct{}{}
+	for _, p := range mesh.GetExtensionProviders() {
+		names[p.Name] = struct{}{}
+	}
+	rateLimitProviders := 0
+	for _, p := range providers {
+		if p.Name == "" {
+			errs = appendErrors(errs, fmt.Errorf("empty extension provider name"))
+		} else if _, f := names[p.Name]; f {
+			errs = appendErrors(errs, fmt.Errorf("duplicate extension provider name %s", p.Name))
+		}
+		names[p.Name] = struct{}{}
+
+		if (p.EnvoyRateLimit == nil) == (p.OpenTelemetry == nil) {
+			errs = appendErrors(errs, fmt.Errorf("extension provider %s: exactly one provider type must be set", p.Name))
+		}
+		if otel := p.OpenTelemetry; otel != nil {
+			if err := validateExtensionProviderService(otel.Service); err != nil {
+				errs = appendErrors(errs, fmt.Errorf("extension provider %s: %v", p.Name, err))
+			}
+			if err := ValidatePort(int(otel.Port)); err != nil {
+				errs = appendErrors(errs, fmt.Errorf("extension provider %s: %v", p.Name, err))
+			}
+			if err := ValidatePort(int(otel.GetTracingPort())); err != nil {
+				errs = appendErrors(errs, fmt.Errorf("extension provider %s: tracing %v", p.Name, err))
+			}
+		}
+		if rl := p.EnvoyRateLimit; rl != nil {
+			rateLimitProviders++
+			if err := validateExtensionProviderService(rl.Service); err != nil {
+				errs = appendErrors(errs, fmt.Errorf("extension provider %s: %v", p.Name, err))
+			}
+			if err := ValidatePort(int(rl.Port)); err != nil {
+				errs = appendErrors(errs, fmt.Errorf("extension provider %s: %v", p.Name, err))
+			}
+			if rl.Domain == "" {
+				errs = appendErrors(errs, fmt.Errorf("extension provider %s: domain must be set", p.Name))
+			}
+			if rl.Timeout != nil && rl.Timeout.Duration <= 0 {
+				errs = appendErrors(errs, fmt.Errorf("extension provider %s: timeout must be positive", p.Name))
+			}
+		}
+	}
+	if rateLimitProviders > extensionprovider.MaxEnvoyRateLimitProviders {
+		errs = appendErrors(errs, fmt.Errorf("at most %d envoyRateLimit extension providers are supported, found %d",
+			extensionprovider.MaxEnvoyRateLimitProviders, rateLimitProviders))
+	}
+	return errs
+}
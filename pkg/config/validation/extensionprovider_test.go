@@ -15,9 +15,14 @@
 package validation
 
 import (
+	"fmt"
 	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/extensionprovider"
 )
 
 func TestValidateExtensionProviderService(t *testing.T) {
@@ -289,3 +294,144 @@ func TestValidateExtensionProviderTracingOpenCensusAgent(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateExtensionProviders(t *testing.T) {
+	rateLimit := func(name string) *extensionprovider.ExtensionProvider {
+		return &extensionprovider.ExtensionProvider{
+			Name: name,
+			EnvoyRateLimit: &extensionprovider.EnvoyRateLimitProvider{
+				Service: "ratelimit.foo.svc.cluster.local",
+				Port:    8081,
+				Domain:  "reviews",
+				Timeout: &metav1.Duration{Duration: 100 * time.Millisecond},
+			},
+		}
+	}
+	openTelemetry := func(name string) *extensionprovider.ExtensionProvider {
+		return &extensionprovider.ExtensionProvider{
+			Name: name,
+			OpenTelemetry: &extensionprovider.OpenTelemetryProvider{
+				Service: "otel-collector.foo.svc.cluster.local",
+				Port:    4317,
+			},
+		}
+	}
+	mesh := &meshconfig.MeshConfig{
+		ExtensionProviders: []*meshconfig.MeshConfig_ExtensionProvider{{Name: "authz"}},
+	}
+	cases := []struct {
+		name      string
+		mesh      *meshconfig.MeshConfig
+		providers func() []*extensionprovider.ExtensionProvider
+		valid     bool
+	}{
+		{
+			name: "valid",
+			mesh: mesh,
+			providers: func() []*extensionprovider.ExtensionProvider {
+				return []*extensionprovider.ExtensionProvider{rateLimit("ratelimit"), openTelemetry("otel")}
+			},
+			valid: true,
+		},
+		{
+			name: "valid without mesh",
+			providers: func() []*extensionprovider.ExtensionProvider {
+				return []*extensionprovider.ExtensionProvider{rateLimit("authz")}
+			},
+			valid: true,
+		},
+		{
+			name: "name conflicts with mesh config",
+			mesh: mesh,
+			providers: func() []*extensionprovider.ExtensionProvider {
+				return []*extensionprovider.ExtensionProvider{rateLimit("authz")}
+			},
+			valid: false,
+		},
+		{
+			name: "duplicate name",
+			providers: func() []*extensionprovider.ExtensionProvider {
+				return []*extensionprovider.ExtensionProvider{rateLimit("ratelimit"), openTelemetry("ratelimit")}
+			},
+			valid: false,
+		},
+		{
+			name: "empty name",
+			providers: func() []*extensionprovider.ExtensionProvider {
+				return []*extensionprovider.ExtensionProvider{rateLimit("")}
+			},
+			valid: false,
+		},
+		{
+			name: "no provider type",
+			providers: func() []*extensionprovider.ExtensionProvider {
+				return []*extensionprovider.ExtensionProvider{{Name: "empty"}}
+			},
+			valid: false,
+		},
+		{
+			name: "invalid service",
+			providers: func() []*extensionprovider.ExtensionProvider {
+				p := openTelemetry("otel")
+				p.OpenTelemetry.Service = "otel-collector.foo.svc.cluster.local:4317"
+				return []*extensionprovider.ExtensionProvider{p}
+			},
+			valid: false,
+		},
+		{
+			name: "invalid port",
+			providers: func() []*extensionprovider.ExtensionProvider {
+				p := rateLimit("ratelimit")
+				p.EnvoyRateLimit.Port = 0
+				return []*extensionprovider.ExtensionProvider{p}
+			},
+			valid: false,
+		},
+		{
+			name: "invalid tracing port",
+			providers: func() []*extensionprovider.ExtensionProvider {
+				p := openTelemetry("otel")
+				p.OpenTelemetry.TracingPort = 65536
+				return []*extensionprovider.ExtensionProvider{p}
+			},
+			valid: false,
+		},
+		{
+			name: "missing domain",
+			providers: func() []*extensionprovider.ExtensionProvider {
+				p := rateLimit("ratelimit")
+				p.EnvoyRateLimit.Domain = ""
+				return []*extensionprovider.ExtensionProvider{p}
+			},
+			valid: false,
+		},
+		{
+			name: "negative timeout",
+			providers: func() []*extensionprovider.ExtensionProvider {
+				p := rateLimit("ratelimit")
+				p.EnvoyRateLimit.Timeout.Duration = -time.Second
+				return []*extensionprovider.ExtensionProvider{p}
+			},
+			valid: false,
+		},
+		{
+			name: "too many rate limit providers",
+			providers: func() []*extensionprovider.ExtensionProvider {
+				var providers []*extensionprovider.ExtensionProvider
+				for i := 0; i <= extensionprovider.MaxEnvoyRateLimitProviders; i++ {
+					providers = append(providers, rateLimit(fmt.Sprintf("ratelimit-%d", i)))
+				}
+				return providers
+			},
+			valid: false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateExtensionProviders(c.mesh, c.providers())
+			if valid := err == nil; valid != c.valid {
+				t.Errorf("Expected valid=%v, got valid=%v: %v", c.valid, valid, err)
+			}
+		})
+	}
+}
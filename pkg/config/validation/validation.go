@@ -40,15 +40,19 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	security_beta "istio.io/api/security/v1beta1"
+	telemetrypb "istio.io/api/telemetry/v1alpha1"
 	type_beta "istio.io/api/type/v1beta1"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/gateway"
+	"istio.io/istio/pkg/config/healthcheck"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/ratelimit"
 	"istio.io/istio/pkg/config/security"
+	"istio.io/istio/pkg/config/telemetry"
 	"istio.io/istio/pkg/config/visibility"
 	"istio.io/istio/pkg/config/xds"
 	"istio.io/istio/pkg/kube/apimirror"
@@ -623,10 +627,66 @@ var ValidateDestinationRule = registerValidateFunc("ValidateDestinationRule",
 			v = appendValidation(v, validateSubset(subset))
 		}
 
+		v = appendValidation(v, validateHealthCheck(cfg.Annotations, rule))
+
 		v = appendValidation(v, validateExportTo(cfg.Namespace, rule.ExportTo, false))
 		return v.Unwrap()
 	})
 
+// validateHealthCheck validates the health check set in the annotations of a destination rule.
+func validateHealthCheck(annotations map[string]string, rule *networking.DestinationRule) (errs error) {
+	hc, err := healthcheck.Parse(annotations)
+	if err != nil || hc == nil {
+		return err
+	}
+	checkers := 0
+	if hc.HTTP != nil {
+		checkers++
+		if !strings.HasPrefix(hc.HTTP.Path, "/") {
+			errs = appendErrors(errs, fmt.Errorf("health check: http path must start with '/': %q", hc.HTTP.Path))
+		}
+		for _, status := range hc.HTTP.ExpectedStatuses {
+			if status < 100 || status > 599 {
+				errs = appendErrors(errs, fmt.Errorf("health check: invalid expected status %d", status))
+			}
+		}
+	}
+	if hc.GRPC != nil {
+		checkers++
+	}
+	if hc.TCP != nil {
+		checkers++
+		if hc.TCP.Send == "" && len(hc.TCP.Receive) > 0 {
+			errs = appendErrors(errs, fmt.Errorf("health check: tcp receive requires send"))
+		}
+	}
+	if checkers != 1 {
+		errs = appendErrors(errs, fmt.Errorf("health check must set exactly one of http, grpc or tcp"))
+	}
+	if hc.Interval.Duration <= 0 {
+		errs = appendErrors(errs, fmt.Errorf("health check: interval must be greater than 0"))
+	}
+	if hc.Timeout.Duration <= 0 {
+		errs = appendErrors(errs, fmt.Errorf("health check: timeout must be greater than 0"))
+	}
+	if hc.NoTrafficInterval != nil && hc.NoTrafficInterval.Duration <= 0 {
+		errs = appendErrors(errs, fmt.Errorf("health check: noTrafficInterval must be greater than 0"))
+	}
+	for _, name := range hc.Subsets {
+		found := false
+		for _, subset := range rule.Subsets {
+			if subset.GetName() == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = appendErrors(errs, fmt.Errorf("health check: unknown subset %q", name))
+		}
+	}
+	return
+}
+
 func validateExportTo(namespace string, exportTo []string, isServiceEntry bool) (errs error) {
 	if len(exportTo) > 0 {
 		// Make sure there are no duplicates
@@ -1868,6 +1928,81 @@ var ValidatePeerAuthentication = registerValidateFunc("ValidatePeerAuthenticatio
 		return nil, errs
 	})
 
+// ValidateTelemetry validates a Telemetry.
+var ValidateTelemetry = registerValidateFunc("ValidateTelemetry",
+	func(cfg config.Config) (Warning, error) {
+		in, ok := cfg.Spec.(*telemetrypb.Telemetry)
+		if !ok {
+			return nil, errors.New("cannot cast to Telemetry")
+		}
+
+		var errs error
+		errs = appendErrors(errs, validateWorkloadSelector(in.Selector))
+		errs = appendErrors(errs, validateAccessLogging(cfg.Annotations))
+		errs = appendErrors(errs, validateMetrics(cfg.Annotations))
+		return nil, errs
+	})
+
+// validateAccessLogging validates the access logging set in the annotations of a Telemetry.
+func validateAccessLogging(annotations map[string]string) (errs error) {
+	al, err := telemetry.ParseAccessLogging(annotations)
+	if err != nil || al == nil {
+		return err
+	}
+	for _, p := range al.Providers {
+		if p.Name == "" {
+			errs = appendErrors(errs, fmt.Errorf("access logging: provider name must not be empty"))
+		}
+	}
+	if al.Filter != nil {
+		if _, err := telemetry.ParseFilterExpression(al.Filter.Expression); err != nil {
+			errs = appendErrors(errs, fmt.Errorf("access logging: %v", err))
+		}
+	}
+	return
+}
+
+// validateMetrics validates the metrics overrides set in the annotations of a Telemetry.
+func validateMetrics(annotations map[string]string) (errs error) {
+	m, err := telemetry.ParseMetrics(annotations)
+	if err != nil || m == nil {
+		return err
+	}
+	for i, o := range m.Overrides {
+		if o.Match != nil {
+			if o.Match.Metric != "" && o.Match.CustomMetric != "" {
+				errs = appendErrors(errs, fmt.Errorf("metrics override %d: only one of metric or customMetric may be set", i))
+			}
+			if _, f := telemetry.StandardMetrics[o.Match.Metric]; !f && o.Match.Metric != "" && o.Match.Metric != telemetry.AllMetrics {
+				errs = appendErrors(errs, fmt.Errorf("metrics override %d: unknown metric %q", i, o.Match.Metric))
+			}
+			switch o.Match.Mode {
+			case "", telemetry.ClientAndServerMode, telemetry.ClientMode, telemetry.ServerMode:
+			default:
+				errs = appendErrors(errs, fmt.Errorf("metrics override %d: unknown mode %q", i, o.Match.Mode))
+			}
+		}
+		for tag, to := range o.TagOverrides {
+			if tag == "" {
+				errs = appendErrors(errs, fmt.Errorf("metrics override %d: tag name must not be empty", i))
+			}
+			switch to.Operation {
+			case "", telemetry.UpsertTagOperation:
+				if strings.TrimSpace(to.Value) == "" {
+					errs = appendErrors(errs, fmt.Errorf("metrics override %d: tag %q must have a value", i, tag))
+				}
+			case telemetry.RemoveTagOperation:
+				if to.Value != "" {
+					errs = appendErrors(errs, fmt.Errorf("metrics override %d: removed tag %q must not have a value", i, tag))
+				}
+			default:
+				errs = appendErrors(errs, fmt.Errorf("metrics override %d: unknown operation %q for tag %q", i, to.Operation, tag))
+			}
+		}
+	}
+	return
+}
+
 // ValidateVirtualService checks that a v1alpha3 route rule is well-formed.
 var ValidateVirtualService = registerValidateFunc("ValidateVirtualService",
 	func(cfg config.Config) (Warning, error) {
@@ -1959,6 +2094,7 @@ var ValidateVirtualService = registerValidateFunc("ValidateVirtualService",
 		}
 
 		errs = appendValidation(errs, validateExportTo(cfg.Namespace, virtualService.ExportTo, false))
+		errs = appendValidation(errs, validateRateLimitPolicies(cfg.Annotations))
 
 		warnUnused := func(ruleno, reason string) {
 			errs = appendValidation(errs, WrapWarning(&AnalysisAwareError{
@@ -1982,6 +2118,61 @@ var ValidateVirtualService = registerValidateFunc("ValidateVirtualService",
 		return errs.Unwrap()
 	})
 
+// minRateLimitFillInterval is the smallest token bucket fill interval accepted by Envoy.
+const minRateLimitFillInterval = 50 * time.Millisecond
+
+// validateRateLimitPolicies validates the rate limit policies set in the annotations of a virtual service.
+func validateRateLimitPolicies(annotations map[string]string) (errs error) {
+	policies, err := ratelimit.Parse(annotations)
+	if err != nil {
+		return err
+	}
+	for i, policy := range policies {
+		if policy == nil {
+			errs = appendErrors(errs, fmt.Errorf("rate limit policy %d may not be null", i))
+			continue
+		}
+		if policy.Local == nil && policy.Global == nil {
+			errs = appendErrors(errs, fmt.Errorf("rate limit policy %d must set local or global", i))
+		}
+		for k, v := range policy.WorkloadSelector {
+			if k == "" {
+				errs = appendErrors(errs, fmt.Errorf("rate limit policy %d: empty key is not supported in selector: %q", i, k+"="+v))
+			}
+			if strings.Contains(k, "*") || strings.Contains(v, "*") {
+				errs = appendErrors(errs, fmt.Errorf("rate limit policy %d: wildcard is not supported in selector: %q", i, k+"="+v))
+			}
+		}
+		for _, r := range policy.Routes {
+			if r == "" {
+				errs = appendErrors(errs, fmt.Errorf("rate limit policy %d: route name cannot be empty", i))
+			}
+		}
+		if local := policy.Local; local != nil {
+			if local.MaxTokens == 0 {
+				errs = appendErrors(errs, fmt.Errorf("rate limit policy %d: maxTokens must be greater than 0", i))
+			}
+			if local.FillInterval.Duration < minRateLimitFillInterval {
+				errs = appendErrors(errs, fmt.Errorf("rate limit policy %d: fillInterval must be at least %v", i, minRateLimitFillInterval))
+			}
+		}
+		if global := policy.Global; global != nil {
+			if global.Provider == "" {
+				errs = appendErrors(errs, fmt.Errorf("rate limit policy %d: provider is required", i))
+			}
+			for _, d := range global.Descriptors {
+				if d.Key == "" {
+					errs = appendErrors(errs, fmt.Errorf("rate limit policy %d: descriptor key is required", i))
+				}
+				if (d.Header == "") == (d.Value == "") {
+					errs = appendErrors(errs, fmt.Errorf("rate limit policy %d: descriptor %q must set exactly one of header or value", i, d.Key))
+				}
+			}
+		}
+	}
+	return
+}
+
 func assignExactOrPrefix(exact, prefix string) string {
 	if exact != "" {
 		return matchExact + exact
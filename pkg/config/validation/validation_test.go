@@ -27,10 +27,14 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	security_beta "istio.io/api/security/v1beta1"
+	telemetrypb "istio.io/api/telemetry/v1alpha1"
 	api "istio.io/api/type/v1beta1"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/healthcheck"
+	"istio.io/istio/pkg/config/ratelimit"
+	"istio.io/istio/pkg/config/telemetry"
 )
 
 const (
@@ -2665,6 +2669,230 @@ func TestValidateVirtualService(t *testing.T) {
 	}
 }
 
+func TestValidateVirtualServiceRateLimit(t *testing.T) {
+	vs := &networking.VirtualService{
+		Hosts: []string{"foo.bar"},
+		Http: []*networking.HTTPRoute{{
+			Name: "default",
+			Route: []*networking.HTTPRouteDestination{{
+				Destination: &networking.Destination{Host: "foo.baz"},
+			}},
+		}},
+	}
+	testCases := []struct {
+		name   string
+		policy string
+		valid  bool
+	}{
+		{name: "local", policy: `
+- routes: [default]
+  workloadSelector:
+    app: foo
+  local:
+    maxTokens: 10
+    fillInterval: 1s`, valid: true},
+		{name: "global", policy: `
+- global:
+    provider: ratelimit
+    descriptors:
+    - key: path
+      header: ":path"
+    - key: tier
+      value: gold`, valid: true},
+		{name: "invalid yaml", policy: `- local: [`, valid: false},
+		{name: "unknown field", policy: `- locale: {}`, valid: false},
+		{name: "no limit", policy: `- routes: [default]`, valid: false},
+		{name: "zero tokens", policy: `
+- local:
+    fillInterval: 1s`, valid: false},
+		{name: "short fill interval", policy: `
+- local:
+    maxTokens: 10
+    fillInterval: 10ms`, valid: false},
+		{name: "wildcard selector", policy: `
+- workloadSelector:
+    app: "*"
+  local:
+    maxTokens: 10
+    fillInterval: 1s`, valid: false},
+		{name: "global without provider", policy: `
+- global:
+    descriptors:
+    - key: path
+      header: ":path"`, valid: false},
+		{name: "global with service", policy: `
+- global:
+    service: ratelimit.istio-system.svc.cluster.local
+    port: 8081
+    domain: foo`, valid: false},
+		{name: "descriptor with header and value", policy: `
+- global:
+    provider: ratelimit
+    descriptors:
+    - key: path
+      header: ":path"
+      value: "/"`, valid: false},
+		{name: "several providers", policy: `
+- global:
+    provider: ratelimit
+- global:
+    provider: other`, valid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			warn, err := ValidateVirtualService(config.Config{
+				Meta: config.Meta{Annotations: map[string]string{ratelimit.Annotation: tc.policy}},
+				Spec: vs,
+			})
+			checkValidation(t, warn, err, tc.valid, false)
+		})
+	}
+}
+
+func TestValidateTelemetry(t *testing.T) {
+	testCases := []struct {
+		name          string
+		accessLogging string
+		metrics       string
+		valid         bool
+	}{
+		{name: "providers and filter", accessLogging: `
+providers:
+- name: envoy
+- name: envoy-als
+filter:
+  expression: response.code >= 500`, valid: true},
+		{name: "disabled", accessLogging: `disabled: true`, valid: true},
+		{name: "invalid yaml", accessLogging: `providers: [`, valid: false},
+		{name: "unknown field", accessLogging: `provider: envoy`, valid: false},
+		{name: "empty provider name", accessLogging: `providers: [{name: ""}]`, valid: false},
+		{name: "empty filter", accessLogging: `filter: {expression: " "}`, valid: false},
+		{name: "code range filter", accessLogging: `filter: {expression: "response.code > 399 && response.code < 500"}`, valid: true},
+		{name: "any code filter", accessLogging: `filter: {expression: "response.code == 404 || response.code >= 500"}`, valid: true},
+		{name: "mixed filter", accessLogging: `filter: {expression: "response.code == 404 || response.code >= 500 && response.code < 600"}`,
+			valid: false},
+		{name: "unsupported attribute filter", accessLogging: `filter: {expression: "request.method == 'GET'"}`, valid: false},
+		{name: "unsupported comparison filter", accessLogging: `filter: {expression: "response.code != 200"}`, valid: false},
+		{name: "invalid code filter", accessLogging: `filter: {expression: "response.code >= five"}`, valid: false},
+		{name: "metrics overrides", metrics: `
+overrides:
+- match:
+    metric: REQUEST_COUNT
+    mode: CLIENT
+  tagOverrides:
+    request_host:
+      value: request.host
+    response_flags:
+      operation: REMOVE
+- match:
+    customMetric: custom_total
+  disabled: true
+- disabled: true`, valid: true},
+		{name: "metrics unknown field", metrics: `override: []`, valid: false},
+		{name: "metric and custom metric", metrics: `overrides: [{match: {metric: REQUEST_COUNT, customMetric: custom_total}}]`, valid: false},
+		{name: "unknown metric", metrics: `overrides: [{match: {metric: REQUEST_TOTAL}}]`, valid: false},
+		{name: "unknown mode", metrics: `overrides: [{match: {mode: BOTH}}]`, valid: false},
+		{name: "upsert without value", metrics: `overrides: [{tagOverrides: {request_host: {}}}]`, valid: false},
+		{name: "remove with value", metrics: `overrides: [{tagOverrides: {request_host: {operation: REMOVE, value: request.host}}}]`, valid: false},
+		{name: "unknown operation", metrics: `overrides: [{tagOverrides: {request_host: {operation: DELETE}}}]`, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			annotations := map[string]string{}
+			if tc.accessLogging != "" {
+				annotations[telemetry.AccessLoggingAnnotation] = tc.accessLogging
+			}
+			if tc.metrics != "" {
+				annotations[telemetry.MetricsAnnotation] = tc.metrics
+			}
+			warn, err := ValidateTelemetry(config.Config{
+				Meta: config.Meta{Annotations: annotations},
+				Spec: &telemetrypb.Telemetry{},
+			})
+			checkValidation(t, warn, err, tc.valid, false)
+		})
+	}
+}
+
+func TestValidateDestinationRuleHealthCheck(t *testing.T) {
+	dr := &networking.DestinationRule{
+		Host: "reviews",
+		Subsets: []*networking.Subset{
+			{Name: "v1", Labels: map[string]string{"version": "v1"}},
+		},
+	}
+	testCases := []struct {
+		name        string
+		healthCheck string
+		valid       bool
+	}{
+		{name: "http", healthCheck: `
+interval: 5s
+timeout: 1s
+unhealthyThreshold: 2
+http:
+  path: /healthz
+  expectedStatuses: [200, 204]`, valid: true},
+		{name: "grpc on subset", healthCheck: `
+subsets: [v1]
+interval: 5s
+timeout: 1s
+grpc:
+  serviceName: reviews`, valid: true},
+		{name: "tcp", healthCheck: `{"interval": "5s", "timeout": "1s", "tcp": {"send": "ping", "receive": ["pong"]}}`, valid: true},
+		{name: "invalid yaml", healthCheck: `interval: [`, valid: false},
+		{name: "unknown field", healthCheck: `intervals: 5s`, valid: false},
+		{name: "no checker", healthCheck: `
+interval: 5s
+timeout: 1s`, valid: false},
+		{name: "two checkers", healthCheck: `
+interval: 5s
+timeout: 1s
+http:
+  path: /healthz
+tcp: {}`, valid: false},
+		{name: "no interval", healthCheck: `
+timeout: 1s
+tcp: {}`, valid: false},
+		{name: "no timeout", healthCheck: `
+interval: 5s
+tcp: {}`, valid: false},
+		{name: "relative path", healthCheck: `
+interval: 5s
+timeout: 1s
+http:
+  path: healthz`, valid: false},
+		{name: "invalid status", healthCheck: `
+interval: 5s
+timeout: 1s
+http:
+  path: /healthz
+  expectedStatuses: [700]`, valid: false},
+		{name: "receive without send", healthCheck: `
+interval: 5s
+timeout: 1s
+tcp:
+  receive: [pong]`, valid: false},
+		{name: "unknown subset", healthCheck: `
+subsets: [v2]
+interval: 5s
+timeout: 1s
+tcp: {}`, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			warn, err := ValidateDestinationRule(config.Config{
+				Meta: config.Meta{Annotations: map[string]string{healthcheck.Annotation: tc.healthCheck}},
+				Spec: dr,
+			})
+			checkValidation(t, warn, err, tc.valid, false)
+		})
+	}
+}
+
 func TestValidateWorkloadEntry(t *testing.T) {
 	testCases := []struct {
 		name    string
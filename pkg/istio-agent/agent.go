@@ -158,6 +158,13 @@ type AgentOptions struct {
 	// Path to local UDS to communicate with Envoy
 	XdsUdsPath string
 
+	// Directory to persist the last XDS configuration ACKed by Envoy. If set, the XDS proxy serves
+	// this snapshot to a starting Envoy when the upstream is unavailable for XDSSnapshotGracePeriod.
+	XDSSnapshotDir string
+
+	// Time to wait for the upstream XDS server before serving the persisted snapshot.
+	XDSSnapshotGracePeriod time.Duration
+
 	// Ability to retrieve ProxyConfig dynamically through XDS
 	EnableDynamicProxyConfig bool
 
@@ -371,6 +378,10 @@ func (a *Agent) Run(ctx context.Context) (func(), error) {
 	a.sdsServer = sds.NewServer(a.secOpts, a.secretCache)
 	a.secretCache.SetUpdateCallback(a.sdsServer.UpdateCallback)
 
+	if a.secOpts.FederatedBundleEndpoints != "" {
+		go a.refreshFederatedBundles(ctx)
+	}
+
 	if err = a.initLocalDNSServer(); err != nil {
 		return nil, fmt.Errorf("failed to start local DNS server: %v", err)
 	}
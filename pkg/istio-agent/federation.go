@@ -0,0 +1,50 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"context"
+	"strings"
+
+	"istio.io/istio/pilot/pkg/trustbundle"
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/pkg/log"
+)
+
+// refreshFederatedBundles fetches the bundles of the federated trust domains from their SPIFFE bundle endpoints and
+// serves them to Envoy, until ctx is done. The last bundle fetched of a trust domain is served while its endpoint fails.
+func (a *Agent) refreshFederatedBundles(ctx context.Context) {
+	endpoints, err := spiffe.ParseSpiffeBundleEndpoints(a.secOpts.FederatedBundleEndpoints)
+	if err != nil {
+		log.Errorf("invalid bundle endpoints of the federated trust domains: %v", err)
+		return
+	}
+	tb := trustbundle.NewTrustBundle(nil)
+	tb.UpdateCb(func() {
+		a.updateFederatedBundles(tb.GetTrustDomainBundles())
+	})
+	tb.UpdateTrustDomainEndpoints(endpoints)
+	tb.ProcessRemoteTrustAnchors(ctx.Done(), trustbundle.RemoteDefaultPollPeriod)
+}
+
+func (a *Agent) updateFederatedBundles(trustDomainCerts map[string][]string) {
+	bundles := make(map[string][]byte, len(trustDomainCerts))
+	for trustDomain, certs := range trustDomainCerts {
+		bundles[trustDomain] = []byte(strings.Join(certs, ""))
+	}
+	if err := a.secretCache.UpdateTrustDomainBundles(bundles); err != nil {
+		log.Errorf("failed to update the bundles of the federated trust domains: %v", err)
+	}
+}
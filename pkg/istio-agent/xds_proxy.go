@@ -118,6 +118,11 @@ type XdsProxy struct {
 	// in case istiod changes its behavior, or a different ECDS server is used.
 	ecdsLastAckVersion atomic.String
 	ecdsLastNonce      atomic.String
+
+	// snapshot persists the configuration ACKed by Envoy, to be served when Istiod is unreachable.
+	// It is nil if the snapshot is disabled.
+	snapshot            *xdsSnapshot
+	snapshotGracePeriod time.Duration
 }
 
 var proxyLog = log.RegisterScope("xdsproxy", "XDS Proxy in Istio Agent", 0)
@@ -178,6 +183,13 @@ func initXdsProxy(ia *Agent) (*XdsProxy, error) {
 		}
 	}
 
+	if ia.cfg.XDSSnapshotDir != "" {
+		if proxy.snapshot, err = newXdsSnapshot(ia.cfg.XDSSnapshotDir); err != nil {
+			return nil, err
+		}
+		proxy.snapshotGracePeriod = ia.cfg.XDSSnapshotGracePeriod
+	}
+
 	proxyLog.Infof("Initializing with upstream address %q and cluster %q", proxy.istiodAddress, proxy.clusterID)
 
 	if err = proxy.initDownstreamServer(); err != nil {
@@ -277,6 +289,8 @@ type ProxyConnection struct {
 	upstream           discovery.AggregatedDiscoveryService_StreamAggregatedResourcesClient
 	downstreamDeltas   discovery.AggregatedDiscoveryService_DeltaAggregatedResourcesServer
 	upstreamDeltas     discovery.AggregatedDiscoveryService_DeltaAggregatedResourcesClient
+	// pendingRequests are sent upstream before any request from requestsChan.
+	pendingRequests []*discovery.DiscoveryRequest
 }
 
 type adsStream interface {
@@ -306,6 +320,7 @@ func (p *XdsProxy) handleStream(downstream adsStream) error {
 
 	p.RegisterStream(con)
 	defer p.UnregisterStream(con)
+	p.snapshot.reset()
 
 	// Handle downstream xds
 	initialRequestsSent := false
@@ -317,6 +332,7 @@ func (p *XdsProxy) handleStream(downstream adsStream) error {
 				con.downstreamError <- err
 				return
 			}
+			p.snapshot.onRequest(req)
 			// forward to istiod
 			con.requestsChan <- req
 			if !initialRequestsSent && req.TypeUrl == v3.ListenerType {
@@ -344,9 +360,13 @@ func (p *XdsProxy) handleStream(downstream adsStream) error {
 		}
 	}()
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-	defer cancel()
-	upstreamConn, err := grpc.DialContext(ctx, p.istiodAddress, p.istiodDialOptions...)
+	var upstreamConn *grpc.ClientConn
+	var err error
+	if p.snapshot.empty() {
+		upstreamConn, err = p.dialUpstream()
+	} else {
+		upstreamConn, err = p.dialUpstreamWithSnapshot(con)
+	}
 	if err != nil {
 		proxyLog.Errorf("failed to connect to upstream %s: %v", p.istiodAddress, err)
 		metrics.IstiodConnectionFailures.Increment()
@@ -355,7 +375,7 @@ func (p *XdsProxy) handleStream(downstream adsStream) error {
 	defer upstreamConn.Close()
 
 	xds := discovery.NewAggregatedDiscoveryServiceClient(upstreamConn)
-	ctx = metadata.AppendToOutgoingContext(context.Background(), "ClusterID", p.clusterID)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "ClusterID", p.clusterID)
 	for k, v := range p.xdsHeaders {
 		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
 	}
@@ -363,6 +383,12 @@ func (p *XdsProxy) handleStream(downstream adsStream) error {
 	return p.HandleUpstream(ctx, con, xds)
 }
 
+func (p *XdsProxy) dialUpstream() (*grpc.ClientConn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	return grpc.DialContext(ctx, p.istiodAddress, p.istiodDialOptions...)
+}
+
 func (p *XdsProxy) HandleUpstream(ctx context.Context, con *ProxyConnection, xds discovery.AggregatedDiscoveryServiceClient) error {
 	upstream, err := xds.StreamAggregatedResources(ctx,
 		grpc.MaxCallRecvMsgSize(defaultClientMaxReceiveMessageSize))
@@ -424,20 +450,31 @@ func (p *XdsProxy) HandleUpstream(ctx context.Context, con *ProxyConnection, xds
 
 func (p *XdsProxy) handleUpstreamRequest(con *ProxyConnection) {
 	defer con.upstream.CloseSend() // nolint
+	send := func(req *discovery.DiscoveryRequest) error {
+		proxyLog.Debugf("request for type url %s", req.TypeUrl)
+		metrics.XdsProxyRequests.Increment()
+		if req.TypeUrl == v3.ExtensionConfigurationType {
+			if req.VersionInfo != "" {
+				p.ecdsLastAckVersion.Store(req.VersionInfo)
+			}
+			p.ecdsLastNonce.Store(req.ResponseNonce)
+		}
+		if err := sendUpstream(con.upstream, req); err != nil {
+			proxyLog.Errorf("upstream [%d] send error for type url %s: %v", con.conID, req.TypeUrl, err)
+			con.upstreamError <- err
+			return err
+		}
+		return nil
+	}
+	for _, req := range con.pendingRequests {
+		if err := send(req); err != nil {
+			return
+		}
+	}
 	for {
 		select {
 		case req := <-con.requestsChan:
-			proxyLog.Debugf("request for type url %s", req.TypeUrl)
-			metrics.XdsProxyRequests.Increment()
-			if req.TypeUrl == v3.ExtensionConfigurationType {
-				if req.VersionInfo != "" {
-					p.ecdsLastAckVersion.Store(req.VersionInfo)
-				}
-				p.ecdsLastNonce.Store(req.ResponseNonce)
-			}
-			if err := sendUpstream(con.upstream, req); err != nil {
-				proxyLog.Errorf("upstream [%d] send error for type url %s: %v", con.conID, req.TypeUrl, err)
-				con.upstreamError <- err
+			if err := send(req); err != nil {
 				return
 			}
 		case <-con.stopChan:
@@ -483,13 +520,13 @@ func (p *XdsProxy) handleUpstreamResponse(con *ProxyConnection) {
 					go p.rewriteAndForward(con, resp)
 				} else {
 					// Otherwise, forward ECDS resource update directly to Envoy.
-					forwardToEnvoy(con, resp)
+					p.forwardToEnvoy(con, resp)
 				}
 			default:
 				if strings.HasPrefix(resp.TypeUrl, "istio.io/debug") {
 					p.forwardToTap(resp)
 				} else {
-					forwardToEnvoy(con, resp)
+					p.forwardToEnvoy(con, resp)
 				}
 			}
 		case <-con.stopChan:
@@ -514,7 +551,7 @@ func (p *XdsProxy) rewriteAndForward(con *ProxyConnection, resp *discovery.Disco
 		return
 	}
 	proxyLog.Debugf("forward ECDS resources %+v", resp.Resources)
-	forwardToEnvoy(con, resp)
+	p.forwardToEnvoy(con, resp)
 }
 
 func (p *XdsProxy) forwardToTap(resp *discovery.DiscoveryResponse) {
@@ -525,12 +562,15 @@ func (p *XdsProxy) forwardToTap(resp *discovery.DiscoveryResponse) {
 	}
 }
 
-func forwardToEnvoy(con *ProxyConnection, resp *discovery.DiscoveryResponse) {
+func (p *XdsProxy) forwardToEnvoy(con *ProxyConnection, resp *discovery.DiscoveryResponse) {
 	if !v3.IsEnvoyType(resp.TypeUrl) {
 		proxyLog.Errorf("Skipping forwarding type url %s to Envoy as is not a valid Envoy type", resp.TypeUrl)
 		return
 	}
+	// Record the response before sending it, as Envoy may ACK it before Send returns.
+	p.snapshot.onResponse(resp)
 	if err := sendDownstream(con.downstream, resp); err != nil {
+		p.snapshot.discard(resp)
 		select {
 		case con.downstreamError <- err:
 			// we cannot return partial error and hope to restart just the downstream
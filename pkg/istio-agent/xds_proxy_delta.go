@@ -197,12 +197,13 @@ func (p *XdsProxy) handleUpstreamDeltaResponse(con *ProxyConnection) {
 			proxyLog.Debugf("response for type url %s", resp.TypeUrl)
 			metrics.XdsProxyResponses.Increment()
 			if h, f := p.handlers[resp.TypeUrl]; f {
-				if len(resp.Resources) == 0 {
-					// Empty response, nothing to do
-					// This assumes internal types are always singleton
-					return
+				var err error
+				// Delta responses only hold the changed resources, so an empty response means there is
+				// nothing to do, but it must still be ACKed.
+				// This assumes internal types are always singleton
+				if len(resp.Resources) > 0 {
+					err = h(resp.Resources[0].Resource)
 				}
-				err := h(resp.Resources[0].Resource)
 				var errorResp *google_rpc.Status
 				if err != nil {
 					errorResp = &google_rpc.Status{
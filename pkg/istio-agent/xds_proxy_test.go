@@ -20,17 +20,23 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"os"
 	"path"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
 	wasmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/wasm/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/any"
 	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/test/bufconn"
 	"google.golang.org/protobuf/proto"
@@ -391,14 +397,14 @@ func TestXdsProxyReconnects(t *testing.T) {
 
 type fakeAckCache struct{}
 
-func (f *fakeAckCache) Get(string, string, time.Duration) (string, error) {
+func (f *fakeAckCache) Get(string, string, time.Duration, []byte) (string, error) {
 	return "test", nil
 }
 func (f *fakeAckCache) Cleanup() {}
 
 type fakeNackCache struct{}
 
-func (f *fakeNackCache) Get(string, string, time.Duration) (string, error) {
+func (f *fakeNackCache) Get(string, string, time.Duration, []byte) (string, error) {
 	return "", errors.New("errror")
 }
 func (f *fakeNackCache) Cleanup() {}
@@ -579,3 +585,196 @@ func setupDownstreamConnectionUDS(t test.Failer, path string) *grpc.ClientConn {
 func setupDownstreamConnection(t *testing.T, proxy *XdsProxy) *grpc.ClientConn {
 	return setupDownstreamConnectionUDS(t, proxy.xdsUdsPath)
 }
+
+func TestXdsSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	snapshot, err := newXdsSnapshot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cla := func(name string) *any.Any {
+		return util.MessageToAny(&endpoint.ClusterLoadAssignment{ClusterName: name})
+	}
+	names := func(resp *discovery.DiscoveryResponse) []string {
+		res := []string{}
+		for _, r := range resp.Resources {
+			res = append(res, resourceName(resp.TypeUrl, r))
+		}
+		return res
+	}
+
+	snapshot.onResponse(&discovery.DiscoveryResponse{TypeUrl: v3.EndpointType, VersionInfo: "1", Nonce: "a", Resources: []*any.Any{cla("a"), cla("b")}})
+	snapshot.onRequest(&discovery.DiscoveryRequest{TypeUrl: v3.EndpointType, ResponseNonce: "a", ResourceNames: []string{"a", "b"}})
+	// Incremental push of a single cluster is merged with the previous state
+	snapshot.onResponse(&discovery.DiscoveryResponse{TypeUrl: v3.EndpointType, VersionInfo: "2", Nonce: "b", Resources: []*any.Any{cla("c")}})
+	snapshot.onRequest(&discovery.DiscoveryRequest{TypeUrl: v3.EndpointType, ResponseNonce: "b", ResourceNames: []string{"b", "c"}})
+	// NACKed responses are not stored
+	snapshot.onResponse(&discovery.DiscoveryResponse{TypeUrl: v3.EndpointType, VersionInfo: "3", Nonce: "c", Resources: []*any.Any{cla("d")}})
+	snapshot.onRequest(&discovery.DiscoveryRequest{TypeUrl: v3.EndpointType, ResponseNonce: "c", ErrorDetail: &google_rpc.Status{Message: "nack"}})
+	// Internal types are not stored
+	snapshot.onResponse(&discovery.DiscoveryResponse{TypeUrl: v3.NameTableType, VersionInfo: "1", Nonce: "d"})
+	snapshot.onRequest(&discovery.DiscoveryRequest{TypeUrl: v3.NameTableType, ResponseNonce: "d"})
+
+	resp := snapshot.response(&discovery.DiscoveryRequest{TypeUrl: v3.EndpointType})
+	if resp.VersionInfo != "2" || !reflect.DeepEqual(names(resp), []string{"b", "c"}) {
+		t.Fatalf("unexpected snapshot version %v resources %v", resp.VersionInfo, names(resp))
+	}
+	resp = snapshot.response(&discovery.DiscoveryRequest{TypeUrl: v3.EndpointType, ResourceNames: []string{"c"}})
+	if !reflect.DeepEqual(names(resp), []string{"c"}) {
+		t.Fatalf("unexpected snapshot resources %v", names(resp))
+	}
+	if snapshot.response(&discovery.DiscoveryRequest{TypeUrl: v3.NameTableType}) != nil {
+		t.Fatalf("unexpected response for internal type")
+	}
+
+	// The snapshot is persisted asynchronously, and reloaded from disk.
+	retry.UntilSuccessOrFail(t, func() error {
+		loaded, err := newXdsSnapshot(dir)
+		if err != nil {
+			return err
+		}
+		resp := loaded.response(&discovery.DiscoveryRequest{TypeUrl: v3.EndpointType})
+		if resp == nil || resp.VersionInfo != "2" {
+			return fmt.Errorf("snapshot not persisted: %v", resp)
+		}
+		if got := loaded.types(); !reflect.DeepEqual(got, []string{"EDS"}) {
+			return fmt.Errorf("unexpected types %v", got)
+		}
+		return nil
+	}, retry.Timeout(time.Second*5))
+}
+
+// ackingStream is a downstream which ACKs each response from within Send, as Envoy may do before Send returns.
+type ackingStream struct {
+	adsStream
+	onSend func(*discovery.DiscoveryResponse) error
+}
+
+func (s *ackingStream) Send(resp *discovery.DiscoveryResponse) error {
+	return s.onSend(resp)
+}
+
+func (s *ackingStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestXdsSnapshotAckDuringSend(t *testing.T) {
+	snapshot, err := newXdsSnapshot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := &XdsProxy{snapshot: snapshot}
+	con := &ProxyConnection{
+		downstreamError: make(chan error, 1),
+		downstream: &ackingStream{onSend: func(resp *discovery.DiscoveryResponse) error {
+			snapshot.onRequest(&discovery.DiscoveryRequest{TypeUrl: resp.TypeUrl, VersionInfo: resp.VersionInfo, ResponseNonce: resp.Nonce})
+			return nil
+		}},
+	}
+
+	proxy.forwardToEnvoy(con, &discovery.DiscoveryResponse{TypeUrl: v3.ClusterType, VersionInfo: "1", Nonce: "a"})
+	if resp := snapshot.response(&discovery.DiscoveryRequest{TypeUrl: v3.ClusterType}); resp == nil || resp.VersionInfo != "1" {
+		t.Fatalf("expected the ACK received during Send to commit version 1, got %v", resp)
+	}
+
+	// A response which could not be sent is no longer pending.
+	con.downstream = &ackingStream{onSend: func(*discovery.DiscoveryResponse) error { return errors.New("send failed") }}
+	proxy.forwardToEnvoy(con, &discovery.DiscoveryResponse{TypeUrl: v3.ClusterType, VersionInfo: "2", Nonce: "b"})
+	snapshot.mu.Lock()
+	pending := len(snapshot.pending)
+	snapshot.mu.Unlock()
+	if pending != 0 {
+		t.Fatalf("expected no pending responses after a failed send, got %d", pending)
+	}
+}
+
+// Validates the snapshot is served to a new Envoy when Istiod is down, and that the proxy switches
+// over to Istiod once it comes back.
+func TestXdsProxySnapshot(t *testing.T) {
+	dir := t.TempDir()
+	node := &core.Node{
+		Id:       "sidecar~1.1.1.1~debug~cluster.local",
+		Metadata: model.NodeMetadata{Namespace: "default", InstanceIPs: []string{"1.1.1.1"}}.ToStruct(),
+	}
+	f := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+
+	// Populate the snapshot through a healthy Istiod
+	proxy := setupXdsProxy(t)
+	setDialOptions(proxy, f.Listener)
+	snapshot, err := newXdsSnapshot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy.snapshot = snapshot
+	downstream := stream(t, setupDownstreamConnection(t, proxy))
+	if err := downstream.Send(&discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, Node: node}); err != nil {
+		t.Fatal(err)
+	}
+	cds, err := downstream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := downstream.Send(&discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, VersionInfo: cds.VersionInfo, ResponseNonce: cds.Nonce}); err != nil {
+		t.Fatal(err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		if _, err := os.Stat(filepath.Join(dir, "cds.pb")); err != nil {
+			return err
+		}
+		return nil
+	}, retry.Timeout(time.Second*5))
+
+	// Restart with Istiod down
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	proxy = setupXdsProxy(t)
+	if proxy.snapshot, err = newXdsSnapshot(dir); err != nil {
+		t.Fatal(err)
+	}
+	proxy.snapshotGracePeriod = time.Millisecond * 100
+	proxy.istiodAddress = address
+	proxy.istiodDialOptions = []grpc.DialOption{
+		grpc.WithBlock(),
+		grpc.WithInsecure(),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.Config{BaseDelay: time.Millisecond * 50, Multiplier: 1, MaxDelay: time.Millisecond * 50},
+			MinConnectTimeout: time.Millisecond * 100,
+		}),
+	}
+	downstream = stream(t, setupDownstreamConnection(t, proxy))
+	if err := downstream.Send(&discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, Node: node}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := downstream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(resp.Nonce, "snapshot-") || resp.VersionInfo != cds.VersionInfo || len(resp.Resources) != len(cds.Resources) {
+		t.Fatalf("expected snapshot response, got nonce %v version %v with %d resources", resp.Nonce, resp.VersionInfo, len(resp.Resources))
+	}
+	if err := downstream.Send(&discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, VersionInfo: resp.VersionInfo, ResponseNonce: resp.Nonce}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Istiod comes back, the proxy switches over and Istiod responds to the replayed request
+	listener, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	t.Cleanup(grpcServer.Stop)
+	f.Discovery.Register(grpcServer)
+	go grpcServer.Serve(listener)
+
+	resp, err = downstream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.TypeUrl != v3.ClusterType || strings.HasPrefix(resp.Nonce, "snapshot-") {
+		t.Fatalf("expected response from Istiod, got %v with nonce %v", resp.TypeUrl, resp.Nonce)
+	}
+}
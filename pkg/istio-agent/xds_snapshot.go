@@ -0,0 +1,473 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/any"
+	"go.uber.org/atomic"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// snapshotTypes are the types persisted in the XDS snapshot, in the order Envoy expects them.
+var snapshotTypes = []string{
+	v3.ClusterType,
+	v3.EndpointType,
+	v3.ListenerType,
+	v3.RouteType,
+	v3.ExtensionConfigurationType,
+}
+
+func isSnapshotType(typeURL string) bool {
+	for _, t := range snapshotTypes {
+		if t == typeURL {
+			return true
+		}
+	}
+	return false
+}
+
+// isWildcardType returns true if a response of the type always contains the full state of the type.
+// Responses of other types may contain a subset of the watched resources, which must be merged.
+func isWildcardType(typeURL string) bool {
+	return typeURL == v3.ClusterType || typeURL == v3.ListenerType
+}
+
+// xdsSnapshot keeps the last responses ACKed by Envoy for the core XDS types, and persists them to
+// disk. When Envoy starts while Istiod is unreachable, the snapshot is served in place of Istiod so
+// that the workload can come up with its last known configuration.
+type xdsSnapshot struct {
+	dir string
+
+	mu sync.Mutex
+	// acked holds the last ACKed state per type url.
+	acked map[string]*discovery.DiscoveryResponse
+	// pending holds responses forwarded to Envoy which have not been ACKed yet, keyed by nonce.
+	pending map[string]*discovery.DiscoveryResponse
+
+	// writeMu serializes writes to disk, so that the last write always contains the latest state.
+	writeMu sync.Mutex
+
+	nonce atomic.Uint64
+}
+
+// newXdsSnapshot creates a snapshot persisted in dir, loading any state persisted by a previous run.
+func newXdsSnapshot(dir string) (*xdsSnapshot, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create XDS snapshot directory: %v", err)
+	}
+	s := &xdsSnapshot{
+		dir:     dir,
+		acked:   map[string]*discovery.DiscoveryResponse{},
+		pending: map[string]*discovery.DiscoveryResponse{},
+	}
+	for _, t := range snapshotTypes {
+		b, err := ioutil.ReadFile(s.file(t))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read XDS snapshot for %s: %v", v3.GetShortType(t), err)
+		}
+		resp := &discovery.DiscoveryResponse{}
+		if err := proto.Unmarshal(b, resp); err != nil {
+			proxyLog.Warnf("ignoring corrupted XDS snapshot for %s: %v", v3.GetShortType(t), err)
+			continue
+		}
+		s.acked[t] = resp
+	}
+	proxyLog.Infof("loaded XDS snapshot from %s with types %v", dir, s.types())
+	return s, nil
+}
+
+func (s *xdsSnapshot) file(typeURL string) string {
+	return filepath.Join(s.dir, strings.ToLower(v3.GetShortType(typeURL))+".pb")
+}
+
+// types returns the short names of the types present in the snapshot.
+func (s *xdsSnapshot) types() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := []string{}
+	for _, t := range snapshotTypes {
+		if _, f := s.acked[t]; f {
+			res = append(res, v3.GetShortType(t))
+		}
+	}
+	return res
+}
+
+// empty returns true if there is nothing to serve from the snapshot.
+func (s *xdsSnapshot) empty() bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.acked) == 0
+}
+
+// reset drops the responses awaiting an ACK. It is called whenever Envoy opens a new stream, as
+// nonces from a previous stream will never be ACKed.
+func (s *xdsSnapshot) reset() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = map[string]*discovery.DiscoveryResponse{}
+}
+
+// onResponse records a response forwarded to Envoy, to be committed once Envoy ACKs it.
+func (s *xdsSnapshot) onResponse(resp *discovery.DiscoveryResponse) {
+	if s == nil || !isSnapshotType(resp.TypeUrl) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[resp.Nonce] = resp
+}
+
+// discard drops a response recorded by onResponse which could not be sent to Envoy.
+func (s *xdsSnapshot) discard(resp *discovery.DiscoveryResponse) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, resp.Nonce)
+}
+
+// onRequest commits the response matching the request nonce if the request is an ACK.
+func (s *xdsSnapshot) onRequest(req *discovery.DiscoveryRequest) {
+	if s == nil || req.ResponseNonce == "" || !isSnapshotType(req.TypeUrl) {
+		return
+	}
+	s.mu.Lock()
+	resp, f := s.pending[req.ResponseNonce]
+	if !f {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.pending, req.ResponseNonce)
+	if req.ErrorDetail != nil {
+		// NACK, keep the previous state
+		s.mu.Unlock()
+		return
+	}
+	s.acked[req.TypeUrl] = s.merge(s.acked[req.TypeUrl], resp, req.ResourceNames)
+	s.mu.Unlock()
+
+	go s.persist(req.TypeUrl)
+}
+
+// merge computes the state of a type after ACKing resp. Must be called with mu held.
+func (s *xdsSnapshot) merge(prev, resp *discovery.DiscoveryResponse, watched []string) *discovery.DiscoveryResponse {
+	if prev == nil || isWildcardType(resp.TypeUrl) {
+		return resp
+	}
+	merged := map[string]*any.Any{}
+	for _, r := range prev.Resources {
+		merged[resourceName(resp.TypeUrl, r)] = r
+	}
+	for _, r := range resp.Resources {
+		merged[resourceName(resp.TypeUrl, r)] = r
+	}
+	res := &discovery.DiscoveryResponse{
+		TypeUrl:     resp.TypeUrl,
+		VersionInfo: resp.VersionInfo,
+		Nonce:       resp.Nonce,
+	}
+	names := make([]string, 0, len(merged))
+	for n := range merged {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range filterNames(names, watched) {
+		res.Resources = append(res.Resources, merged[n])
+	}
+	return res
+}
+
+// persist writes the current state of a type to disk. The file is replaced atomically, so a crash
+// while writing never leaves a partial snapshot behind.
+func (s *xdsSnapshot) persist(typeURL string) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.mu.Lock()
+	resp := s.acked[typeURL]
+	s.mu.Unlock()
+	if resp == nil {
+		return
+	}
+	b, err := proto.Marshal(resp)
+	if err != nil {
+		proxyLog.Warnf("failed to marshal XDS snapshot for %s: %v", v3.GetShortType(typeURL), err)
+		return
+	}
+	tmp, err := ioutil.TempFile(s.dir, filepath.Base(s.file(typeURL))+".tmp")
+	if err != nil {
+		proxyLog.Warnf("failed to write XDS snapshot for %s: %v", v3.GetShortType(typeURL), err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		proxyLog.Warnf("failed to write XDS snapshot for %s: %v", v3.GetShortType(typeURL), err)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		proxyLog.Warnf("failed to write XDS snapshot for %s: %v", v3.GetShortType(typeURL), err)
+		return
+	}
+	if err := os.Rename(tmp.Name(), s.file(typeURL)); err != nil {
+		proxyLog.Warnf("failed to write XDS snapshot for %s: %v", v3.GetShortType(typeURL), err)
+		return
+	}
+	proxyLog.Debugf("persisted XDS snapshot for %s version %s", v3.GetShortType(typeURL), resp.VersionInfo)
+}
+
+// response builds a response for req from the snapshot, or nil if the type is not in the snapshot.
+func (s *xdsSnapshot) response(req *discovery.DiscoveryRequest) *discovery.DiscoveryResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acked, f := s.acked[req.TypeUrl]
+	if !f {
+		return nil
+	}
+	resp := &discovery.DiscoveryResponse{
+		TypeUrl:     acked.TypeUrl,
+		VersionInfo: acked.VersionInfo,
+		Nonce:       fmt.Sprintf("snapshot-%d", s.nonce.Inc()),
+	}
+	if isWildcardType(req.TypeUrl) || len(req.ResourceNames) == 0 {
+		resp.Resources = acked.Resources
+		return resp
+	}
+	watched := map[string]struct{}{}
+	for _, n := range req.ResourceNames {
+		watched[n] = struct{}{}
+	}
+	for _, r := range acked.Resources {
+		if _, f := watched[resourceName(req.TypeUrl, r)]; f {
+			resp.Resources = append(resp.Resources, r)
+		}
+	}
+	return resp
+}
+
+// filterNames returns the names which are watched. An empty watch list watches everything.
+func filterNames(names []string, watched []string) []string {
+	if len(watched) == 0 {
+		return names
+	}
+	w := map[string]struct{}{}
+	for _, n := range watched {
+		w[n] = struct{}{}
+	}
+	res := make([]string, 0, len(names))
+	for _, n := range names {
+		if _, f := w[n]; f {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// resourceName returns the XDS resource name of a resource of a non wildcard type.
+func resourceName(typeURL string, r *any.Any) string {
+	switch typeURL {
+	case v3.EndpointType:
+		cla := &endpoint.ClusterLoadAssignment{}
+		if err := r.UnmarshalTo(cla); err == nil {
+			return cla.ClusterName
+		}
+	case v3.RouteType:
+		rc := &route.RouteConfiguration{}
+		if err := r.UnmarshalTo(rc); err == nil {
+			return rc.Name
+		}
+	case v3.ExtensionConfigurationType:
+		ec := &core.TypedExtensionConfig{}
+		if err := r.UnmarshalTo(ec); err == nil {
+			return ec.Name
+		}
+	}
+	return ""
+}
+
+// snapshotRequests keeps the latest request per type url received while serving the snapshot, in
+// the order the types were first requested. These are replayed to Istiod once it is reachable.
+type snapshotRequests struct {
+	// node is sent by Envoy on the first request only, and must be sent on the first replayed request.
+	node   *core.Node
+	order  []string
+	latest map[string]*discovery.DiscoveryRequest
+	// served holds the resource names last served per type url.
+	served map[string]string
+}
+
+func newSnapshotRequests() *snapshotRequests {
+	return &snapshotRequests{
+		latest: map[string]*discovery.DiscoveryRequest{},
+		served: map[string]string{},
+	}
+}
+
+func (r *snapshotRequests) add(req *discovery.DiscoveryRequest) {
+	if r.node == nil {
+		r.node = req.Node
+	}
+	if _, f := r.latest[req.TypeUrl]; !f {
+		r.order = append(r.order, req.TypeUrl)
+	}
+	r.latest[req.TypeUrl] = req
+}
+
+// needsResponse returns true if req is not an ACK of a response served from the snapshot.
+func (r *snapshotRequests) needsResponse(req *discovery.DiscoveryRequest) bool {
+	if !isSnapshotType(req.TypeUrl) {
+		return false
+	}
+	names := append([]string{}, req.ResourceNames...)
+	sort.Strings(names)
+	key := strings.Join(names, ",")
+	served, f := r.served[req.TypeUrl]
+	if req.ResponseNonce != "" && f && served == key {
+		return false
+	}
+	r.served[req.TypeUrl] = key
+	return true
+}
+
+// replay returns the requests to send to Istiod. Requests for the snapshot types are turned into
+// initial requests, as Istiod does not know about the nonces generated from the snapshot.
+func (r *snapshotRequests) replay() []*discovery.DiscoveryRequest {
+	res := make([]*discovery.DiscoveryRequest, 0, len(r.order))
+	for _, t := range r.order {
+		req := r.latest[t]
+		if isSnapshotType(t) {
+			req = proto.Clone(req).(*discovery.DiscoveryRequest)
+			req.ResponseNonce = ""
+			req.ErrorDetail = nil
+		}
+		res = append(res, req)
+	}
+	if len(res) > 0 && res[0].Node == nil {
+		res[0] = proto.Clone(res[0]).(*discovery.DiscoveryRequest)
+		res[0].Node = r.node
+	}
+	return res
+}
+
+// dialUpstreamWithSnapshot connects to the upstream XDS server. If Envoy is starting without any
+// configuration and the upstream cannot be reached within the grace period, the snapshot is served
+// to Envoy until the upstream becomes available. The requests received in the meantime are stored
+// on the connection, to be replayed to the upstream.
+func (p *XdsProxy) dialUpstreamWithSnapshot(con *ProxyConnection) (*grpc.ClientConn, error) {
+	var first *discovery.DiscoveryRequest
+	select {
+	case first = <-con.requestsChan:
+	case err := <-con.downstreamError:
+		return nil, err
+	case <-con.stopChan:
+		return nil, fmt.Errorf("stream stopped")
+	}
+	con.pendingRequests = []*discovery.DiscoveryRequest{first}
+	if first.VersionInfo != "" {
+		// Envoy already has configuration, there is no need for the snapshot.
+		return p.dialUpstream()
+	}
+
+	dialOptions := append(append([]grpc.DialOption{}, p.istiodDialOptions...), grpc.WithBlock())
+	ctx, cancel := context.WithTimeout(context.Background(), p.snapshotGracePeriod)
+	upstreamConn, err := grpc.DialContext(ctx, p.istiodAddress, dialOptions...)
+	cancel()
+	if err == nil {
+		return upstreamConn, nil
+	}
+	proxyLog.Warnf("upstream %s unavailable after %v, serving XDS snapshot to Envoy: %v", p.istiodAddress, p.snapshotGracePeriod, err)
+
+	// Keep trying to connect in the background, gRPC handles the backoff.
+	type dialResult struct {
+		conn *grpc.ClientConn
+		err  error
+	}
+	dialCtx, dialCancel := context.WithCancel(context.Background())
+	defer dialCancel()
+	dialed := make(chan dialResult, 1)
+	go func() {
+		conn, err := grpc.DialContext(dialCtx, p.istiodAddress, dialOptions...)
+		if err == nil && dialCtx.Err() != nil {
+			// Nobody is waiting for the connection anymore.
+			_ = conn.Close()
+		}
+		dialed <- dialResult{conn, err}
+	}()
+
+	requests := newSnapshotRequests()
+	serve := func(req *discovery.DiscoveryRequest) error {
+		requests.add(req)
+		if !requests.needsResponse(req) {
+			return nil
+		}
+		resp := p.snapshot.response(req)
+		if resp == nil {
+			return nil
+		}
+		proxyLog.Debugf("serving %s from XDS snapshot", v3.GetShortType(req.TypeUrl))
+		return sendDownstream(con.downstream, resp)
+	}
+	if err := serve(first); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	for {
+		select {
+		case req := <-con.requestsChan:
+			if err := serve(req); err != nil {
+				return nil, err
+			}
+		case res := <-dialed:
+			if res.err != nil {
+				return nil, res.err
+			}
+			proxyLog.Infof("upstream %s available after serving XDS snapshot for %v", p.istiodAddress, time.Since(start))
+			con.pendingRequests = requests.replay()
+			return res.conn, nil
+		case err := <-con.downstreamError:
+			return nil, err
+		case <-con.stopChan:
+			return nil, fmt.Errorf("stream stopped")
+		}
+	}
+}
@@ -27,7 +27,6 @@ import (
 	"strings"
 	"text/template"
 
-	"github.com/Masterminds/sprig/v3"
 	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/ghodss/yaml"
 	"github.com/hashicorp/go-multierror"
@@ -346,16 +345,20 @@ func RunTemplate(params InjectionParameters) (mergedPod *corev1.Pod, templatePod
 		Revision:             params.revision,
 		EstimatedConcurrency: estimateConcurrency(meshConfig.GetDefaultConfig(), metadata.Annotations, valuesStruct),
 	}
-	funcMap := CreateInjectionFuncmap()
+	funcMap := injectionFuncmap()
+	namespaceFuncMap := namespaceInjectionFuncmap()
 
 	// Need to use FuncMap and SidecarTemplateData context
-	funcMap["render"] = func(template string) string {
-		bbuf, err := parseTemplate(template, funcMap, data)
-		if err != nil {
-			return ""
-		}
+	for _, m := range []map[string]interface{}{funcMap, namespaceFuncMap} {
+		m := m
+		m["render"] = func(template string) string {
+			bbuf, err := parseTemplate(template, m, data)
+			if err != nil {
+				return ""
+			}
 
-		return bbuf.String()
+			return bbuf.String()
+		}
 	}
 
 	mergedPod = params.pod
@@ -366,7 +369,11 @@ func RunTemplate(params InjectionParameters) (mergedPod *corev1.Pod, templatePod
 			return nil, nil, fmt.Errorf("requested template %q not found; have %v",
 				templateName, strings.Join(knownTemplates(params.templates), ", "))
 		}
-		bbuf, err := parseTemplate(templateYAML, funcMap, data)
+		templateFuncMap := funcMap
+		if _, f := params.namespaceTemplates[templateName]; f {
+			templateFuncMap = namespaceFuncMap
+		}
+		bbuf, err := parseTemplate(templateYAML, templateFuncMap, data)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -474,7 +481,7 @@ func injectionStatus(pod *corev1.Pod) *SidecarInjectionStatus {
 func parseTemplate(tmplStr string, funcMap map[string]interface{}, data SidecarTemplateData) (bytes.Buffer, error) {
 	var tmpl bytes.Buffer
 	temp := template.New("inject")
-	t, err := temp.Funcs(sprig.TxtFuncMap()).Funcs(funcMap).Parse(tmplStr)
+	t, err := temp.Funcs(funcMap).Parse(tmplStr)
 	if err != nil {
 		log.Infof("Failed to parse template: %v %v\n", err, tmplStr)
 		return bytes.Buffer{}, err
@@ -71,6 +71,13 @@ func TestInjection(t *testing.T) {
 				m.DefaultConfig.InterceptionMode = meshapi.ProxyConfig_TPROXY
 			},
 		},
+		{
+			in:   "hello.yaml",
+			want: "hello-trust-domain-aliases.yaml.injected",
+			mesh: func(m *meshapi.MeshConfig) {
+				m.TrustDomainAliases = []string{"old.example", "older.example"}
+			},
+		},
 		{
 			in:       "hello.yaml",
 			want:     "hello-always.yaml.injected",
@@ -38,6 +38,11 @@ var (
 		"sidecar_injection_skip_total",
 		"Total number of skipped sidecar injection requests.",
 	)
+
+	totalInvalidNamespaceTemplates = monitoring.NewSum(
+		"sidecar_injection_invalid_namespace_templates_total",
+		"Total number of namespace injection template ConfigMaps rejected as invalid.",
+	)
 )
 
 func init() {
@@ -46,5 +51,6 @@ func init() {
 		totalSuccessfulInjections,
 		totalFailedInjections,
 		totalSkippedInjections,
+		totalInvalidNamespaceTemplates,
 	)
 }
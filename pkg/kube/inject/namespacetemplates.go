@@ -0,0 +1,186 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"istio.io/api/label"
+	"istio.io/pkg/log"
+)
+
+// NamespaceTemplatesLabel selects the ConfigMaps holding injection templates for the pods of their namespace.
+// Each key of the ConfigMap is the name of a template, overriding the template of the same name of the global
+// injection config. ConfigMaps with an istio.io/rev label are only used by the injector of that revision.
+// These templates are written by the users of the namespace, so they are rendered with the functions of
+// namespaceInjectionFuncmap, which cannot access the environment of istiod.
+const NamespaceTemplatesLabel = "istio.io/inject-templates"
+
+// namespaceTemplates holds the injection templates defined in the namespaces.
+type namespaceTemplates struct {
+	informer cache.SharedIndexInformer
+	revision string
+
+	mu sync.RWMutex
+	// templates holds the last valid templates of each ConfigMap, by namespace then ConfigMap name.
+	templates map[string]map[string]Templates
+}
+
+func newNamespaceTemplates(client kubernetes.Interface, revision string) *namespaceTemplates {
+	n := &namespaceTemplates{
+		revision:  revision,
+		templates: map[string]map[string]Templates{},
+	}
+	n.informer = informers.NewSharedInformerFactoryWithOptions(client, 12*time.Hour,
+		informers.WithTweakListOptions(func(listOptions *metav1.ListOptions) {
+			listOptions.LabelSelector = NamespaceTemplatesLabel
+		})).
+		Core().V1().ConfigMaps().Informer()
+	n.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			n.update(obj.(*v1.ConfigMap))
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			n.update(newObj.(*v1.ConfigMap))
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if cm, ok := obj.(*v1.ConfigMap); ok {
+				n.delete(cm)
+			}
+		},
+	})
+	return n
+}
+
+// Run starts watching the ConfigMaps holding namespace templates. Until they are synced, apply fails.
+func (n *namespaceTemplates) Run(stop <-chan struct{}) {
+	go n.informer.Run(stop)
+}
+
+// matchesRevision returns true if the ConfigMap is meant for the injector of this revision.
+func (n *namespaceTemplates) matchesRevision(cm *v1.ConfigMap) bool {
+	rev, f := cm.Labels[label.IoIstioRev.Name]
+	if !f {
+		return true
+	}
+	if rev == "default" {
+		rev = ""
+	}
+	revision := n.revision
+	if revision == "default" {
+		revision = ""
+	}
+	return rev == revision
+}
+
+func (n *namespaceTemplates) update(cm *v1.ConfigMap) {
+	if _, f := cm.Labels[NamespaceTemplatesLabel]; !f || !n.matchesRevision(cm) {
+		n.delete(cm)
+		return
+	}
+	templates := Templates{}
+	for name, tmpl := range cm.Data {
+		templates[name] = tmpl
+	}
+	if err := validateTemplates(templates); err != nil {
+		// Keep the last valid templates, so a bad edit does not break the injection of the namespace.
+		log.Errorf("ignoring invalid injection templates of ConfigMap %s/%s: %v", cm.Namespace, cm.Name, err)
+		totalInvalidNamespaceTemplates.Increment()
+		return
+	}
+	log.Infof("loaded injection templates of ConfigMap %s/%s: %v", cm.Namespace, cm.Name, knownTemplates(templates))
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.templates[cm.Namespace] == nil {
+		n.templates[cm.Namespace] = map[string]Templates{}
+	}
+	n.templates[cm.Namespace][cm.Name] = templates
+}
+
+func (n *namespaceTemplates) delete(cm *v1.ConfigMap) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.templates[cm.Namespace], cm.Name)
+	if len(n.templates[cm.Namespace]) == 0 {
+		delete(n.templates, cm.Namespace)
+	}
+}
+
+// apply returns the templates used for the pods of the namespace: the global templates, overridden by the templates
+// defined in the namespace. If several ConfigMaps define the same template, the one of the last ConfigMap by name
+// is used. The names of the templates defined in the namespace are also returned. An error is returned until the
+// ConfigMaps are synced, rather than injecting the pods with the global templates their namespace may override.
+func (n *namespaceTemplates) apply(namespace string, global Templates) (Templates, map[string]struct{}, error) {
+	if !n.informer.HasSynced() {
+		return nil, nil, fmt.Errorf("namespace injection templates are not synced yet")
+	}
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	configMaps := n.templates[namespace]
+	if len(configMaps) == 0 {
+		return global, nil, nil
+	}
+	names := make([]string, 0, len(configMaps))
+	for name := range configMaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	merged := make(Templates, len(global))
+	for name, tmpl := range global {
+		merged[name] = tmpl
+	}
+	overridden := map[string]struct{}{}
+	for _, name := range names {
+		for tmplName, tmpl := range configMaps[name] {
+			merged[tmplName] = tmpl
+			overridden[tmplName] = struct{}{}
+		}
+	}
+	return merged, overridden, nil
+}
+
+// validateTemplates checks that the namespace templates are not empty and can be parsed, as done by parseTemplate.
+func validateTemplates(templates Templates) error {
+	var errs error
+	if len(templates) == 0 {
+		return fmt.Errorf("no templates defined")
+	}
+	funcMap := namespaceInjectionFuncmap()
+	funcMap["render"] = func(string) string { return "" }
+	for _, name := range knownTemplates(templates) {
+		if templates[name] == "" {
+			errs = multierror.Append(errs, fmt.Errorf("template %q is empty", name))
+			continue
+		}
+		if _, err := template.New(name).Funcs(funcMap).Parse(templates[name]); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("template %q is invalid: %v", name, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,187 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"istio.io/api/label"
+	"istio.io/istio/pkg/kube"
+)
+
+func makeTemplatesConfigMap(ns, name, revision string, data map[string]string) *v1.ConfigMap {
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ns,
+			Name:      name,
+			Labels:    map[string]string{NamespaceTemplatesLabel: ""},
+		},
+		Data: data,
+	}
+	if revision != "" {
+		cm.Labels[label.IoIstioRev.Name] = revision
+	}
+	return cm
+}
+
+func TestNamespaceTemplates(t *testing.T) {
+	global := Templates{"sidecar": "global-sidecar", "gateway": "global-gateway"}
+
+	valid := makeTemplatesConfigMap("ns1", "a", "", map[string]string{"sidecar": "ns1-sidecar"})
+	invalid := makeTemplatesConfigMap("ns1", "a", "", map[string]string{"sidecar": "{{ .Unclosed"})
+	custom := makeTemplatesConfigMap("ns1", "b", "", map[string]string{"sidecar": "ns1-b-sidecar", "custom": "ns1-custom"})
+	unlabeled := makeTemplatesConfigMap("ns1", "c", "", map[string]string{"gateway": "ns1-gateway"})
+	delete(unlabeled.Labels, NamespaceTemplatesLabel)
+	canary := makeTemplatesConfigMap("ns2", "a", "canary", map[string]string{"sidecar": "canary-sidecar"})
+	stable := makeTemplatesConfigMap("ns2", "b", "default", map[string]string{"sidecar": "stable-sidecar"})
+
+	client := kube.NewFakeClient()
+	n := newNamespaceTemplates(client.Kube(), "")
+	if _, _, err := n.apply("ns1", global); err == nil {
+		t.Fatalf("expected namespace templates to be refused before they are synced")
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	n.Run(stop)
+	cache.WaitForCacheSync(stop, n.informer.HasSynced)
+
+	steps := []struct {
+		name    string
+		added   *v1.ConfigMap
+		updated *v1.ConfigMap
+		deleted *v1.ConfigMap
+		want    map[string]Templates
+	}{
+		{
+			name:  "override",
+			added: valid,
+			want: map[string]Templates{
+				"ns1":   {"sidecar": "ns1-sidecar", "gateway": "global-gateway"},
+				"other": global,
+			},
+		},
+		{
+			name:    "invalid update keeps the last valid templates",
+			updated: invalid,
+			want:    map[string]Templates{"ns1": {"sidecar": "ns1-sidecar", "gateway": "global-gateway"}},
+		},
+		{
+			name:  "merged by ConfigMap name",
+			added: custom,
+			want:  map[string]Templates{"ns1": {"sidecar": "ns1-b-sidecar", "gateway": "global-gateway", "custom": "ns1-custom"}},
+		},
+		{
+			name:  "unlabeled ConfigMap ignored",
+			added: unlabeled,
+			want:  map[string]Templates{"ns1": {"sidecar": "ns1-b-sidecar", "gateway": "global-gateway", "custom": "ns1-custom"}},
+		},
+		{
+			name:    "delete",
+			deleted: custom,
+			want:    map[string]Templates{"ns1": {"sidecar": "ns1-sidecar", "gateway": "global-gateway"}},
+		},
+		{
+			name:  "other revision ignored",
+			added: canary,
+			want:  map[string]Templates{"ns2": global},
+		},
+		{
+			name:  "default revision",
+			added: stable,
+			want:  map[string]Templates{"ns2": {"sidecar": "stable-sidecar", "gateway": "global-gateway"}},
+		},
+	}
+
+	for i, step := range steps {
+		t.Run(fmt.Sprintf("[%v] %s", i, step.name), func(t *testing.T) {
+			g := NewWithT(t)
+
+			switch {
+			case step.added != nil:
+				_, err := client.Kube().CoreV1().ConfigMaps(step.added.Namespace).Create(context.TODO(), step.added, metav1.CreateOptions{})
+				g.Expect(err).Should(BeNil())
+			case step.updated != nil:
+				_, err := client.Kube().CoreV1().ConfigMaps(step.updated.Namespace).Update(context.TODO(), step.updated, metav1.UpdateOptions{})
+				g.Expect(err).Should(BeNil())
+			case step.deleted != nil:
+				g.Expect(client.Kube().CoreV1().ConfigMaps(step.deleted.Namespace).
+					Delete(context.TODO(), step.deleted.Name, metav1.DeleteOptions{})).Should(Succeed())
+			}
+
+			for ns, want := range step.want {
+				g.Eventually(func() Templates {
+					templates, _, err := n.apply(ns, global)
+					g.Expect(err).Should(BeNil())
+					return templates
+				}, time.Second).Should(Equal(want))
+			}
+		})
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	cases := []struct {
+		name      string
+		templates Templates
+		valid     bool
+	}{
+		{"valid", Templates{"sidecar": "spec:\n  containers:\n  - name: {{ .ProxyConfig.ProxyName | default \"istio-proxy\" }}"}, true},
+		{"no templates", Templates{}, false},
+		{"empty template", Templates{"sidecar": ""}, false},
+		{"parse error", Templates{"sidecar": "{{ if }}"}, false},
+		{"unknown function", Templates{"sidecar": "{{ notAFunction }}"}, false},
+		{"render", Templates{"sidecar": "{{ render \"spec: {}\" }}"}, true},
+		{"env", Templates{"sidecar": "{{ env \"HOME\" }}"}, false},
+		{"expandenv", Templates{"sidecar": "{{ expandenv \"$HOME\" }}"}, false},
+		{"getHostByName", Templates{"sidecar": "{{ getHostByName \"istiod\" }}"}, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTemplates(tt.templates)
+			if tt.valid && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatalf("expected an error")
+			}
+		})
+	}
+}
+
+func TestNamespaceInjectionFuncmap(t *testing.T) {
+	global := injectionFuncmap()
+	namespace := namespaceInjectionFuncmap()
+	for _, name := range []string{"env", "expandenv", "getHostByName"} {
+		if _, f := global[name]; !f {
+			t.Errorf("global templates should have function %q", name)
+		}
+		if _, f := namespace[name]; f {
+			t.Errorf("namespace templates should not have function %q", name)
+		}
+	}
+	for _, name := range []string{"toYaml", "annotation", "default"} {
+		if _, f := namespace[name]; !f {
+			t.Errorf("namespace templates should have function %q", name)
+		}
+	}
+}
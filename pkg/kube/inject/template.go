@@ -24,6 +24,7 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/Masterminds/sprig/v3"
 	"github.com/ghodss/yaml"
 	"github.com/gogo/protobuf/jsonpb"
 	"github.com/gogo/protobuf/proto"
@@ -36,6 +37,28 @@ import (
 	"istio.io/pkg/log"
 )
 
+// injectionFuncmap returns the functions available to the templates of the injection configuration: the sprig
+// functions, and the ones of CreateInjectionFuncmap.
+func injectionFuncmap() template.FuncMap {
+	funcMap := sprig.TxtFuncMap()
+	for name, f := range CreateInjectionFuncmap() {
+		funcMap[name] = f
+	}
+	return funcMap
+}
+
+// namespaceInjectionFuncmap returns the functions available to the injection templates defined in namespaces. These
+// templates are written by the users of the namespaces, so the functions reading the environment of istiod, such as
+// env and expandenv, or accessing the network, such as getHostByName, are removed.
+func namespaceInjectionFuncmap() template.FuncMap {
+	funcMap := sprig.HermeticTxtFuncMap()
+	for name, f := range CreateInjectionFuncmap() {
+		funcMap[name] = f
+	}
+	delete(funcMap, "env")
+	return funcMap
+}
+
 func CreateInjectionFuncmap() template.FuncMap {
 	return template.FuncMap{
 		"formatDuration":      formatDuration,
@@ -80,6 +80,9 @@ type Webhook struct {
 
 	env      *model.Environment
 	revision string
+
+	// namespaceTemplates overrides the templates for the pods of namespaces defining their own, if enabled.
+	namespaceTemplates *namespaceTemplates
 }
 
 // nolint directives: interfacer
@@ -132,6 +135,10 @@ type WebhookParameters struct {
 
 	// The istio.io/rev this injector is responsible for
 	Revision string
+
+	// KubeClient is used to watch the ConfigMaps overriding the templates of their namespace, see
+	// NamespaceTemplatesLabel. If nil, only the templates of the injection configuration are used.
+	KubeClient kube.Client
 }
 
 // NewWebhook creates a new instance of a mutating webhook for automatic sidecar injection.
@@ -146,6 +153,9 @@ func NewWebhook(p WebhookParameters) (*Webhook, error) {
 		env:        p.Env,
 		revision:   p.Revision,
 	}
+	if p.KubeClient != nil {
+		wh.namespaceTemplates = newNamespaceTemplates(p.KubeClient.Kube(), p.Revision)
+	}
 
 	p.Watcher.SetHandler(wh.updateConfig)
 	sidecarConfig, valuesConfig, err := p.Watcher.Get()
@@ -169,6 +179,9 @@ func NewWebhook(p WebhookParameters) (*Webhook, error) {
 // Run implements the webhook server
 func (wh *Webhook) Run(stop <-chan struct{}) {
 	go wh.watcher.Run(stop)
+	if wh.namespaceTemplates != nil {
+		wh.namespaceTemplates.Run(stop)
+	}
 }
 
 func (wh *Webhook) updateConfig(sidecarConfig *Config, valuesConfig string) {
@@ -272,10 +285,12 @@ func toAdmissionResponse(err error) *kube.AdmissionResponse {
 }
 
 type InjectionParameters struct {
-	pod                 *corev1.Pod
-	deployMeta          metav1.ObjectMeta
-	typeMeta            metav1.TypeMeta
-	templates           Templates
+	pod        *corev1.Pod
+	deployMeta metav1.ObjectMeta
+	typeMeta   metav1.TypeMeta
+	templates  Templates
+	// namespaceTemplates are the names of the templates defined in the namespace of the pod.
+	namespaceTemplates  map[string]struct{}
 	defaultTemplate     []string
 	aliases             map[string][]string
 	meshConfig          *meshconfig.MeshConfig
@@ -338,37 +353,45 @@ func getInjectionStatus(podSpec corev1.PodSpec) string {
 // handle cases that cannot feasibly be covered in the template, such as
 // re-ordering pods, rewriting readiness probes, etc.
 func injectPod(req InjectionParameters) ([]byte, error) {
+	_, patch, err := renderPod(req)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("AdmissionResponse: patch=%v\n", string(patch))
+	return patch, nil
+}
+
+// renderPod returns the injected pod, and the JSON patch turning the original pod into the injected pod.
+func renderPod(req InjectionParameters) (*corev1.Pod, []byte, error) {
 	checkPreconditions(req)
 
 	// The patch will be built relative to the initial pod, capture its current state
 	originalPodSpec, err := json.Marshal(req.pod)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Run the injection template, giving us a partial pod spec
 	mergedPod, injectedPodData, err := RunTemplate(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to run injection template: %v", err)
+		return nil, nil, fmt.Errorf("failed to run injection template: %v", err)
 	}
 
 	mergedPod, err = reapplyOverwrittenContainers(mergedPod, req.pod, injectedPodData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to re apply container: %v", err)
+		return nil, nil, fmt.Errorf("failed to re apply container: %v", err)
 	}
 
 	// Apply some additional transformations to the pod
 	if err := postProcessPod(mergedPod, *injectedPodData, req); err != nil {
-		return nil, fmt.Errorf("failed to process pod: %v", err)
+		return nil, nil, fmt.Errorf("failed to process pod: %v", err)
 	}
 
 	patch, err := createPatch(mergedPod, originalPodSpec)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create patch: %v", err)
+		return nil, nil, fmt.Errorf("failed to create patch: %v", err)
 	}
-
-	log.Debugf("AdmissionResponse: patch=%v\n", string(patch))
-	return patch, nil
+	return mergedPod, patch, nil
 }
 
 // OverrideAnnotation is used to store the overrides for injected containers
@@ -721,32 +744,19 @@ func (wh *Webhook) inject(ar *kube.AdmissionReview, path string) *kube.Admission
 	log.Debugf("Object: %v", string(req.Object.Raw))
 	log.Debugf("OldObject: %v", string(req.OldObject.Raw))
 
-	wh.mu.RLock()
-	if !injectRequired(IgnoredNamespaces, wh.Config, &pod.Spec, pod.ObjectMeta) {
+	params, required, err := wh.injectionParameters(&pod, path)
+	if err != nil {
+		handleError(fmt.Sprintf("Pod injection failed: %v", err))
+		return toAdmissionResponse(err)
+	}
+	if !required {
 		log.Infof("Skipping %s/%s due to policy check", pod.ObjectMeta.Namespace, podName)
 		totalSkippedInjections.Increment()
-		wh.mu.RUnlock()
 		return &kube.AdmissionResponse{
 			Allowed: true,
 		}
 	}
 
-	deploy, typeMeta := kube.GetDeployMetaFromPod(&pod)
-	params := InjectionParameters{
-		pod:                 &pod,
-		deployMeta:          deploy,
-		typeMeta:            typeMeta,
-		templates:           wh.Config.Templates,
-		defaultTemplate:     wh.Config.DefaultTemplates,
-		aliases:             wh.Config.Aliases,
-		meshConfig:          wh.meshConfig,
-		valuesConfig:        wh.valuesConfig,
-		revision:            wh.revision,
-		injectedAnnotations: wh.Config.InjectedAnnotations,
-		proxyEnvs:           parseInjectEnvs(path),
-	}
-	wh.mu.RUnlock()
-
 	patchBytes, err := injectPod(params)
 	if err != nil {
 		handleError(fmt.Sprintf("Pod injection failed: %v", err))
@@ -765,8 +775,112 @@ func (wh *Webhook) inject(ar *kube.AdmissionReview, path string) *kube.Admission
 	return &reviewResponse
 }
 
+// injectionParameters returns the parameters of the injection of the pod, or false if the pod must not be injected.
+func (wh *Webhook) injectionParameters(pod *corev1.Pod, path string) (InjectionParameters, bool, error) {
+	wh.mu.RLock()
+	defer wh.mu.RUnlock()
+	if !injectRequired(IgnoredNamespaces, wh.Config, &pod.Spec, pod.ObjectMeta) {
+		return InjectionParameters{}, false, nil
+	}
+
+	templates := wh.Config.Templates
+	var namespaceTemplates map[string]struct{}
+	if wh.namespaceTemplates != nil {
+		var err error
+		if templates, namespaceTemplates, err = wh.namespaceTemplates.apply(pod.Namespace, templates); err != nil {
+			return InjectionParameters{}, false, err
+		}
+	}
+	deploy, typeMeta := kube.GetDeployMetaFromPod(pod)
+	return InjectionParameters{
+		pod:                 pod,
+		deployMeta:          deploy,
+		typeMeta:            typeMeta,
+		templates:           templates,
+		namespaceTemplates:  namespaceTemplates,
+		defaultTemplate:     wh.Config.DefaultTemplates,
+		aliases:             wh.Config.Aliases,
+		meshConfig:          wh.meshConfig,
+		valuesConfig:        wh.valuesConfig,
+		revision:            wh.revision,
+		injectedAnnotations: wh.Config.InjectedAnnotations,
+		proxyEnvs:           parseInjectEnvs(path),
+	}, true, nil
+}
+
+// DryRunResult is the response of the injection webhook to dry run requests, made with /inject?dryRun=true.
+type DryRunResult struct {
+	// Injected is false if the pod is not injected, per the injection policy.
+	Injected bool `json:"injected"`
+	// Pod is the injected pod, or the original pod if it is not injected.
+	Pod *corev1.Pod `json:"pod"`
+	// Patch is the JSON patch the webhook returns to inject the pod.
+	Patch json.RawMessage `json:"patch,omitempty"`
+}
+
+// dryRun renders the injection of a pod, without counting it as an injection.
+func (wh *Webhook) dryRun(pod *corev1.Pod, path string) (*DryRunResult, error) {
+	params, required, err := wh.injectionParameters(pod, path)
+	if err != nil {
+		return nil, err
+	}
+	if !required {
+		return &DryRunResult{Pod: pod}, nil
+	}
+	injected, patch, err := renderPod(params)
+	if err != nil {
+		return nil, err
+	}
+	return &DryRunResult{Injected: true, Pod: injected, Patch: patch}, nil
+}
+
+// serveDryRun serves dry run requests. The body is either an AdmissionReview, as sent by the API server, or a Pod.
+func (wh *Webhook) serveDryRun(w http.ResponseWriter, body []byte, path string) {
+	out, _, err := deserializer.Decode(body, nil, nil)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not decode body: %v", err), http.StatusBadRequest)
+		return
+	}
+	var pod *corev1.Pod
+	if p, ok := out.(*corev1.Pod); ok {
+		pod = p
+	} else {
+		ar, err := kube.AdmissionReviewKubeToAdapter(out)
+		if err != nil || ar.Request == nil {
+			http.Error(w, "expected a Pod or an AdmissionReview", http.StatusBadRequest)
+			return
+		}
+		pod = &corev1.Pod{}
+		if err := json.Unmarshal(ar.Request.Object.Raw, pod); err != nil {
+			http.Error(w, fmt.Sprintf("could not decode pod: %v", err), http.StatusBadRequest)
+			return
+		}
+		if pod.Namespace == "" {
+			pod.Namespace = ar.Request.Namespace
+		}
+	}
+
+	result, err := wh.dryRun(pod, path)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("pod injection failed: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+	resp, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(resp); err != nil {
+		log.Errorf("Could not write response: %v", err)
+	}
+}
+
 func (wh *Webhook) serveInject(w http.ResponseWriter, r *http.Request) {
-	totalInjections.Increment()
+	dryRun := r.URL != nil && r.URL.Query().Get("dryRun") == "true"
+	if !dryRun {
+		totalInjections.Increment()
+	}
 	var body []byte
 	if r.Body != nil {
 		if data, err := ioutil.ReadAll(r.Body); err == nil {
@@ -774,7 +888,9 @@ func (wh *Webhook) serveInject(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	if len(body) == 0 {
-		handleError("no body found")
+		if !dryRun {
+			handleError("no body found")
+		}
 		http.Error(w, "no body found", http.StatusBadRequest)
 		return
 	}
@@ -782,7 +898,9 @@ func (wh *Webhook) serveInject(w http.ResponseWriter, r *http.Request) {
 	// verify the content type is accurate
 	contentType := r.Header.Get("Content-Type")
 	if contentType != "application/json" {
-		handleError(fmt.Sprintf("contentType=%s, expect application/json", contentType))
+		if !dryRun {
+			handleError(fmt.Sprintf("contentType=%s, expect application/json", contentType))
+		}
 		http.Error(w, "invalid Content-Type, want `application/json`", http.StatusUnsupportedMediaType)
 		return
 	}
@@ -791,6 +909,10 @@ func (wh *Webhook) serveInject(w http.ResponseWriter, r *http.Request) {
 	if r.URL != nil {
 		path = r.URL.Path
 	}
+	if dryRun {
+		wh.serveDryRun(w, body, path)
+		return
+	}
 
 	var reviewResponse *kube.AdmissionResponse
 	var obj runtime.Object
@@ -16,6 +16,7 @@ package inject
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -49,6 +50,7 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/test/util"
 	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/test/util/retry"
 	sutil "istio.io/istio/security/pkg/nodeagent/util"
 )
@@ -1014,6 +1016,127 @@ func TestRunAndServe(t *testing.T) {
 	testSideCarInjectorMetrics(t)
 }
 
+func TestDryRun(t *testing.T) {
+	wh, cleanup := createWebhook(t, minimalSidecarTemplate)
+	defer cleanup()
+
+	client := kube.NewFakeClient()
+	wh.namespaceTemplates = newNamespaceTemplates(client.Kube(), "")
+	stop := make(chan struct{})
+	defer close(stop)
+	wh.Run(stop)
+	cm := makeTemplatesConfigMap("custom", "templates", "", map[string]string{SidecarTemplateName: `
+spec:
+  containers:
+  - name: custom-proxy
+`})
+	if _, err := client.Kube().CoreV1().ConfigMaps(cm.Namespace).Create(context.TODO(), cm, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		if templates, _, _ := wh.namespaceTemplates.apply("custom", nil); templates[SidecarTemplateName] == "" {
+			return fmt.Errorf("namespace templates not loaded")
+		}
+		return nil
+	})
+
+	podJSON := func(ns string) []byte {
+		pod := corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: ns},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "c1"}}},
+		}
+		return convertToJSON(pod, t)
+	}
+
+	cases := []struct {
+		name           string
+		body           []byte
+		wantInjected   bool
+		wantContainers []string
+	}{
+		{
+			name:           "admission review",
+			body:           makeTestData(t, false, "v1beta1"),
+			wantInjected:   true,
+			wantContainers: []string{"c1", "istio-proxy"},
+		},
+		{
+			name:           "skipped",
+			body:           makeTestData(t, true, "v1"),
+			wantContainers: []string{"c1"},
+		},
+		{
+			name:           "pod",
+			body:           podJSON("default"),
+			wantInjected:   true,
+			wantContainers: []string{"c1", "istio-proxy"},
+		},
+		{
+			name:           "namespace templates",
+			body:           podJSON("custom"),
+			wantInjected:   true,
+			wantContainers: []string{"custom-proxy", "c1"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "http://sidecar-injector/inject?dryRun=true", bytes.NewReader(c.body))
+			req.Header.Add("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			wh.serveInject(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+			}
+
+			var result DryRunResult
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatalf("could not decode response body: %v", err)
+			}
+			if result.Injected != c.wantInjected {
+				t.Fatalf("got injected %v, want %v", result.Injected, c.wantInjected)
+			}
+			var containers []string
+			for _, container := range result.Pod.Spec.Containers {
+				containers = append(containers, container.Name)
+			}
+			if !reflect.DeepEqual(containers, c.wantContainers) {
+				t.Fatalf("got containers %v, want %v", containers, c.wantContainers)
+			}
+			if !c.wantInjected {
+				if len(result.Patch) != 0 {
+					t.Fatalf("unexpected patch: %s", result.Patch)
+				}
+				return
+			}
+
+			// The patch turns the original pod into the rendered pod.
+			var original []byte
+			if ar, err := kube.AdmissionReviewKubeToAdapter(decodeObject(t, c.body)); err == nil && ar.Request != nil {
+				original = ar.Request.Object.Raw
+			} else {
+				original = c.body
+			}
+			var patched corev1.Pod
+			if err := json.Unmarshal(applyJSONPatch(original, result.Patch, t), &patched); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(patched.Spec.Containers, result.Pod.Spec.Containers) {
+				t.Fatalf("patched containers %v do not match rendered containers %v", patched.Spec.Containers, result.Pod.Spec.Containers)
+			}
+		})
+	}
+}
+
+func decodeObject(t *testing.T, body []byte) runtime.Object {
+	t.Helper()
+	obj, _, err := deserializer.Decode(body, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
 func testSideCarInjectorMetrics(t *testing.T) {
 	expected := []string{
 		"sidecar_injection_requests_total",
@@ -100,6 +100,14 @@ type Options struct {
 	// https://github.com/spiffe/spiffe/blob/master/standards/SPIFFE-ID.md#21-trust-domain
 	TrustDomain string
 
+	// TrustDomainAliases are the aliases of TrustDomain. The peers of these trust domains are trusted with the root
+	// certificates of TrustDomain.
+	TrustDomainAliases []string
+
+	// FederatedBundleEndpoints maps the federated SPIFFE trust domains to their bundle endpoints, in the format
+	// <trustdomain>|<url>||<trustdomain>|<url>. The workloads trust the peers of each trust domain with its bundle.
+	FederatedBundleEndpoints string
+
 	// Whether to generate PKCS#8 private keys.
 	Pkcs8Keys bool
 
@@ -249,6 +257,11 @@ type SecretItem struct {
 
 	RootCert []byte
 
+	// TrustDomainBundles maps SPIFFE trust domains to the PEM encoded root certificates of their bundle. It is only
+	// set for the root certificate request when federated trust domains are configured, in which case the peers
+	// are validated against the bundle of their trust domain instead of RootCert.
+	TrustDomainBundles map[string][]byte
+
 	// ResourceName passed from envoy SDS discovery request.
 	// "ROOTCA" for root cert request, "default" for key/cert request.
 	ResourceName string
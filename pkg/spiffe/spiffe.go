@@ -157,13 +157,9 @@ func GetTrustDomainFromURISAN(uriSan string) (string, error) {
 	return parsed.TrustDomain, nil
 }
 
-// RetrieveSpiffeBundleRootCertsFromStringInput retrieves the trusted CA certificates from a list of SPIFFE bundle endpoints.
-// It can use the system cert pool and the supplied certificates to validate the endpoints.
-// The input endpointTuples should be in the format of:
-// "foo|URL1||bar|URL2||baz|URL3..."
-func RetrieveSpiffeBundleRootCertsFromStringInput(inputString string, extraTrustedCerts []*x509.Certificate) (
-	map[string][]*x509.Certificate, error) {
-	spiffeLog.Infof("Processing SPIFFE bundle configuration: %v", inputString)
+// ParseSpiffeBundleEndpoints parses a list of SPIFFE bundle endpoints, in the format of "foo|URL1||bar|URL2||baz|URL3...",
+// into a map of trust domains to their endpoints.
+func ParseSpiffeBundleEndpoints(inputString string) (map[string]string, error) {
 	config := make(map[string]string)
 	tuples := strings.Split(inputString, "||")
 	for _, tuple := range tuples {
@@ -175,6 +171,20 @@ func RetrieveSpiffeBundleRootCertsFromStringInput(inputString string, extraTrust
 		endpoint := items[1]
 		config[trustDomain] = endpoint
 	}
+	return config, nil
+}
+
+// RetrieveSpiffeBundleRootCertsFromStringInput retrieves the trusted CA certificates from a list of SPIFFE bundle endpoints.
+// It can use the system cert pool and the supplied certificates to validate the endpoints.
+// The input endpointTuples should be in the format of:
+// "foo|URL1||bar|URL2||baz|URL3..."
+func RetrieveSpiffeBundleRootCertsFromStringInput(inputString string, extraTrustedCerts []*x509.Certificate) (
+	map[string][]*x509.Certificate, error) {
+	spiffeLog.Infof("Processing SPIFFE bundle configuration: %v", inputString)
+	config, err := ParseSpiffeBundleEndpoints(inputString)
+	if err != nil {
+		return nil, err
+	}
 
 	caCertPool, err := x509.SystemCertPool()
 	if err != nil {
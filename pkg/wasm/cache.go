@@ -40,7 +40,7 @@ const (
 
 // Cache models a Wasm module cache.
 type Cache interface {
-	Get(url, checksum string, timeout time.Duration) (string, error)
+	Get(url, checksum string, timeout time.Duration, pullSecret []byte) (string, error)
 	Cleanup()
 }
 
@@ -98,8 +98,9 @@ func NewLocalFileCache(dir string, purgeInterval, moduleExpiry time.Duration) *L
 	return cache
 }
 
-// Get returns path the local Wasm module file.
-func (c *LocalFileCache) Get(downloadURL, checksum string, timeout time.Duration) (string, error) {
+// Get returns path the local Wasm module file. pullSecret holds the docker config JSON used to
+// authenticate against the registry when the module is fetched from an `oci://` URL.
+func (c *LocalFileCache) Get(downloadURL, checksum string, timeout time.Duration, pullSecret []byte) (string, error) {
 	url, err := url.Parse(downloadURL)
 	if err != nil {
 		return "", fmt.Errorf("fail to parse Wasm module fetch url: %s", downloadURL)
@@ -110,42 +111,55 @@ func (c *LocalFileCache) Get(downloadURL, checksum string, timeout time.Duration
 		checksum:    checksum,
 	}
 
+	var fetch func() ([]byte, error)
 	switch url.Scheme {
 	case "http", "https":
-		// First check if the cache entry is already downloaded.
-		if modulePath := c.getEntry(key); modulePath != "" {
-			return modulePath, nil
+		fetch = func() ([]byte, error) {
+			return c.httpFetcher.Fetch(downloadURL, timeout)
 		}
-
-		// If the module is not available locally, download the Wasm module with http fetcher.
-		b, err := c.httpFetcher.Fetch(downloadURL, timeout)
-		if err != nil {
-			wasmRemoteFetchCount.With(resultTag.Value(downloadFailure)).Increment()
-			return "", err
+	case "oci":
+		fetch = func() ([]byte, error) {
+			imageFetcher, err := NewImageFetcher(ImageFetcherOption{PullSecret: pullSecret})
+			if err != nil {
+				return nil, err
+			}
+			return imageFetcher.Fetch(downloadURL, timeout)
 		}
+	default:
+		return "", fmt.Errorf("unsupported Wasm module downloading URL scheme: %v", url.Scheme)
+	}
 
-		// Get sha256 checksum and check if it is the same as provided one.
-		dChecksum := fmt.Sprintf("%x", sha256.Sum256(b))
-		if checksum != "" && dChecksum != checksum {
-			wasmRemoteFetchCount.With(resultTag.Value(checksumMismatch)).Increment()
-			return "", fmt.Errorf("module downloaded from %v has checksum %v, which does not match: %v", downloadURL, dChecksum, checksum)
-		}
+	// First check if the cache entry is already downloaded.
+	if modulePath := c.getEntry(key); modulePath != "" {
+		return modulePath, nil
+	}
 
-		wasmRemoteFetchCount.With(resultTag.Value(fetchSuccess)).Increment()
+	// If the module is not available locally, download the Wasm module with the fetcher of the URL scheme.
+	b, err := fetch()
+	if err != nil {
+		wasmRemoteFetchCount.With(resultTag.Value(downloadFailure)).Increment()
+		return "", err
+	}
 
-		// TODO(bianpengyuan): Add sanity check on downloaded file to make sure it is a valid Wasm module.
+	// Get sha256 checksum and check if it is the same as provided one.
+	dChecksum := fmt.Sprintf("%x", sha256.Sum256(b))
+	if checksum != "" && dChecksum != checksum {
+		wasmRemoteFetchCount.With(resultTag.Value(checksumMismatch)).Increment()
+		return "", fmt.Errorf("module downloaded from %v has checksum %v, which does not match: %v", downloadURL, dChecksum, checksum)
+	}
 
-		key.checksum = dChecksum
-		f := filepath.Join(c.dir, fmt.Sprintf("%s.wasm", dChecksum))
+	wasmRemoteFetchCount.With(resultTag.Value(fetchSuccess)).Increment()
 
-		if err := c.addEntry(key, b, f); err != nil {
-			return "", err
-		}
+	// TODO(bianpengyuan): Add sanity check on downloaded file to make sure it is a valid Wasm module.
 
-		return f, nil
-	default:
-		return "", fmt.Errorf("unsupported Wasm module downloading URL scheme: %v", url.Scheme)
+	key.checksum = dChecksum
+	f := filepath.Join(c.dir, fmt.Sprintf("%s.wasm", dChecksum))
+
+	if err := c.addEntry(key, b, f); err != nil {
+		return "", err
 	}
+
+	return f, nil
 }
 
 // Cleanup closes background Wasm module purge routine.
@@ -15,26 +15,49 @@
 package wasm
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
 	udpa "github.com/cncf/udpa/go/udpa/type/v1"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
+	wasmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/wasm/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/conversion"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/any"
 	"go.uber.org/atomic"
 	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/pkg/env"
 )
 
 const (
+	// PullSecretEnv is the Wasm VM environment variable holding the name of the image pull secret used to
+	// fetch modules from `oci://` URLs. The agent resolves the name to the docker config JSON mounted under
+	// the pull secrets directory, so the credentials never appear in the proxy configuration, and removes
+	// the variable from the configuration before handing it to Envoy.
+	PullSecretEnv = "ISTIO_META_WASM_IMAGE_PULL_SECRET"
+
+	// DefaultPullSecretsDir is the default directory where the image pull secrets are mounted, one
+	// directory per secret holding its `.dockerconfigjson` or `.dockercfg` key. The sidecar injector
+	// mounts the image pull secrets of the pod there.
+	DefaultPullSecretsDir = "/etc/istio/wasm-pull-secrets"
+
 	apiTypePrefix      = "type.googleapis.com/"
 	typedStructType    = apiTypePrefix + "udpa.type.v1.TypedStruct"
 	wasmHTTPFilterType = apiTypePrefix + "envoy.extensions.filters.http.wasm.v3.Wasm"
 )
 
+// pullSecretsDir is the directory where the image pull secrets referenced by the Wasm VM environments are mounted.
+var pullSecretsDir = env.RegisterStringVar("WASM_PULL_SECRETS_DIR", DefaultPullSecretsDir,
+	"The directory where the image pull secrets used to fetch Wasm modules from OCI registries are mounted, "+
+		"one directory per secret holding its .dockerconfigjson or .dockercfg key.").Get()
+
 // MaybeConvertWasmExtensionConfig converts any presence of module remote download to local file.
 // It downloads the Wasm module and stores the module locally in the file system.
 func MaybeConvertWasmExtensionConfig(resources []*any.Any, cache Cache) bool {
@@ -156,7 +179,13 @@ func convert(resource *any.Any, cache Cache) (newExtensionConfig *any.Any, sendN
 	if remote.GetHttpUri().Timeout != nil {
 		timeout = remote.GetHttpUri().Timeout.AsDuration()
 	}
-	f, err := cache.Get(httpURI.GetUri(), remote.GetSha256(), timeout)
+	pullSecret, err := resolvePullSecret(extractPullSecret(vm))
+	if err != nil {
+		status = fetchFailure
+		wasmLog.Errorf("cannot fetch Wasm module %v: %v", remote.GetHttpUri().GetUri(), err)
+		return
+	}
+	f, err := cache.Get(httpURI.GetUri(), remote.GetSha256(), timeout, pullSecret)
 	if err != nil {
 		status = fetchFailure
 		wasmLog.Errorf("cannot fetch Wasm module %v: %v", remote.GetHttpUri().GetUri(), err)
@@ -196,3 +225,36 @@ func convert(resource *any.Any, cache Cache) (newExtensionConfig *any.Any, sendN
 	sendNack = false
 	return
 }
+
+// extractPullSecret returns the name of the image pull secret set in the VM environment, and removes it so it is not
+// exposed to the module.
+func extractPullSecret(vm *wasmv3.VmConfig) string {
+	envs := vm.GetEnvironmentVariables()
+	secret, ok := envs.GetKeyValues()[PullSecretEnv]
+	if !ok {
+		return ""
+	}
+	delete(envs.KeyValues, PullSecretEnv)
+	return secret
+}
+
+// resolvePullSecret returns the docker config JSON of the image pull secret mounted under the pull secrets directory,
+// or nil if name is empty.
+func resolvePullSecret(name string) ([]byte, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid image pull secret name %q", name)
+	}
+	for _, key := range []string{".dockerconfigjson", ".dockercfg"} {
+		secret, err := ioutil.ReadFile(filepath.Join(pullSecretsDir, name, key))
+		if err == nil {
+			return secret, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read image pull secret %q: %v", name, err)
+		}
+	}
+	return nil, fmt.Errorf("image pull secret %q is not mounted in %s", name, pullSecretsDir)
+}
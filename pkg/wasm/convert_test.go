@@ -16,7 +16,11 @@ package wasm
 
 import (
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -34,7 +38,7 @@ import (
 
 type mockCache struct{}
 
-func (c *mockCache) Get(downloadURL, checksum string, timeout time.Duration) (string, error) {
+func (c *mockCache) Get(downloadURL, checksum string, timeout time.Duration, pullSecret []byte) (string, error) {
 	url, _ := url.Parse(downloadURL)
 	query := url.Query()
 
@@ -44,12 +48,26 @@ func (c *mockCache) Get(downloadURL, checksum string, timeout time.Duration) (st
 	if errMsg != "" {
 		err = errors.New(errMsg)
 	}
+	if secret := query.Get("secret"); secret != string(pullSecret) {
+		err = fmt.Errorf("got pull secret %q, want %q", pullSecret, secret)
+	}
 
 	return module, err
 }
 func (c *mockCache) Cleanup() {}
 
 func TestWasmConvert(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "secret"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "secret", ".dockerconfigjson"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	origPullSecretsDir := pullSecretsDir
+	pullSecretsDir = dir
+	t.Cleanup(func() { pullSecretsDir = origPullSecretsDir })
+
 	cases := []struct {
 		name       string
 		input      []*core.TypedExtensionConfig
@@ -66,6 +84,36 @@ func TestWasmConvert(t *testing.T) {
 			},
 			wantNack: false,
 		},
+		{
+			name: "remote load with pull secret",
+			input: []*core.TypedExtensionConfig{
+				extensionConfigMap["remote-load-secret"],
+			},
+			wantOutput: []*core.TypedExtensionConfig{
+				extensionConfigMap["remote-load-secret-local-file"],
+			},
+			wantNack: false,
+		},
+		{
+			name: "remote load with missing pull secret",
+			input: []*core.TypedExtensionConfig{
+				extensionConfigMap["remote-load-missing-secret"],
+			},
+			wantOutput: []*core.TypedExtensionConfig{
+				extensionConfigMap["remote-load-missing-secret"],
+			},
+			wantNack: true,
+		},
+		{
+			name: "remote load with invalid pull secret name",
+			input: []*core.TypedExtensionConfig{
+				extensionConfigMap["remote-load-invalid-secret"],
+			},
+			wantOutput: []*core.TypedExtensionConfig{
+				extensionConfigMap["remote-load-invalid-secret"],
+			},
+			wantNack: true,
+		},
 		{
 			name: "remote load fail",
 			input: []*core.TypedExtensionConfig{
@@ -257,6 +305,78 @@ var extensionConfigMap = map[string]*core.TypedExtensionConfig{
 			},
 		},
 	}),
+	"remote-load-secret": buildTypedStructExtensionConfig("remote-load-secret", &wasm.Wasm{
+		Config: &v3.PluginConfig{
+			Vm: &v3.PluginConfig_VmConfig{
+				VmConfig: &v3.VmConfig{
+					Code: &core.AsyncDataSource{Specifier: &core.AsyncDataSource_Remote{
+						Remote: &core.RemoteDataSource{
+							HttpUri: &core.HttpUri{
+								Uri: "oci://test?module=test.wasm&secret=secret",
+							},
+						},
+					}},
+					EnvironmentVariables: &v3.EnvironmentVariables{
+						KeyValues: map[string]string{PullSecretEnv: "secret", "foo": "bar"},
+					},
+				},
+			},
+		},
+	}),
+	"remote-load-secret-local-file": buildWasmExtensionConfig("remote-load-secret", &wasm.Wasm{
+		Config: &v3.PluginConfig{
+			Vm: &v3.PluginConfig_VmConfig{
+				VmConfig: &v3.VmConfig{
+					Code: &core.AsyncDataSource{Specifier: &core.AsyncDataSource_Local{
+						Local: &core.DataSource{
+							Specifier: &core.DataSource_Filename{
+								Filename: "test.wasm",
+							},
+						},
+					}},
+					EnvironmentVariables: &v3.EnvironmentVariables{
+						KeyValues: map[string]string{"foo": "bar"},
+					},
+				},
+			},
+		},
+	}),
+	"remote-load-missing-secret": buildTypedStructExtensionConfig("remote-load-missing-secret", &wasm.Wasm{
+		Config: &v3.PluginConfig{
+			Vm: &v3.PluginConfig_VmConfig{
+				VmConfig: &v3.VmConfig{
+					Code: &core.AsyncDataSource{Specifier: &core.AsyncDataSource_Remote{
+						Remote: &core.RemoteDataSource{
+							HttpUri: &core.HttpUri{
+								Uri: "oci://test?module=test.wasm",
+							},
+						},
+					}},
+					EnvironmentVariables: &v3.EnvironmentVariables{
+						KeyValues: map[string]string{PullSecretEnv: "missing"},
+					},
+				},
+			},
+		},
+	}),
+	"remote-load-invalid-secret": buildTypedStructExtensionConfig("remote-load-invalid-secret", &wasm.Wasm{
+		Config: &v3.PluginConfig{
+			Vm: &v3.PluginConfig_VmConfig{
+				VmConfig: &v3.VmConfig{
+					Code: &core.AsyncDataSource{Specifier: &core.AsyncDataSource_Remote{
+						Remote: &core.RemoteDataSource{
+							HttpUri: &core.HttpUri{
+								Uri: "oci://test?module=test.wasm",
+							},
+						},
+					}},
+					EnvironmentVariables: &v3.EnvironmentVariables{
+						KeyValues: map[string]string{PullSecretEnv: "../secret"},
+					},
+				},
+			},
+		},
+	}),
 	"remote-load-fail": buildTypedStructExtensionConfig("remote-load-fail", &wasm.Wasm{
 		Config: &v3.PluginConfig{
 			Vm: &v3.PluginConfig_VmConfig{
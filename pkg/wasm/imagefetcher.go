@@ -0,0 +1,571 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff"
+)
+
+// Media types of the manifests and layers understood by the ImageFetcher.
+const (
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	// Wasm artifact layout, as produced by `wasme` and `oras`: a single layer holding the raw binary.
+	mediaTypeWasmLayer = "application/vnd.module.wasm.content.layer.v1+wasm"
+	// Docker image layout: a (possibly compressed) tar layer containing the module.
+	mediaTypeOCILayer          = "application/vnd.oci.image.layer.v1.tar"
+	mediaTypeOCILayerGzip      = "application/vnd.oci.image.layer.v1.tar+gzip"
+	mediaTypeDockerLayer       = "application/vnd.docker.image.rootfs.diff.tar"
+	mediaTypeDockerLayerGzip   = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+	dockerImageWasmModuleName  = "plugin.wasm"
+	defaultDockerHubRegistry   = "docker.io"
+	dockerHubRegistryEndpoint  = "registry-1.docker.io"
+	defaultImageTag            = "latest"
+	maxImageFetchAttempts      = 5
+	maxWasmModuleSizeInBytes   = 256 << 20
+	manifestAcceptHeaderValues = mediaTypeOCIManifest + "," + mediaTypeDockerManifest + "," + mediaTypeOCIIndex + "," + mediaTypeDockerList
+)
+
+// ImageFetcherOption configures an ImageFetcher.
+type ImageFetcherOption struct {
+	// PullSecret is a docker config JSON, in either the `.dockerconfigjson` or the legacy `.dockercfg`
+	// format, holding the credentials used to authenticate against registries.
+	PullSecret []byte
+	// Insecure allows fetching from registries over plain HTTP. Registries on the loopback interface
+	// are always accessed over plain HTTP.
+	Insecure bool
+}
+
+// ImageFetcher fetches Wasm modules stored as images in OCI compatible registries.
+type ImageFetcher struct {
+	client      *http.Client
+	credentials map[string]registryCredential
+	insecure    bool
+}
+
+// registryCredential holds the credentials of a single registry.
+type registryCredential struct {
+	username string
+	password string
+}
+
+// imageReference is a parsed image reference, such as `oci://gcr.io/project/filter:v1`.
+type imageReference struct {
+	registry   string
+	repository string
+	// reference is either a tag or a digest.
+	reference string
+}
+
+// descriptor is the OCI content descriptor.
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// manifest covers both image manifests and image indexes, in the OCI and Docker flavours.
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    descriptor   `json:"config"`
+	Layers    []descriptor `json:"layers"`
+	Manifests []descriptor `json:"manifests"`
+}
+
+// NewImageFetcher creates an ImageFetcher authenticating with the credentials of the given pull secret.
+func NewImageFetcher(opt ImageFetcherOption) (*ImageFetcher, error) {
+	creds, err := parsePullSecret(opt.PullSecret)
+	if err != nil {
+		return nil, err
+	}
+	return &ImageFetcher{
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		credentials: creds,
+		insecure:    opt.Insecure,
+	}, nil
+}
+
+// Fetch downloads the Wasm module of an image referenced by an `oci://` URL.
+func (f *ImageFetcher) Fetch(imageURL string, timeout time.Duration) ([]byte, error) {
+	ref, err := parseImageReference(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	c := f.client
+	if timeout != 0 {
+		c = &http.Client{
+			Timeout: timeout,
+		}
+	}
+	s := &registrySession{
+		fetcher: f,
+		client:  c,
+		ref:     ref,
+	}
+
+	m, err := s.resolveManifest()
+	if err != nil {
+		return nil, err
+	}
+	return s.extractWasmModule(m)
+}
+
+// registrySession holds the state of a single image fetch, such as the bearer token of the registry.
+type registrySession struct {
+	fetcher *ImageFetcher
+	client  *http.Client
+	ref     imageReference
+	token   string
+}
+
+// resolveManifest fetches the image manifest, following image indexes to the first image manifest.
+func (s *registrySession) resolveManifest() (*manifest, error) {
+	reference := s.ref.reference
+	// Bound the number of indexes followed, nested indexes are legal but should not be deep.
+	for i := 0; i < 3; i++ {
+		b, mediaType, err := s.get("manifests/"+reference, manifestAcceptHeaderValues, reference)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch manifest of image %v: %v", s.ref, err)
+		}
+		m := &manifest{}
+		if err := json.Unmarshal(b, m); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest of image %v: %v", s.ref, err)
+		}
+		if m.MediaType == "" {
+			m.MediaType = mediaType
+		}
+		switch m.MediaType {
+		case mediaTypeOCIIndex, mediaTypeDockerList:
+			// Wasm modules are platform independent, pick the first entry.
+			if len(m.Manifests) == 0 {
+				return nil, fmt.Errorf("image index of %v has no manifests", s.ref)
+			}
+			reference = m.Manifests[0].Digest
+			continue
+		default:
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("too many nested image indexes in image %v", s.ref)
+}
+
+// extractWasmModule returns the Wasm module held by the layers of an image.
+func (s *registrySession) extractWasmModule(m *manifest) ([]byte, error) {
+	// Wasm artifact layout.
+	for _, l := range m.Layers {
+		if l.MediaType == mediaTypeWasmLayer {
+			b, _, err := s.get("blobs/"+l.Digest, "", l.Digest)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch layer %v of image %v: %v", l.Digest, s.ref, err)
+			}
+			return b, nil
+		}
+	}
+	// Docker image layout. The module may be in any layer, search from the top most one.
+	for i := len(m.Layers) - 1; i >= 0; i-- {
+		l := m.Layers[i]
+		var compressed bool
+		switch l.MediaType {
+		case mediaTypeOCILayerGzip, mediaTypeDockerLayerGzip:
+			compressed = true
+		case mediaTypeOCILayer, mediaTypeDockerLayer:
+		default:
+			continue
+		}
+		b, _, err := s.get("blobs/"+l.Digest, "", l.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch layer %v of image %v: %v", l.Digest, s.ref, err)
+		}
+		module, err := extractFromTarLayer(b, compressed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract layer %v of image %v: %v", l.Digest, s.ref, err)
+		}
+		if module != nil {
+			return module, nil
+		}
+	}
+	return nil, fmt.Errorf("image %v does not contain a Wasm module", s.ref)
+}
+
+// extractFromTarLayer returns the Wasm module stored in a tar layer, or nil if there is none.
+func extractFromTarLayer(b []byte, compressed bool) ([]byte, error) {
+	var r io.Reader = bytes.NewReader(b)
+	if compressed {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg || path.Clean("/"+h.Name) != "/"+dockerImageWasmModuleName {
+			continue
+		}
+		return readAllWithLimit(tr, maxWasmModuleSizeInBytes)
+	}
+}
+
+// errContentTooLarge is returned when the content of a wasm image exceeds the size limit.
+var errContentTooLarge = errors.New("wasm image content exceeds the size limit")
+
+// readAllWithLimit reads r until EOF, and fails if r holds more than limit bytes.
+func readAllWithLimit(r io.Reader, limit int64) ([]byte, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, fmt.Errorf("%w of %d bytes", errContentTooLarge, limit)
+	}
+	return b, nil
+}
+
+// get fetches an object of the repository, authenticating and retrying as needed. If digest is a
+// sha256 digest, the content is verified against it.
+func (s *registrySession) get(object, accept, digest string) ([]byte, string, error) {
+	u := fmt.Sprintf("%s://%s/v2/%s/%s", s.fetcher.scheme(s.ref.registry), registryEndpoint(s.ref.registry),
+		s.ref.repository, object)
+	b := backoff.NewExponentialBackOff()
+	var lastError error
+	for attempts := 0; attempts < maxImageFetchAttempts; attempts++ {
+		body, mediaType, retry, err := s.doGet(u, accept)
+		if err == nil {
+			if err := verifyDigest(body, digest); err != nil {
+				return nil, "", err
+			}
+			return body, mediaType, nil
+		}
+		lastError = err
+		if !retry {
+			break
+		}
+		wasmLog.Debugf("wasm image fetch request failed: %v", err)
+		time.Sleep(b.NextBackOff())
+	}
+	return nil, "", lastError
+}
+
+// doGet issues a single GET request, returning the body, its media type and whether the request can be retried.
+func (s *registrySession) doGet(u, accept string) ([]byte, string, bool, error) {
+	resp, err := s.request(u, accept)
+	if err != nil {
+		return nil, "", true, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && s.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := s.authenticate(challenge); err != nil {
+			return nil, "", false, err
+		}
+		if resp, err = s.request(u, accept); err != nil {
+			return nil, "", true, err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		wasmLog.Debugf("wasm image fetch failed: status code %v, body %v", resp.StatusCode, string(body))
+		return nil, "", retryable(resp.StatusCode), fmt.Errorf("wasm image fetch request failed: status code %v", resp.StatusCode)
+	}
+	body, err := readAllWithLimit(resp.Body, maxWasmModuleSizeInBytes)
+	if err != nil {
+		return nil, "", !errors.Is(err, errContentTooLarge), err
+	}
+	return body, resp.Header.Get("Content-Type"), false, nil
+}
+
+func (s *registrySession) request(u, accept string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", s.token)
+	}
+	return s.client.Do(req)
+}
+
+// authenticate handles the authentication challenge returned by the registry, following the
+// Docker registry token authentication specification for bearer challenges.
+func (s *registrySession) authenticate(challenge string) error {
+	cred, hasCred := s.fetcher.credentials[s.ref.registry]
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if !hasCred {
+			return fmt.Errorf("registry %v requires authentication, but no pull secret credential was found", s.ref.registry)
+		}
+		s.token = "Basic " + basicAuth(cred.username, cred.password)
+		return nil
+	case "bearer":
+		realm := params["realm"]
+		if realm == "" {
+			return fmt.Errorf("registry %v returned a bearer challenge without realm", s.ref.registry)
+		}
+		tokenURL, err := url.Parse(realm)
+		if err != nil {
+			return fmt.Errorf("invalid token realm %q: %v", realm, err)
+		}
+		q := tokenURL.Query()
+		if service := params["service"]; service != "" {
+			q.Set("service", service)
+		}
+		scope := params["scope"]
+		if scope == "" {
+			scope = fmt.Sprintf("repository:%s:pull", s.ref.repository)
+		}
+		q.Set("scope", scope)
+		tokenURL.RawQuery = q.Encode()
+		req, err := http.NewRequest(http.MethodGet, tokenURL.String(), nil)
+		if err != nil {
+			return err
+		}
+		if hasCred {
+			req.SetBasicAuth(cred.username, cred.password)
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to fetch registry token: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed to fetch registry token: status code %v", resp.StatusCode)
+		}
+		tok := struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+			return fmt.Errorf("failed to parse registry token: %v", err)
+		}
+		if tok.Token == "" {
+			tok.Token = tok.AccessToken
+		}
+		if tok.Token == "" {
+			return errors.New("registry token response has no token")
+		}
+		s.token = "Bearer " + tok.Token
+		return nil
+	default:
+		return fmt.Errorf("registry %v returned unsupported authentication challenge %q", s.ref.registry, challenge)
+	}
+}
+
+// parseChallenge parses a WWW-Authenticate header, such as `Bearer realm="https://auth",service="registry"`.
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	challenge = strings.TrimSpace(challenge)
+	idx := strings.IndexByte(challenge, ' ')
+	if idx < 0 {
+		return challenge, params
+	}
+	scheme, rest := challenge[:idx], challenge[idx+1:]
+	for rest != "" {
+		kv := strings.SplitN(rest, "=", 2)
+		if len(kv) != 2 {
+			break
+		}
+		key := strings.TrimSpace(kv[0])
+		rest = strings.TrimSpace(kv[1])
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if end := strings.IndexByte(rest, ','); end >= 0 {
+			value, rest = rest[:end], rest[end:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[strings.ToLower(key)] = value
+		rest = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+	}
+	return scheme, params
+}
+
+// verifyDigest checks that content matches a sha256 digest. Digests of other algorithms, and tags, are not verified.
+func verifyDigest(b []byte, digest string) error {
+	if !strings.HasPrefix(digest, "sha256:") {
+		return nil
+	}
+	got := fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+	if got != digest {
+		return fmt.Errorf("content digest %v does not match expected digest %v", got, digest)
+	}
+	return nil
+}
+
+func (f *ImageFetcher) scheme(registry string) string {
+	if f.insecure {
+		return "http"
+	}
+	host := registry
+	if h, _, err := net.SplitHostPort(registry); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return "http"
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return "http"
+	}
+	return "https"
+}
+
+func registryEndpoint(registry string) string {
+	if registry == defaultDockerHubRegistry {
+		return dockerHubRegistryEndpoint
+	}
+	return registry
+}
+
+// parseImageReference parses an `oci://` URL into an image reference.
+func parseImageReference(imageURL string) (imageReference, error) {
+	ref := imageReference{}
+	name := strings.TrimPrefix(imageURL, "oci://")
+	if name == imageURL || name == "" {
+		return ref, fmt.Errorf("invalid image URL %q", imageURL)
+	}
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.reference = name[i+1:]
+		name = name[:i]
+		if !strings.Contains(ref.reference, ":") {
+			return ref, fmt.Errorf("invalid digest in image URL %q", imageURL)
+		}
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.reference = name[i+1:]
+		name = name[:i]
+	}
+	if ref.reference == "" {
+		ref.reference = defaultImageTag
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.registry, ref.repository = parts[0], parts[1]
+	} else {
+		ref.registry, ref.repository = defaultDockerHubRegistry, name
+	}
+	if ref.registry == defaultDockerHubRegistry && !strings.Contains(ref.repository, "/") {
+		ref.repository = "library/" + ref.repository
+	}
+	if ref.repository == "" {
+		return ref, fmt.Errorf("image URL %q has no repository", imageURL)
+	}
+	return ref, nil
+}
+
+func (r imageReference) String() string {
+	sep := ":"
+	if strings.Contains(r.reference, ":") {
+		sep = "@"
+	}
+	return r.registry + "/" + r.repository + sep + r.reference
+}
+
+// parsePullSecret extracts the registry credentials from a docker config JSON.
+func parsePullSecret(secret []byte) (map[string]registryCredential, error) {
+	creds := map[string]registryCredential{}
+	if len(secret) == 0 {
+		return creds, nil
+	}
+	type authEntry struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	config := struct {
+		Auths map[string]authEntry `json:"auths"`
+	}{}
+	if err := json.Unmarshal(secret, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse pull secret: %v", err)
+	}
+	if config.Auths == nil {
+		// Legacy .dockercfg format, which is the auths map itself.
+		if err := json.Unmarshal(secret, &config.Auths); err != nil {
+			return nil, fmt.Errorf("failed to parse pull secret: %v", err)
+		}
+	}
+	for server, entry := range config.Auths {
+		cred := registryCredential{username: entry.Username, password: entry.Password}
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode pull secret auth of %v: %v", server, err)
+			}
+			kv := strings.SplitN(string(decoded), ":", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid pull secret auth of %v", server)
+			}
+			cred.username, cred.password = kv[0], kv[1]
+		}
+		creds[normalizeRegistry(server)] = cred
+	}
+	return creds, nil
+}
+
+// normalizeRegistry turns a docker config server entry, which may be a URL, into a registry host.
+func normalizeRegistry(server string) string {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	if i := strings.Index(server, "/"); i >= 0 {
+		server = server[:i]
+	}
+	switch server {
+	case "index.docker.io", dockerHubRegistryEndpoint:
+		return defaultDockerHubRegistry
+	}
+	return server
+}
+
+func basicAuth(username, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+}
@@ -0,0 +1,409 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRegistry is a minimal stand-in for an OCI distribution registry.
+type fakeRegistry struct {
+	t *testing.T
+	// blobs and manifests are keyed by "<repository>/<digest or tag>".
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	// If set, requests must carry a bearer token obtained with these credentials.
+	username string
+	password string
+	requests int
+}
+
+const fakeRegistryToken = "fake-token"
+
+func newFakeRegistry(t *testing.T) (*fakeRegistry, *httptest.Server) {
+	r := &fakeRegistry{
+		t:         t,
+		blobs:     map[string][]byte{},
+		manifests: map[string][]byte{},
+	}
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	return r, ts
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.requests++
+	if req.URL.Path == "/token" {
+		u, p, ok := req.BasicAuth()
+		if !ok || u != r.username || p != r.password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"token": %q}`, fakeRegistryToken)
+		return
+	}
+	if r.username != "" && req.Header.Get("Authorization") != "Bearer "+fakeRegistryToken {
+		w.Header().Set("WWW-Authenticate",
+			fmt.Sprintf(`Bearer realm="http://%s/token",service="fake",scope="repository:test:pull"`, req.Host))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	p := strings.TrimPrefix(req.URL.Path, "/v2/")
+	if i := strings.Index(p, "/manifests/"); i >= 0 {
+		m, ok := r.manifests[p[:i]+"/"+p[i+len("/manifests/"):]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mt := struct {
+			MediaType string `json:"mediaType"`
+		}{}
+		_ = json.Unmarshal(m, &mt)
+		w.Header().Set("Content-Type", mt.MediaType)
+		_, _ = w.Write(m)
+		return
+	}
+	if i := strings.Index(p, "/blobs/"); i >= 0 {
+		b, ok := r.blobs[p[:i]+"/"+p[i+len("/blobs/"):]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(b)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func digestOf(b []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+}
+
+// addBlob stores a blob, returning its descriptor.
+func (r *fakeRegistry) addBlob(repo, mediaType string, b []byte) descriptor {
+	d := digestOf(b)
+	r.blobs[repo+"/"+d] = b
+	return descriptor{MediaType: mediaType, Digest: d, Size: int64(len(b))}
+}
+
+// addManifest stores a manifest under its digest and the given tag, returning its descriptor.
+func (r *fakeRegistry) addManifest(repo, tag string, m manifest) descriptor {
+	b, err := json.Marshal(m)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	d := digestOf(b)
+	r.manifests[repo+"/"+d] = b
+	if tag != "" {
+		r.manifests[repo+"/"+tag] = b
+	}
+	return descriptor{MediaType: m.MediaType, Digest: d, Size: int64(len(b))}
+}
+
+func tarLayer(t *testing.T, compress bool, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	var gz *gzip.Writer
+	var tw *tar.Writer
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gz)
+	} else {
+		tw = tar.NewWriter(&buf)
+	}
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestImageFetcher(t *testing.T) {
+	wasmBinary := []byte("\x00asm\x01\x00\x00\x00")
+	reg, ts := newFakeRegistry(t)
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	// Wasm artifact layout
+	config := reg.addBlob("artifact", "application/vnd.module.wasm.config.v1+json", []byte("{}"))
+	wasmLayer := reg.addBlob("artifact", mediaTypeWasmLayer, wasmBinary)
+	artifact := reg.addManifest("artifact", "v1", manifest{
+		MediaType: mediaTypeOCIManifest,
+		Config:    config,
+		Layers:    []descriptor{wasmLayer},
+	})
+
+	// Docker image layout, with the module on top of a base layer
+	imgConfig := reg.addBlob("image", "application/vnd.docker.container.image.v1+json", []byte("{}"))
+	base := reg.addBlob("image", mediaTypeDockerLayerGzip, tarLayer(t, true, map[string][]byte{"etc/hosts": []byte("")}))
+	module := reg.addBlob("image", mediaTypeDockerLayerGzip, tarLayer(t, true, map[string][]byte{"./plugin.wasm": wasmBinary}))
+	image := reg.addManifest("image", "latest", manifest{
+		MediaType: mediaTypeDockerManifest,
+		Config:    imgConfig,
+		Layers:    []descriptor{base, module},
+	})
+	reg.addManifest("image", "index", manifest{
+		MediaType: mediaTypeOCIIndex,
+		Manifests: []descriptor{image},
+	})
+
+	// Image without a module
+	empty := reg.addBlob("empty", mediaTypeOCILayer, tarLayer(t, false, map[string][]byte{"README": []byte("")}))
+	reg.addManifest("empty", "v1", manifest{MediaType: mediaTypeOCIManifest, Layers: []descriptor{empty}})
+
+	// Image whose layer content does not match its digest
+	reg.addManifest("corrupted", "v1", manifest{
+		MediaType: mediaTypeOCIManifest,
+		Layers:    []descriptor{{MediaType: mediaTypeWasmLayer, Digest: wasmLayer.Digest}},
+	})
+	reg.blobs["corrupted/"+wasmLayer.Digest] = []byte("corrupted")
+	wrongDigest := "sha256:" + strings.Repeat("0", 64)
+	reg.manifests["artifact/"+wrongDigest] = reg.manifests["artifact/v1"]
+
+	cases := []struct {
+		name       string
+		url        string
+		want       []byte
+		wantErrMsg string
+	}{
+		{
+			name: "wasm artifact by tag",
+			url:  fmt.Sprintf("oci://%s/artifact:v1", host),
+			want: wasmBinary,
+		},
+		{
+			name: "wasm artifact by digest",
+			url:  fmt.Sprintf("oci://%s/artifact@%s", host, artifact.Digest),
+			want: wasmBinary,
+		},
+		{
+			name: "docker image with default tag",
+			url:  fmt.Sprintf("oci://%s/image", host),
+			want: wasmBinary,
+		},
+		{
+			name: "image index",
+			url:  fmt.Sprintf("oci://%s/image:index", host),
+			want: wasmBinary,
+		},
+		{
+			name:       "no module",
+			url:        fmt.Sprintf("oci://%s/empty:v1", host),
+			wantErrMsg: "does not contain a Wasm module",
+		},
+		{
+			name:       "digest mismatch",
+			url:        fmt.Sprintf("oci://%s/corrupted:v1", host),
+			wantErrMsg: "does not match expected digest",
+		},
+		{
+			name:       "manifest digest mismatch",
+			url:        fmt.Sprintf("oci://%s/artifact@%s", host, wrongDigest),
+			wantErrMsg: "does not match expected digest",
+		},
+		{
+			name:       "not found",
+			url:        fmt.Sprintf("oci://%s/artifact:v2", host),
+			wantErrMsg: "status code 404",
+		},
+	}
+	fetcher, err := NewImageFetcher(ImageFetcherOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := fetcher.Fetch(c.url, 0)
+			if c.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErrMsg) {
+					t.Fatalf("got error %v, want error containing %q", err, c.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, c.want) {
+				t.Fatalf("got module %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestImageFetcherAuth(t *testing.T) {
+	wasmBinary := []byte("\x00asm\x01\x00\x00\x00")
+	reg, ts := newFakeRegistry(t)
+	reg.username, reg.password = "user", "pass"
+	host := strings.TrimPrefix(ts.URL, "http://")
+	layer := reg.addBlob("test", mediaTypeWasmLayer, wasmBinary)
+	reg.addManifest("test", "v1", manifest{MediaType: mediaTypeOCIManifest, Layers: []descriptor{layer}})
+	url := fmt.Sprintf("oci://%s/test:v1", host)
+
+	secret := func(user, pass string) []byte {
+		return []byte(fmt.Sprintf(`{"auths": {%q: {"auth": %q}}}`, host, base64.StdEncoding.EncodeToString([]byte(user+":"+pass))))
+	}
+	cases := []struct {
+		name    string
+		secret  []byte
+		wantErr bool
+	}{
+		{name: "valid credentials", secret: secret("user", "pass")},
+		{name: "legacy format", secret: []byte(fmt.Sprintf(`{%q: {"username": "user", "password": "pass"}}`, "http://"+host))},
+		{name: "invalid credentials", secret: secret("user", "wrong"), wantErr: true},
+		{name: "no credentials", wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fetcher, err := NewImageFetcher(ImageFetcherOption{PullSecret: c.secret})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := fetcher.Fetch(url, time.Second)
+			if c.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, wasmBinary) {
+				t.Fatalf("got module %q, want %q", got, wasmBinary)
+			}
+		})
+	}
+
+	if _, err := NewImageFetcher(ImageFetcherOption{PullSecret: []byte("invalid")}); err == nil {
+		t.Fatal("expected error for invalid pull secret")
+	}
+}
+
+func TestParseImageReference(t *testing.T) {
+	cases := []struct {
+		url     string
+		want    imageReference
+		wantErr bool
+	}{
+		{
+			url:  "oci://gcr.io/project/filter:v1",
+			want: imageReference{registry: "gcr.io", repository: "project/filter", reference: "v1"},
+		},
+		{
+			url:  "oci://localhost:5000/filter",
+			want: imageReference{registry: "localhost:5000", repository: "filter", reference: "latest"},
+		},
+		{
+			url:  "oci://filter",
+			want: imageReference{registry: "docker.io", repository: "library/filter", reference: "latest"},
+		},
+		{
+			url:  "oci://org/filter@sha256:abc",
+			want: imageReference{registry: "docker.io", repository: "org/filter", reference: "sha256:abc"},
+		},
+		{url: "http://gcr.io/filter", wantErr: true},
+		{url: "oci://", wantErr: true},
+		{url: "oci://gcr.io/filter@abc", wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.url, func(t *testing.T) {
+			got, err := parseImageReference(c.url)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("got error %v, wantErr %v", err, c.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestReadAllWithLimit(t *testing.T) {
+	cases := []struct {
+		content string
+		wantErr bool
+	}{
+		{content: ""},
+		{content: "wasm"},
+		{content: "wasm module", wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.content, func(t *testing.T) {
+			got, err := readAllWithLimit(strings.NewReader(c.content), int64(len("wasm")))
+			if (err != nil) != c.wantErr {
+				t.Fatalf("got error %v, wantErr %v", err, c.wantErr)
+			}
+			if err == nil && string(got) != c.content {
+				t.Fatalf("got %q, want %q", got, c.content)
+			}
+		})
+	}
+}
+
+func TestWasmCacheImage(t *testing.T) {
+	wasmBinary := []byte("\x00asm\x01\x00\x00\x00")
+	reg, ts := newFakeRegistry(t)
+	layer := reg.addBlob("test", mediaTypeWasmLayer, wasmBinary)
+	reg.addManifest("test", "v1", manifest{MediaType: mediaTypeOCIManifest, Layers: []descriptor{layer}})
+	url := fmt.Sprintf("oci://%s/test:v1", strings.TrimPrefix(ts.URL, "http://"))
+	checksum := fmt.Sprintf("%x", sha256.Sum256(wasmBinary))
+
+	tmpDir := t.TempDir()
+	cache := NewLocalFileCache(tmpDir, DefaultWasmModulePurgeInterval, DefaultWasmModuleExpiry)
+	defer close(cache.stopChan)
+
+	want := filepath.Join(tmpDir, checksum+".wasm")
+	for i := 0; i < 2; i++ {
+		got, err := cache.Get(url, checksum, 0, nil)
+		if err != nil {
+			t.Fatalf("failed to fetch Wasm module: %v", err)
+		}
+		if got != want {
+			t.Fatalf("got path %v, want %v", got, want)
+		}
+	}
+	// The second lookup must be served from the cache: one manifest and one blob request.
+	if reg.requests != 2 {
+		t.Fatalf("got %v registry requests, want 2", reg.requests)
+	}
+
+	if _, err := cache.Get(url, "wrongchecksum", 0, nil); err == nil ||
+		!strings.HasPrefix(err.Error(), fmt.Sprintf("module downloaded from %v has checksum", url)) {
+		t.Fatalf("got error %v, want checksum mismatch", err)
+	}
+}
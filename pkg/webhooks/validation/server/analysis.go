@@ -0,0 +1,196 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+	multierror "github.com/hashicorp/go-multierror"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// SelectAnalyzers returns the analyzers with the given names. The analyzers must only read collections of the
+// schemas, as the webhook has no view of the other resources of the cluster.
+func SelectAnalyzers(names []string, schemas collection.Schemas) ([]analysis.Analyzer, error) {
+	all := map[string]analysis.Analyzer{}
+	for _, a := range analyzers.All() {
+		all[a.Metadata().Name] = a
+	}
+	var errs error
+	var selected []analysis.Analyzer
+	for _, name := range names {
+		a, f := all[name]
+		if !f {
+			errs = multierror.Append(errs, fmt.Errorf("unknown analyzer %q", name))
+			continue
+		}
+		supported := true
+		for _, in := range a.Metadata().Inputs {
+			if _, f := schemas.Find(in.String()); !f {
+				errs = multierror.Append(errs, fmt.Errorf("analyzer %q reads unsupported collection %s", name, in))
+				supported = false
+				break
+			}
+		}
+		if supported {
+			selected = append(selected, a)
+		}
+	}
+	return selected, errs
+}
+
+// analyze runs the analyzers reading the collection of the incoming resource, against the configuration of the
+// store with the incoming resource added or updated. Only the messages reported for the incoming resource are
+// returned: existing issues of other resources must not block unrelated changes.
+func (wh *Webhook) analyze(s collection.Schema, cfg config.Config) (warnings []string, errs error) {
+	ctx := &analysisContext{
+		store:    wh.configStore,
+		schemas:  wh.schemas,
+		incoming: toInstance(s, cfg),
+		col:      s.Name(),
+	}
+	for _, a := range wh.analyzers {
+		if !readsCollection(a, s.Name()) {
+			continue
+		}
+		a.Analyze(ctx)
+	}
+	for _, m := range ctx.messages {
+		switch m.Type.Level() {
+		case diag.Error:
+			errs = multierror.Append(errs, fmt.Errorf("%s", m.String()))
+		case diag.Warning:
+			warnings = append(warnings, m.String())
+		}
+	}
+	return warnings, errs
+}
+
+func readsCollection(a analysis.Analyzer, col collection.Name) bool {
+	for _, in := range a.Metadata().Inputs {
+		if in == col {
+			return true
+		}
+	}
+	return false
+}
+
+// analysisContext implements analysis.Context over a config store.
+type analysisContext struct {
+	store   model.ConfigStore
+	schemas collection.Schemas
+
+	// incoming is the resource being validated, in the collection col.
+	incoming *resource.Instance
+	col      collection.Name
+
+	messages []diag.Message
+}
+
+var _ analysis.Context = &analysisContext{}
+
+func (c *analysisContext) isIncoming(col collection.Name, name resource.FullName) bool {
+	return col == c.col && name == c.incoming.Metadata.FullName
+}
+
+// Report implements analysis.Context
+func (c *analysisContext) Report(col collection.Name, m diag.Message) {
+	if m.Resource != nil && c.isIncoming(col, m.Resource.Metadata.FullName) {
+		c.messages = append(c.messages, m)
+	}
+}
+
+// Find implements analysis.Context
+func (c *analysisContext) Find(col collection.Name, name resource.FullName) *resource.Instance {
+	if c.isIncoming(col, name) {
+		return c.incoming
+	}
+	s, f := c.schemas.Find(col.String())
+	if !f || c.store == nil {
+		return nil
+	}
+	cfg := c.store.Get(s.Resource().GroupVersionKind(), name.Name.String(), name.Namespace.String())
+	if cfg == nil {
+		return nil
+	}
+	return toInstance(s, *cfg)
+}
+
+// Exists implements analysis.Context
+func (c *analysisContext) Exists(col collection.Name, name resource.FullName) bool {
+	return c.Find(col, name) != nil
+}
+
+// ForEach implements analysis.Context
+func (c *analysisContext) ForEach(col collection.Name, fn analysis.IteratorFn) {
+	if col == c.col && !fn(c.incoming) {
+		return
+	}
+	s, f := c.schemas.Find(col.String())
+	if !f || c.store == nil {
+		return
+	}
+	configs, err := c.store.List(s.Resource().GroupVersionKind(), model.NamespaceAll)
+	if err != nil {
+		scope.Warnf("failed to list %v for analysis: %v", col, err)
+		return
+	}
+	for _, cfg := range configs {
+		r := toInstance(s, cfg)
+		if c.isIncoming(col, r.Metadata.FullName) {
+			// The incoming resource replaces the stored one.
+			continue
+		}
+		if !fn(r) {
+			return
+		}
+	}
+}
+
+// Canceled implements analysis.Context
+func (c *analysisContext) Canceled() bool {
+	return false
+}
+
+func toInstance(s collection.Schema, cfg config.Config) *resource.Instance {
+	name := resource.NewFullName(resource.Namespace(cfg.Namespace), resource.LocalName(cfg.Name))
+	msg, _ := cfg.Spec.(proto.Message)
+	return &resource.Instance{
+		Metadata: resource.Metadata{
+			Schema:      s.Resource(),
+			FullName:    name,
+			CreateTime:  cfg.CreationTimestamp,
+			Version:     resource.Version(cfg.ResourceVersion),
+			Labels:      cfg.Labels,
+			Annotations: cfg.Annotations,
+		},
+		Message: msg,
+		Origin: &rt.Origin{
+			Collection: s.Name(),
+			Kind:       s.Resource().Kind(),
+			FullName:   name,
+			Version:    resource.Version(cfg.ResourceVersion),
+		},
+	}
+}
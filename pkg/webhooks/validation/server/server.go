@@ -30,7 +30,9 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 
+	"istio.io/istio/galley/pkg/config/analysis"
 	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/config/schema/resource"
 	"istio.io/istio/pkg/config/validation"
@@ -78,6 +80,14 @@ type Options struct {
 
 	// Use an existing mux instead of creating our own.
 	Mux *http.ServeMux
+
+	// Analyzers are run on the created and updated resources, against the configuration of ConfigStore. Errors
+	// reported for the resource deny the request, warnings are returned as admission warnings.
+	// See SelectAnalyzers.
+	Analyzers []analysis.Analyzer
+
+	// ConfigStore is the configuration of the cluster the analyzers run against.
+	ConfigStore model.ConfigStore
 }
 
 // String produces a stringified version of the arguments for debugging.
@@ -102,6 +112,9 @@ type Webhook struct {
 	// pilot
 	schemas      collection.Schemas
 	domainSuffix string
+
+	analyzers   []analysis.Analyzer
+	configStore model.ConfigStore
 }
 
 // New creates a new instance of the admission webhook server.
@@ -113,6 +126,8 @@ func New(o Options) (*Webhook, error) {
 	wh := &Webhook{
 		schemas:      o.Schemas,
 		domainSuffix: o.DomainSuffix,
+		analyzers:    o.Analyzers,
+		configStore:  o.ConfigStore,
 	}
 
 	o.Mux.HandleFunc("/validate", wh.serveValidate)
@@ -257,8 +272,22 @@ func (wh *Webhook) validate(request *kube.AdmissionRequest) *kube.AdmissionRespo
 		return toAdmissionResponse(err)
 	}
 
+	kubeWarnings := toKubeWarnings(warnings)
+	if len(wh.analyzers) > 0 {
+		if out.Namespace == "" && !s.Resource().IsClusterScoped() {
+			out.Namespace = request.Namespace
+		}
+		analysisWarnings, err := wh.analyze(s, *out)
+		if err != nil {
+			scope.Infof("configuration failed analysis: %v", err)
+			reportValidationFailed(request, reasonAnalysisError)
+			return toAdmissionResponse(fmt.Errorf("configuration failed analysis: %v", err))
+		}
+		kubeWarnings = append(kubeWarnings, analysisWarnings...)
+	}
+
 	reportValidationPass(request)
-	return &kube.AdmissionResponse{Allowed: true, Warnings: toKubeWarnings(warnings)}
+	return &kube.AdmissionResponse{Allowed: true, Warnings: kubeWarnings}
 }
 
 func toKubeWarnings(warn validation.Warning) []string {
@@ -34,7 +34,11 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/memory"
+	istioconfig "istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/test/config"
 	"istio.io/istio/pkg/testcerts"
@@ -227,6 +231,124 @@ func TestAdmitPilot(t *testing.T) {
 	}
 }
 
+func TestAdmitAnalysis(t *testing.T) {
+	store := memory.Make(collections.Istio)
+	for _, cfg := range []istioconfig.Config{
+		{
+			Meta: istioconfig.Meta{GroupVersionKind: gvk.DestinationRule, Name: "reviews", Namespace: "default"},
+			Spec: &networking.DestinationRule{Host: "reviews", Subsets: []*networking.Subset{{Name: "v1"}}},
+		},
+		{
+			Meta: istioconfig.Meta{GroupVersionKind: gvk.Gateway, Name: "gw", Namespace: "default"},
+			Spec: &networking.Gateway{Servers: []*networking.Server{{
+				Port:  &networking.Port{Number: 80, Name: "http", Protocol: "HTTP"},
+				Hosts: []string{"a.example.com"},
+			}}},
+		},
+		{
+			// An existing issue, which must not block unrelated changes.
+			Meta: istioconfig.Meta{GroupVersionKind: gvk.VirtualService, Name: "broken", Namespace: "default"},
+			Spec: &networking.VirtualService{Hosts: []string{"ratings"}, Http: []*networking.HTTPRoute{{
+				Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "ratings", Subset: "v1"}}},
+			}}},
+		},
+	} {
+		if _, err := store.Create(cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	analyzers, err := SelectAnalyzers([]string{"virtualservice.DestinationRuleAnalyzer", "virtualservice.GatewayAnalyzer"},
+		collections.Istio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wh, err := New(Options{
+		DomainSuffix: testDomainSuffix,
+		Schemas:      collections.Istio,
+		Mux:          http.NewServeMux(),
+		Analyzers:    analyzers,
+		ConfigStore:  store,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	virtualService := func(spec string) []byte {
+		return []byte(`{"apiVersion": "networking.istio.io/v1alpha3", "kind": "VirtualService",
+"metadata": {"name": "reviews", "namespace": "default"}, "spec": ` + spec + `}`)
+	}
+	cases := []struct {
+		name         string
+		kind         string
+		raw          []byte
+		allowed      bool
+		wantWarnings int
+	}{
+		{
+			name:    "existing subset",
+			kind:    "VirtualService",
+			raw:     virtualService(`{"hosts": ["reviews"], "http": [{"route": [{"destination": {"host": "reviews", "subset": "v1"}}]}]}`),
+			allowed: true,
+		},
+		{
+			name:    "missing subset",
+			kind:    "VirtualService",
+			raw:     virtualService(`{"hosts": ["reviews"], "http": [{"route": [{"destination": {"host": "reviews", "subset": "v2"}}]}]}`),
+			allowed: false,
+		},
+		{
+			name:    "missing gateway",
+			kind:    "VirtualService",
+			raw:     virtualService(`{"hosts": ["a.example.com"], "gateways": ["missing"], "http": [{"route": [{"destination": {"host": "reviews"}}]}]}`),
+			allowed: false,
+		},
+		{
+			name:         "host not in gateway",
+			kind:         "VirtualService",
+			raw:          virtualService(`{"hosts": ["b.example.com"], "gateways": ["gw"], "http": [{"route": [{"destination": {"host": "reviews"}}]}]}`),
+			allowed:      true,
+			wantWarnings: 1,
+		},
+		{
+			name: "unrelated issue",
+			kind: "DestinationRule",
+			raw: []byte(`{"apiVersion": "networking.istio.io/v1alpha3", "kind": "DestinationRule",
+"metadata": {"name": "productpage", "namespace": "default"}, "spec": {"host": "productpage"}}`),
+			allowed: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := wh.validate(&kube.AdmissionRequest{
+				Kind:      kubeApisMeta.GroupVersionKind{Kind: c.kind},
+				Namespace: "default",
+				Object:    runtime.RawExtension{Raw: c.raw},
+				Operation: kube.Create,
+			})
+			if got.Allowed != c.allowed {
+				t.Fatalf("got allowed %v want %v: %v", got.Allowed, c.allowed, got.Result)
+			}
+			if len(got.Warnings) != c.wantWarnings {
+				t.Fatalf("got warnings %v, want %d warnings", got.Warnings, c.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestSelectAnalyzers(t *testing.T) {
+	if _, err := SelectAnalyzers([]string{"virtualservice.DestinationRuleAnalyzer"}, collections.Istio); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := SelectAnalyzers([]string{"unknown.Analyzer"}, collections.Istio); err == nil {
+		t.Fatalf("expected an error for an unknown analyzer")
+	}
+	// Services are not in the view of the webhook.
+	if _, err := SelectAnalyzers([]string{"virtualservice.DestinationHostAnalyzer"}, collections.Istio); err == nil {
+		t.Fatalf("expected an error for an analyzer reading Kubernetes resources")
+	}
+}
+
 func makeTestReview(t *testing.T, valid bool, apiVersion string) []byte {
 	t.Helper()
 	review := kubeApiAdmission.AdmissionReview{
@@ -0,0 +1,359 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chiron
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	certclient "k8s.io/client-go/kubernetes/typed/certificates/v1beta1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"istio.io/istio/security/pkg/pki/ca"
+	"istio.io/istio/security/pkg/pki/util"
+	certutil "istio.io/istio/security/pkg/util"
+	"istio.io/pkg/log"
+)
+
+/* #nosec: disable gas linter */
+const (
+	// CertificateRequestSecretType is the type of the secrets requesting a DNS certificate. The certificate, its
+	// key and the CA certificate are written to the secret, like the secrets of IstioDNSSecretType.
+	CertificateRequestSecretType = "istio.io/dns-certificate-request"
+
+	// DNSNamesAnnotation holds the comma separated DNS names of the certificate requested by a secret. The names
+	// must be services of the namespace of the secret: <service>.<namespace>, <service>.<namespace>.svc or
+	// <service>.<namespace>.svc.<domain suffix>. Removing the annotation revokes the certificate.
+	DNSNamesAnnotation = "certificates.istio.io/dns-names"
+
+	// StatusAnnotation holds the conditions of a certificate request, as a JSON list of conditions.
+	StatusAnnotation = "certificates.istio.io/status"
+
+	// ConditionReady is true when the secret holds a valid certificate for the requested DNS names.
+	ConditionReady = "Ready"
+
+	ReasonIssued         = "Issued"
+	ReasonInvalidRequest = "InvalidRequest"
+	ReasonSigningFailed  = "SigningFailed"
+	ReasonRevoked        = "Revoked"
+
+	// The requests are renewed on time from the certificate expiration, the resync is a safety net.
+	requestResyncPeriod = time.Hour
+)
+
+// RequestController issues DNS certificates signed by the K8s CA to the secrets of CertificateRequestSecretType of
+// any namespace, and renews them before they expire. Istiod runs it on the leader only, so a single replica creates
+// and approves the CSRs. The secrets are still updated with their resource version, so a replica which just lost the
+// leadership cannot overwrite the certificate written by the new leader.
+//
+// Certificates signed by the K8s CA cannot be revoked. Deleting a request, or removing its DNS names, deletes the
+// certificate and its key and stops the renewal.
+type RequestController struct {
+	core       corev1.CoreV1Interface
+	certClient certclient.CertificatesV1beta1Interface
+	// The file path to the k8s CA certificate
+	k8sCaCertFile  string
+	domainSuffix   string
+	minGracePeriod time.Duration
+	certUtil       certutil.CertUtil
+
+	informer cache.SharedIndexInformer
+	queue    workqueue.RateLimitingInterface
+}
+
+// NewRequestController returns a pointer to a newly constructed RequestController instance.
+func NewRequestController(gracePeriodRatio float32, minGracePeriod time.Duration,
+	core corev1.CoreV1Interface, certClient certclient.CertificatesV1beta1Interface, k8sCaCertFile,
+	domainSuffix string) (*RequestController, error) {
+	if gracePeriodRatio < 0 || gracePeriodRatio > 1 {
+		return nil, fmt.Errorf("grace period ratio %f should be within [0, 1]", gracePeriodRatio)
+	}
+	if _, err := readCACert(k8sCaCertFile); err != nil {
+		return nil, err
+	}
+
+	c := &RequestController{
+		core:           core,
+		certClient:     certClient,
+		k8sCaCertFile:  k8sCaCertFile,
+		domainSuffix:   domainSuffix,
+		minGracePeriod: minGracePeriod,
+		certUtil:       certutil.NewCertUtil(int(gracePeriodRatio * 100)),
+		queue:          workqueue.NewRateLimitingQueue(workqueue.DefaultItemBasedRateLimiter()),
+	}
+
+	requestSelector := fields.SelectorFromSet(map[string]string{"type": CertificateRequestSecretType}).String()
+	c.informer = cache.NewSharedIndexInformer(&cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = requestSelector
+			return core.Secrets(metav1.NamespaceAll).List(context.TODO(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = requestSelector
+			return core.Secrets(metav1.NamespaceAll).Watch(context.TODO(), options)
+		},
+	}, &v1.Secret{}, requestResyncPeriod, cache.Indexers{})
+	c.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldScrt, newScrt := oldObj.(*v1.Secret), newObj.(*v1.Secret)
+			if oldScrt.ResourceVersion != newScrt.ResourceVersion &&
+				oldScrt.Annotations[DNSNamesAnnotation] == newScrt.Annotations[DNSNamesAnnotation] &&
+				reflect.DeepEqual(oldScrt.Data, newScrt.Data) {
+				// Only the status changed.
+				return
+			}
+			c.enqueue(newObj)
+		},
+		DeleteFunc: c.enqueue,
+	})
+	return c, nil
+}
+
+func (c *RequestController) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Warnf("failed to get the key of certificate request %v: %v", obj, err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// Run starts the RequestController until stopCh is notified.
+func (c *RequestController) Run(stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	go c.informer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, c.informer.HasSynced) {
+		log.Error("failed to wait for cache sync")
+		return
+	}
+	go wait.Until(c.runWorker, time.Second, stopCh)
+	<-stopCh
+}
+
+func (c *RequestController) runWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *RequestController) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.sync(key.(string)); err != nil {
+		log.Errorf("failed to process certificate request %s (retrying): %v", key, err)
+		c.queue.AddRateLimited(key)
+	} else {
+		c.queue.Forget(key)
+	}
+	return true
+}
+
+// sync issues, renews or revokes the certificate of a request.
+func (c *RequestController) sync(key string) error {
+	obj, exists, err := c.informer.GetStore().GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		log.Infof("certificate request %s deleted", key)
+		return nil
+	}
+	scrt := obj.(*v1.Secret).DeepCopy()
+
+	dnsNames := parseDNSNames(scrt.Annotations[DNSNamesAnnotation])
+	if len(dnsNames) == 0 {
+		return c.revoke(scrt)
+	}
+	if err := validateDNSNames(dnsNames, scrt.Namespace, c.domainSuffix); err != nil {
+		return c.updateStatus(scrt, metav1.ConditionFalse, ReasonInvalidRequest, err.Error())
+	}
+
+	caCert, err := readCACert(c.k8sCaCertFile)
+	if err != nil {
+		return err
+	}
+	if renewIn, ok := c.renewalWait(scrt, dnsNames, caCert); ok {
+		c.queue.AddAfter(key, renewIn)
+		return nil
+	}
+
+	chain, keyPEM, caCertPEM, err := GenKeyCertK8sCA(c.certClient.CertificateSigningRequests(), strings.Join(dnsNames, ","),
+		scrt.Name, scrt.Namespace, c.k8sCaCertFile)
+	if err != nil {
+		if statusErr := c.updateStatus(scrt, metav1.ConditionFalse, ReasonSigningFailed, err.Error()); statusErr != nil {
+			log.Warnf("failed to update the status of certificate request %s: %v", key, statusErr)
+		}
+		return err
+	}
+	if scrt.Data == nil {
+		scrt.Data = map[string][]byte{}
+	}
+	scrt.Data[ca.CertChainFile] = chain
+	scrt.Data[ca.PrivateKeyFile] = keyPEM
+	scrt.Data[ca.RootCertFile] = caCertPEM
+	msg := fmt.Sprintf("certificate issued for %s", strings.Join(dnsNames, ","))
+	if cert, err := util.ParsePemEncodedCertificate(chain); err == nil {
+		msg += fmt.Sprintf(", valid until %s", cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+	setCondition(scrt, metav1.ConditionTrue, ReasonIssued, msg)
+	if err := c.update(scrt); err != nil {
+		return err
+	}
+	log.Infof("issued certificate for request %s: %s", key, strings.Join(dnsNames, ","))
+	return nil
+}
+
+// renewalWait returns the time until the certificate of the secret must be renewed, or false if it must be renewed
+// now: the certificate is missing, invalid, about to expire, not for the requested DNS names, or signed by another
+// CA.
+func (c *RequestController) renewalWait(scrt *v1.Secret, dnsNames []string, caCert []byte) (time.Duration, bool) {
+	certBytes := scrt.Data[ca.CertChainFile]
+	cert, err := util.ParsePemEncodedCertificate(certBytes)
+	if err != nil {
+		return 0, false
+	}
+	issued := append([]string(nil), cert.DNSNames...)
+	sort.Strings(issued)
+	if !reflect.DeepEqual(issued, dnsNames) || !bytes.Equal(caCert, scrt.Data[ca.RootCertFile]) {
+		return 0, false
+	}
+	renewIn, err := c.certUtil.GetWaitTime(certBytes, time.Now(), c.minGracePeriod)
+	if err != nil {
+		return 0, false
+	}
+	return renewIn, true
+}
+
+// revoke deletes the certificate and key of a request without DNS names.
+func (c *RequestController) revoke(scrt *v1.Secret) error {
+	_, hasCert := scrt.Data[ca.CertChainFile]
+	_, hasKey := scrt.Data[ca.PrivateKeyFile]
+	if !hasCert && !hasKey {
+		return c.updateStatus(scrt, metav1.ConditionFalse, ReasonRevoked, "no DNS names requested")
+	}
+	delete(scrt.Data, ca.CertChainFile)
+	delete(scrt.Data, ca.PrivateKeyFile)
+	delete(scrt.Data, ca.RootCertFile)
+	setCondition(scrt, metav1.ConditionFalse, ReasonRevoked, "no DNS names requested")
+	if err := c.update(scrt); err != nil {
+		return err
+	}
+	log.Infof("revoked certificate of request %s/%s", scrt.Namespace, scrt.Name)
+	return nil
+}
+
+// updateStatus sets the Ready condition of the request, if it changed.
+func (c *RequestController) updateStatus(scrt *v1.Secret, status metav1.ConditionStatus, reason, message string) error {
+	if cond := meta.FindStatusCondition(conditions(scrt), ConditionReady); cond != nil &&
+		cond.Status == status && cond.Reason == reason && cond.Message == message {
+		return nil
+	}
+	setCondition(scrt, status, reason, message)
+	return c.update(scrt)
+}
+
+func (c *RequestController) update(scrt *v1.Secret) error {
+	_, err := c.core.Secrets(scrt.Namespace).Update(context.TODO(), scrt, metav1.UpdateOptions{})
+	if errors.IsConflict(err) || errors.IsNotFound(err) {
+		// The secret was updated by another replica, or deleted: the change is handled by its own event.
+		log.Debugf("skipped update of certificate request %s/%s: %v", scrt.Namespace, scrt.Name, err)
+		return nil
+	}
+	return err
+}
+
+// conditions returns the conditions of the status of a request.
+func conditions(scrt *v1.Secret) []metav1.Condition {
+	var conds []metav1.Condition
+	if status := scrt.Annotations[StatusAnnotation]; status != "" {
+		if err := json.Unmarshal([]byte(status), &conds); err != nil {
+			log.Debugf("ignoring invalid status of certificate request %s/%s: %v", scrt.Namespace, scrt.Name, err)
+			return nil
+		}
+	}
+	return conds
+}
+
+func setCondition(scrt *v1.Secret, status metav1.ConditionStatus, reason, message string) {
+	conds := conditions(scrt)
+	meta.SetStatusCondition(&conds, metav1.Condition{
+		Type:    ConditionReady,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+	raw, _ := json.Marshal(conds)
+	if scrt.Annotations == nil {
+		scrt.Annotations = map[string]string{}
+	}
+	scrt.Annotations[StatusAnnotation] = string(raw)
+}
+
+// parseDNSNames returns the sorted and deduplicated DNS names of the annotation.
+func parseDNSNames(annotation string) []string {
+	set := map[string]struct{}{}
+	for _, name := range strings.Split(annotation, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			set[name] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateDNSNames checks that the DNS names are services of the namespace, so a namespace cannot get certificates
+// for the services of other namespaces.
+func validateDNSNames(names []string, namespace, domainSuffix string) error {
+	for _, name := range names {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return fmt.Errorf("invalid DNS name %q: %s", name, strings.Join(errs, ", "))
+		}
+		parts := strings.SplitN(name, ".", 3)
+		if len(parts) < 2 || parts[1] != namespace ||
+			(len(parts) == 3 && parts[2] != "svc" && parts[2] != "svc."+domainSuffix) {
+			return fmt.Errorf("DNS name %q is not a service of namespace %s, expected <service>.%s.svc.%s",
+				name, namespace, namespace, domainSuffix)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chiron
+
+import (
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	cert "k8s.io/api/certificates/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	kt "k8s.io/client-go/testing"
+
+	"istio.io/istio/security/pkg/pki/ca"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+// newSigningClient returns a fake client signing the CSRs with a new CA, and the path of the CA certificate.
+func newSigningClient(t *testing.T) (*fake.Clientset, string) {
+	caCertPEM, caKeyPEM, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Org:          "K8s CA",
+		TTL:          time.Hour,
+		IsCA:         true,
+		IsSelfSigned: true,
+		RSAKeySize:   2048,
+	})
+	if err != nil {
+		t.Fatalf("failed to generate the CA: %v", err)
+	}
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := ioutil.WriteFile(caFile, caCertPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := util.ParsePemEncodedCertificate(caCertPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caKey, err := util.ParsePemEncodedKey(caKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "certificatesigningrequests", func(act kt.Action) (bool, runtime.Object, error) {
+		r := act.(kt.CreateAction).GetObject().(*cert.CertificateSigningRequest)
+		csr, err := util.ParsePemEncodedCSR(r.Spec.Request)
+		if err != nil {
+			return true, nil, err
+		}
+		der, err := util.GenCertFromCSR(csr, caCert, csr.PublicKey, caKey, csr.DNSNames, time.Hour, false)
+		if err != nil {
+			return true, nil, err
+		}
+		r.Status.Certificate = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+		// Let the tracker store the signed CSR.
+		return false, nil, nil
+	})
+	return client, caFile
+}
+
+func newRequest(name, namespace, dnsNames string) *v1.Secret {
+	scrt := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type: CertificateRequestSecretType,
+	}
+	if dnsNames != "" {
+		scrt.Annotations = map[string]string{DNSNamesAnnotation: dnsNames}
+	}
+	return scrt
+}
+
+func issuedDNSNames(t *testing.T, scrt *v1.Secret) []string {
+	t.Helper()
+	c, err := util.ParsePemEncodedCertificate(scrt.Data[ca.CertChainFile])
+	if err != nil {
+		t.Fatalf("failed to parse the certificate of %s: %v", scrt.Name, err)
+	}
+	names := append([]string(nil), c.DNSNames...)
+	sort.Strings(names)
+	return names
+}
+
+func readyCondition(t *testing.T, scrt *v1.Secret) metav1.Condition {
+	t.Helper()
+	cond := meta.FindStatusCondition(conditions(scrt), ConditionReady)
+	if cond == nil {
+		t.Fatalf("no %s condition in the status of %s: %q", ConditionReady, scrt.Name, scrt.Annotations[StatusAnnotation])
+	}
+	return *cond
+}
+
+func TestRequestController(t *testing.T) {
+	certWatchTimeout = time.Millisecond
+	client, caFile := newSigningClient(t)
+	c, err := NewRequestController(0.5, time.Minute, client.CoreV1(), client.CertificatesV1beta1(), caFile, "cluster.local")
+	if err != nil {
+		t.Fatalf("failed to create the request controller: %v", err)
+	}
+
+	// set writes the request as the user would, and syncs it.
+	set := func(scrt *v1.Secret) *v1.Secret {
+		t.Helper()
+		secrets := client.CoreV1().Secrets(scrt.Namespace)
+		if existing, err := secrets.Get(context.TODO(), scrt.Name, metav1.GetOptions{}); err == nil {
+			scrt.Data = existing.Data
+			if status, f := existing.Annotations[StatusAnnotation]; f {
+				if scrt.Annotations == nil {
+					scrt.Annotations = map[string]string{}
+				}
+				scrt.Annotations[StatusAnnotation] = status
+			}
+			if _, err := secrets.Update(context.TODO(), scrt, metav1.UpdateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		} else if _, err := secrets.Create(context.TODO(), scrt, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		return syncRequest(t, c, client, scrt.Namespace, scrt.Name)
+	}
+
+	// Issue.
+	scrt := set(newRequest("webhook-certs", "foo", "webhook.foo.svc, webhook.foo"))
+	if got, want := issuedDNSNames(t, scrt), []string{"webhook.foo", "webhook.foo.svc"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got DNS names %v, want %v", got, want)
+	}
+	if cond := readyCondition(t, scrt); cond.Status != metav1.ConditionTrue || cond.Reason != ReasonIssued {
+		t.Fatalf("unexpected condition %+v", cond)
+	}
+	if len(scrt.Data[ca.PrivateKeyFile]) == 0 || len(scrt.Data[ca.RootCertFile]) == 0 {
+		t.Fatalf("the key or the CA certificate is missing")
+	}
+
+	// A valid certificate is not renewed.
+	issued := scrt.Data[ca.CertChainFile]
+	scrt = syncRequest(t, c, client, "foo", "webhook-certs")
+	if !reflect.DeepEqual(scrt.Data[ca.CertChainFile], issued) {
+		t.Fatalf("the certificate was renewed before its grace period")
+	}
+
+	// The certificate is re-issued when the DNS names change.
+	scrt = set(newRequest("webhook-certs", "foo", "webhook.foo.svc.cluster.local"))
+	if got, want := issuedDNSNames(t, scrt), []string{"webhook.foo.svc.cluster.local"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got DNS names %v, want %v", got, want)
+	}
+
+	// The DNS names of other namespaces are rejected.
+	other := set(newRequest("stolen-certs", "foo", "istiod.istio-system.svc"))
+	if _, f := other.Data[ca.CertChainFile]; f {
+		t.Fatalf("a certificate was issued for another namespace")
+	}
+	if cond := readyCondition(t, other); cond.Status != metav1.ConditionFalse || cond.Reason != ReasonInvalidRequest {
+		t.Fatalf("unexpected condition %+v", cond)
+	}
+
+	// Removing the DNS names revokes the certificate.
+	scrt = set(newRequest("webhook-certs", "foo", ""))
+	for _, k := range []string{ca.CertChainFile, ca.PrivateKeyFile, ca.RootCertFile} {
+		if _, f := scrt.Data[k]; f {
+			t.Fatalf("%s was not deleted on revocation", k)
+		}
+	}
+	if cond := readyCondition(t, scrt); cond.Status != metav1.ConditionFalse || cond.Reason != ReasonRevoked {
+		t.Fatalf("unexpected condition %+v", cond)
+	}
+}
+
+// syncRequest updates the informer store from the client, syncs the request and returns the written secret.
+func syncRequest(t *testing.T, c *RequestController, client *fake.Clientset, namespace, name string) *v1.Secret {
+	t.Helper()
+	scrt, err := client.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.informer.GetStore().Update(scrt); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.sync(namespace + "/" + name); err != nil {
+		t.Fatalf("failed to sync %s/%s: %v", namespace, name, err)
+	}
+	scrt, err = client.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scrt
+}
+
+func TestParseDNSNames(t *testing.T) {
+	got := parseDNSNames(" b.ns ,a.ns,,b.ns")
+	if want := []string{"a.ns", "b.ns"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := parseDNSNames(""); len(got) != 0 {
+		t.Fatalf("got %v, want no names", got)
+	}
+}
+
+func TestValidateDNSNames(t *testing.T) {
+	cases := []struct {
+		name  string
+		dns   string
+		valid bool
+	}{
+		{"service and namespace", "svc.foo", true},
+		{"svc suffix", "svc.foo.svc", true},
+		{"domain suffix", "svc.foo.svc.cluster.local", true},
+		{"other namespace", "svc.bar.svc", false},
+		{"other domain suffix", "svc.foo.svc.example.com", false},
+		{"not a service", "svc.foo.example.com", false},
+		{"no namespace", "svc", false},
+		{"invalid name", "Svc_1.foo", false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDNSNames([]string{tt.dns}, "foo", "cluster.local")
+			if tt.valid && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatalf("expected an error")
+			}
+		})
+	}
+}
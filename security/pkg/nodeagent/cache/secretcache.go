@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -108,10 +109,12 @@ type SecretManagerClient struct {
 	// outputMutex protects writes of certificates to disk
 	outputMutex sync.Mutex
 
-	// Dynamically configured Trust Bundle Mutex
+	// Dynamically configured Trust Bundle Mutex, guarding configTrustBundle and trustDomainBundles
 	configTrustBundleMutex sync.RWMutex
 	// Dynamically configured Trust Bundle
 	configTrustBundle []byte
+	// Trust bundles of the federated trust domains, keyed by trust domain
+	trustDomainBundles map[string][]byte
 
 	// queue maintains all certificate rotation events that need to be triggered when they are about to expire
 	queue queue.Delayed
@@ -218,8 +221,9 @@ func (sc *SecretManagerClient) getCachedSecret(resourceName string) (secret *sec
 		if resourceName == security.RootCertReqResourceName {
 			rootCertBundle = sc.mergeConfigTrustBundle(c.RootCert)
 			ns = &security.SecretItem{
-				ResourceName: resourceName,
-				RootCert:     rootCertBundle,
+				ResourceName:       resourceName,
+				RootCert:           rootCertBundle,
+				TrustDomainBundles: sc.mergeTrustDomainBundles(rootCertBundle),
 			}
 			cacheLog.WithLabels("ttl", time.Until(c.ExpireTime)).Info("returned workload trust anchor from cache")
 
@@ -301,6 +305,7 @@ func (sc *SecretManagerClient) GenerateSecret(resourceName string) (secret *secu
 
 	if resourceName == security.RootCertReqResourceName {
 		ns.RootCert = sc.mergeConfigTrustBundle(ns.RootCert)
+		ns.TrustDomainBundles = sc.mergeTrustDomainBundles(ns.RootCert)
 	} else {
 		// If periodic cert refresh resulted in discovery of a new root, trigger a ROOTCA request to refresh trust anchor
 		oldRoot := sc.cache.GetRoot()
@@ -482,6 +487,7 @@ func (sc *SecretManagerClient) generateFileSecret(resourceName string) (bool, *s
 		if sitem, err = sc.generateRootCertFromExistingFile(cf.CaCertificatePath, resourceName, true); err == nil {
 			// If retrieving workload trustBundle, then merge other configured trustAnchors in ProxyConfig
 			sitem.RootCert = sc.mergeConfigTrustBundle(sitem.RootCert)
+			sitem.TrustDomainBundles = sc.mergeTrustDomainBundles(sitem.RootCert)
 			sc.addFileWatcher(cf.CaCertificatePath, resourceName)
 		}
 	// Default workload certificate.
@@ -719,3 +725,42 @@ func (sc *SecretManagerClient) UpdateConfigTrustBundle(trustBundle []byte) error
 func (sc *SecretManagerClient) mergeConfigTrustBundle(rootCert []byte) []byte {
 	return pkiutil.AppendCertByte(sc.getConfigTrustBundle(), rootCert)
 }
+
+// UpdateTrustDomainBundles updates the trust bundles of the federated trust domains, keyed by trust domain. The peers of
+// each trust domain are validated against its bundle rather than against the merged root certificates.
+func (sc *SecretManagerClient) UpdateTrustDomainBundles(bundles map[string][]byte) error {
+	sc.configTrustBundleMutex.Lock()
+	if reflect.DeepEqual(sc.trustDomainBundles, bundles) {
+		sc.configTrustBundleMutex.Unlock()
+		return nil
+	}
+	sc.trustDomainBundles = bundles
+	sc.configTrustBundleMutex.Unlock()
+	sc.CallUpdateCallback(security.RootCertReqResourceName)
+	return nil
+}
+
+// mergeTrustDomainBundles returns the trust bundles of the federated trust domains along with rootCert as the bundle of
+// the trust domain of the workload and of its aliases, or nil if there are no federated trust domains.
+func (sc *SecretManagerClient) mergeTrustDomainBundles(rootCert []byte) map[string][]byte {
+	sc.configTrustBundleMutex.RLock()
+	defer sc.configTrustBundleMutex.RUnlock()
+	if len(sc.trustDomainBundles) == 0 {
+		return nil
+	}
+	trustDomains := []string{spiffe.GetTrustDomain()}
+	if sc.configOptions != nil {
+		if sc.configOptions.TrustDomain != "" {
+			trustDomains[0] = sc.configOptions.TrustDomain
+		}
+		trustDomains = append(trustDomains, sc.configOptions.TrustDomainAliases...)
+	}
+	bundles := make(map[string][]byte, len(sc.trustDomainBundles)+len(trustDomains))
+	for td, bundle := range sc.trustDomainBundles {
+		bundles[td] = bundle
+	}
+	for _, td := range trustDomains {
+		bundles[td] = pkiutil.AppendCertByte(bundles[td], rootCert)
+	}
+	return bundles
+}
@@ -489,6 +489,10 @@ func verifySecret(t *testing.T, gotSecret *security.SecretItem, expectedSecret *
 			t.Fatalf("root cert: expected %v but got %v", expectedSecret.RootCert,
 				gotSecret.RootCert)
 		}
+		if !reflect.DeepEqual(expectedSecret.TrustDomainBundles, gotSecret.TrustDomainBundles) {
+			t.Fatalf("trust domain bundles: expected %v but got %v", expectedSecret.TrustDomainBundles,
+				gotSecret.TrustDomainBundles)
+		}
 	} else {
 		if !bytes.Equal(expectedSecret.CertificateChain, gotSecret.CertificateChain) {
 			t.Fatalf("cert chain: expected %s but got %s", string(expectedSecret.CertificateChain),
@@ -604,3 +608,89 @@ func TestProxyConfigAnchors(t *testing.T) {
 		RootCert:     rootCert,
 	})
 }
+
+func TestTrustDomainBundles(t *testing.T) {
+	fakeCACli, err := mock.NewMockCAClient(time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating Mock CA client: %v", err)
+	}
+	u := NewUpdateTracker(t)
+
+	sc := createCache(t, fakeCACli, u.Callback, security.Options{TrustDomain: "cluster.local"})
+	if _, err = sc.GenerateSecret(security.WorkloadKeyCertResourceName); err != nil {
+		t.Fatalf("failed to generate certificate: %v", err)
+	}
+	u.Expect(map[string]int{security.RootCertReqResourceName: 1})
+	u.Reset()
+
+	caClientRootCert := []byte(fakeCACli.GeneratedCerts[0][2])
+	federatedRootCert, err := ioutil.ReadFile(filepath.Join("./testdata", "root-cert.pem"))
+	if err != nil {
+		t.Fatalf("Error reading the root cert file: %v", err)
+	}
+
+	// Without federated trust domains, only the merged root certificates are served.
+	checkSecret(t, sc, security.RootCertReqResourceName, security.SecretItem{
+		ResourceName: security.RootCertReqResourceName,
+		RootCert:     caClientRootCert,
+	})
+
+	if err := sc.UpdateTrustDomainBundles(map[string][]byte{"federated.example": federatedRootCert}); err != nil {
+		t.Fatal(err)
+	}
+	u.Expect(map[string]int{security.RootCertReqResourceName: 1})
+	u.Reset()
+
+	checkSecret(t, sc, security.RootCertReqResourceName, security.SecretItem{
+		ResourceName: security.RootCertReqResourceName,
+		RootCert:     caClientRootCert,
+		TrustDomainBundles: map[string][]byte{
+			"cluster.local":     caClientRootCert,
+			"federated.example": federatedRootCert,
+		},
+	})
+
+	// Updating with the same bundles does not trigger a push.
+	if err := sc.UpdateTrustDomainBundles(map[string][]byte{"federated.example": federatedRootCert}); err != nil {
+		t.Fatal(err)
+	}
+	u.Expect(map[string]int{})
+}
+
+func TestTrustDomainBundlesWithAliases(t *testing.T) {
+	fakeCACli, err := mock.NewMockCAClient(time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating Mock CA client: %v", err)
+	}
+	u := NewUpdateTracker(t)
+
+	sc := createCache(t, fakeCACli, u.Callback, security.Options{
+		TrustDomain:        "cluster.local",
+		TrustDomainAliases: []string{"old.example"},
+	})
+	if _, err = sc.GenerateSecret(security.WorkloadKeyCertResourceName); err != nil {
+		t.Fatalf("failed to generate certificate: %v", err)
+	}
+	u.Reset()
+
+	caClientRootCert := []byte(fakeCACli.GeneratedCerts[0][2])
+	federatedRootCert, err := ioutil.ReadFile(filepath.Join("./testdata", "root-cert.pem"))
+	if err != nil {
+		t.Fatalf("Error reading the root cert file: %v", err)
+	}
+	if err := sc.UpdateTrustDomainBundles(map[string][]byte{"federated.example": federatedRootCert}); err != nil {
+		t.Fatal(err)
+	}
+	u.Expect(map[string]int{security.RootCertReqResourceName: 1})
+
+	// The peers of the trust domain aliases are validated against the root certificates of the trust domain.
+	checkSecret(t, sc, security.RootCertReqResourceName, security.SecretItem{
+		ResourceName: security.RootCertReqResourceName,
+		RootCert:     caClientRootCert,
+		TrustDomainBundles: map[string][]byte{
+			"cluster.local":     caClientRootCert,
+			"old.example":       caClientRootCert,
+			"federated.example": federatedRootCert,
+		},
+	})
+}
@@ -18,6 +18,7 @@ package sds
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/cenkalti/backoff"
@@ -41,6 +42,10 @@ import (
 
 var sdsServiceLog = log.RegisterScope("sds", "SDS service debugging", 0)
 
+// spiffeCertValidatorName is the name of the Envoy extension validating the peer certificates against the bundles of
+// their SPIFFE trust domains.
+const spiffeCertValidatorName = "envoy.tls.cert_validator.spiffe"
+
 type sdsservice struct {
 	st security.SecretManager
 
@@ -205,7 +210,13 @@ func toEnvoySecret(s *security.SecretItem) *tls.Secret {
 	}
 
 	cfg, ok := model.SdsCertificateConfigFromResourceName(s.ResourceName)
-	if s.ResourceName == security.RootCertReqResourceName || (ok && cfg.IsRootCertificate()) {
+	if s.ResourceName == security.RootCertReqResourceName && len(s.TrustDomainBundles) > 0 {
+		secret.Type = &tls.Secret_ValidationContext{
+			ValidationContext: &tls.CertificateValidationContext{
+				CustomValidatorConfig: spiffeCertValidatorConfig(s.TrustDomainBundles),
+			},
+		}
+	} else if s.ResourceName == security.RootCertReqResourceName || (ok && cfg.IsRootCertificate()) {
 		secret.Type = &tls.Secret_ValidationContext{
 			ValidationContext: &tls.CertificateValidationContext{
 				TrustedCa: &core.DataSource{
@@ -235,6 +246,32 @@ func toEnvoySecret(s *security.SecretItem) *tls.Secret {
 	return secret
 }
 
+// spiffeCertValidatorConfig returns the config of the SPIFFE certificate validator, validating the peers against the
+// bundle of their trust domain.
+func spiffeCertValidatorConfig(bundles map[string][]byte) *core.TypedExtensionConfig {
+	trustDomains := make([]string, 0, len(bundles))
+	for td := range bundles {
+		trustDomains = append(trustDomains, td)
+	}
+	sort.Strings(trustDomains)
+
+	cfg := &tls.SPIFFECertValidatorConfig{}
+	for _, td := range trustDomains {
+		cfg.TrustDomains = append(cfg.TrustDomains, &tls.SPIFFECertValidatorConfig_TrustDomain{
+			Name: td,
+			TrustBundle: &core.DataSource{
+				Specifier: &core.DataSource_InlineBytes{
+					InlineBytes: bundles[td],
+				},
+			},
+		})
+	}
+	return &core.TypedExtensionConfig{
+		Name:        spiffeCertValidatorName,
+		TypedConfig: util.MessageToAny(cfg),
+	}
+}
+
 func pushLog(names []string) model.XdsLogDetails {
 	if len(names) == 1 {
 		// For common case of single resource, show which resource it was
@@ -19,6 +19,7 @@ import (
 	"strings"
 	"testing"
 
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/net/context"
@@ -301,3 +302,43 @@ func setupConnection(socket string) (*grpc.ClientConn, error) {
 
 	return conn, nil
 }
+
+func TestToEnvoySecretTrustDomainBundles(t *testing.T) {
+	secret := toEnvoySecret(&ca2.SecretItem{
+		ResourceName: rootResourceName,
+		RootCert:     fakeRootCert,
+		TrustDomainBundles: map[string][]byte{
+			"foo.example":   fakeCertificateChain,
+			"cluster.local": fakeRootCert,
+		},
+	})
+	vc := secret.GetValidationContext()
+	if vc.GetTrustedCa() != nil {
+		t.Fatalf("expected no trusted CA with the SPIFFE validator, got %v", vc.GetTrustedCa())
+	}
+	if got := vc.GetCustomValidatorConfig().GetName(); got != spiffeCertValidatorName {
+		t.Fatalf("expected the %s validator, got %q", spiffeCertValidatorName, got)
+	}
+	cfg := &tls.SPIFFECertValidatorConfig{}
+	if err := vc.GetCustomValidatorConfig().GetTypedConfig().UnmarshalTo(cfg); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string][]byte{}
+	var order []string
+	for _, td := range cfg.TrustDomains {
+		order = append(order, td.Name)
+		got[td.Name] = td.TrustBundle.GetInlineBytes()
+	}
+	if diff := cmp.Diff([]string{"cluster.local", "foo.example"}, order); diff != "" {
+		t.Fatalf("unexpected trust domains: %v", diff)
+	}
+	if diff := cmp.Diff(map[string][]byte{"cluster.local": fakeRootCert, "foo.example": fakeCertificateChain}, got); diff != "" {
+		t.Fatalf("unexpected trust bundles: %v", diff)
+	}
+
+	// Other root certificates are not affected by the bundles.
+	secret = toEnvoySecret(&ca2.SecretItem{ResourceName: "file-root:/etc/certs/root.pem", RootCert: fakeRootCert})
+	if got := secret.GetValidationContext().GetTrustedCa().GetInlineBytes(); !cmp.Equal(got, fakeRootCert) {
+		t.Fatalf("expected the trusted CA %v, got %v", fakeRootCert, got)
+	}
+}
@@ -50,6 +50,12 @@ type IstioRAOptions struct {
 	K8sClient certificatesv1beta1.CertificatesV1beta1Interface
 	// TrustDomain
 	TrustDomain string
+	// ExternalCAAddress : Base URL of the external CA when using the REST signing API
+	ExternalCAAddress string
+	// ClientCertFile : File containing the PEM encoded client certificate used for mTLS with the external CA
+	ClientCertFile string
+	// ClientKeyFile : File containing the PEM encoded client private key used for mTLS with the external CA
+	ClientKeyFile string
 }
 
 const (
@@ -59,6 +65,9 @@ const (
 	// ExtCAGrpc : Integration with external CA using Istio CA gRPC API
 	ExtCAGrpc CaExternalType = "ISTIOD_RA_ISTIO_API"
 
+	// ExtCARest : Integration with external CA using a REST signing API over mTLS
+	ExtCARest CaExternalType = "ISTIOD_RA_REST_API"
+
 	// DefaultExtCACertDir : Location of external CA certificate
 	DefaultExtCACertDir string = "./etc/external-ca-cert"
 )
@@ -92,12 +101,19 @@ func ValidateCSR(csrPEM []byte, subjectIDs []string) bool {
 // NewIstioRA is a factory method that returns an RA that implements the RegistrationAuthority functionality.
 // the caOptions defines the external provider
 func NewIstioRA(opts *IstioRAOptions) (RegistrationAuthority, error) {
-	if opts.ExternalCAType == ExtCAK8s {
+	switch opts.ExternalCAType {
+	case ExtCAK8s:
 		istioRA, err := NewKubernetesRA(opts)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create an K8s CA: %v", err)
 		}
 		return istioRA, err
+	case ExtCARest:
+		istioRA, err := NewRestRA(opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create a REST CA: %v", err)
+		}
+		return istioRA, err
 	}
 	return nil, fmt.Errorf("invalid CA Name %s", opts.ExternalCAType)
 }
@@ -0,0 +1,234 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ra
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+// FakeExternalCA is an in memory CA serving the REST signing API over mTLS. It is intended for tests.
+type FakeExternalCA struct {
+	// URL of the signing API
+	URL string
+	// RootCertFile contains the root certificate of the CA, which also issued the server certificate.
+	RootCertFile string
+	// ClientCertFile and ClientKeyFile contain a client certificate accepted by the CA.
+	ClientCertFile string
+	ClientKeyFile  string
+
+	rootCert *x509.Certificate
+	rootKey  crypto.PrivateKey
+
+	mutex            sync.Mutex
+	intermediateCert *x509.Certificate
+	intermediateKey  crypto.PrivateKey
+	intermediatePem  []byte
+	// failures holds status codes returned by the next requests, in order, instead of serving them.
+	failures     []int
+	signRequests []RestSignRequest
+	chainFetches int
+}
+
+// NewFakeExternalCA starts a fake external CA, which is stopped at the end of the test.
+func NewFakeExternalCA(t test.Failer) *FakeExternalCA {
+	rootPem, rootKeyPem, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Org:          "fake-external-ca",
+		TTL:          24 * time.Hour,
+		IsCA:         true,
+		IsSelfSigned: true,
+		RSAKeySize:   2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &FakeExternalCA{}
+	if f.rootCert, err = util.ParsePemEncodedCertificate(rootPem); err != nil {
+		t.Fatal(err)
+	}
+	if f.rootKey, err = util.ParsePemEncodedKey(rootKeyPem); err != nil {
+		t.Fatal(err)
+	}
+	f.RotateIntermediate(t)
+
+	serverCert, serverKey := f.issue(t, util.CertOptions{Host: "127.0.0.1,localhost", IsServer: true})
+	clientCert, clientKey := f.issue(t, util.CertOptions{Host: "istiod.istio-system.svc", IsClient: true})
+
+	dir := t.TempDir()
+	f.RootCertFile = filepath.Join(dir, "root-cert.pem")
+	f.ClientCertFile = filepath.Join(dir, "client-cert.pem")
+	f.ClientKeyFile = filepath.Join(dir, "client-key.pem")
+	for file, content := range map[string][]byte{
+		f.RootCertFile:   rootPem,
+		f.ClientCertFile: clientCert,
+		f.ClientKeyFile:  clientKey,
+	} {
+		if err := ioutil.WriteFile(file, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tlsCert, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(f.rootCert)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(restSignPath, f.handleSign)
+	mux.HandleFunc(restChainPath, f.handleChain)
+	server := httptest.NewUnstartedServer(mux)
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{tlsCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	f.URL = server.URL
+	return f
+}
+
+// issue generates a leaf certificate and key signed by the root.
+func (f *FakeExternalCA) issue(t test.Failer, opts util.CertOptions) ([]byte, []byte) {
+	opts.TTL = 24 * time.Hour
+	opts.SignerCert = f.rootCert
+	opts.SignerPriv = f.rootKey
+	opts.RSAKeySize = 2048
+	cert, key, err := util.GenCertKeyFromOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// RotateIntermediate replaces the intermediate certificate used to sign workload certificates.
+func (f *FakeExternalCA) RotateIntermediate(t test.Failer) {
+	certPem, keyPem, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Org:        "fake-external-ca-intermediate",
+		TTL:        24 * time.Hour,
+		IsCA:       true,
+		SignerCert: f.rootCert,
+		SignerPriv: f.rootKey,
+		RSAKeySize: 2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := util.ParsePemEncodedCertificate(certPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := util.ParsePemEncodedKey(keyPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.intermediateCert = cert
+	f.intermediateKey = key
+	f.intermediatePem = certPem
+}
+
+// FailNext makes the next requests fail with the given status codes, one per request.
+func (f *FakeExternalCA) FailNext(codes ...int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.failures = append(f.failures, codes...)
+}
+
+// SignRequests returns the signing requests received so far, including failed ones.
+func (f *FakeExternalCA) SignRequests() []RestSignRequest {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]RestSignRequest{}, f.signRequests...)
+}
+
+// ChainFetches returns the number of certificate chain requests received so far.
+func (f *FakeExternalCA) ChainFetches() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.chainFetches
+}
+
+// injectedFailure returns the status code the current request should fail with, or 0.
+func (f *FakeExternalCA) injectedFailure() int {
+	if len(f.failures) == 0 {
+		return 0
+	}
+	code := f.failures[0]
+	f.failures = f.failures[1:]
+	return code
+}
+
+func (f *FakeExternalCA) handleChain(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.chainFetches++
+	if code := f.injectedFailure(); code != 0 {
+		http.Error(w, "injected failure", code)
+		return
+	}
+	writeJSON(w, &RestChainResponse{CertChain: string(f.intermediatePem)})
+}
+
+func (f *FakeExternalCA) handleSign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req := RestSignRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.signRequests = append(f.signRequests, req)
+	if code := f.injectedFailure(); code != 0 {
+		http.Error(w, "injected failure", code)
+		return
+	}
+	csr, err := util.ParsePemEncodedCSR([]byte(req.CSR))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	der, err := util.GenCertFromCSR(csr, f.intermediateCert, csr.PublicKey, f.intermediateKey,
+		req.SubjectIDs, time.Duration(req.TTLSeconds)*time.Second, false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, &RestSignResponse{Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,292 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ra
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/security/pkg/pki/ca"
+	raerror "istio.io/istio/security/pkg/pki/error"
+	"istio.io/istio/security/pkg/pki/util"
+	"istio.io/pkg/log"
+)
+
+const (
+	// restSignPath is the path of the signing endpoint of the external CA.
+	restSignPath = "/v1/sign"
+	// restChainPath is the path of the endpoint returning the CA certificate chain of the external CA.
+	restChainPath = "/v1/chain"
+
+	restRequestTimeout = 10 * time.Second
+	// restMaxAttempts is the number of attempts made for a request before giving up.
+	restMaxAttempts = 4
+	// restInitialBackoff is doubled after every failed attempt.
+	restInitialBackoff = 100 * time.Millisecond
+	// restChainRefreshInterval is how long the fetched certificate chain is cached.
+	restChainRefreshInterval = 10 * time.Minute
+)
+
+var restLog = log.RegisterScope("restra", "External CA REST registration authority", 0)
+
+// RestSignRequest is the body of a signing request sent to the external CA.
+type RestSignRequest struct {
+	// CSR is the PEM encoded certificate signing request.
+	CSR string `json:"csr"`
+	// TTLSeconds is the requested lifetime of the certificate, after the RA policy has been applied.
+	TTLSeconds int64 `json:"ttlSeconds"`
+	// SubjectIDs are the authenticated identities of the caller.
+	SubjectIDs []string `json:"subjectIDs,omitempty"`
+}
+
+// RestSignResponse is the body of a successful signing response from the external CA.
+type RestSignResponse struct {
+	// Certificate is the PEM encoded signed leaf certificate.
+	Certificate string `json:"certificate"`
+}
+
+// RestChainResponse is the body of a certificate chain response from the external CA.
+type RestChainResponse struct {
+	// CertChain is the PEM encoded chain of intermediate certificates, ordered from the issuer of
+	// the leaf certificates towards the root. It does not include the root.
+	CertChain string `json:"certChain"`
+}
+
+// RestRA integrates with an external CA exposing a simple REST signing API, authenticated with mTLS.
+type RestRA struct {
+	client  *http.Client
+	address string
+	raOpts  *IstioRAOptions
+	backoff time.Duration
+
+	mutex sync.RWMutex
+	// keyCertBundle holds the cached intermediate chain and the root cert of the external CA.
+	keyCertBundle *util.KeyCertBundle
+	chainFetched  time.Time
+}
+
+// NewRestRA : Create a RA that interfaces with an external CA over the REST signing API
+func NewRestRA(raOpts *IstioRAOptions) (*RestRA, error) {
+	if raOpts.ExternalCAAddress == "" {
+		return nil, raerror.NewError(raerror.CAIllegalConfig, fmt.Errorf("external CA address is required for the REST RA"))
+	}
+	rootCert, err := ioutil.ReadFile(raOpts.CaCertFile)
+	if err != nil {
+		return nil, raerror.NewError(raerror.CAInitFail, fmt.Errorf("error reading root certificate for REST RA: %v", err))
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(rootCert) {
+		return nil, raerror.NewError(raerror.CAInitFail, fmt.Errorf("failed to parse root certificate %s", raOpts.CaCertFile))
+	}
+	tlsConfig := &tls.Config{
+		RootCAs:    roots,
+		MinVersion: tls.VersionTLS12,
+	}
+	if raOpts.ClientCertFile != "" || raOpts.ClientKeyFile != "" {
+		clientCert, err := tls.LoadX509KeyPair(raOpts.ClientCertFile, raOpts.ClientKeyFile)
+		if err != nil {
+			return nil, raerror.NewError(raerror.CAInitFail, fmt.Errorf("error loading client certificate for REST RA: %v", err))
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+	istioRA := &RestRA{
+		client: &http.Client{
+			Timeout:   restRequestTimeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+		address:       strings.TrimSuffix(raOpts.ExternalCAAddress, "/"),
+		raOpts:        raOpts,
+		backoff:       restInitialBackoff,
+		keyCertBundle: util.NewKeyCertBundleFromPem(nil, nil, nil, rootCert),
+	}
+	// The external CA may be temporarily unavailable at startup, the chain is fetched again on demand.
+	if err := istioRA.refreshChain(); err != nil {
+		restLog.Warnf("failed to fetch certificate chain from external CA %s: %v", istioRA.address, err)
+	}
+	return istioRA, nil
+}
+
+// retryable returns whether a request failing with the given status code should be retried.
+func retryable(code int) bool {
+	return code == http.StatusTooManyRequests || (code >= 500 && code != http.StatusNotImplemented)
+}
+
+// do sends a request to the external CA, retrying with exponential backoff on transport errors and
+// retryable status codes. The status code of the last response is returned along with any error.
+func (r *RestRA) do(method, path string, body []byte, out interface{}) (int, error) {
+	backoff := r.backoff
+	var lastErr error
+	code := 0
+	for attempt := 1; attempt <= restMaxAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		req, err := http.NewRequest(method, r.address+path, bytes.NewReader(body))
+		if err != nil {
+			return 0, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := r.client.Do(req)
+		if err != nil {
+			lastErr = err
+			restLog.Debugf("request to %s failed (attempt %d): %v", path, attempt, err)
+			continue
+		}
+		respBody, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		code = resp.StatusCode
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if code == http.StatusOK {
+			return code, json.Unmarshal(respBody, out)
+		}
+		lastErr = fmt.Errorf("status code %d: %s", code, strings.TrimSpace(string(respBody)))
+		if !retryable(code) {
+			break
+		}
+		restLog.Debugf("request to %s failed (attempt %d): %v", path, attempt, lastErr)
+	}
+	return code, fmt.Errorf("request to external CA %s%s failed: %v", r.address, path, lastErr)
+}
+
+// refreshChain fetches the intermediate chain from the external CA and caches it.
+func (r *RestRA) refreshChain() error {
+	chain := &RestChainResponse{}
+	if _, err := r.do(http.MethodGet, restChainPath, nil, chain); err != nil {
+		return err
+	}
+	chainPem := []byte(chain.CertChain)
+	if len(chainPem) > 0 {
+		if _, err := util.ParsePemEncodedCertificate(chainPem); err != nil {
+			return fmt.Errorf("invalid certificate chain from external CA: %v", err)
+		}
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	rootCert := r.keyCertBundle.GetRootCertPem()
+	r.keyCertBundle = util.NewKeyCertBundleFromPem(nil, nil, chainPem, rootCert)
+	r.chainFetched = time.Now()
+	return nil
+}
+
+// maybeRefreshChain refreshes the cached chain if it is missing or stale. A stale chain is kept if the
+// refresh fails.
+func (r *RestRA) maybeRefreshChain() {
+	r.mutex.RLock()
+	fresh := !r.chainFetched.IsZero() && time.Since(r.chainFetched) < restChainRefreshInterval
+	r.mutex.RUnlock()
+	if fresh {
+		return
+	}
+	if err := r.refreshChain(); err != nil {
+		restLog.Warnf("failed to refresh certificate chain from external CA %s: %v", r.address, err)
+	}
+}
+
+// verify checks the signed certificate chains up to the root of the external CA through the cached chain.
+func (r *RestRA) verify(certPem []byte) error {
+	cert, err := util.ParsePemEncodedCertificate(certPem)
+	if err != nil {
+		return err
+	}
+	bundle := r.GetCAKeyCertBundle()
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(bundle.GetRootCertPem())
+	intermediates := x509.NewCertPool()
+	intermediates.AppendCertsFromPEM(bundle.GetCertChainPem())
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+func (r *RestRA) restSign(csrPEM []byte, subjectIDs []string, lifetime time.Duration) ([]byte, error) {
+	body, err := json.Marshal(&RestSignRequest{
+		CSR:        string(csrPEM),
+		TTLSeconds: int64(lifetime.Seconds()),
+		SubjectIDs: subjectIDs,
+	})
+	if err != nil {
+		return nil, raerror.NewError(raerror.CSRError, err)
+	}
+	resp := &RestSignResponse{}
+	code, err := r.do(http.MethodPost, restSignPath, body, resp)
+	if err != nil {
+		if code >= 400 && code < 500 && code != http.StatusTooManyRequests {
+			return nil, raerror.NewError(raerror.CSRError, err)
+		}
+		return nil, raerror.NewError(raerror.CertGenError, err)
+	}
+	cert := []byte(resp.Certificate)
+	if !r.raOpts.VerifyAppendCA {
+		return cert, nil
+	}
+	r.maybeRefreshChain()
+	if err := r.verify(cert); err != nil {
+		// The external CA may have rotated its intermediate, refresh the chain once before failing.
+		if rerr := r.refreshChain(); rerr != nil {
+			return nil, raerror.NewError(raerror.CertGenError, fmt.Errorf("failed to verify signed certificate: %v", err))
+		}
+		if err := r.verify(cert); err != nil {
+			return nil, raerror.NewError(raerror.CertGenError, fmt.Errorf("failed to verify signed certificate: %v", err))
+		}
+	}
+	return cert, nil
+}
+
+// Sign takes a PEM-encoded CSR and cert opts, and returns a certificate signed by the external CA.
+func (r *RestRA) Sign(csrPEM []byte, certOpts ca.CertOpts) ([]byte, error) {
+	lifetime, err := preSign(r.raOpts, csrPEM, certOpts.SubjectIDs, certOpts.TTL, certOpts.ForCA)
+	if err != nil {
+		return nil, err
+	}
+	return r.restSign(csrPEM, certOpts.SubjectIDs, lifetime)
+}
+
+// SignWithCertChain is similar to Sign but returns the leaf cert and the entire cert chain.
+func (r *RestRA) SignWithCertChain(csrPEM []byte, certOpts ca.CertOpts) ([]byte, error) {
+	cert, err := r.Sign(csrPEM, certOpts)
+	if err != nil {
+		return nil, err
+	}
+	r.maybeRefreshChain()
+	chainPem := r.GetCAKeyCertBundle().GetCertChainPem()
+	if len(chainPem) > 0 {
+		cert = append(cert, chainPem...)
+	}
+	return cert, nil
+}
+
+// GetCAKeyCertBundle returns the KeyCertBundle for the CA.
+func (r *RestRA) GetCAKeyCertBundle() *util.KeyCertBundle {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.keyCertBundle
+}
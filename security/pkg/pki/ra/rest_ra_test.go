@@ -0,0 +1,173 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ra
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"istio.io/istio/security/pkg/pki/ca"
+	raerror "istio.io/istio/security/pkg/pki/error"
+	pkiutil "istio.io/istio/security/pkg/pki/util"
+)
+
+func createFakeRestRA(t *testing.T, fakeCA *FakeExternalCA) *RestRA {
+	raOpts := &IstioRAOptions{
+		ExternalCAType:    ExtCARest,
+		DefaultCertTTL:    30 * time.Minute,
+		MaxCertTTL:        time.Hour,
+		CaCertFile:        fakeCA.RootCertFile,
+		VerifyAppendCA:    true,
+		ExternalCAAddress: fakeCA.URL,
+		ClientCertFile:    fakeCA.ClientCertFile,
+		ClientKeyFile:     fakeCA.ClientKeyFile,
+	}
+	r, err := NewIstioRA(raOpts)
+	if err != nil {
+		t.Fatalf("failed to create REST RA: %v", err)
+	}
+	restRA := r.(*RestRA)
+	restRA.backoff = time.Millisecond
+	return restRA
+}
+
+func TestRestSign(t *testing.T) {
+	fakeCA := NewFakeExternalCA(t)
+	r := createFakeRestRA(t, fakeCA)
+	csrPEM := createFakeCsr(t)
+
+	certChain, err := r.SignWithCertChain(csrPEM, ca.CertOpts{SubjectIDs: []string{testCsrHostName}})
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	cert, err := pkiutil.ParsePemEncodedCertificate(certChain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cert.NotAfter.Sub(cert.NotBefore); got < 30*time.Minute {
+		t.Errorf("expected the default TTL to be applied, got lifetime %v", got)
+	}
+	if !bytes.HasSuffix(certChain, r.GetCAKeyCertBundle().GetCertChainPem()) {
+		t.Errorf("expected the cached chain to be appended to the signed certificate")
+	}
+	reqs := fakeCA.SignRequests()
+	if len(reqs) != 1 || reqs[0].TTLSeconds != int64((30*time.Minute).Seconds()) {
+		t.Errorf("unexpected sign requests: %+v", reqs)
+	}
+	if fakeCA.ChainFetches() != 1 {
+		t.Errorf("expected the chain to be fetched once and cached, got %d fetches", fakeCA.ChainFetches())
+	}
+}
+
+func TestRestSignPreSign(t *testing.T) {
+	fakeCA := NewFakeExternalCA(t)
+	r := createFakeRestRA(t, fakeCA)
+	csrPEM := createFakeCsr(t)
+
+	cases := []struct {
+		name     string
+		certOpts ca.CertOpts
+		errType  string
+	}{
+		{"unauthenticated identity", ca.CertOpts{SubjectIDs: []string{"spiffe://cluster.local/ns/default/sa/other"}}, "CSR_ERROR"},
+		{"ttl too long", ca.CertOpts{SubjectIDs: []string{testCsrHostName}, TTL: 2 * time.Hour}, "TTL_ERROR"},
+		{"ca cert", ca.CertOpts{SubjectIDs: []string{testCsrHostName}, ForCA: true}, "CSR_ERROR"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Sign(csrPEM, tt.certOpts)
+			if err == nil {
+				t.Fatalf("expected signing to fail")
+			}
+			if got := err.(*raerror.Error).ErrorType(); got != tt.errType {
+				t.Errorf("expected error type %s, got %s", tt.errType, got)
+			}
+		})
+	}
+	if len(fakeCA.SignRequests()) != 0 {
+		t.Errorf("requests rejected by policy must not reach the external CA")
+	}
+}
+
+func TestRestSignRetries(t *testing.T) {
+	fakeCA := NewFakeExternalCA(t)
+	r := createFakeRestRA(t, fakeCA)
+	csrPEM := createFakeCsr(t)
+	certOpts := ca.CertOpts{SubjectIDs: []string{testCsrHostName}}
+
+	fakeCA.FailNext(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	if _, err := r.Sign(csrPEM, certOpts); err != nil {
+		t.Fatalf("expected transient failures to be retried: %v", err)
+	}
+	if got := len(fakeCA.SignRequests()); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+
+	fakeCA.FailNext(http.StatusBadRequest)
+	_, err := r.Sign(csrPEM, certOpts)
+	if err == nil || err.(*raerror.Error).ErrorType() != "CSR_ERROR" {
+		t.Fatalf("expected a CSR error, got %v", err)
+	}
+	if got := len(fakeCA.SignRequests()); got != 4 {
+		t.Errorf("expected client errors not to be retried, got %d attempts", got)
+	}
+
+	fakeCA.FailNext(http.StatusInternalServerError, http.StatusInternalServerError,
+		http.StatusInternalServerError, http.StatusInternalServerError)
+	_, err = r.Sign(csrPEM, certOpts)
+	if err == nil || err.(*raerror.Error).ErrorType() != "CERT_GEN_ERROR" {
+		t.Fatalf("expected a cert generation error, got %v", err)
+	}
+}
+
+func TestRestSignIntermediateRotation(t *testing.T) {
+	fakeCA := NewFakeExternalCA(t)
+	r := createFakeRestRA(t, fakeCA)
+	csrPEM := createFakeCsr(t)
+	oldChain := r.GetCAKeyCertBundle().GetCertChainPem()
+
+	fakeCA.RotateIntermediate(t)
+	certChain, err := r.SignWithCertChain(csrPEM, ca.CertOpts{SubjectIDs: []string{testCsrHostName}})
+	if err != nil {
+		t.Fatalf("failed to sign after rotation: %v", err)
+	}
+	newChain := r.GetCAKeyCertBundle().GetCertChainPem()
+	if bytes.Equal(oldChain, newChain) {
+		t.Fatalf("expected the cached chain to be refreshed")
+	}
+	if !bytes.HasSuffix(certChain, newChain) {
+		t.Errorf("expected the refreshed chain to be appended to the signed certificate")
+	}
+}
+
+func TestRestRAClientCertRequired(t *testing.T) {
+	fakeCA := NewFakeExternalCA(t)
+	r, err := NewRestRA(&IstioRAOptions{
+		ExternalCAType:    ExtCARest,
+		DefaultCertTTL:    30 * time.Minute,
+		MaxCertTTL:        time.Hour,
+		CaCertFile:        fakeCA.RootCertFile,
+		ExternalCAAddress: fakeCA.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.backoff = time.Millisecond
+	if _, err := r.Sign(createFakeCsr(t), ca.CertOpts{SubjectIDs: []string{testCsrHostName}}); err == nil {
+		t.Fatalf("expected signing without a client certificate to fail")
+	}
+}
@@ -54,6 +54,14 @@ func removeOldChains(cfg *config.Config, ext dep.Dependencies, cmd string) {
 	flushAndDeleteChains(ext, cmd, constants.NAT, chains)
 }
 
+// removeNftablesTables deletes the tables created by the nftables backend. All the Istio rules live in these tables,
+// so deleting them removes every rule.
+func removeNftablesTables(ext dep.Dependencies) {
+	for _, family := range []string{constants.NftablesIPv4, constants.NftablesIPv6} {
+		ext.RunQuietlyAndIgnore(constants.NFT, "delete", "table", family, constants.NftablesTable)
+	}
+}
+
 func cleanup(cfg *config.Config) {
 	var ext dep.Dependencies
 	if cfg.DryRun {
@@ -62,6 +70,15 @@ func cleanup(cfg *config.Config) {
 		ext = &dep.RealDependencies{}
 	}
 
+	if cfg.Backend == constants.NftablesBackend {
+		defer func() {
+			// nft list is best efforts
+			_ = ext.Run(constants.NFT, "list", "ruleset")
+		}()
+		removeNftablesTables(ext)
+		return
+	}
+
 	defer func() {
 		for _, cmd := range []string{constants.IPTABLESSAVE, constants.IP6TABLESSAVE} {
 			// iptables-save is best efforts
@@ -45,6 +45,9 @@ var rootCmd = &cobra.Command{
 	PreRun: bindFlags,
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := constructConfig()
+		if cfg.Backend != constants.IptablesBackend && cfg.Backend != constants.NftablesBackend {
+			handleError(fmt.Errorf("unsupported backend %q, expected %q or %q", cfg.Backend, constants.IptablesBackend, constants.NftablesBackend))
+		}
 		cleanup(cfg)
 	},
 }
@@ -56,6 +59,7 @@ func constructConfig() *config.Config {
 		ProxyGID:      viper.GetString(constants.ProxyGID),
 		RedirectDNS:   viper.GetBool(constants.RedirectDNS),
 		CaptureAllDNS: viper.GetBool(constants.CaptureAllDNS),
+		Backend:       viper.GetString(constants.Backend),
 	}
 
 	// TODO: Make this more configurable, maybe with an allowlist of users to be captured for output instead of a denylist.
@@ -115,6 +119,11 @@ func bindFlags(cmd *cobra.Command, args []string) {
 		handleError(err)
 	}
 	viper.SetDefault(constants.RedirectDNS, dnsCaptureByAgent)
+
+	if err := viper.BindPFlag(constants.Backend, cmd.Flags().Lookup(constants.Backend)); err != nil {
+		handleError(err)
+	}
+	viper.SetDefault(constants.Backend, constants.IptablesBackend)
 }
 
 // https://github.com/spf13/viper/issues/233.
@@ -130,6 +139,9 @@ func init() {
 		"Specify the GID of the user for which the redirection is not applied. (same default value as -u param)")
 
 	rootCmd.Flags().Bool(constants.RedirectDNS, dnsCaptureByAgent, "Enable capture of dns traffic by istio-agent")
+
+	rootCmd.Flags().String(constants.Backend, constants.IptablesBackend,
+		"The backend used to apply the rules, either \"iptables\" or \"nftables\"")
 }
 
 func GetCommand() *cobra.Command {
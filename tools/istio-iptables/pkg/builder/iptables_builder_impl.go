@@ -16,6 +16,7 @@ package builder
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"istio.io/istio/tools/istio-iptables/pkg/constants"
@@ -162,3 +163,76 @@ func (rb *IptablesBuilderImpl) BuildV4Restore() string {
 func (rb *IptablesBuilderImpl) BuildV6Restore() string {
 	return rb.buildRestore(rb.rules.rulesv6)
 }
+
+// Chain is an iptables chain with its rules, in the order they are evaluated once all the rules are applied.
+type Chain struct {
+	Table string
+	Name  string
+	// Rules holds the matches and target of each rule, without the operation.
+	Rules [][]string
+}
+
+// buildChains replays the append and insert operations of the rules to compute the resulting chains. Chains are
+// returned in the order they are first referenced, including chains which are only the target of a jump.
+func buildChains(rules []*Rule) ([]*Chain, error) {
+	chains := []*Chain{}
+	chainTableLookupMap := map[string]*Chain{}
+	lookup := func(table, name string) *Chain {
+		chainTable := fmt.Sprintf("%s:%s", name, table)
+		c, present := chainTableLookupMap[chainTable]
+		if !present {
+			c = &Chain{Table: table, Name: name, Rules: [][]string{}}
+			chainTableLookupMap[chainTable] = c
+			chains = append(chains, c)
+		}
+		return c
+	}
+	for _, r := range rules {
+		c := lookup(r.table, r.chain)
+		switch r.params[0] {
+		case "-I":
+			position, err := strconv.Atoi(r.params[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid position %q of rule %q: %v", r.params[2], strings.Join(r.params, " "), err)
+			}
+			params := r.params[3:]
+			idx := position - 1
+			if idx > len(c.Rules) {
+				idx = len(c.Rules)
+			}
+			if idx < 0 {
+				idx = 0
+			}
+			c.Rules = append(c.Rules[:idx], append([][]string{params}, c.Rules[idx:]...)...)
+		default:
+			c.Rules = append(c.Rules, r.params[2:])
+		}
+		if target := jumpTarget(r.params); target != "" {
+			lookup(r.table, target)
+		}
+	}
+	return chains, nil
+}
+
+// jumpTarget returns the chain targeted by the params of a rule, if any.
+func jumpTarget(params []string) string {
+	for i := 0; i+1 < len(params); i++ {
+		if params[i] == "-j" {
+			if _, builtin := constants.BuiltInTargetsMap[params[i+1]]; !builtin {
+				return params[i+1]
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// BuildV4Chains returns the IPv4 chains as they will be once the rules are applied.
+func (rb *IptablesBuilderImpl) BuildV4Chains() ([]*Chain, error) {
+	return buildChains(rb.rules.rulesv4)
+}
+
+// BuildV6Chains returns the IPv6 chains as they will be once the rules are applied.
+func (rb *IptablesBuilderImpl) BuildV6Chains() ([]*Chain, error) {
+	return buildChains(rb.rules.rulesv6)
+}
@@ -0,0 +1,282 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+	"strings"
+
+	"istio.io/istio/tools/istio-iptables/pkg/constants"
+)
+
+// nftBaseChains maps the built-in iptables chains used by Istio to the definition of the equivalent nftables base
+// chain. Priorities match the ones of the iptables tables, so the rules are evaluated at the same point.
+var nftBaseChains = map[string]string{
+	constants.NAT + ":" + constants.PREROUTING:    "type nat hook prerouting priority -100; policy accept;",
+	constants.NAT + ":" + constants.OUTPUT:        "type nat hook output priority -100; policy accept;",
+	constants.NAT + ":" + constants.POSTROUTING:   "type nat hook postrouting priority 100; policy accept;",
+	constants.MANGLE + ":" + constants.PREROUTING: "type filter hook prerouting priority -150; policy accept;",
+	constants.MANGLE + ":" + constants.OUTPUT:     "type route hook output priority -150; policy accept;",
+}
+
+// nftChain returns the name of the nftables chain holding the rules of an iptables table and chain. All chains live in
+// a single table, so the name of the iptables table is used as prefix.
+func nftChain(table, chain string) string {
+	return table + "_" + chain
+}
+
+// buildNftables translates the rules to a nftables script for the given family. The script replaces the Istio table
+// atomically when applied with `nft -f`. Rules are applied in order, inserted rules are placed as iptables would.
+func (rb *IptablesBuilderImpl) buildNftables(family string, rules []*Rule) (string, error) {
+	if len(rules) == 0 {
+		return "", nil
+	}
+	chains, err := buildChains(rules)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	// Declaring then deleting the table makes the script idempotent: any previous Istio table is
+	// removed in the same transaction. All chains are declared before the rules, so jumps can be resolved.
+	fmt.Fprintf(&b, "table %s %s\n", family, constants.NftablesTable)
+	fmt.Fprintf(&b, "delete table %s %s\n", family, constants.NftablesTable)
+	fmt.Fprintf(&b, "table %s %s {\n", family, constants.NftablesTable)
+	for _, c := range chains {
+		if def, f := nftBaseChains[c.Table+":"+c.Name]; f {
+			fmt.Fprintf(&b, "\tchain %s {\n\t\t%s\n\t}\n", nftChain(c.Table, c.Name), def)
+		} else {
+			fmt.Fprintf(&b, "\tchain %s {\n\t}\n", nftChain(c.Table, c.Name))
+		}
+	}
+	fmt.Fprintln(&b, "}")
+	for _, c := range chains {
+		for _, params := range c.Rules {
+			expr, err := translateToNftables(family, c.Table, params)
+			if err != nil {
+				return "", fmt.Errorf("failed to translate rule %q in %s/%s: %v", strings.Join(params, " "), c.Table, c.Name, err)
+			}
+			fmt.Fprintf(&b, "add rule %s %s %s %s\n", family, constants.NftablesTable, nftChain(c.Table, c.Name), expr)
+		}
+	}
+	return b.String(), nil
+}
+
+// translateToNftables translates the matches and target of an iptables rule to a nftables rule expression.
+// Only the options used by Istio are supported.
+func translateToNftables(family, table string, params []string) (string, error) {
+	addr := "ip"
+	if family == constants.NftablesIPv6 {
+		addr = "ip6"
+	}
+	exprs := []string{}
+	// Index of the protocol match in exprs, replaced by the port match if any.
+	protoIdx := -1
+	proto := ""
+	negate := false
+	op := func() string {
+		if negate {
+			negate = false
+			return "!= "
+		}
+		return ""
+	}
+	next := func(i int) (string, error) {
+		if i+1 >= len(params) {
+			return "", fmt.Errorf("missing value for %s", params[i])
+		}
+		return params[i+1], nil
+	}
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch p {
+		case "!":
+			negate = true
+			continue
+		case "-p":
+			v, err := next(i)
+			if err != nil {
+				return "", err
+			}
+			proto = v
+			protoIdx = len(exprs)
+			exprs = append(exprs, fmt.Sprintf("meta l4proto %s%s", op(), v))
+			i++
+		case "--dport":
+			v, err := next(i)
+			if err != nil {
+				return "", err
+			}
+			if proto == "" {
+				return "", fmt.Errorf("--dport requires a protocol")
+			}
+			exprs[protoIdx] = fmt.Sprintf("%s dport %s%s", proto, op(), v)
+			i++
+		case "-s", "-d":
+			v, err := next(i)
+			if err != nil {
+				return "", err
+			}
+			dir := "saddr"
+			if p == "-d" {
+				dir = "daddr"
+			}
+			exprs = append(exprs, fmt.Sprintf("%s %s %s%s", addr, dir, op(), v))
+			i++
+		case "-i", "-o":
+			v, err := next(i)
+			if err != nil {
+				return "", err
+			}
+			name := "iifname"
+			if p == "-o" {
+				name = "oifname"
+			}
+			exprs = append(exprs, fmt.Sprintf("%s %s%q", name, op(), v))
+			i++
+		case "-m":
+			// Match extensions are implied by the options below.
+			if _, err := next(i); err != nil {
+				return "", err
+			}
+			i++
+		case "--uid-owner", "--gid-owner":
+			v, err := next(i)
+			if err != nil {
+				return "", err
+			}
+			key := "skuid"
+			if p == "--gid-owner" {
+				key = "skgid"
+			}
+			exprs = append(exprs, fmt.Sprintf("meta %s %s%s", key, op(), v))
+			i++
+		case "--mark":
+			v, err := next(i)
+			if err != nil {
+				return "", err
+			}
+			// --mark follows either "-m mark" or "-m connmark".
+			key := "meta mark"
+			if i >= 2 && params[i-2] == "-m" && params[i-1] == "connmark" {
+				key = "ct mark"
+			} else if i >= 3 && params[i-3] == "-m" && params[i-2] == "connmark" {
+				key = "ct mark"
+			}
+			exprs = append(exprs, fmt.Sprintf("%s %s%s", key, op(), v))
+			i++
+		case "--ctstate":
+			v, err := next(i)
+			if err != nil {
+				return "", err
+			}
+			exprs = append(exprs, fmt.Sprintf("ct state %s%s", op(), strings.ToLower(v)))
+			i++
+		case "-j":
+			v, err := next(i)
+			if err != nil {
+				return "", err
+			}
+			target, err := translateTarget(table, v, params[i+2:])
+			if err != nil {
+				return "", err
+			}
+			exprs = append(exprs, target)
+			return strings.Join(exprs, " "), nil
+		default:
+			return "", fmt.Errorf("unsupported option %q", p)
+		}
+		if negate {
+			return "", fmt.Errorf("unsupported negation of %q", p)
+		}
+	}
+	return "", fmt.Errorf("missing target")
+}
+
+// translateTarget translates an iptables target and its options to a nftables statement.
+func translateTarget(table, target string, options []string) (string, error) {
+	opts := map[string]string{}
+	for i := 0; i < len(options); i++ {
+		if i+1 < len(options) && !strings.HasPrefix(options[i+1], "--") {
+			opts[options[i]] = options[i+1]
+			i++
+		} else {
+			opts[options[i]] = ""
+		}
+	}
+	switch target {
+	case constants.RETURN:
+		return "return", nil
+	case constants.ACCEPT:
+		return "accept", nil
+	case constants.REJECT:
+		return "reject", nil
+	case constants.REDIRECT:
+		port, f := opts["--to-ports"]
+		if !f {
+			port, f = opts["--to-port"]
+		}
+		if !f {
+			return "redirect", nil
+		}
+		return "redirect to :" + port, nil
+	case constants.MARK:
+		mark, f := opts["--set-mark"]
+		if !f {
+			return "", fmt.Errorf("MARK requires --set-mark")
+		}
+		return "meta mark set " + mark, nil
+	case constants.CONNMARK:
+		if _, f := opts["--save-mark"]; f {
+			return "ct mark set meta mark", nil
+		}
+		if _, f := opts["--restore-mark"]; f {
+			return "meta mark set ct mark", nil
+		}
+		return "", fmt.Errorf("CONNMARK requires --save-mark or --restore-mark")
+	case constants.TPROXY:
+		port, f := opts["--on-port"]
+		if !f {
+			return "", fmt.Errorf("TPROXY requires --on-port")
+		}
+		stmt := "tproxy to :" + port
+		if mark, f := opts["--tproxy-mark"]; f {
+			value := mark
+			if i := strings.Index(mark, "/"); i >= 0 {
+				if mask := mark[i+1:]; mask != "0xffffffff" {
+					return "", fmt.Errorf("unsupported TPROXY mark mask %q", mask)
+				}
+				value = mark[:i]
+			}
+			stmt += " meta mark set " + value
+		}
+		// Unlike the iptables target, the tproxy statement does not end rule evaluation.
+		return stmt + " accept", nil
+	}
+	if len(options) > 0 {
+		return "", fmt.Errorf("unsupported target %q", target)
+	}
+	return "jump " + nftChain(table, target), nil
+}
+
+// BuildV4Nftables returns the IPv4 rules as a nftables script, applied atomically with `nft -f`.
+func (rb *IptablesBuilderImpl) BuildV4Nftables() (string, error) {
+	return rb.buildNftables(constants.NftablesIPv4, rb.rules.rulesv4)
+}
+
+// BuildV6Nftables returns the IPv6 rules as a nftables script, applied atomically with `nft -f`.
+func (rb *IptablesBuilderImpl) BuildV6Nftables() (string, error) {
+	return rb.buildNftables(constants.NftablesIPv6, rb.rules.rulesv6)
+}
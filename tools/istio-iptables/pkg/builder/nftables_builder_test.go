@@ -0,0 +1,144 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"testing"
+
+	"istio.io/istio/tools/istio-iptables/pkg/constants"
+)
+
+func TestBuildNftablesEmpty(t *testing.T) {
+	iptables := NewIptablesBuilder()
+	for _, build := range []func() (string, error){iptables.BuildV4Nftables, iptables.BuildV6Nftables} {
+		actual, err := build()
+		if err != nil || actual != "" {
+			t.Errorf("Expected empty script; but instead got Actual: %q, err: %v", actual, err)
+		}
+	}
+}
+
+func TestBuildV4NftablesInsertOrder(t *testing.T) {
+	iptables := NewIptablesBuilder()
+	iptables.AppendRuleV4(constants.PREROUTING, constants.NAT, "-p", "tcp", "-j", constants.ISTIOINBOUND)
+	iptables.InsertRuleV4(constants.PREROUTING, constants.NAT, 1, "-i", "eth0", "-j", constants.RETURN)
+	iptables.InsertRuleV4(constants.PREROUTING, constants.NAT, 1, "-i", "eth1", "-j", constants.RETURN)
+	iptables.InsertRuleV4(constants.PREROUTING, constants.NAT, 5, "-i", "eth2", "-j", constants.RETURN)
+	actual, err := iptables.BuildV4Nftables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `table ip istio
+delete table ip istio
+table ip istio {
+	chain nat_PREROUTING {
+		type nat hook prerouting priority -100; policy accept;
+	}
+	chain nat_ISTIO_INBOUND {
+	}
+}
+add rule ip istio nat_PREROUTING iifname "eth1" return
+add rule ip istio nat_PREROUTING iifname "eth0" return
+add rule ip istio nat_PREROUTING meta l4proto tcp jump nat_ISTIO_INBOUND
+add rule ip istio nat_PREROUTING iifname "eth2" return
+`
+	if actual != expected {
+		t.Errorf("Output didn't match: Got:\n%s\nExpected:\n%s", actual, expected)
+	}
+}
+
+func TestTranslateToNftables(t *testing.T) {
+	cases := []struct {
+		name     string
+		family   string
+		table    string
+		params   []string
+		expected string
+	}{
+		{
+			"negated port",
+			constants.NftablesIPv4, constants.NAT,
+			[]string{"-o", "lo", "-p", "tcp", "!", "--dport", "53", "-m", "owner", "--uid-owner", "1337", "-j", constants.ISTIOINREDIRECT},
+			`oifname "lo" tcp dport != 53 meta skuid 1337 jump nat_ISTIO_IN_REDIRECT`,
+		},
+		{
+			"negated owner",
+			constants.NftablesIPv4, constants.NAT,
+			[]string{"-o", "lo", "-m", "owner", "!", "--gid-owner", "1337", "-j", constants.RETURN},
+			`oifname "lo" meta skgid != 1337 return`,
+		},
+		{
+			"ipv6 address",
+			constants.NftablesIPv6, constants.NAT,
+			[]string{"!", "-d", "::1/128", "-j", constants.ISTIOREDIRECT},
+			"ip6 daddr != ::1/128 jump nat_ISTIO_REDIRECT",
+		},
+		{
+			"redirect",
+			constants.NftablesIPv4, constants.NAT,
+			[]string{"-p", "udp", "--dport", "53", "-j", constants.REDIRECT, "--to-port", "15053"},
+			"udp dport 53 redirect to :15053",
+		},
+		{
+			"connmark",
+			constants.NftablesIPv4, constants.MANGLE,
+			[]string{"-p", "tcp", "-m", "connmark", "--mark", "1337", "-j", "CONNMARK", "--restore-mark"},
+			"meta l4proto tcp ct mark 1337 meta mark set ct mark",
+		},
+		{
+			"negated mark",
+			constants.NftablesIPv4, constants.MANGLE,
+			[]string{"-p", "tcp", "-i", "lo", "-m", "mark", "!", "--mark", "1338", "-j", constants.RETURN},
+			`meta l4proto tcp iifname "lo" meta mark != 1338 return`,
+		},
+		{
+			"tproxy",
+			constants.NftablesIPv4, constants.MANGLE,
+			[]string{"-p", "tcp", "-j", constants.TPROXY, "--tproxy-mark", "1337/0xffffffff", "--on-port", "15006"},
+			"meta l4proto tcp tproxy to :15006 meta mark set 1337 accept",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := translateToNftables(tt.family, tt.table, tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != tt.expected {
+				t.Errorf("Output didn't match: Got: %s, Expected: %s", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTranslateToNftablesUnsupported(t *testing.T) {
+	cases := []struct {
+		name   string
+		params []string
+	}{
+		{"unknown option", []string{"--foo", "bar", "-j", constants.RETURN}},
+		{"missing target", []string{"-p", "tcp"}},
+		{"port without protocol", []string{"--dport", "80", "-j", constants.RETURN}},
+		{"partial tproxy mask", []string{"-p", "tcp", "-j", constants.TPROXY, "--tproxy-mark", "1/0xff", "--on-port", "15006"}},
+		{"unknown target", []string{"-j", "LOG", "--log-prefix", "foo"}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual, err := translateToNftables(constants.NftablesIPv4, constants.MANGLE, tt.params); err == nil {
+				t.Errorf("Expected translation to fail; but instead got %q", actual)
+			}
+		})
+	}
+}
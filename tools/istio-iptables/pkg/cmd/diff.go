@@ -0,0 +1,422 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"istio.io/istio/tools/istio-iptables/pkg/builder"
+	"istio.io/istio/tools/istio-iptables/pkg/constants"
+	dep "istio.io/istio/tools/istio-iptables/pkg/dependencies"
+)
+
+// Kinds of drift between the expected and the installed rules.
+const (
+	DriftMissing   = "missing"
+	DriftExtra     = "extra"
+	DriftReordered = "reordered"
+)
+
+// RuleDrift is a difference between the rules generated for the configuration and the rules installed in the network
+// namespace.
+type RuleDrift struct {
+	Table string
+	Chain string
+	Kind  string
+	// Rules holds the missing or extra rule, or the expected order of the rules of a reordered chain.
+	Rules []string
+	// Actual holds the installed order of the rules of a reordered chain.
+	Actual []string
+}
+
+// savedRule is a rule of the iptables-save output, or a generated rule, with its normalized form used for comparison.
+type savedRule struct {
+	display string
+	key     string
+}
+
+// diffRules compares the rules generated for the configuration with the ones reported by iptables-save, and
+// ip6tables-save if IPv6 is enabled. The rules must have been built first.
+func (iptConfigurator *IptablesConfigurator) diffRules(ext dep.Dependencies) ([]RuleDrift, error) {
+	if iptConfigurator.cfg.Backend == constants.NftablesBackend {
+		return nil, fmt.Errorf("comparing the installed rules is only supported by the %s backend", constants.IptablesBackend)
+	}
+	expected, err := iptConfigurator.iptables.BuildV4Chains()
+	if err != nil {
+		return nil, err
+	}
+	saved, err := ext.RunWithOutput(constants.IPTABLESSAVE)
+	if err != nil {
+		return nil, err
+	}
+	drifts := diffChains(expected, parseIptablesSave(saved))
+	if !iptConfigurator.cfg.EnableInboundIPv6 {
+		return drifts, nil
+	}
+	expected, err = iptConfigurator.iptables.BuildV6Chains()
+	if err != nil {
+		return nil, err
+	}
+	saved, err = ext.RunWithOutput(constants.IP6TABLESSAVE)
+	if err != nil {
+		return nil, err
+	}
+	return append(drifts, diffChains(expected, parseIptablesSave(saved))...), nil
+}
+
+// PrintRuleDrifts writes a human readable report of the drifts.
+func PrintRuleDrifts(w io.Writer, drifts []RuleDrift) {
+	if len(drifts) == 0 {
+		fmt.Fprintln(w, "Installed rules match the expected rules.")
+		return
+	}
+	for _, d := range drifts {
+		switch d.Kind {
+		case DriftReordered:
+			fmt.Fprintf(w, "%s %s/%s:\n  expected order:\n", d.Kind, d.Table, d.Chain)
+			for _, r := range d.Rules {
+				fmt.Fprintf(w, "    %s\n", r)
+			}
+			fmt.Fprintln(w, "  installed order:")
+			for _, r := range d.Actual {
+				fmt.Fprintf(w, "    %s\n", r)
+			}
+		default:
+			for _, r := range d.Rules {
+				fmt.Fprintf(w, "%s %s/%s: %s\n", d.Kind, d.Table, d.Chain, r)
+			}
+		}
+	}
+}
+
+// parseIptablesSave returns the rules of each table and chain of the iptables-save output, keyed by table:chain.
+// Declared chains without rules are included.
+func parseIptablesSave(out string) map[string][]savedRule {
+	chains := map[string][]savedRule{}
+	table := ""
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "*"):
+			table = line[1:]
+		case strings.HasPrefix(line, ":"):
+			if fields := strings.Fields(line[1:]); len(fields) > 0 {
+				key := table + ":" + fields[0]
+				if _, f := chains[key]; !f {
+					chains[key] = []savedRule{}
+				}
+			}
+		case strings.HasPrefix(line, "-A "):
+			params := splitRule(line)
+			if len(params) < 2 {
+				continue
+			}
+			key := table + ":" + params[1]
+			chains[key] = append(chains[key], savedRule{display: line, key: normalizeRule(params[2:])})
+		}
+	}
+	return chains
+}
+
+// diffChains compares the expected chains with the saved ones. Built-in chains are shared with other components, so
+// only the rules jumping to Istio chains are considered as extra there. Istio chains which are not expected anymore
+// are reported as extra as a whole.
+func diffChains(expected []*builder.Chain, saved map[string][]savedRule) []RuleDrift {
+	drifts := []RuleDrift{}
+	seen := map[string]struct{}{}
+	for _, c := range expected {
+		key := c.Table + ":" + c.Name
+		seen[key] = struct{}{}
+		if len(c.Rules) == 0 {
+			continue
+		}
+		want := make([]savedRule, 0, len(c.Rules))
+		for _, params := range c.Rules {
+			want = append(want, savedRule{display: strings.Join(append([]string{"-A", c.Name}, params...), " "), key: normalizeRule(params)})
+		}
+		got := saved[key]
+		if _, builtin := constants.BuiltInChainsMap[c.Name]; builtin {
+			got = filterIstioRules(got, want)
+		}
+		drifts = append(drifts, diffChain(c.Table, c.Name, want, got)...)
+	}
+
+	stale := []string{}
+	for key := range saved {
+		if _, f := seen[key]; f {
+			continue
+		}
+		if chain := key[strings.Index(key, ":")+1:]; strings.HasPrefix(chain, "ISTIO_") {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(stale)
+	for _, key := range stale {
+		i := strings.Index(key, ":")
+		drifts = append(drifts, diffChain(key[:i], key[i+1:], nil, saved[key])...)
+	}
+	return drifts
+}
+
+// filterIstioRules returns the rules of a built-in chain which are managed by Istio: the expected ones and the ones
+// jumping to Istio chains.
+func filterIstioRules(rules []savedRule, want []savedRule) []savedRule {
+	expected := map[string]struct{}{}
+	for _, r := range want {
+		expected[r.key] = struct{}{}
+	}
+	out := []savedRule{}
+	for _, r := range rules {
+		if _, f := expected[r.key]; f || strings.Contains(r.key, "-j ISTIO_") {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func diffChain(table, chain string, want, got []savedRule) []RuleDrift {
+	drifts := []RuleDrift{}
+	remaining := map[string]int{}
+	for _, r := range got {
+		remaining[r.key]++
+	}
+	// Expected rules present in the chain, in the expected order.
+	common := []savedRule{}
+	for _, r := range want {
+		if remaining[r.key] > 0 {
+			remaining[r.key]--
+			common = append(common, r)
+			continue
+		}
+		drifts = append(drifts, RuleDrift{Table: table, Chain: chain, Kind: DriftMissing, Rules: []string{r.display}})
+	}
+	expected := map[string]int{}
+	for _, r := range want {
+		expected[r.key]++
+	}
+	// Installed rules which are expected, in the installed order.
+	installed := []savedRule{}
+	for _, r := range got {
+		if expected[r.key] > 0 {
+			expected[r.key]--
+			installed = append(installed, r)
+			continue
+		}
+		drifts = append(drifts, RuleDrift{Table: table, Chain: chain, Kind: DriftExtra, Rules: []string{r.display}})
+	}
+	for i := range common {
+		if common[i].key != installed[i].key {
+			d := RuleDrift{Table: table, Chain: chain, Kind: DriftReordered}
+			for j := range common {
+				d.Rules = append(d.Rules, common[j].display)
+				d.Actual = append(d.Actual, installed[j].display)
+			}
+			drifts = append(drifts, d)
+			break
+		}
+	}
+	return drifts
+}
+
+// splitRule splits a rule of the iptables-save output in arguments, honoring double quotes.
+func splitRule(line string) []string {
+	args := []string{}
+	var cur strings.Builder
+	quoted, escaped, pending := false, false, false
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			pending = true
+		case r == ' ' && !quoted:
+			if pending || cur.Len() > 0 {
+				args = append(args, cur.String())
+				cur.Reset()
+				pending = false
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if pending || cur.Len() > 0 {
+		args = append(args, cur.String())
+	}
+	return args
+}
+
+// basicMatches are the options iptables-save prints first, in this order, whatever the order they were given in.
+var basicMatches = []string{"-s", "-d", "-i", "-o", "-p"}
+
+var longOptions = map[string]string{
+	"--source":           "-s",
+	"--destination":      "-d",
+	"--in-interface":     "-i",
+	"--out-interface":    "-o",
+	"--protocol":         "-p",
+	"--jump":             "-j",
+	"--match":            "-m",
+	"--destination-port": "--dport",
+	"--source-port":      "--sport",
+}
+
+// normalizeRule returns a canonical form of the matches and target of a rule, so that a rule given to iptables and
+// the same rule printed by iptables-save compare equal. iptables-save reorders the basic matches, makes the protocol
+// match explicit, prints marks in hexadecimal and expands the defaults of some targets.
+func normalizeRule(params []string) string {
+	basic := map[string]string{}
+	matches := []string{}
+	target := []string{}
+	proto := ""
+	negate := ""
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		if long, f := longOptions[p]; f {
+			p = long
+		}
+		value := ""
+		if i+1 < len(params) {
+			value = params[i+1]
+		}
+		switch p {
+		case "!":
+			negate = "! "
+			continue
+		case "-s", "-d", "-i", "-o", "-p":
+			if p == "-p" {
+				value = strings.ToLower(value)
+				proto = value
+			}
+			if (p == "-s" || p == "-d") && !strings.Contains(value, "/") {
+				if strings.Contains(value, ":") {
+					value += "/128"
+				} else {
+					value += "/32"
+				}
+			}
+			basic[p] = negate + p + " " + value
+			i++
+		case "-m":
+			// The protocol match is implied by the port options.
+			if value != proto {
+				matches = append(matches, "-m "+value)
+			}
+			i++
+		case "-j":
+			target = normalizeTarget(value, params[i+2:])
+			i = len(params)
+		case "--mark", "--set-mark", "--set-xmark":
+			matches = append(matches, negate+p+" "+normalizeMark(value))
+			i++
+		default:
+			if i+1 < len(params) && !strings.HasPrefix(value, "-") && value != "!" {
+				matches = append(matches, negate+p+" "+value)
+				i++
+			} else {
+				matches = append(matches, negate+p)
+			}
+		}
+		negate = ""
+	}
+	out := []string{}
+	for _, m := range basicMatches {
+		if v, f := basic[m]; f {
+			out = append(out, v)
+		}
+	}
+	out = append(out, matches...)
+	out = append(out, target...)
+	return strings.Join(out, " ")
+}
+
+// normalizeTarget returns a canonical form of a target and its options.
+func normalizeTarget(target string, options []string) []string {
+	opts := map[string]string{}
+	for i := 0; i < len(options); i++ {
+		if i+1 < len(options) && !strings.HasPrefix(options[i+1], "--") {
+			opts[options[i]] = options[i+1]
+			i++
+		} else {
+			opts[options[i]] = ""
+		}
+	}
+	switch target {
+	case constants.REDIRECT:
+		if v, f := opts["--to-port"]; f {
+			delete(opts, "--to-port")
+			opts["--to-ports"] = v
+		}
+	case constants.MARK:
+		if v, f := opts["--set-mark"]; f {
+			delete(opts, "--set-mark")
+			opts["--set-xmark"] = v
+		}
+	case constants.TPROXY:
+		if v := opts["--on-ip"]; v == "0.0.0.0" || v == "::" {
+			delete(opts, "--on-ip")
+		}
+	case constants.CONNMARK:
+		for _, mask := range []string{"--nfmask", "--ctmask"} {
+			if v, f := opts[mask]; f && normalizeMark(v) == normalizeMark("0xffffffff") {
+				delete(opts, mask)
+			}
+		}
+	}
+	for _, mark := range []string{"--set-xmark", "--tproxy-mark"} {
+		if v, f := opts[mark]; f {
+			opts[mark] = normalizeMark(v)
+		}
+	}
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := []string{"-j", target}
+	for _, k := range keys {
+		out = append(out, k)
+		if opts[k] != "" {
+			out = append(out, opts[k])
+		}
+	}
+	return out
+}
+
+// normalizeMark returns a mark, with an optional mask, in hexadecimal. A full mask is omitted.
+func normalizeMark(mark string) string {
+	value, mask := mark, ""
+	if i := strings.Index(mark, "/"); i >= 0 {
+		value, mask = mark[:i], mark[i+1:]
+	}
+	hex := func(s string) string {
+		n, err := strconv.ParseUint(s, 0, 32)
+		if err != nil {
+			return s
+		}
+		return fmt.Sprintf("0x%x", n)
+	}
+	if mask == "" || hex(mask) == "0xffffffff" {
+		return hex(value)
+	}
+	return hex(value) + "/" + hex(mask)
+}
@@ -0,0 +1,169 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"istio.io/istio/tools/istio-iptables/pkg/constants"
+	dep "istio.io/istio/tools/istio-iptables/pkg/dependencies"
+)
+
+// savedRulesDependencies returns canned iptables-save output.
+type savedRulesDependencies struct {
+	dep.StdoutStubDependencies
+	saved string
+}
+
+func (s *savedRulesDependencies) RunWithOutput(cmd string, args ...string) (string, error) {
+	return s.saved, nil
+}
+
+func TestDiffRules(t *testing.T) {
+	saved, err := ioutil.ReadFile("testdata/tproxy.iptables-save")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name     string
+		mutate   func(string) string
+		expected []RuleDrift
+	}{
+		{
+			name:     "in sync",
+			mutate:   func(s string) string { return s },
+			expected: []RuleDrift{},
+		},
+		{
+			name: "missing rule",
+			mutate: func(s string) string {
+				return strings.Replace(s, "-A ISTIO_OUTPUT -d 127.0.0.1/32 -j RETURN\n", "", 1)
+			},
+			expected: []RuleDrift{
+				{Table: "nat", Chain: "ISTIO_OUTPUT", Kind: DriftMissing, Rules: []string{"-A ISTIO_OUTPUT -d 127.0.0.1/32 -j RETURN"}},
+			},
+		},
+		{
+			name: "extra rules",
+			mutate: func(s string) string {
+				s = strings.Replace(s, "-A ISTIO_OUTPUT -j ISTIO_REDIRECT\n",
+					"-A ISTIO_OUTPUT -d 10.0.0.0/8 -j RETURN\n-A ISTIO_OUTPUT -j ISTIO_REDIRECT\n", 1)
+				return strings.Replace(s, "-A OUTPUT -p tcp -j ISTIO_OUTPUT\n",
+					"-A OUTPUT -p tcp -j ISTIO_OUTPUT\n-A OUTPUT -p udp -j ISTIO_OUTPUT\n", 1)
+			},
+			expected: []RuleDrift{
+				{Table: "nat", Chain: "OUTPUT", Kind: DriftExtra, Rules: []string{"-A OUTPUT -p udp -j ISTIO_OUTPUT"}},
+				{Table: "nat", Chain: "ISTIO_OUTPUT", Kind: DriftExtra, Rules: []string{"-A ISTIO_OUTPUT -d 10.0.0.0/8 -j RETURN"}},
+			},
+		},
+		{
+			name: "reordered rules",
+			mutate: func(s string) string {
+				return strings.Replace(s, "-A ISTIO_DIVERT -j MARK --set-xmark 0x539/0xffffffff\n-A ISTIO_DIVERT -j ACCEPT\n",
+					"-A ISTIO_DIVERT -j ACCEPT\n-A ISTIO_DIVERT -j MARK --set-xmark 0x539/0xffffffff\n", 1)
+			},
+			expected: []RuleDrift{
+				{
+					Table: "mangle", Chain: "ISTIO_DIVERT", Kind: DriftReordered,
+					Rules:  []string{"-A ISTIO_DIVERT -j MARK --set-mark 1337", "-A ISTIO_DIVERT -j ACCEPT"},
+					Actual: []string{"-A ISTIO_DIVERT -j ACCEPT", "-A ISTIO_DIVERT -j MARK --set-xmark 0x539/0xffffffff"},
+				},
+			},
+		},
+		{
+			name: "stale chain",
+			mutate: func(s string) string {
+				return strings.Replace(s, "COMMIT\n", ":ISTIO_OLD - [0:0]\n-A ISTIO_OLD -j RETURN\nCOMMIT\n", 1)
+			},
+			expected: []RuleDrift{
+				{Table: "mangle", Chain: "ISTIO_OLD", Kind: DriftExtra, Rules: []string{"-A ISTIO_OLD -j RETURN"}},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := constructTestConfig()
+			cfg.InboundInterceptionMode = constants.TPROXY
+			cfg.InboundPortsInclude = "*"
+			cfg.OutboundIPRangesInclude = "*"
+			iptConfigurator := NewIptablesConfigurator(cfg, &dep.StdoutStubDependencies{})
+			iptConfigurator.buildRules()
+			actual, err := iptConfigurator.diffRules(&savedRulesDependencies{saved: tt.mutate(string(saved))})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("Output mismatch.\nExpected: %#v\nActual: %#v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNormalizeRule(t *testing.T) {
+	cases := []struct {
+		generated string
+		saved     string
+	}{
+		{
+			"-o lo ! -d 127.0.0.1/32 -p tcp ! --dport 53 -m owner --uid-owner 1337 -j ISTIO_IN_REDIRECT",
+			"! -d 127.0.0.1/32 -o lo -p tcp -m tcp ! --dport 53 -m owner --uid-owner 1337 -j ISTIO_IN_REDIRECT",
+		},
+		{
+			"-p udp --dport 53 -d 127.0.0.53/32 -j REDIRECT --to-port 15053",
+			"-d 127.0.0.53/32 -p udp -m udp --dport 53 -j REDIRECT --to-ports 15053",
+		},
+		{
+			"-i eth0 -d 10.0.0.0/8 -j ISTIO_REDIRECT",
+			"-d 10.0.0.0/8 -i eth0 -j ISTIO_REDIRECT",
+		},
+		{
+			"-o lo -s ::6/128 -j RETURN",
+			"-s ::6/128 -o lo -j RETURN",
+		},
+	}
+	for _, tt := range cases {
+		generated, saved := normalizeRule(strings.Fields(tt.generated)), normalizeRule(splitRule(tt.saved))
+		if generated != saved {
+			t.Errorf("Normalized rules mismatch.\nGenerated: %s\nSaved: %s", generated, saved)
+		}
+	}
+	if got := splitRule(`-A OUTPUT -m comment --comment "metadata server" -j RETURN`); len(got) != 8 || got[5] != "metadata server" {
+		t.Errorf("Unexpected split of quoted comment: %#v", got)
+	}
+}
+
+func TestPrintRuleDrifts(t *testing.T) {
+	var out bytes.Buffer
+	PrintRuleDrifts(&out, []RuleDrift{
+		{Table: "nat", Chain: "ISTIO_OUTPUT", Kind: DriftMissing, Rules: []string{"-A ISTIO_OUTPUT -j RETURN"}},
+		{Table: "mangle", Chain: "ISTIO_DIVERT", Kind: DriftReordered, Rules: []string{"-A a", "-A b"}, Actual: []string{"-A b", "-A a"}},
+	})
+	expected := `missing nat/ISTIO_OUTPUT: -A ISTIO_OUTPUT -j RETURN
+reordered mangle/ISTIO_DIVERT:
+  expected order:
+    -A a
+    -A b
+  installed order:
+    -A b
+    -A a
+`
+	if out.String() != expected {
+		t.Errorf("Output mismatch.\nExpected:\n%s\nActual:\n%s", expected, out.String())
+	}
+}
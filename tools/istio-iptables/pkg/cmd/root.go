@@ -48,6 +48,9 @@ var rootCmd = &cobra.Command{
 	PreRun: bindFlags,
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := constructConfig()
+		if cfg.Backend != constants.IptablesBackend && cfg.Backend != constants.NftablesBackend {
+			handleError(fmt.Errorf("unsupported backend %q, expected %q or %q", cfg.Backend, constants.IptablesBackend, constants.NftablesBackend))
+		}
 		var ext dep.Dependencies
 		if cfg.DryRun {
 			ext = &dep.StdoutStubDependencies{}
@@ -55,6 +58,21 @@ var rootCmd = &cobra.Command{
 			ext = &dep.RealDependencies{}
 		}
 
+		if cfg.Diff {
+			// Only build the rules, the commands applying them are printed like in dry run.
+			iptConfigurator := NewIptablesConfigurator(cfg, &dep.StdoutStubDependencies{})
+			iptConfigurator.buildRules()
+			drifts, err := iptConfigurator.diffRules(ext)
+			if err != nil {
+				handleError(err)
+			}
+			PrintRuleDrifts(os.Stdout, drifts)
+			if len(drifts) > 0 {
+				handleErrorWithCode(fmt.Errorf("installed rules differ from the expected rules"), constants.DiffErrorCode)
+			}
+			return
+		}
+
 		iptConfigurator := NewIptablesConfigurator(cfg, ext)
 		if !cfg.SkipRuleApply {
 			iptConfigurator.run()
@@ -78,6 +96,8 @@ func constructConfig() *config.Config {
 	cfg := &config.Config{
 		DryRun:                  viper.GetBool(constants.DryRun),
 		RestoreFormat:           viper.GetBool(constants.RestoreFormat),
+		Backend:                 viper.GetString(constants.Backend),
+		Diff:                    viper.GetBool(constants.Diff),
 		ProxyPort:               viper.GetString(constants.EnvoyPort),
 		InboundCapturePort:      viper.GetString(constants.InboundCapturePort),
 		InboundTunnelPort:       viper.GetString(constants.InboundTunnelPort),
@@ -260,6 +280,16 @@ func bindFlags(cmd *cobra.Command, args []string) {
 	}
 	viper.SetDefault(constants.RestoreFormat, true)
 
+	if err := viper.BindPFlag(constants.Backend, cmd.Flags().Lookup(constants.Backend)); err != nil {
+		handleError(err)
+	}
+	viper.SetDefault(constants.Backend, constants.IptablesBackend)
+
+	if err := viper.BindPFlag(constants.Diff, cmd.Flags().Lookup(constants.Diff)); err != nil {
+		handleError(err)
+	}
+	viper.SetDefault(constants.Diff, false)
+
 	if err := viper.BindPFlag(constants.IptablesProbePort, cmd.Flags().Lookup(constants.IptablesProbePort)); err != nil {
 		handleError(err)
 	}
@@ -345,6 +375,14 @@ func init() {
 
 	rootCmd.Flags().BoolP(constants.RestoreFormat, "f", true, "Print iptables rules in iptables-restore interpretable format")
 
+	rootCmd.Flags().String(constants.Backend, constants.IptablesBackend,
+		"The backend used to apply the rules, either \"iptables\" or \"nftables\". "+
+			"The nftables backend applies all the rules in a single \"nft -f\" transaction, in a dedicated \"istio\" table")
+
+	rootCmd.Flags().Bool(constants.Diff, false,
+		"Compare the rules with the ones installed in the network namespace instead of applying them. "+
+			"Missing, extra and reordered rules are reported, and the command exits with code 125 if there is any")
+
 	rootCmd.Flags().String(constants.IptablesProbePort, strconv.Itoa(constants.DefaultIptablesProbePort), "set listen port for failure detection")
 
 	rootCmd.Flags().Duration(constants.ProbeTimeout, constants.DefaultProbeTimeout, "failure detection timeout")
@@ -380,12 +380,22 @@ func SplitV4V6(ips []string) (ipv4 []string, ipv6 []string) {
 func (iptConfigurator *IptablesConfigurator) run() {
 	defer func() {
 		// Best effort since we don't know if the commands exist
+		if iptConfigurator.cfg.Backend == constants.NftablesBackend {
+			_ = iptConfigurator.ext.Run(constants.NFT, "list", "ruleset")
+			return
+		}
 		_ = iptConfigurator.ext.Run(constants.IPTABLESSAVE)
 		if iptConfigurator.cfg.EnableInboundIPv6 {
 			_ = iptConfigurator.ext.Run(constants.IP6TABLESSAVE)
 		}
 	}()
 
+	iptConfigurator.buildRules()
+	iptConfigurator.executeCommands()
+}
+
+// buildRules generates the rules for the configuration, without applying them.
+func (iptConfigurator *IptablesConfigurator) buildRules() {
 	// Since OUTBOUND_IP_RANGES_EXCLUDE could carry ipv4 and ipv6 ranges
 	// need to split them in different arrays one for ipv4 and one for ipv6
 	// in order to not to fail
@@ -599,7 +609,6 @@ func (iptConfigurator *IptablesConfigurator) run() {
 		iptConfigurator.iptables.InsertRuleV4(constants.ISTIOINBOUND, constants.MANGLE, 3,
 			"-p", constants.TCP, "-i", "lo", "-m", "mark", "!", "--mark", outboundMark, "-j", constants.RETURN)
 	}
-	iptConfigurator.executeCommands()
 }
 
 // HandleDNSUDP is a helper function to tackle with DNS UDP specific operations.
@@ -734,8 +743,37 @@ func (iptConfigurator *IptablesConfigurator) executeIptablesRestoreCommand(isIpv
 	return nil
 }
 
+// executeNftablesCommand applies the IPv4 and IPv6 rules in a single nftables transaction, so either all of them
+// are applied or none.
+func (iptConfigurator *IptablesConfigurator) executeNftablesCommand() error {
+	v4, err := iptConfigurator.iptables.BuildV4Nftables()
+	if err != nil {
+		return err
+	}
+	v6, err := iptConfigurator.iptables.BuildV6Nftables()
+	if err != nil {
+		return err
+	}
+	filename := fmt.Sprintf("nftables-rules-%d.nft", time.Now().UnixNano())
+	rulesFile, err := ioutil.TempFile("", filename)
+	if err != nil {
+		return fmt.Errorf("unable to create nftables file: %v", err)
+	}
+	defer os.Remove(rulesFile.Name())
+	if err := iptConfigurator.createRulesFile(rulesFile, v4+v6); err != nil {
+		return err
+	}
+	iptConfigurator.ext.RunOrFail(constants.NFT, "-f", rulesFile.Name())
+	return nil
+}
+
 func (iptConfigurator *IptablesConfigurator) executeCommands() {
-	if iptConfigurator.cfg.RestoreFormat {
+	if iptConfigurator.cfg.Backend == constants.NftablesBackend {
+		if err := iptConfigurator.executeNftablesCommand(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	} else if iptConfigurator.cfg.RestoreFormat {
 		// Execute iptables-restore
 		err := iptConfigurator.executeIptablesRestoreCommand(true)
 		if err != nil {
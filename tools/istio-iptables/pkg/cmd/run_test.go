@@ -16,9 +16,11 @@ package cmd
 
 import (
 	"net"
+	"path/filepath"
 	"reflect"
 	"testing"
 
+	testutil "istio.io/istio/pilot/test/util"
 	"istio.io/istio/tools/istio-iptables/pkg/config"
 	"istio.io/istio/tools/istio-iptables/pkg/constants"
 	dep "istio.io/istio/tools/istio-iptables/pkg/dependencies"
@@ -847,3 +849,69 @@ func TestRulesWithLoopbackIpInOutboundIpRanges(t *testing.T) {
 		t.Errorf("Output mismatch. Expected: \n%#v ; Actual: \n%#v", expected, actual)
 	}
 }
+
+func TestNftablesRules(t *testing.T) {
+	cases := []struct {
+		name   string
+		config func(cfg *config.Config)
+	}{
+		{
+			"redirect",
+			func(cfg *config.Config) {
+				cfg.InboundPortsInclude = "*"
+				cfg.InboundPortsExclude = "7000"
+				cfg.OutboundPortsExclude = "8000"
+				cfg.OutboundIPRangesInclude = "*"
+				cfg.OutboundIPRangesExclude = "1.1.0.0/16"
+				cfg.KubevirtInterfaces = "eth1"
+			},
+		},
+		{
+			"tproxy",
+			func(cfg *config.Config) {
+				cfg.InboundInterceptionMode = constants.TPROXY
+				cfg.InboundPortsInclude = "*"
+				cfg.OutboundIPRangesExclude = "1.1.0.0/16"
+				cfg.OutboundIPRangesInclude = "9.9.0.0/16"
+			},
+		},
+		{
+			"dns",
+			func(cfg *config.Config) {
+				cfg.OutboundIPRangesInclude = "*"
+				cfg.RedirectDNS = true
+				cfg.DNSServersV4 = []string{"127.0.0.53"}
+				cfg.ProxyGID = "1,2"
+				cfg.ProxyUID = "3,4"
+			},
+		},
+		{
+			"ipv6",
+			func(cfg *config.Config) {
+				cfg.EnableInboundIPv6 = true
+				cfg.InboundPortsInclude = "4000,5000"
+				cfg.OutboundIPRangesInclude = "*"
+				cfg.OutboundIPRangesExclude = "2001:db8::/32,10.0.0.0/8"
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := constructTestConfig()
+			cfg.DryRun = true
+			cfg.Backend = constants.NftablesBackend
+			tt.config(cfg)
+			iptConfigurator := NewIptablesConfigurator(cfg, &dep.StdoutStubDependencies{})
+			iptConfigurator.run()
+			v4, err := iptConfigurator.iptables.BuildV4Nftables()
+			if err != nil {
+				t.Fatal(err)
+			}
+			v6, err := iptConfigurator.iptables.BuildV6Nftables()
+			if err != nil {
+				t.Fatal(err)
+			}
+			testutil.CompareContent([]byte(v4+v6), filepath.Join("testdata", tt.name+".nft.golden"), t)
+		})
+	}
+}
@@ -51,8 +51,20 @@ const (
 	REJECT   = "REJECT"
 	REDIRECT = "REDIRECT"
 	MARK     = "MARK"
+	CONNMARK = "CONNMARK"
 )
 
+// BuiltInTargetsMap holds the iptables targets used by Istio, any other target is a chain.
+var BuiltInTargetsMap = map[string]struct{}{
+	TPROXY:   {},
+	RETURN:   {},
+	ACCEPT:   {},
+	REJECT:   {},
+	REDIRECT: {},
+	MARK:     {},
+	CONNMARK: {},
+}
+
 // iptables chains
 const (
 	ISTIOOUTPUT     = "ISTIO_OUTPUT"
@@ -89,6 +101,14 @@ const (
 	ProbeTimeout              = "probe-timeout"
 	RedirectDNS               = "redirect-dns"
 	CaptureAllDNS             = "capture-all-dns"
+	Backend                   = "backend"
+	Diff                      = "diff"
+)
+
+// Backends used to apply the rules
+const (
+	IptablesBackend = "iptables"
+	NftablesBackend = "nftables"
 )
 
 const (
@@ -110,6 +130,15 @@ const (
 	IP6TABLESRESTORE = "ip6tables-restore"
 	IP6TABLESSAVE    = "ip6tables-save"
 	IP               = "ip"
+	NFT              = "nft"
+)
+
+// Constants for the nftables ruleset
+const (
+	// NftablesTable is the name of the table holding all the Istio rules, in each address family.
+	NftablesTable = "istio"
+	NftablesIPv4  = "ip"
+	NftablesIPv6  = "ip6"
 )
 
 // Constants for syscall
@@ -126,6 +155,8 @@ const (
 const (
 	ValidationContainerName = "istio-validation"
 	ValidationErrorCode     = 126
+	// DiffErrorCode is the exit code when the installed rules differ from the expected ones.
+	DiffErrorCode = 125
 )
 
 // DNS ports
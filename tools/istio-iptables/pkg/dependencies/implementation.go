@@ -154,3 +154,15 @@ func (r *RealDependencies) RunQuietlyAndIgnore(cmd string, args ...string) {
 		_ = r.execute(cmd, true, args...)
 	}
 }
+
+// RunWithOutput runs a command and returns its standard output
+func (r *RealDependencies) RunWithOutput(cmd string, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	externalCommand := exec.Command(cmd, args...)
+	externalCommand.Stdout = &stdout
+	externalCommand.Stderr = os.Stderr
+	if err := externalCommand.Run(); err != nil {
+		return "", fmt.Errorf("failed to execute: %s %s, %v", cmd, strings.Join(args, " "), err)
+	}
+	return stdout.String(), nil
+}
@@ -37,3 +37,9 @@ func (s *StdoutStubDependencies) Run(cmd string, args ...string) error {
 func (s *StdoutStubDependencies) RunQuietlyAndIgnore(cmd string, args ...string) {
 	fmt.Printf("%s %s\n", cmd, strings.Join(args, " "))
 }
+
+// RunWithOutput runs a command and returns an empty output
+func (s *StdoutStubDependencies) RunWithOutput(cmd string, args ...string) (string, error) {
+	fmt.Printf("%s %s\n", cmd, strings.Join(args, " "))
+	return "", nil
+}